@@ -1,54 +1,91 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"cruder/internal/app"
+	"cruder/internal/config"
 	"cruder/pkg/logger"
 )
 
+//go:generate sh -c "cd .. && swag init -g ./cmd/main.go -o ./docs"
+
+// @title                       Cruder API
+// @version                     1.0
+// @description                 CRUD service for users, authenticated with per-client API keys.
+// @BasePath                    /
+// @securityDefinitions.apikey  APIKeyAuth
+// @in                          header
+// @name                        X-API-Key
 func main() {
-	envOptions := map[string]string{
-		"LOG_OUTPUT": os.Getenv("LOG_OUTPUT"),
-		"LOG_FILE":   os.Getenv("LOG_FILE"),
-		"LOG_LEVEL":  os.Getenv("LOG_LEVEL"),
+	os.Exit(run())
+}
+
+// run starts the service and blocks until it is signalled to stop. It
+// returns the exit code rather than exiting, so its deferred closes run on
+// every path.
+func run() int {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
 	}
-	logOptions := logger.OptionsFromEnv(envOptions)
 
-	appLogger, err := logger.Configure(logOptions)
+	appLogger, err := logger.Configure(cfg.Log)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to configure logger: %v\n", err)
-		os.Exit(1)
+		return 1
 	}
+	defer func() {
+		if err := appLogger.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to close logger: %v\n", err)
+		}
+	}()
 
-	dsn := os.Getenv("POSTGRES_DSN")
-	if dsn == "" {
-		appLogger.Error("no postgres DSN is defined, exiting")
-		os.Exit(1)
+	for _, warning := range cfg.Warnings {
+		appLogger.Warn("configuration warning", slog.String("warning", warning))
 	}
 
-	application, err := app.New(dsn)
+	application, err := app.New(cfg)
 	if err != nil {
 		appLogger.Error("failed to initialize application", slog.String("error", err.Error()))
-		os.Exit(1)
+		return 1
 	}
 	appLogger.Info("application initialized")
 	defer func() {
 		if err := application.Close(); err != nil {
 			appLogger.Warn("failed to close application cleanly", slog.String("error", err.Error()))
 		}
-		if appLogger != nil {
-			if err := appLogger.Close(); err != nil {
-				fmt.Fprintf(os.Stderr, "failed to close logger: %v\n", err)
-			}
-		}
 	}()
 
-	appLogger.Info("starting http server")
-	if err := application.Engine.Run(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	serveErr := make(chan error, 1)
+	go func() {
+		appLogger.Info("starting http server", slog.String("addr", cfg.Addr))
+		serveErr <- application.Server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
 		appLogger.Error("failed to run server", slog.String("error", err.Error()))
-		os.Exit(1)
+		return 1
+	case err := <-application.Failed():
+		appLogger.Error("failed to initialize application", slog.String("error", err.Error()))
+		return 1
+	case <-ctx.Done():
+	}
+
+	appLogger.Info("shutting down http server", slog.Duration("http.shutdown_timeout", cfg.Server.Shutdown))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.Shutdown)
+	defer cancel()
+	if err := application.Server.Shutdown(shutdownCtx); err != nil {
+		appLogger.Warn("http server did not shut down cleanly", slog.String("error", err.Error()))
 	}
+	return 0
 }
@@ -0,0 +1,9 @@
+package docs
+
+import "embed"
+
+// FS holds the spec generated by swag (`make swagger`), compiled into the
+// binary so serving it needs no files at runtime.
+//
+//go:embed swagger.json swagger.yaml
+var FS embed.FS
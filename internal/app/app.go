@@ -1,11 +1,20 @@
 package app
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"io"
+	stdlog "log"
 	"log/slog"
+	"net/http"
 	"os"
+	"path/filepath"
+	"sync/atomic"
 	"time"
 
+	"cruder/docs"
+	"cruder/internal/config"
 	"cruder/internal/controller"
 	"cruder/internal/handler"
 	"cruder/internal/middleware"
@@ -14,21 +23,63 @@ import (
 	"cruder/pkg/logger"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
 type App struct {
-	Engine  *gin.Engine
+	Engine *gin.Engine
+	// Server serves Engine on the configured address with the configured
+	// connection timeouts.
+	Server  *http.Server
 	Service *service.Service
 
 	Logger *logger.Logger
+	// baseLogger is Logger without the app component, for the components
+	// start builds.
+	baseLogger *logger.Logger
 
-	conn repository.DatabaseConnection
+	conn     repository.DatabaseConnection
+	replica  repository.DatabaseConnection
+	webhooks *service.WebhookPublisher
+	// shutdownTimeout bounds how long Close waits for queued webhooks.
+	shutdownTimeout time.Duration
+	// keys is set by start before startupDone closes.
+	keys *repository.APIKeyListener
+
+	// ready opens middleware.ReadinessGate once start has finished the
+	// setup that needs the database. stopStartup cancels start on Close,
+	// startupDone closes when it returns, and failed carries its error.
+	ready       *atomic.Bool
+	stopStartup context.CancelFunc
+	startupDone chan struct{}
+	failed      chan error
+
+	// Maintenance turns away writes through middleware.Maintenance while
+	// set. The admin switch at handler.MaintenancePath flips it.
+	Maintenance *atomic.Bool
+
+	// accessLog is the writer behind middleware.AccessLog, or nil when
+	// ACCESS_LOG_FILE is unset.
+	accessLog io.Writer
 }
 
-func New(dsn string) (*App, error) {
-	if dsn == "" {
+// New wires the application from cfg, which config.Load has already
+// validated.
+func New(cfg config.Config) (_ *App, err error) {
+	if cfg.DSN == "" {
 		return nil, fmt.Errorf("DSN cannot be empty")
 	}
+	// cleanup undoes, newest first, what was set up before a failure
+	var cleanup []func()
+	defer func() {
+		if err != nil {
+			for i := len(cleanup) - 1; i >= 0; i-- {
+				cleanup[i]()
+			}
+		}
+	}()
 
 	baseLogger := logger.Get()
 	appLogger := baseLogger.With(slog.String("component", "app"))
@@ -37,34 +88,249 @@ func New(dsn string) (*App, error) {
 	gin.DefaultWriter = logger.Writer(baseLogger, slog.LevelInfo)
 	gin.DefaultErrorWriter = logger.Writer(baseLogger, slog.LevelError)
 
-	appLogger.Info("connecting to database")
-	dbConn, err := repository.NewPostgresConnection(dsn)
+	// The pools are opened without connecting, so the server can listen and
+	// answer 503 through the readiness gate while the database comes up;
+	// start does the rest once it answers.
+	dbConn, err := repository.OpenPostgresConnection(cfg.DSN)
 	if err != nil {
-		appLogger.Error("failed to connect to database", slog.String("error", err.Error()))
-		return nil, fmt.Errorf("connect to database: %w", err)
+		return nil, fmt.Errorf("open database: %w", err)
 	}
-	appLogger.Info("database connection established")
+	cleanup = append(cleanup, func() { _ = dbConn.DB().Close() })
 
-	repos := repository.NewRepository(dbConn.DB())
-	apiKeyTTL := parseAPIKeyTTL(appLogger)
-	services := service.NewService(repos, apiKeyTTL)
-	controllers := controller.NewController(services)
+	var replicaConn *repository.PostgresConnection
+	var replicaDB *sql.DB
+	if cfg.ReplicaDSN != "" {
+		replicaConn, err = repository.OpenPostgresConnection(cfg.ReplicaDSN)
+		if err != nil {
+			return nil, fmt.Errorf("open read replica: %w", err)
+		}
+		replicaDB = replicaConn.DB()
+		cleanup = append(cleanup, func() { _ = replicaDB.Close() })
+	}
+
+	repository.SetSlowQueryThreshold(cfg.SlowQuery)
+	repos := repository.NewRepositoryWithOptions(dbConn.DB(), repository.Options{
+		ReadRetry:   cfg.ReadRetry,
+		UserCache:   cfg.UserCache,
+		ReadReplica: replicaDB,
+		Logger:      baseLogger,
+	})
+	apiKeyOpts := cfg.APIKeys
+	if apiKeyOpts.RefreshInterval > 0 && apiKeyOpts.RefreshInterval >= apiKeyOpts.CacheTTL {
+		appLogger.Warn("API_KEY_REFRESH_INTERVAL is not shorter than API_KEY_CACHE_TTL; cached keys may expire before a refresh",
+			slog.Duration("api_key.cache_ttl", apiKeyOpts.CacheTTL),
+			slog.Duration("api_key.refresh_interval", apiKeyOpts.RefreshInterval),
+		)
+	}
+	userOpts := cfg.Users
+	var webhooks *service.WebhookPublisher
+	if len(cfg.Webhooks.URLs) > 0 {
+		webhookOpts := cfg.Webhooks
+		webhookOpts.Logger = baseLogger
+		webhooks = service.NewWebhookPublisher(webhookOpts)
+		userOpts.Events = webhooks
+		cleanup = append(cleanup, func() { closeWebhooks(webhooks, cfg.Server.Shutdown, appLogger) })
+		appLogger.Info("webhooks enabled", slog.Int("webhook.urls", len(cfg.Webhooks.URLs)))
+	}
+	services := service.NewService(repos, apiKeyOpts, userOpts, baseLogger)
+	cleanup = append(cleanup, func() { _ = services.Close() })
+	controllers := controller.NewController(services, cfg.UserController)
+	accessLog, err := openAccessLog(cfg.AccessLogFile)
+	if err != nil {
+		return nil, fmt.Errorf("open access log: %w", err)
+	}
+	cleanup = append(cleanup, func() { closeAccessLog(accessLog) })
 
 	router := gin.New()
+	// gin trusts X-Forwarded-For from anyone by default, which would let a
+	// client pick the IP the lockout and rate limit key on
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("set trusted proxies: %w", err)
+	}
+	if accessLog != nil {
+		// outermost, so the line records the status every other layer settled on
+		router.Use(middleware.AccessLog(accessLog, cfg.AccessLogFormat))
+		appLogger.Info("access log enabled", slog.String("access_log.file", cfg.AccessLogFile), slog.String("access_log.format", string(cfg.AccessLogFormat)))
+	}
 	router.Use(
-		middleware.Recovery(appLogger),
-		middleware.RequestLogger(appLogger),
-		middleware.APIKeyAuth(services.APIKeys, baseLogger),
+		middleware.RecoveryWithOptions(appLogger, cfg.Recovery),
+		middleware.RequestLoggerWithOptions(appLogger, middleware.RequestLoggerOptions{
+			SamplePerSecond: cfg.LogSamplePerSec,
+		}),
+		middleware.Metrics(),
+		middleware.BodyLimit(cfg.MaxBodyBytes),
+		middleware.HeaderLimit(cfg.MaxContentHeaderBytes),
 	)
-	handler.New(router, controllers.Users)
+	// registered before auth so scrapers and probes don't need an API key
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	health := controller.NewHealthController(healthChecks(dbConn.DB(), replicaDB))
+	router.GET("/healthz", health.Live)
+	router.GET("/health/detail", health.Detail)
+	adminAuth := middleware.AdminAuth(cfg.AdminAPIKey, baseLogger)
+	if cfg.EnablePprof {
+		// ahead of the readiness gate, so a pod stuck waiting can be profiled
+		handler.RegisterPprof(router, adminAuth)
+		appLogger.Warn("pprof enabled", slog.String("path", handler.PprofPrefix))
+	}
+	maintenance := new(atomic.Bool)
+	handler.RegisterMaintenance(router, adminAuth, maintenance, appLogger)
+	ready := new(atomic.Bool)
+	if err := registerDocs(router); err != nil {
+		return nil, err
+	}
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		router.Use(middleware.CORS(cfg.CORS))
+		appLogger.Info("cors enabled", slog.Any("cors.allowed_origins", cfg.CORS.AllowedOrigins), slog.Bool("cors.allow_credentials", cfg.CORS.AllowCredentials))
+	}
+	// the routes above answer while the database is unreachable
+	router.Use(middleware.ReadinessGate(ready, baseLogger))
+	router.Use(middleware.APIKeyAuthWithOptions(services.APIKeys, baseLogger, cfg.APIKeyAuth))
+	router.Use(middleware.Maintenance(maintenance, cfg.MaintenanceRetryAfter, baseLogger))
+	if rateLimit := cfg.RateLimit; rateLimit.RequestsPerSecond > 0 {
+		router.Use(middleware.RateLimit(rateLimit, baseLogger))
+		appLogger.Info("rate limiting enabled",
+			slog.Float64("rate_limit.rps", rateLimit.RequestsPerSecond),
+			slog.Int("rate_limit.burst", rateLimit.Burst),
+		)
+	}
+	router.Use(middleware.Timeout(cfg.RequestTimeout, handler.StreamingRoutes...))
+	createIdempotency := middleware.Idempotency(services.Idempotency, http.StatusCreated, baseLogger)
+	handler.New(router, controllers, adminAuth, createIdempotency, middleware.BodyLimit(cfg.MaxBulkBodyBytes))
 	appLogger.Info("http router configured")
 
-	return &App{
-		Engine:  router,
-		Service: services,
-		Logger:  appLogger,
-		conn:    dbConn,
-	}, nil
+	startupCtx, stopStartup := context.WithCancel(context.Background())
+	a := &App{
+		Engine:          router,
+		Server:          newServer(cfg, router, baseLogger),
+		Service:         services,
+		Logger:          appLogger,
+		baseLogger:      baseLogger,
+		conn:            dbConn,
+		webhooks:        webhooks,
+		shutdownTimeout: cfg.Server.Shutdown,
+		ready:           ready,
+		stopStartup:     stopStartup,
+		startupDone:     make(chan struct{}),
+		failed:          make(chan error, 1),
+		Maintenance:     maintenance,
+		accessLog:       accessLog,
+	}
+	if replicaConn != nil {
+		a.replica = replicaConn
+	}
+	go a.start(startupCtx, cfg, dbConn, replicaConn)
+	return a, nil
+}
+
+// start finishes the setup that needs the database once it answers, then
+// opens the readiness gate. A failed step is not retried, since it needs an
+// operator; its error goes to Failed and the gate stays closed.
+func (a *App) start(ctx context.Context, cfg config.Config, primary, replica *repository.PostgresConnection) {
+	defer close(a.startupDone)
+	err := a.prepare(ctx, cfg, primary, replica)
+	switch {
+	case err == nil:
+		a.ready.Store(true)
+		a.Logger.Info("database reachable; serving requests")
+	case ctx.Err() == nil:
+		a.failed <- err
+	}
+}
+
+// prepare waits for the database, then warms the pools, migrates, checks
+// the full_name index, runs the self-test, and starts the API key listener,
+// as configured.
+func (a *App) prepare(ctx context.Context, cfg config.Config, primary, replica *repository.PostgresConnection) error {
+	if err := awaitDatabase(ctx, primary.DB(), "database", a.Logger); err != nil {
+		return err
+	}
+	if err := primary.Warmup(ctx, cfg.MinIdleConns); err != nil {
+		return fmt.Errorf("connect to database: %w", err)
+	}
+	if cfg.RunMigrations {
+		a.Logger.Info("running database migrations")
+		if err := migrate(ctx, primary.DB(), a.Logger); err != nil {
+			return fmt.Errorf("run migrations: %w", err)
+		}
+	}
+	if cfg.Users.UniqueFullName {
+		if err := ensureFullNameIndex(ctx, primary.DB(), cfg.RunMigrations, a.Logger); err != nil {
+			return err
+		}
+	}
+	if replica != nil {
+		if err := awaitDatabase(ctx, replica.DB(), "read replica", a.Logger); err != nil {
+			return err
+		}
+		if err := replica.Warmup(ctx, cfg.MinIdleConns); err != nil {
+			return fmt.Errorf("connect to read replica: %w", err)
+		}
+	}
+	if cfg.SelfTest {
+		a.Logger.Info("running startup self-test")
+		if err := service.SelfTest(a.Service.Users, a.baseLogger); err != nil {
+			return fmt.Errorf("startup self-test: %w", err)
+		}
+	}
+	if cfg.APIKeyListen {
+		keys, err := repository.ListenAPIKeyChanges(cfg.DSN, a.Service.APIKeys, a.baseLogger)
+		if err != nil {
+			return fmt.Errorf("listen for api key changes: %w", err)
+		}
+		a.keys = keys
+		a.Logger.Info("listening for api key changes", slog.String("channel", repository.APIKeyChangesChannel))
+	}
+	return nil
+}
+
+// readinessPingInterval is the wait between readiness pings while the
+// database is unreachable.
+const readinessPingInterval = time.Second
+
+// awaitDatabase pings db, named name in the log, until it answers or ctx
+// ends. The first ping runs at once, so a healthy start doesn't wait.
+func awaitDatabase(ctx context.Context, db *sql.DB, name string, log *logger.Logger) error {
+	ticker := time.NewTicker(readinessPingInterval)
+	defer ticker.Stop()
+	for {
+		err := db.PingContext(ctx)
+		if err == nil {
+			log.Info(name + " connection established")
+			return nil
+		}
+		log.Warn(name+" not reachable yet; answering 503", slog.String("error", err.Error()))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Ready reports whether the readiness gate is open.
+func (a *App) Ready() bool {
+	return a.ready.Load()
+}
+
+// Failed delivers the error that stopped startup after New returned, such
+// as a migration that doesn't apply. The gate then stays closed for good,
+// so the caller should shut down.
+func (a *App) Failed() <-chan error {
+	return a.failed
+}
+
+// newServer builds the HTTP server for handler. Its own errors, such as
+// failed TLS handshakes or headers that arrive too slowly, go to the log.
+func newServer(cfg config.Config, handler http.Handler, log *logger.Logger) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.Server.ReadHeader,
+		ReadTimeout:       cfg.Server.Read,
+		WriteTimeout:      cfg.Server.Write,
+		IdleTimeout:       cfg.Server.Idle,
+		ErrorLog:          stdlog.New(logger.Writer(log, slog.LevelWarn), "", 0),
+	}
 }
 
 func (a *App) Close() error {
@@ -72,23 +338,93 @@ func (a *App) Close() error {
 		return nil
 	}
 
+	a.stopStartup()
+	<-a.startupDone
+	if a.keys != nil {
+		if err := a.keys.Close(); err != nil {
+			a.Logger.Warn("failed to stop api key listener", slog.String("error", err.Error()))
+		}
+	}
+	if err := a.Service.Close(); err != nil {
+		a.Logger.Warn("failed to stop services", slog.String("error", err.Error()))
+	}
+	if a.webhooks != nil {
+		// drains queued deliveries, so changes made before shutdown still go out
+		closeWebhooks(a.webhooks, a.shutdownTimeout, a.Logger)
+	}
+
+	if a.replica != nil {
+		a.Logger.Info("closing read replica connection")
+		if err := a.replica.DB().Close(); err != nil {
+			a.Logger.Warn("failed to close read replica connection", slog.String("error", err.Error()))
+		}
+	}
+
+	// after the server has drained, so the last requests still get lines
+	closeAccessLog(a.accessLog)
+
 	a.Logger.Info("closing database connection")
 	return a.conn.DB().Close()
 }
 
-func parseAPIKeyTTL(log *logger.Logger) time.Duration {
-	value := os.Getenv("API_KEY_CACHE_TTL")
-	if value == "" {
-		return 5 * time.Minute
+// closeWebhooks waits up to timeout for queued webhook deliveries; those
+// still pending then are dropped and logged by the publisher.
+func closeWebhooks(webhooks *service.WebhookPublisher, timeout time.Duration, log *logger.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := webhooks.Close(ctx); err != nil {
+		log.Warn("webhook deliveries did not finish before shutdown", slog.Duration("http.shutdown_timeout", timeout))
+	}
+}
+
+// openAccessLog opens the access log destination: stdout for "-", otherwise
+// path, appended to and created if missing. It returns nil for "".
+func openAccessLog(path string) (io.Writer, error) {
+	switch path {
+	case "":
+		return nil, nil
+	case "-":
+		return os.Stdout, nil
 	}
-	ttl, err := time.ParseDuration(value)
+	return os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+}
+
+// closeAccessLog closes an access log file opened by openAccessLog, leaving
+// stdout open.
+func closeAccessLog(w io.Writer) {
+	if f, ok := w.(*os.File); ok && f != os.Stdout {
+		_ = f.Close()
+	}
+}
+
+// registerDocs serves the OpenAPI spec embedded at build time and a Swagger
+// UI that loads it.
+func registerDocs(router *gin.Engine) error {
+	spec, err := docs.FS.ReadFile("swagger.json")
 	if err != nil {
-		log.Warn("invalid API_KEY_CACHE_TTL, using default", slog.String("value", value), slog.String("error", err.Error()))
-		return 5 * time.Minute
+		return fmt.Errorf("read embedded openapi spec: %w", err)
 	}
-	if ttl <= 0 {
-		log.Warn("non-positive API_KEY_CACHE_TTL, using default", slog.String("value", value))
-		return 5 * time.Minute
+	router.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", spec)
+	})
+	ui := ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("/openapi.json"))
+	router.GET("/docs/*any", func(c *gin.Context) {
+		if c.Param("any") == "/" {
+			c.Redirect(http.StatusMovedPermanently, "/docs/index.html")
+			return
+		}
+		ui(c)
+	})
+	return nil
+}
+
+// healthChecks lists the dependencies GET /health/detail reports on. New
+// dependencies register here. A configured replica serves every user read,
+// so it is as critical as the primary.
+func healthChecks(primary, replica *sql.DB) []service.HealthCheck {
+	checks := []service.HealthCheck{{Name: "db", Critical: true, Check: primary.PingContext}}
+	if replica != nil {
+		checks = append(checks, service.HealthCheck{Name: "replica", Critical: true, Check: replica.PingContext})
 	}
-	return ttl
+	return checks
 }
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cruder/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// unreachableDSN points at a port nothing listens on, so every ping is
+// refused at once.
+const unreachableDSN = "host=127.0.0.1 port=1 user=postgres dbname=postgres sslmode=disable connect_timeout=1"
+
+func TestNew_GatesRequestsUntilDatabaseAnswers(t *testing.T) {
+	// Given: a database that refuses connections
+	gin.SetMode(gin.TestMode)
+	t.Setenv("POSTGRES_DSN", unreachableDSN)
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	// When: starting the application
+	a, err := New(cfg)
+
+	// Then: startup doesn't wait for the database
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, a.Close()) })
+	require.False(t, a.Ready())
+	serve := func(path string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		a.Engine.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		return resp
+	}
+
+	// Then: the API answers 503 with Retry-After while the probes answer
+	resp := serve("/api/v1/users/")
+	require.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	require.Equal(t, "1", resp.Header().Get("Retry-After"))
+	require.JSONEq(t, `{"error":"service is starting"}`, resp.Body.String())
+	require.Equal(t, http.StatusOK, serve("/healthz").Code)
+	require.Equal(t, http.StatusServiceUnavailable, serve("/health/detail").Code)
+	select {
+	case err := <-a.Failed():
+		t.Fatalf("startup failed: %v", err)
+	default:
+	}
+}
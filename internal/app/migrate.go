@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"cruder/internal/repository"
+	"cruder/migrations"
+	"cruder/pkg/logger"
+
+	"github.com/pressly/goose/v3"
+)
+
+// migrate applies any pending embedded migrations and logs each version it
+// applied. It stops at the first failing migration.
+func migrate(ctx context.Context, db *sql.DB, log *logger.Logger) error {
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, migrations.FS)
+	if err != nil {
+		return fmt.Errorf("create migration provider: %w", err)
+	}
+
+	results, err := provider.Up(ctx)
+	var partial *goose.PartialError
+	if errors.As(err, &partial) {
+		results = partial.Applied
+	}
+	for _, result := range results {
+		log.Info("applied migration",
+			slog.Int64("migration.version", result.Source.Version),
+			slog.Duration("migration.duration", result.Duration),
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("apply migrations: %w", err)
+	}
+	if len(results) == 0 {
+		log.Info("database schema is up to date")
+	}
+	return nil
+}
+
+// ensureFullNameIndex makes sure the unique index behind UNIQUE_FULL_NAME
+// exists. It creates the index when create is set, as with RUN_MIGRATIONS,
+// and otherwise fails if the index is missing, so the flag can't be on while
+// nothing enforces it.
+func ensureFullNameIndex(ctx context.Context, db *sql.DB, create bool, log *logger.Logger) error {
+	if create {
+		if _, err := db.ExecContext(ctx, repository.CreateFullNameIndexSQL); err != nil {
+			return fmt.Errorf("create %s (do users share a full_name?): %w", repository.FullNameIndex, err)
+		}
+		log.Info("full_name uniqueness enforced", slog.String("index", repository.FullNameIndex))
+		return nil
+	}
+
+	var exists bool
+	if err := db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM pg_indexes WHERE schemaname = current_schema() AND tablename = 'users' AND indexname = $1)`,
+		repository.FullNameIndex).Scan(&exists); err != nil {
+		return fmt.Errorf("look up %s: %w", repository.FullNameIndex, err)
+	}
+	if !exists {
+		return fmt.Errorf("UNIQUE_FULL_NAME is set but index %s is missing; create it or set RUN_MIGRATIONS", repository.FullNameIndex)
+	}
+	return nil
+}
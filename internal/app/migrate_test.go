@@ -0,0 +1,79 @@
+package app
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"regexp"
+	"testing"
+
+	"cruder/internal/repository"
+	"cruder/pkg/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	discardLogger    = logger.FromSlog(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	fullNameIndexSQL = regexp.QuoteMeta(repository.CreateFullNameIndexSQL)
+	indexLookupSQL   = regexp.QuoteMeta(`FROM pg_indexes WHERE schemaname = current_schema() AND tablename = 'users' AND indexname = $1`)
+)
+
+func TestEnsureFullNameIndex_MissingWithoutMigrations(t *testing.T) {
+	// Given: UNIQUE_FULL_NAME without RUN_MIGRATIONS, and no index yet
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(indexLookupSQL).
+		WithArgs(repository.FullNameIndex).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	// When: checking for the index
+	err = ensureFullNameIndex(context.Background(), db, false, discardLogger)
+
+	// Then: startup fails rather than run with nothing enforcing the flag
+	require.ErrorContains(t, err, "index users_full_name_key is missing")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEnsureFullNameIndex_PresentWithoutMigrations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(indexLookupSQL).
+		WithArgs(repository.FullNameIndex).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	require.NoError(t, ensureFullNameIndex(context.Background(), db, false, discardLogger))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEnsureFullNameIndex_CreatesWithMigrations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectExec(fullNameIndexSQL).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, ensureFullNameIndex(context.Background(), db, true, discardLogger))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEnsureFullNameIndex_CreateFailsOnDuplicates(t *testing.T) {
+	// Given: existing users who already share a full_name
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectExec(fullNameIndexSQL).
+		WillReturnError(&pq.Error{Code: "23505", Message: `could not create unique index "users_full_name_key"`})
+
+	// When: creating the index under RUN_MIGRATIONS
+	err = ensureFullNameIndex(context.Background(), db, true, discardLogger)
+
+	// Then: startup fails and points at the duplicates
+	require.ErrorContains(t, err, "do users share a full_name?")
+	var pqErr *pq.Error
+	require.ErrorAs(t, err, &pqErr)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,453 @@
+// Package config reads the service's environment variables once at startup,
+// optionally layered over a JSON config file.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"cruder/internal/controller"
+	"cruder/internal/middleware"
+	"cruder/internal/repository"
+	"cruder/internal/service"
+	"cruder/pkg/logger"
+
+	"github.com/lib/pq"
+)
+
+const defaultAddr = ":8080"
+
+// Config is every setting the service reads from the environment, parsed
+// and validated.
+type Config struct {
+	// Addr is the HTTP listen address: HTTP_ADDR, else ":"+PORT, else :8080.
+	Addr   string
+	Server ServerTimeouts
+
+	DSN          string
+	ReplicaDSN   string
+	MinIdleConns int
+	// RunMigrations applies the embedded migrations before serving.
+	RunMigrations bool
+	// SelfTest exercises the user CRUD path before serving.
+	SelfTest  bool
+	SlowQuery time.Duration
+	ReadRetry repository.RetryOptions
+	UserCache repository.CacheOptions
+
+	Log             logger.Options
+	LogSamplePerSec int
+	// AccessLogFile, when set, receives one Apache-style line per request
+	// in AccessLogFormat, apart from the structured log. "-" is stdout.
+	AccessLogFile   string
+	AccessLogFormat middleware.AccessLogFormat
+
+	APIKeys      service.APIKeyServiceOptions
+	APIKeyListen bool
+	APIKeyAuth   middleware.APIKeyAuthOptions
+	AdminAPIKey  string
+	// EnablePprof mounts net/http/pprof under /debug/pprof, behind the
+	// admin key.
+	EnablePprof bool
+
+	// Users carries only the settings that come from the environment; the
+	// app fills in the rest.
+	Users          service.UserServiceOptions
+	Webhooks       service.WebhookOptions
+	UserController controller.UserControllerOptions
+
+	RequestTimeout        time.Duration
+	MaxBodyBytes          int64
+	MaxBulkBodyBytes      int64 // replaces MaxBodyBytes on the bulk routes
+	MaxContentHeaderBytes int
+	RateLimit             middleware.RateLimitOptions
+	CORS                  middleware.CORSOptions
+	Recovery              middleware.RecoveryOptions
+	// TrustedProxies lists the proxy addresses and CIDRs whose
+	// X-Forwarded-For is believed when taking the client IP. Empty trusts
+	// none, so the client IP is the peer address.
+	TrustedProxies []string
+	// MaintenanceRetryAfter is the Retry-After sent with writes refused
+	// while maintenance mode is on.
+	MaintenanceRetryAfter time.Duration
+
+	// Warnings are problems that don't stop startup, such as unknown keys in
+	// the config file. They are returned here because the logger isn't set
+	// up yet while loading.
+	Warnings []string
+}
+
+// ServerTimeouts bound each phase of a connection on the HTTP server. Zero
+// disables a limit; ReadHeader is always set, since it is what stops
+// slow-loris clients.
+type ServerTimeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	// Write covers reading the body and the handler, so it must outlast
+	// RequestTimeout or the timeout middleware's 503 could not be sent.
+	Write time.Duration
+	Idle  time.Duration
+	// Shutdown is how long in-flight requests get to finish on SIGTERM.
+	Shutdown time.Duration
+}
+
+// Error lists every problem Load found, so one failed start reports them all.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Load reads the configuration from the process environment. When
+// CONFIG_FILE names a JSON file, its values fill in every variable the
+// environment leaves unset.
+func Load() (Config, error) {
+	return load(os.Getenv)
+}
+
+func load(getenv func(string) string) (Config, error) {
+	e := &env{getenv: getenv, read: map[string]bool{}}
+	if path := strings.TrimSpace(getenv("CONFIG_FILE")); path != "" {
+		file, err := readFile(path)
+		if err != nil {
+			e.fail("CONFIG_FILE", "could not be loaded: %v", err)
+		}
+		e.file = file
+	}
+	var cfg Config
+
+	cfg.Addr = e.str("HTTP_ADDR")
+	if cfg.Addr == "" {
+		cfg.Addr = defaultAddr
+		if port := e.str("PORT"); port != "" {
+			cfg.Addr = ":" + port
+		}
+	}
+
+	cfg.DSN = e.str("POSTGRES_DSN")
+	if cfg.DSN == "" {
+		e.fail("POSTGRES_DSN", "is required")
+	} else {
+		e.dsn("POSTGRES_DSN", cfg.DSN)
+	}
+	if cfg.ReplicaDSN = e.str("POSTGRES_REPLICA_DSN"); cfg.ReplicaDSN != "" {
+		e.dsn("POSTGRES_REPLICA_DSN", cfg.ReplicaDSN)
+	}
+	cfg.MinIdleConns = e.integer("POSTGRES_MIN_IDLE_CONNS", 0, 0)
+	cfg.RunMigrations = e.boolean("RUN_MIGRATIONS")
+	cfg.SelfTest = e.boolean("SELF_TEST")
+	cfg.SlowQuery = time.Duration(e.integer("SLOW_QUERY_MS", 500, 0)) * time.Millisecond
+	cfg.ReadRetry = repository.RetryOptions{
+		Retries:   e.integer("DB_READ_RETRIES", 0, 0),
+		BaseDelay: e.duration("DB_RETRY_BASE_DELAY", 50*time.Millisecond, false),
+	}
+	cfg.UserCache = repository.CacheOptions{
+		MaxEntries: e.integer("USER_CACHE_SIZE", 0, 0),
+		TTL:        e.duration("USER_CACHE_TTL", time.Minute, false),
+	}
+
+	cfg.Log = logger.Options{
+		Output:      e.str("LOG_OUTPUT"),
+		FilePath:    e.str("LOG_FILE"),
+		Level:       e.str("LOG_LEVEL"),
+		Format:      e.str("LOG_FORMAT"),
+		TimeFormat:  e.str("LOG_TIME_FORMAT"),
+		MaxSizeMB:   e.integer("LOG_MAX_SIZE_MB", 0, 0),
+		MaxBackups:  e.integer("LOG_MAX_BACKUPS", 0, 0),
+		OTelLogs:    e.boolean("OTEL_LOGS"),
+		MaskKeys:    e.list("LOG_MASK_KEYS"),
+		ServiceName: e.str("SERVICE_NAME"),
+		Environment: e.str("DEPLOYMENT_ENVIRONMENT"),
+	}
+	if cfg.Log.ServiceName == "" {
+		cfg.Log.ServiceName = "cruder"
+	}
+	if err := cfg.Log.Validate(); err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			e.fail("logging", "%s", line)
+		}
+	}
+	cfg.LogSamplePerSec = e.integer("LOG_SAMPLE_PER_SEC", 0, 0)
+	cfg.AccessLogFile = e.str("ACCESS_LOG_FILE")
+	if path := cfg.AccessLogFile; path != "" && path != "-" && !filepath.IsAbs(path) {
+		e.fail("ACCESS_LOG_FILE", "must be an absolute path or -, got %q", path)
+	}
+	cfg.AccessLogFormat = middleware.AccessLogCombinedDuration
+	if value := e.str("ACCESS_LOG_FORMAT"); value != "" {
+		format, err := middleware.ParseAccessLogFormat(value)
+		if err != nil {
+			e.fail("ACCESS_LOG_FORMAT", "must be common, combined, or combined_duration, got %q", value)
+		}
+		cfg.AccessLogFormat = format
+	}
+
+	cfg.APIKeys = service.APIKeyServiceOptions{
+		CacheTTL:        e.duration("API_KEY_CACHE_TTL", 5*time.Minute, false),
+		RefreshInterval: e.duration("API_KEY_REFRESH_INTERVAL", 0, true),
+		SweepInterval:   e.duration("API_KEY_SWEEP_INTERVAL", 0, true),
+		StaleOnError:    e.duration("API_KEY_STALE_ON_ERROR", 0, true),
+	}
+	cfg.APIKeyListen = e.boolean("API_KEY_LISTEN")
+	cfg.APIKeyAuth = middleware.APIKeyAuthOptions{
+		Lockout: middleware.LockoutOptions{
+			Threshold: e.integer("AUTH_LOCKOUT_THRESHOLD", 10, 0),
+			BaseDelay: e.duration("AUTH_LOCKOUT_BASE_DELAY", time.Second, false),
+			MaxDelay:  e.duration("AUTH_LOCKOUT_MAX_DELAY", 15*time.Minute, false),
+		},
+	}
+	if lockout := cfg.APIKeyAuth.Lockout; lockout.MaxDelay < lockout.BaseDelay {
+		e.fail("AUTH_LOCKOUT_MAX_DELAY", "must be at least AUTH_LOCKOUT_BASE_DELAY (%s), got %s", lockout.BaseDelay, lockout.MaxDelay)
+	}
+	cfg.AdminAPIKey = e.str("ADMIN_API_KEY")
+	if cfg.EnablePprof = e.boolean("ENABLE_PPROF"); cfg.EnablePprof && cfg.AdminAPIKey == "" {
+		// without an admin key every profile request would be refused
+		e.fail("ADMIN_API_KEY", "is required when ENABLE_PPROF is set")
+	}
+
+	cfg.Users = service.UserServiceOptions{
+		RejectEmailLikeUsernames: e.boolean("USERNAME_EMAIL_CHECK"),
+		MaxBulkDelete:            e.integer("BULK_DELETE_MAX", service.DefaultMaxBulkDelete, 1),
+		MaxBulkUpdate:            e.integer("BULK_UPDATE_MAX", service.DefaultMaxBulkUpdate, 1),
+		VerificationTTL:          e.duration("EMAIL_VERIFICATION_TTL", service.DefaultVerificationTTL, false),
+		PrecheckUniqueness:       e.boolean("UNIQUENESS_PRECHECK"),
+		UniqueFullName:           e.boolean("UNIQUE_FULL_NAME"),
+		StatsTTL:                 e.duration("STATS_CACHE_TTL", service.DefaultStatsTTL, true),
+	}
+	cfg.Webhooks = service.WebhookOptions{
+		URLs:   e.list("WEBHOOK_URLS"),
+		Secret: e.str("WEBHOOK_SECRET"),
+	}
+	if len(cfg.Webhooks.URLs) > 0 && cfg.Webhooks.Secret == "" {
+		// unsigned deliveries aren't allowed
+		e.fail("WEBHOOK_SECRET", "is required when WEBHOOK_URLS is set")
+	}
+	cfg.UserController = controller.UserControllerOptions{
+		UnprocessableEntity: e.boolean("VALIDATION_ERROR_422"),
+		UUIDOnly:            e.boolean("UUID_ONLY"),
+	}
+
+	cfg.RequestTimeout = e.duration("HTTP_REQUEST_TIMEOUT", 10*time.Second, false)
+	cfg.Server = ServerTimeouts{
+		ReadHeader: e.duration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second, false),
+		Read:       e.duration("HTTP_READ_TIMEOUT", 30*time.Second, true),
+		Write:      e.duration("HTTP_WRITE_TIMEOUT", 60*time.Second, true),
+		Idle:       e.duration("HTTP_IDLE_TIMEOUT", 2*time.Minute, true),
+		Shutdown:   e.duration("HTTP_SHUTDOWN_TIMEOUT", 15*time.Second, false),
+	}
+	if cfg.Server.Write > 0 && cfg.Server.Write <= cfg.RequestTimeout {
+		e.fail("HTTP_WRITE_TIMEOUT", "must be longer than HTTP_REQUEST_TIMEOUT (%s), got %s", cfg.RequestTimeout, cfg.Server.Write)
+	}
+	cfg.MaxBodyBytes = int64(e.integer("MAX_BODY_BYTES", 1<<20, 1))
+	cfg.MaxBulkBodyBytes = int64(e.integer("MAX_BULK_BODY_BYTES", max(8<<20, int(cfg.MaxBodyBytes)), 1))
+	if cfg.MaxBulkBodyBytes < cfg.MaxBodyBytes {
+		e.fail("MAX_BULK_BODY_BYTES", "must be at least MAX_BODY_BYTES (%d), got %d", cfg.MaxBodyBytes, cfg.MaxBulkBodyBytes)
+	}
+	cfg.MaxContentHeaderBytes = e.integer("MAX_CONTENT_HEADER_BYTES", 0, 1)
+	cfg.RateLimit = middleware.RateLimitOptions{
+		RequestsPerSecond: e.float("RATE_LIMIT_RPS"),
+		Burst:             e.integer("RATE_LIMIT_BURST", 0, 0),
+	}
+	cfg.CORS = middleware.CORSOptions{
+		AllowedOrigins:   e.list("CORS_ALLOWED_ORIGINS"),
+		AllowCredentials: e.boolean("CORS_ALLOW_CREDENTIALS"),
+		MaxAge:           e.duration("CORS_MAX_AGE", 0, true),
+	}
+	if cfg.CORS.AllowCredentials && slices.Contains(cfg.CORS.AllowedOrigins, "*") {
+		// credentials must be scoped to origins the operator named
+		e.fail("CORS_ALLOW_CREDENTIALS", "cannot be combined with CORS_ALLOWED_ORIGINS=*; list the origins instead")
+	}
+	cfg.TrustedProxies = e.list("TRUSTED_PROXIES")
+	for _, proxy := range cfg.TrustedProxies {
+		if !validProxy(proxy) {
+			e.fail("TRUSTED_PROXIES", "must list IP addresses or CIDRs, got %q", proxy)
+		}
+	}
+	cfg.Recovery = middleware.RecoveryOptions{ExposePanic: e.boolean("PANIC_DETAILS")}
+	cfg.MaintenanceRetryAfter = e.duration("MAINTENANCE_RETRY_AFTER", 30*time.Second, false)
+
+	for _, name := range e.unreadFileKeys() {
+		cfg.Warnings = append(cfg.Warnings, fmt.Sprintf("CONFIG_FILE: unknown key %q ignored", strings.ToLower(name)))
+	}
+
+	if len(e.problems) > 0 {
+		return cfg, &Error{Problems: e.problems}
+	}
+	return cfg, nil
+}
+
+// readFile reads a config file: one JSON object whose keys are the
+// environment variable names, in either case. Values may be strings,
+// numbers, booleans, or arrays of strings for the comma-separated lists.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s is not a JSON object: %w", path, err)
+	}
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		name := strings.ToUpper(key)
+		switch v := value.(type) {
+		case string:
+			values[name] = v
+		case float64:
+			values[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			values[name] = strconv.FormatBool(v)
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s: %s must hold only strings", path, key)
+				}
+				items[i] = s
+			}
+			values[name] = strings.Join(items, ",")
+		default:
+			return nil, fmt.Errorf("%s: %s must be a string, number, boolean, or list of strings", path, key)
+		}
+	}
+	return values, nil
+}
+
+// env parses variables, collecting a problem for each invalid one instead of
+// stopping at the first. A variable set in the environment wins over the
+// same key in file.
+type env struct {
+	getenv   func(string) string
+	file     map[string]string
+	read     map[string]bool
+	problems []string
+}
+
+func (e *env) fail(name, format string, args ...any) {
+	e.problems = append(e.problems, name+" "+fmt.Sprintf(format, args...))
+}
+
+func (e *env) str(name string) string {
+	e.read[name] = true
+	if value := strings.TrimSpace(e.getenv(name)); value != "" {
+		return value
+	}
+	return strings.TrimSpace(e.file[name])
+}
+
+// unreadFileKeys lists, sorted, the file keys no setting asked for.
+func (e *env) unreadFileKeys() []string {
+	var unread []string
+	for name := range e.file {
+		if !e.read[name] {
+			unread = append(unread, name)
+		}
+	}
+	slices.Sort(unread)
+	return unread
+}
+
+// integer returns def when name is unset.
+func (e *env) integer(name string, def, min int) int {
+	value := e.str(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		e.fail(name, "must be an integer, got %q", value)
+		return def
+	}
+	if n < min {
+		e.fail(name, "must be at least %d, got %d", min, n)
+		return def
+	}
+	return n
+}
+
+func (e *env) float(name string) float64 {
+	value := e.str(name)
+	if value == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		e.fail(name, "must be a non-negative number, got %q", value)
+		return 0
+	}
+	return f
+}
+
+// duration returns def when name is unset. Zero is accepted only with
+// allowZero, where it usually disables the feature.
+func (e *env) duration(name string, def time.Duration, allowZero bool) time.Duration {
+	value := e.str(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	switch {
+	case err != nil:
+		e.fail(name, "must be a duration such as 30s, got %q", value)
+		return def
+	case d < 0 && allowZero:
+		e.fail(name, "must not be negative, got %s", value)
+		return def
+	case d <= 0 && !allowZero:
+		e.fail(name, "must be positive, got %s", value)
+		return def
+	}
+	return d
+}
+
+func (e *env) boolean(name string) bool {
+	value := e.str(name)
+	if value == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		e.fail(name, "must be true or false, got %q", value)
+		return false
+	}
+	return b
+}
+
+func (e *env) list(name string) []string {
+	var items []string
+	for _, item := range strings.Split(e.str(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// validProxy reports whether proxy is an IP address or a CIDR, the forms
+// gin accepts as trusted proxies.
+func validProxy(proxy string) bool {
+	if _, err := netip.ParsePrefix(proxy); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(proxy)
+	return err == nil
+}
+
+// dsn checks that value parses as a connection string without connecting, so
+// a typo fails here rather than at the first query. The parse error is left
+// out because it can quote the password.
+func (e *env) dsn(name, value string) {
+	if _, err := pq.NewConnector(value); err != nil {
+		e.fail(name, "is not a valid connection string (want key=value pairs or a postgres:// URL)")
+	}
+}
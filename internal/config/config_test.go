@@ -0,0 +1,240 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cruder/internal/middleware"
+	"cruder/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testDSN = "host=localhost port=5432 user=postgres dbname=postgres sslmode=disable"
+
+func fromMap(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := load(fromMap(map[string]string{"POSTGRES_DSN": testDSN}))
+
+	require.NoError(t, err)
+	require.Equal(t, ":8080", cfg.Addr)
+	require.Equal(t, testDSN, cfg.DSN)
+	require.Equal(t, "cruder", cfg.Log.ServiceName)
+	require.Empty(t, cfg.Log.Environment)
+	require.Equal(t, 5*time.Minute, cfg.APIKeys.CacheTTL)
+	require.Equal(t, 10*time.Second, cfg.RequestTimeout)
+	require.Equal(t, ServerTimeouts{
+		ReadHeader: 5 * time.Second,
+		Read:       30 * time.Second,
+		Write:      time.Minute,
+		Idle:       2 * time.Minute,
+		Shutdown:   15 * time.Second,
+	}, cfg.Server)
+	require.EqualValues(t, 1<<20, cfg.MaxBodyBytes)
+	require.EqualValues(t, 8<<20, cfg.MaxBulkBodyBytes)
+	require.Equal(t, 500*time.Millisecond, cfg.SlowQuery)
+	require.Equal(t, 50*time.Millisecond, cfg.ReadRetry.BaseDelay)
+	require.Equal(t, time.Minute, cfg.UserCache.TTL)
+	require.Equal(t, middleware.LockoutOptions{Threshold: 10, BaseDelay: time.Second, MaxDelay: 15 * time.Minute}, cfg.APIKeyAuth.Lockout)
+	require.Equal(t, service.DefaultMaxBulkDelete, cfg.Users.MaxBulkDelete)
+	require.Equal(t, service.DefaultMaxBulkUpdate, cfg.Users.MaxBulkUpdate)
+	require.Equal(t, service.DefaultVerificationTTL, cfg.Users.VerificationTTL)
+	require.Equal(t, 30*time.Second, cfg.MaintenanceRetryAfter)
+	require.Equal(t, service.DefaultStatsTTL, cfg.Users.StatsTTL)
+	require.Empty(t, cfg.AccessLogFile)
+	require.Equal(t, middleware.AccessLogCombinedDuration, cfg.AccessLogFormat)
+	require.Empty(t, cfg.TrustedProxies)
+}
+
+func TestLoad_ParsesValues(t *testing.T) {
+	cfg, err := load(fromMap(map[string]string{
+		"POSTGRES_DSN":           "postgres://app@db:5432/app?sslmode=disable",
+		"PORT":                   "9090",
+		"LOG_OUTPUT":             "both",
+		"LOG_FILE":               "/var/log/app.json",
+		"API_KEY_CACHE_TTL":      "30s",
+		"RATE_LIMIT_RPS":         "2.5",
+		"CORS_ALLOWED_ORIGINS":   "https://a.example, https://b.example,",
+		"WEBHOOK_URLS":           "https://hooks.example/users",
+		"WEBHOOK_SECRET":         "s3cret",
+		"UUID_ONLY":              "true",
+		"PANIC_DETAILS":          "true",
+		"SERVICE_NAME":           "cruder-eu",
+		"DEPLOYMENT_ENVIRONMENT": "prod",
+		"UNIQUENESS_PRECHECK":    "true",
+		"UNIQUE_FULL_NAME":       "true",
+		"API_KEY_STALE_ON_ERROR": "10m",
+		"ACCESS_LOG_FILE":        "/var/log/access.log",
+		"ACCESS_LOG_FORMAT":      "common",
+		"TRUSTED_PROXIES":        "10.0.0.0/8, 192.168.1.10",
+	}))
+
+	require.NoError(t, err)
+	require.Equal(t, ":9090", cfg.Addr)
+	require.Equal(t, "/var/log/app.json", cfg.Log.FilePath)
+	require.Equal(t, 30*time.Second, cfg.APIKeys.CacheTTL)
+	require.InDelta(t, 2.5, cfg.RateLimit.RequestsPerSecond, 0)
+	require.Equal(t, []string{"https://a.example", "https://b.example"}, cfg.CORS.AllowedOrigins)
+	require.Equal(t, []string{"https://hooks.example/users"}, cfg.Webhooks.URLs)
+	require.True(t, cfg.UserController.UUIDOnly)
+	require.True(t, cfg.Recovery.ExposePanic)
+	require.Equal(t, "cruder-eu", cfg.Log.ServiceName)
+	require.Equal(t, "prod", cfg.Log.Environment)
+	require.True(t, cfg.Users.PrecheckUniqueness)
+	require.True(t, cfg.Users.UniqueFullName)
+	require.Equal(t, 10*time.Minute, cfg.APIKeys.StaleOnError)
+	require.Equal(t, "/var/log/access.log", cfg.AccessLogFile)
+	require.Equal(t, middleware.AccessLogCommon, cfg.AccessLogFormat)
+	require.Equal(t, []string{"10.0.0.0/8", "192.168.1.10"}, cfg.TrustedProxies)
+}
+
+func TestLoad_ReportsEveryProblem(t *testing.T) {
+	// Given: several independent mistakes
+	_, err := load(fromMap(map[string]string{
+		"POSTGRES_DSN":         "host=localhost port",
+		"LOG_OUTPUT":           "file",
+		"HTTP_REQUEST_TIMEOUT": "10",
+		"USER_CACHE_SIZE":      "-1",
+		"RUN_MIGRATIONS":       "yes please",
+		"WEBHOOK_URLS":         "https://hooks.example/users",
+		"ACCESS_LOG_FILE":      "access.log",
+		"ACCESS_LOG_FORMAT":    "json",
+		"TRUSTED_PROXIES":      "10.0.0.0/8,load-balancer",
+	}))
+
+	// Then: one error lists all of them
+	var cfgErr *Error
+	require.True(t, errors.As(err, &cfgErr))
+	require.Len(t, cfgErr.Problems, 9)
+	for _, want := range []string{
+		"POSTGRES_DSN is not a valid connection string",
+		"logging file path cannot be empty",
+		`HTTP_REQUEST_TIMEOUT must be a duration such as 30s, got "10"`,
+		"USER_CACHE_SIZE must be at least 0, got -1",
+		`RUN_MIGRATIONS must be true or false, got "yes please"`,
+		"WEBHOOK_SECRET is required when WEBHOOK_URLS is set",
+		`ACCESS_LOG_FILE must be an absolute path or -, got "access.log"`,
+		`ACCESS_LOG_FORMAT must be common, combined, or combined_duration, got "json"`,
+		`TRUSTED_PROXIES must list IP addresses or CIDRs, got "load-balancer"`,
+	} {
+		require.ErrorContains(t, err, want)
+	}
+}
+
+func TestLoad_PprofNeedsAdminKey(t *testing.T) {
+	_, err := load(fromMap(map[string]string{"POSTGRES_DSN": testDSN, "ENABLE_PPROF": "true"}))
+	require.EqualError(t, err, "invalid configuration: ADMIN_API_KEY is required when ENABLE_PPROF is set")
+
+	cfg, err := load(fromMap(map[string]string{"POSTGRES_DSN": testDSN, "ENABLE_PPROF": "true", "ADMIN_API_KEY": "secret"}))
+	require.NoError(t, err)
+	require.True(t, cfg.EnablePprof)
+}
+
+func TestLoad_CORSCredentials(t *testing.T) {
+	_, err := load(fromMap(map[string]string{"POSTGRES_DSN": testDSN, "CORS_ALLOWED_ORIGINS": "*", "CORS_ALLOW_CREDENTIALS": "true"}))
+	require.EqualError(t, err, "invalid configuration: CORS_ALLOW_CREDENTIALS cannot be combined with CORS_ALLOWED_ORIGINS=*; list the origins instead")
+
+	cfg, err := load(fromMap(map[string]string{
+		"POSTGRES_DSN":           testDSN,
+		"CORS_ALLOWED_ORIGINS":   "https://dashboard.example.com",
+		"CORS_ALLOW_CREDENTIALS": "true",
+		"CORS_MAX_AGE":           "2h",
+	}))
+	require.NoError(t, err)
+	require.True(t, cfg.CORS.AllowCredentials)
+	require.Equal(t, 2*time.Hour, cfg.CORS.MaxAge)
+}
+
+func TestLoad_WriteTimeoutMustOutlastRequestTimeout(t *testing.T) {
+	_, err := load(fromMap(map[string]string{
+		"POSTGRES_DSN":         testDSN,
+		"HTTP_REQUEST_TIMEOUT": "30s",
+		"HTTP_WRITE_TIMEOUT":   "30s",
+	}))
+	require.EqualError(t, err, "invalid configuration: HTTP_WRITE_TIMEOUT must be longer than HTTP_REQUEST_TIMEOUT (30s), got 30s")
+
+	_, err = load(fromMap(map[string]string{
+		"POSTGRES_DSN":       testDSN,
+		"HTTP_WRITE_TIMEOUT": "0",
+	}))
+	require.NoError(t, err)
+}
+
+func TestLoad_BulkBodyLimitMustCoverBodyLimit(t *testing.T) {
+	_, err := load(fromMap(map[string]string{
+		"POSTGRES_DSN":        testDSN,
+		"MAX_BODY_BYTES":      "4096",
+		"MAX_BULK_BODY_BYTES": "2048",
+	}))
+	require.EqualError(t, err, "invalid configuration: MAX_BULK_BODY_BYTES must be at least MAX_BODY_BYTES (4096), got 2048")
+
+	cfg, err := load(fromMap(map[string]string{
+		"POSTGRES_DSN":   testDSN,
+		"MAX_BODY_BYTES": "16777216",
+	}))
+	require.NoError(t, err)
+	require.EqualValues(t, 16<<20, cfg.MaxBulkBodyBytes)
+}
+
+func TestLoad_ConfigFile(t *testing.T) {
+	// Given: a config file, one of whose values the environment overrides
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"postgres_dsn": "`+testDSN+`",
+		"LOG_LEVEL": "debug",
+		"http_addr": ":9000",
+		"http_request_timeout": "5s",
+		"api_key_cache_ttl": "1m",
+		"max_body_bytes": 2048,
+		"uuid_only": true,
+		"cors_allowed_origins": ["https://a.example", "https://b.example"],
+		"colour": "blue"
+	}`), 0o600))
+
+	// When: loading
+	cfg, err := load(fromMap(map[string]string{
+		"CONFIG_FILE": path,
+		"HTTP_ADDR":   ":9090",
+	}))
+
+	// Then: file values apply, the environment wins, and the unknown key warns
+	require.NoError(t, err)
+	require.Equal(t, testDSN, cfg.DSN)
+	require.Equal(t, "debug", cfg.Log.Level)
+	require.Equal(t, ":9090", cfg.Addr)
+	require.Equal(t, 5*time.Second, cfg.RequestTimeout)
+	require.Equal(t, time.Minute, cfg.APIKeys.CacheTTL)
+	require.EqualValues(t, 2048, cfg.MaxBodyBytes)
+	require.True(t, cfg.UserController.UUIDOnly)
+	require.Equal(t, []string{"https://a.example", "https://b.example"}, cfg.CORS.AllowedOrigins)
+	require.Equal(t, []string{`CONFIG_FILE: unknown key "colour" ignored`}, cfg.Warnings)
+}
+
+func TestLoad_ConfigFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.json")
+	require.NoError(t, os.WriteFile(bad, []byte(`{"log_level": {"nested": true}}`), 0o600))
+
+	_, err := load(fromMap(map[string]string{"POSTGRES_DSN": testDSN, "CONFIG_FILE": bad}))
+	require.ErrorContains(t, err, "CONFIG_FILE could not be loaded: "+bad+": log_level must be a string")
+
+	_, err = load(fromMap(map[string]string{"POSTGRES_DSN": testDSN, "CONFIG_FILE": filepath.Join(dir, "missing.json")}))
+	require.ErrorContains(t, err, "CONFIG_FILE could not be loaded")
+}
+
+func TestLoad_RequiresDSN(t *testing.T) {
+	_, err := load(fromMap(nil))
+	require.EqualError(t, err, "invalid configuration: POSTGRES_DSN is required")
+}
+
+func TestLoad_DoesNotEchoDSN(t *testing.T) {
+	_, err := load(fromMap(map[string]string{"POSTGRES_DSN": "postgres://app:hunter2@db:bad-port/app"}))
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "hunter2")
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"cruder/internal/controller/request"
+	"cruder/internal/controller/response"
+	"cruder/internal/middleware"
+	"cruder/internal/service"
+	"cruder/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyController struct {
+	service service.APIKeyService
+}
+
+func NewAPIKeyController(service service.APIKeyService) *APIKeyController {
+	return &APIKeyController{service: service}
+}
+
+func (c *APIKeyController) requestLogger(ctx *gin.Context, operation string) *logger.Logger {
+	base := middleware.LoggerFromContext(ctx, logger.Get())
+	return base.With(
+		slog.String("component", "controller.api_keys"),
+		slog.String("operation", operation),
+	)
+}
+
+// CreateAPIKey godoc
+// @Summary      Create API key
+// @Description  Generates a new API key. The plaintext key is only returned in this response. A client may hold several keys with distinct labels.
+// @Tags         apikeys
+// @Security     APIKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        X-Admin-Key  header    string               true  "Super-admin key"
+// @Param        request      body      request.CreateAPIKey  true  "API key payload"
+// @Success      201  {object}  response.CreatedAPIKey
+// @Failure      400  {object}  response.Error
+// @Failure      401  {object}  response.Error
+// @Failure      403  {object}  response.Error
+// @Failure      409  {object}  response.Error
+// @Failure      413  {object}  response.Error
+// @Failure      415  {object}  response.Error
+// @Failure      500  {object}  response.Error
+// @Router       /api/v1/apikeys/ [post]
+func (c *APIKeyController) CreateAPIKey(ctx *gin.Context) {
+	log := c.requestLogger(ctx, "CreateAPIKey")
+	var req request.CreateAPIKey
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Warn("invalid request body", slog.String("error", err.Error()))
+		writeBindError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	log = log.With(
+		slog.String("request.client_name", req.ClientName),
+		slog.String("request.label", req.Label),
+		slog.Bool("request.expires_at_provided", req.ExpiresAt != nil),
+		slog.Any("request.scopes", req.Scopes),
+	)
+
+	key, plain, err := c.service.Create(ctx.Request.Context(), req.ClientName, req.Label, req.ExpiresAt, req.Scopes)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidAPIKeyInput):
+			log.Warn("invalid api key input", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, service.ErrAPIKeyLabelTaken):
+			log.Warn("api key label in use", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusConflict, err.Error())
+			return
+		}
+		log.Error("failed to create api key", slog.String("error", err.Error()))
+		writeError(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	log.Info("api key created", slog.Int("api_key.id", key.ID))
+	ctx.JSON(http.StatusCreated, response.CreatedAPIKey{APIKey: *key, Key: plain})
+}
+
+// ListAPIKeys godoc
+// @Summary      List API keys
+// @Description  Returns every key unless a page is requested with page/per_page or limit/offset (not both). Key hashes are never included.
+// @Tags         apikeys
+// @Security     APIKeyAuth
+// @Produce      json
+// @Param        X-Admin-Key  header  string  true   "Super-admin key"
+// @Param        client       query   string  false  "Only keys whose client name contains this, case-insensitively"
+// @Param        page         query   int     false  "Page number, starting at 1"
+// @Param        per_page     query   int     false  "Page size"
+// @Param        limit        query   int     false  "Maximum number of keys"
+// @Param        offset       query   int     false  "Number of keys to skip"
+// @Success      200  {array}   response.APIKey
+// @Failure      400  {object}  response.Error
+// @Failure      401  {object}  response.Error
+// @Failure      403  {object}  response.Error
+// @Failure      500  {object}  response.Error
+// @Router       /api/v1/apikeys/ [get]
+func (c *APIKeyController) ListAPIKeys(ctx *gin.Context) {
+	log := c.requestLogger(ctx, "ListAPIKeys")
+
+	page, _ := middleware.PageFromContext(ctx)
+	if page.Cursor {
+		writeError(ctx, http.StatusBadRequest, "after is not supported for api keys")
+		return
+	}
+	client := ctx.Query("client")
+	log = log.With(slog.String("request.client", client), slog.Int("page.limit", page.Limit), slog.Int("page.offset", page.Offset))
+
+	keys, err := c.service.List(ctx.Request.Context(), client, page.Limit, page.Offset)
+	if err != nil {
+		log.Error("failed to list api keys", slog.String("error", err.Error()))
+		writeError(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	log.Debug("listed api keys", slog.Int("api_keys.count", len(keys)))
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary      Revoke API key
+// @Tags         apikeys
+// @Security     APIKeyAuth
+// @Param        X-Admin-Key  header  string  true  "Super-admin key"
+// @Param        id           path    int     true  "API key ID"
+// @Success      204  "No Content"
+// @Failure      400  {object}  response.Error
+// @Failure      401  {object}  response.Error
+// @Failure      403  {object}  response.Error
+// @Failure      404  {object}  response.Error
+// @Failure      500  {object}  response.Error
+// @Router       /api/v1/apikeys/{id} [delete]
+func (c *APIKeyController) RevokeAPIKey(ctx *gin.Context) {
+	log := c.requestLogger(ctx, "RevokeAPIKey")
+	var uri request.IDParam
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		log.Warn("invalid id parameter", slog.String("error", err.Error()))
+		writeParamError(ctx, errInvalidID, "id", paramRule(err))
+		return
+	}
+
+	log = log.With(slog.Int64("request.api_key_id", uri.ID.Int64()))
+
+	if err := c.service.Revoke(ctx.Request.Context(), uri.ID.Int64()); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidAPIKeyInput):
+			log.Warn("invalid api key input", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, service.ErrAPIKeyNotFound):
+			log.Warn("api key not found", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusNotFound, err.Error())
+			return
+		default:
+			log.Error("failed to revoke api key", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	log.Info("api key revoked")
+	ctx.Status(http.StatusNoContent)
+}
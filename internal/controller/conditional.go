@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"cruder/internal/controller/response"
+	"cruder/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userETag is a strong validator over every stored field of u, so any
+// change through the API yields a new tag.
+func userETag(u model.User) string {
+	return projectedUserETag(u, nil)
+}
+
+// projectedUserETag is userETag for the representation restricted to
+// fields. The selection is part of the hash, in canonical order, so a
+// projected body never shares a tag with the full one or another
+// projection; an empty selection is the full user.
+func projectedUserETag(u model.User, fields response.UserFields) string {
+	h := sha256.New()
+	for _, field := range []string{strconv.Itoa(u.ID), u.UUID, u.Username, u.Email, u.FullName, u.AvatarURL, strconv.FormatBool(u.EmailVerified), u.Status, strconv.Itoa(u.Version)} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	if len(fields) > 0 {
+		h.Write([]byte("fields=" + strings.Join(slices.Sorted(slices.Values(fields)), ",")))
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// writeUser renders a single-user GET with its ETag, answering 304 instead
+// when If-None-Match already names it.
+func (c *UserController) writeUser(ctx *gin.Context, user model.User, fields response.UserFields) {
+	etag := projectedUserETag(user, fields)
+	ctx.Header("ETag", etag)
+	if etagListMatches(ctx.GetHeader("If-None-Match"), etag, true) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	ctx.JSON(http.StatusOK, fields.User(c.present(user)))
+}
+
+// ifMatchPrecondition turns an If-Match header into an update precondition,
+// or nil when the request is unconditional.
+func ifMatchPrecondition(ctx *gin.Context) func(model.User) bool {
+	header := ctx.GetHeader("If-Match")
+	if header == "" {
+		return nil
+	}
+	return func(current model.User) bool {
+		return etagListMatches(header, userETag(current), false)
+	}
+}
+
+// etagListMatches reports whether an If-Match/If-None-Match header names
+// etag or is "*". If-None-Match compares weakly (RFC 9110 13.1.2), ignoring
+// a W/ prefix; If-Match compares strongly, so weak tags never match.
+func etagListMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cruder/internal/controller/mocks"
+	"cruder/internal/model"
+	"cruder/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserETag_ChangesWithEveryField(t *testing.T) {
+	base := model.User{ID: 1, UUID: "u", Username: "jdoe", Email: "jdoe@example.com", FullName: "John Doe"}
+	variants := []model.User{base, base, base, base, base}
+	variants[0].ID = 2
+	variants[1].UUID = "v"
+	variants[2].Username = "jane"
+	variants[3].Email = "jane@example.com"
+	variants[4].FullName = "Jane Doe"
+
+	require.Equal(t, userETag(base), userETag(base))
+	for _, v := range variants {
+		require.NotEqual(t, userETag(base), userETag(v))
+	}
+}
+
+func TestEtagListMatches(t *testing.T) {
+	const etag = `"abc"`
+	cases := []struct {
+		header string
+		weak   bool
+		want   bool
+	}{
+		{`"abc"`, false, true},
+		{`"x", "abc"`, false, true},
+		{`*`, false, true},
+		{`"x"`, true, false},
+		{`W/"abc"`, true, true},
+		{`W/"abc"`, false, false},
+		{``, true, false},
+	}
+	for _, tc := range cases {
+		require.Equal(t, tc.want, etagListMatches(tc.header, etag, tc.weak), "header %q weak %v", tc.header, tc.weak)
+	}
+}
+
+func TestUserController_GetUser_ETag(t *testing.T) {
+	// Given: a stored user
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	user := model.User{ID: 7, UUID: uuid.NewString(), Username: "jdoe", Email: "jdoe@example.com"}
+	svc.On("GetByUUID", mock.Anything, uuid.MustParse(user.UUID)).Return(&user, nil).Twice()
+
+	// When: fetching it, then revalidating with the returned tag
+	first := serveUserRequest(router, http.MethodGet, "/api/v1/users/uuid/"+user.UUID)
+	etag := first.Header().Get("ETag")
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/uuid/"+user.UUID, nil)
+	req.Header.Set("If-None-Match", etag)
+	second := httptest.NewRecorder()
+	router.ServeHTTP(second, req)
+
+	// Then: the revalidation is a bodiless 304 carrying the same tag
+	require.Equal(t, http.StatusOK, first.Code)
+	require.Equal(t, userETag(user), etag)
+	require.Equal(t, http.StatusNotModified, second.Code)
+	require.Empty(t, second.Body.String())
+	require.Equal(t, etag, second.Header().Get("ETag"))
+}
+
+func TestUserController_GetUser_ProjectedETag(t *testing.T) {
+	// Given: a stored user
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	user := model.User{ID: 7, UUID: uuid.NewString(), Username: "jdoe", Email: "jdoe@example.com"}
+	svc.On("GetByUUID", mock.Anything, uuid.MustParse(user.UUID)).Return(&user, nil).Times(4)
+	path := "/api/v1/users/uuid/" + user.UUID
+
+	// When: fetching it in full, projected, and projected in another order
+	full := serveUserRequest(router, http.MethodGet, path)
+	projected := serveUserRequest(router, http.MethodGet, path+"?fields=username,email")
+	reordered := serveUserRequest(router, http.MethodGet, path+"?fields=email,username")
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("If-None-Match", projected.Header().Get("ETag"))
+	revalidated := httptest.NewRecorder()
+	router.ServeHTTP(revalidated, req)
+
+	// Then: each body has its own tag, and a projected tag doesn't
+	// revalidate the full representation
+	require.NotEqual(t, full.Header().Get("ETag"), projected.Header().Get("ETag"))
+	require.Equal(t, projected.Header().Get("ETag"), reordered.Header().Get("ETag"))
+	require.Equal(t, http.StatusOK, revalidated.Code)
+	require.Contains(t, revalidated.Body.String(), `"status"`)
+}
+
+func TestUserController_GetUser_StaleETag(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	user := model.User{ID: 7, Username: "jdoe"}
+	svc.On("GetByID", mock.Anything, int64(7)).Return(&user, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/id/7", nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Contains(t, resp.Body.String(), "jdoe")
+}
+
+func TestUserController_UpdateUser_IfMatch(t *testing.T) {
+	// Given: a stored user and a service that applies the precondition
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	current := model.User{ID: 3, UUID: uuid.NewString(), Username: "jdoe", Email: "jdoe@example.com"}
+	updated := current
+	updated.FullName = "John Doe"
+	svc.On("UpdateByID", mock.Anything, int64(3), mock.AnythingOfType("service.UpdateUserInput")).
+		Return(func(_ context.Context, _ int64, input service.UpdateUserInput) (*model.User, error) {
+			if input.Precondition == nil || !input.Precondition(current) {
+				return nil, service.ErrPreconditionFailed
+			}
+			return &updated, nil
+		}).Twice()
+
+	// When: patching with a stale tag, then with the current one
+	stale := patchIfMatch(router, "/api/v1/users/id/3", `"stale"`)
+	fresh := patchIfMatch(router, "/api/v1/users/id/3", userETag(current))
+
+	// Then: the stale write is refused and the fresh one returns the new tag
+	require.Equal(t, http.StatusPreconditionFailed, stale.Code)
+	require.JSONEq(t, `{"error":"user has changed"}`, stale.Body.String())
+	require.Equal(t, http.StatusOK, fresh.Code)
+	require.Equal(t, userETag(updated), fresh.Header().Get("ETag"))
+}
+
+func TestUserController_UpdateUser_NoIfMatchIsUnconditional(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("UpdateByID", mock.Anything, int64(3), mock.MatchedBy(func(input service.UpdateUserInput) bool {
+		return input.Precondition == nil
+	})).Return(&model.User{ID: 3}, nil).Once()
+
+	resp := serveUserJSON(router, http.MethodPatch, "/api/v1/users/id/3", `{"full_name":"John Doe"}`)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+}
+
+func patchIfMatch(router http.Handler, path, etag string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(`{"full_name":"John Doe"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", etag)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
@@ -3,11 +3,13 @@ package controller
 import "cruder/internal/service"
 
 type Controller struct {
-	Users *UserController
+	Users   *UserController
+	APIKeys *APIKeyController
 }
 
-func NewController(services *service.Service) *Controller {
+func NewController(services *service.Service, userOpts UserControllerOptions) *Controller {
 	return &Controller{
-		Users: NewUserController(services.Users),
+		Users:   NewUserControllerWithOptions(services.Users, userOpts),
+		APIKeys: NewAPIKeyController(services.APIKeys),
 	}
 }
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"cruder/internal/controller/request"
+	"cruder/internal/controller/response"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// IssueEmailVerification godoc
+// @Summary      Issue an email verification token
+// @Description  Creates a single-use token for the user's current email, replacing any earlier one. The token appears only in this response; deliver it to the user out of band.
+// @Tags         users
+// @Security     APIKeyAuth
+// @Produce      json
+// @Param        uuid  path      string  true  "User UUID"
+// @Success      201  {object}  response.EmailVerification
+// @Failure      400  {object}  response.Error
+// @Failure      404  {object}  response.Error
+// @Failure      409  {object}  response.Error
+// @Failure      500  {object}  response.Error
+// @Failure      503  {object}  response.Error
+// @Router       /api/v1/users/uuid/{uuid}/verification [post]
+func (c *UserController) IssueEmailVerification(ctx *gin.Context) {
+	log := c.requestLogger(ctx, "IssueEmailVerification")
+	var uri request.UUIDParam
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		log.Warn("invalid uuid parameter", slog.String("error", err.Error()))
+		writeParamError(ctx, errInvalidUUID, "uuid", paramRule(err))
+		return
+	}
+
+	parsedUUID, err := uuid.Parse(uri.UUID)
+	if err != nil {
+		log.Warn("failed to parse uuid", slog.String("request.uuid_raw", uri.UUID))
+		writeParamError(ctx, errInvalidUUID, "uuid", "uuid")
+		return
+	}
+
+	log = log.With(slog.String("request.user_uuid", parsedUUID.String()))
+
+	issued, err := c.service.IssueEmailVerification(ctx.Request.Context(), parsedUUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUserNotFound):
+			log.Warn("user not found", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusNotFound, err.Error())
+			return
+		case errors.Is(err, service.ErrEmailAlreadyVerified):
+			log.Warn("email already verified", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusConflict, err.Error())
+			return
+		case errors.Is(err, service.ErrReadOnly):
+			log.Warn("service is read-only", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusServiceUnavailable, err.Error())
+			return
+		default:
+			log.Error("failed to issue email verification", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	log.Info("email verification issued")
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusCreated, response.EmailVerification{Token: issued.Token, ExpiresAt: issued.ExpiresAt})
+}
+
+// VerifyEmail godoc
+// @Summary      Verify a user's email
+// @Description  Redeems a token from the verification endpoint and marks the user's email as verified. A token works once, even when it is rejected.
+// @Tags         users
+// @Security     APIKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      request.VerifyEmail  true  "Verification token"
+// @Success      200  {object}  response.User
+// @Failure      400  {object}  response.Error
+// @Failure      409  {object}  response.Error
+// @Failure      413  {object}  response.Error
+// @Failure      415  {object}  response.Error
+// @Failure      422  {object}  response.Error
+// @Failure      500  {object}  response.Error
+// @Failure      503  {object}  response.Error
+// @Router       /api/v1/users/verify [post]
+func (c *UserController) VerifyEmail(ctx *gin.Context) {
+	log := c.requestLogger(ctx, "VerifyEmail")
+	var req request.VerifyEmail
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		// the bind error names fields, never their values
+		log.Warn("invalid request body", slog.String("error", err.Error()))
+		writeBindError(ctx, c.bindStatus(err), err)
+		return
+	}
+
+	verified, err := c.service.VerifyEmail(ctx.Request.Context(), req.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrVerificationTokenInvalid), errors.Is(err, service.ErrVerificationTokenExpired):
+			log.Warn("verification token rejected", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, service.ErrEmailAlreadyVerified):
+			log.Warn("email already verified", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusConflict, err.Error())
+			return
+		case errors.Is(err, service.ErrReadOnly):
+			log.Warn("service is read-only", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusServiceUnavailable, err.Error())
+			return
+		default:
+			log.Error("failed to verify email", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	log.Info("email verified", slog.String("user.uuid", verified.UUID))
+	ctx.Header("ETag", userETag(*verified))
+	ctx.JSON(http.StatusOK, c.present(*verified))
+}
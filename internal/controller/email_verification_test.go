@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"cruder/internal/controller/mocks"
+	"cruder/internal/model"
+	"cruder/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserController_IssueEmailVerification(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	id := uuid.New()
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	svc.On("IssueEmailVerification", mock.Anything, id).
+		Return(&service.EmailVerification{Token: "abc123", ExpiresAt: expiresAt}, nil).Once()
+
+	resp := serveUserRequest(router, http.MethodPost, "/api/v1/users/uuid/"+id.String()+"/verification")
+
+	require.Equal(t, http.StatusCreated, resp.Code)
+	require.Equal(t, "no-store", resp.Header().Get("Cache-Control"))
+	require.JSONEq(t, `{"token":"abc123","expires_at":"2030-01-02T03:04:05Z"}`, resp.Body.String())
+}
+
+func TestUserController_VerifyEmail(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("VerifyEmail", mock.Anything, "abc123").
+		Return(&model.User{ID: 1, Username: "jdoe", EmailVerified: true}, nil).Once()
+
+	resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/verify", `{"token":"abc123"}`)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Contains(t, resp.Body.String(), `"email_verified":true`)
+}
+
+func TestUserController_VerifyEmail_Errors(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+	}{
+		{service.ErrVerificationTokenInvalid, http.StatusBadRequest},
+		{service.ErrVerificationTokenExpired, http.StatusBadRequest},
+		{service.ErrEmailAlreadyVerified, http.StatusConflict},
+		{service.ErrReadOnly, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			svc := mocks.NewUserServiceMock(t)
+			router := setupUserRouter(svc)
+			svc.On("VerifyEmail", mock.Anything, "abc123").Return((*model.User)(nil), tt.err).Once()
+
+			resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/verify", `{"token":"abc123"}`)
+
+			require.Equal(t, tt.wantStatus, resp.Code)
+			require.JSONEq(t, `{"error":"`+tt.err.Error()+`"}`, resp.Body.String())
+		})
+	}
+}
+
+func TestUserController_VerifyEmail_MissingToken(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+
+	resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/verify", `{}`)
+
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	svc.AssertNotCalled(t, "VerifyEmail", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"cruder/internal/controller/request"
+	"cruder/internal/controller/response"
+	"cruder/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	errCodeInvalidParam = "invalid_param"
+	// errCodeVersionConflict tells a stale update apart from a duplicate
+	// username or email, which is also a 409.
+	errCodeVersionConflict = "version_conflict"
+	// ruleType is reported when a path parameter could not even be converted
+	// to its Go type, so no validation tag was evaluated.
+	ruleType = "type"
+	// ruleRange is reported for an integer too large for its Go type.
+	ruleRange = "range"
+)
+
+// writeError renders an error response through middleware.WriteError, as
+// either {"error": "..."} or an RFC 7807 document depending on Accept.
+func writeError(ctx *gin.Context, status int, message string) {
+	writeFieldErrors(ctx, status, message, nil)
+}
+
+// writeBindError renders a ShouldBindJSON failure. Bodies cut off by
+// middleware.BodyLimit are 413; anything else is the given status.
+func writeBindError(ctx *gin.Context, status int, err error) {
+	if middleware.IsBodyTooLarge(err) {
+		writeError(ctx, http.StatusRequestEntityTooLarge, middleware.ErrBodyTooLarge)
+		return
+	}
+	writeError(ctx, status, errInvalidBody)
+}
+
+// writeParamError renders a 400 for a bad path parameter. The body names the
+// parameter and the rule it failed but never echoes the raw value back.
+func writeParamError(ctx *gin.Context, message, param, rule string) {
+	writeErrorBody(ctx, http.StatusBadRequest, response.Error{
+		Error: message,
+		Code:  errCodeInvalidParam,
+		Param: param,
+		Rule:  rule,
+	})
+}
+
+// paramRule returns the validation tag that rejected a ShouldBindUri call, or
+// ruleType (ruleRange for an overflowing id) when the value failed conversion
+// before validation ran.
+func paramRule(err error) string {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) && len(validationErrs) > 0 {
+		return validationErrs[0].Tag()
+	}
+	if errors.Is(err, request.ErrIDOutOfRange) {
+		return ruleRange
+	}
+	return ruleType
+}
+
+// writeFieldErrors is writeError with per-field validation reasons attached.
+func writeFieldErrors(ctx *gin.Context, status int, message string, fields map[string]string) {
+	writeErrorBody(ctx, status, response.Error{Error: message, Fields: fields})
+}
+
+func writeErrorBody(ctx *gin.Context, status int, body response.Error) {
+	middleware.WriteError(ctx, status, body)
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+
+	"cruder/internal/controller/response"
+	"cruder/internal/middleware"
+	"cruder/internal/service"
+	"cruder/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthController struct {
+	checks []service.HealthCheck
+}
+
+// NewHealthController reports on checks in Detail; new dependencies register
+// by appending to the slice.
+func NewHealthController(checks []service.HealthCheck) *HealthController {
+	return &HealthController{checks: checks}
+}
+
+// Live godoc
+// @Summary      Liveness probe
+// @Description  Answers 200 while the process is serving HTTP. It checks no dependencies.
+// @Tags         health
+// @Success      200  "OK"
+// @Router       /healthz [get]
+func (c *HealthController) Live(ctx *gin.Context) {
+	ctx.Status(http.StatusOK)
+}
+
+// Detail godoc
+// @Summary      Dependency health
+// @Description  Runs every dependency check and reports its status and latency. Answers 503 when a critical check fails.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  map[string]response.HealthCheck
+// @Failure      503  {object}  map[string]response.HealthCheck
+// @Router       /health/detail [get]
+func (c *HealthController) Detail(ctx *gin.Context) {
+	results, healthy := service.CheckHealth(ctx.Request.Context(), c.checks, 0)
+
+	body := make(map[string]response.HealthCheck, len(results))
+	for name, result := range results {
+		check := response.HealthCheck{
+			Status:    response.HealthStatusOK,
+			LatencyMS: result.Latency.Milliseconds(),
+			Critical:  result.Critical,
+		}
+		if result.Err != nil {
+			check.Status = response.HealthStatusFail
+			check.Error = result.Err.Error()
+		}
+		body[name] = check
+	}
+
+	status := http.StatusOK
+	if !healthy {
+		status = http.StatusServiceUnavailable
+		log := middleware.LoggerFromContext(ctx, logger.Get()).With(slog.String("component", "controller.health"))
+		log.Warn("critical health check failed", slog.Any("health", body))
+	}
+	ctx.JSON(status, body)
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func serveHealth(checks []service.HealthCheck, path string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	health := NewHealthController(checks)
+	router := gin.New()
+	router.GET("/healthz", health.Live)
+	router.GET("/health/detail", health.Detail)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+	return resp
+}
+
+func TestHealthController_Detail(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+
+	// Given: a healthy database and a failing optional dependency
+	resp := serveHealth([]service.HealthCheck{
+		{Name: "db", Critical: true, Check: ok},
+		{Name: "cache", Check: down},
+	}, "/health/detail")
+
+	// Then: the report lists both and the service is still up
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.JSONEq(t, `{
+		"db":{"status":"ok","latency_ms":0,"critical":true},
+		"cache":{"status":"fail","latency_ms":0,"critical":false,"error":"connection refused"}
+	}`, resp.Body.String())
+
+	// And: a failing critical dependency turns it into a 503
+	resp = serveHealth([]service.HealthCheck{{Name: "db", Critical: true, Check: down}}, "/health/detail")
+	require.Equal(t, http.StatusServiceUnavailable, resp.Code)
+}
+
+func TestHealthController_LiveChecksNothing(t *testing.T) {
+	called := false
+	resp := serveHealth([]service.HealthCheck{{Name: "db", Critical: true, Check: func(context.Context) error {
+		called = true
+		return errors.New("down")
+	}}}, "/healthz")
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.False(t, called)
+}
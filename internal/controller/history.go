@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"cruder/internal/controller/request"
+	"cruder/internal/controller/response"
+	"cruder/internal/middleware"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// historyPageLimit is the page size when the client asks for none.
+const historyPageLimit = 20
+
+// GetUserHistory godoc
+// @Summary      Get a user's change history
+// @Description  Lists the audit entries for the user, oldest first, in pages of limit (default 20). Pass next_cursor back as after for the next page.
+// @Description  A deleted user's history is still returned; 404 means the user never existed.
+// @Tags         users
+// @Security     APIKeyAuth
+// @Produce      json
+// @Param        X-Admin-Key  header  string  true   "Super-admin key"
+// @Param        uuid         path    string  true   "User UUID"
+// @Param        after        query   int     false  "Return entries with an id above this cursor"
+// @Param        limit        query   int     false  "Maximum number of entries"
+// @Success      200  {object}  response.AuditPage
+// @Failure      400  {object}  response.Error
+// @Failure      401  {object}  response.Error
+// @Failure      403  {object}  response.Error
+// @Failure      404  {object}  response.Error
+// @Failure      500  {object}  response.Error
+// @Router       /api/v1/users/uuid/{uuid}/history [get]
+func (c *UserController) GetUserHistory(ctx *gin.Context) {
+	log := c.requestLogger(ctx, "GetUserHistory")
+	var uri request.UUIDParam
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		log.Warn("invalid uuid parameter", slog.String("error", err.Error()))
+		writeParamError(ctx, errInvalidUUID, "uuid", paramRule(err))
+		return
+	}
+
+	parsedUUID, err := uuid.Parse(uri.UUID)
+	if err != nil {
+		log.Warn("failed to parse uuid", slog.String("request.uuid_raw", uri.UUID))
+		writeParamError(ctx, errInvalidUUID, "uuid", "uuid")
+		return
+	}
+
+	// the log only grows, so a cursor never skips or repeats entries
+	page, paged := middleware.PageFromContext(ctx)
+	if paged && !page.Cursor {
+		writeError(ctx, http.StatusBadRequest, "page and offset are not supported for history; use after")
+		return
+	}
+	if !paged {
+		page.Limit = historyPageLimit
+	}
+	log = log.With(slog.String("request.user_uuid", parsedUUID.String()), slog.Int64("page.after", page.After), slog.Int("page.limit", page.Limit))
+
+	entries, err := c.service.History(ctx.Request.Context(), parsedUUID, page.After, page.Limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUserNotFound):
+			log.Warn("user not found", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusNotFound, err.Error())
+			return
+		case errors.Is(err, service.ErrInvalidUserInput):
+			log.Warn("invalid history page", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusBadRequest, err.Error())
+			return
+		default:
+			log.Error("failed to get user history", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	result := response.AuditPage{Entries: make([]response.AuditEntry, 0, len(entries))}
+	for _, e := range entries {
+		result.Entries = append(result.Entries, response.NewAuditEntry(e))
+	}
+	if len(entries) == page.Limit {
+		next := entries[len(entries)-1].ID
+		result.NextCursor = &next
+	}
+	log.Debug("fetched user history", slog.Int("audit.count", len(entries)))
+	ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"cruder/internal/controller/mocks"
+	"cruder/internal/model"
+	"cruder/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserController_GetUserHistory(t *testing.T) {
+	// Given: a user whose email was changed after creation
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	id := uuid.New()
+	at := time.Date(2026, 10, 16, 8, 30, 0, 0, time.UTC)
+	created := &model.AuditFields{Username: "jdoe", Email: "jdoe@example.com", FullName: "John Doe", Status: model.UserStatusActive}
+	moved := *created
+	moved.Email = "john@example.com"
+	svc.On("History", mock.Anything, id, int64(0), 20).Return([]model.AuditEntry{
+		{ID: 3, Actor: "billing", Action: service.AuditActionCreate, UserUUID: id.String(), After: created, CreatedAt: at},
+		{ID: 9, Actor: "support", Action: service.AuditActionUpdate, UserUUID: id.String(), Before: created, After: &moved, CreatedAt: at.Add(time.Hour)},
+	}, nil).Once()
+
+	// When: reading the history
+	resp := serveUserRequest(router, http.MethodGet, "/api/v1/users/uuid/"+id.String()+"/history")
+
+	// Then: entries come oldest first, naming the fields each one changed
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.JSONEq(t, `{"entries":[
+		{"id":3,"action":"user.create","actor":"billing","changed":["username","email","full_name","status"],"before":null,
+		 "after":{"username":"jdoe","email":"jdoe@example.com","full_name":"John Doe","email_verified":false,"status":"active"},"created_at":"2026-10-16T08:30:00Z"},
+		{"id":9,"action":"user.update","actor":"support","changed":["email"],
+		 "before":{"username":"jdoe","email":"jdoe@example.com","full_name":"John Doe","email_verified":false,"status":"active"},
+		 "after":{"username":"jdoe","email":"john@example.com","full_name":"John Doe","email_verified":false,"status":"active"},"created_at":"2026-10-16T09:30:00Z"}
+	],"next_cursor":null}`, resp.Body.String())
+}
+
+func TestUserController_GetUserHistory_Paginates(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	id := uuid.New()
+	svc.On("History", mock.Anything, id, int64(5), 1).
+		Return([]model.AuditEntry{{ID: 7, Action: service.AuditActionUpdate, UserUUID: id.String()}}, nil).Once()
+
+	resp := serveUserRequest(router, http.MethodGet, "/api/v1/users/uuid/"+id.String()+"/history?after=5&limit=1")
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Contains(t, resp.Body.String(), `"next_cursor":7`)
+
+	resp = serveUserRequest(router, http.MethodGet, "/api/v1/users/uuid/"+id.String()+"/history?page=2")
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestUserController_GetUserHistory_NotFound(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	id := uuid.New()
+	svc.On("History", mock.Anything, id, int64(0), 20).Return(([]model.AuditEntry)(nil), service.ErrUserNotFound).Once()
+
+	resp := serveUserRequest(router, http.MethodGet, "/api/v1/users/uuid/"+id.String()+"/history")
+
+	require.Equal(t, http.StatusNotFound, resp.Code)
+	require.JSONEq(t, `{"error":"user not found"}`, resp.Body.String())
+}
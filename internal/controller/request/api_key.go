@@ -0,0 +1,14 @@
+package request
+
+import "time"
+
+type CreateAPIKey struct {
+	ClientName string `json:"client_name" binding:"required"`
+	// Label tells apart the keys of one client and must be unique per client.
+	// A client rotating its key creates one under a new label, deploys it,
+	// then revokes the old one.
+	Label     string     `json:"label" example:"prod"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	// Scopes defaults to ["*"], full access.
+	Scopes []string `json:"scopes" example:"users:read"`
+}
@@ -1,14 +1,139 @@
 package request
 
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
 type CreateUser struct {
 	Username string `json:"username" binding:"required"`
 	Email    string `json:"email" binding:"required"`
+	// FullName is optional and defaults to the username when blank.
 	FullName string `json:"full_name"`
+	// AvatarURL is optional and must be an http or https URL.
+	AvatarURL string `json:"avatar_url"`
 }
+
+// UpdateUser is a JSON Merge Patch (RFC 7396) of a user: an omitted field
+// is left as it is, null clears it, and a value sets it. Only full_name and
+// avatar_url can be cleared; a null username or email is rejected like an
+// empty one.
 type UpdateUser struct {
-	Username *string `json:"username"`
-	Email    *string `json:"email"`
-	FullName *string `json:"full_name"`
+	Username NullableString `json:"username" swaggertype:"string"`
+	Email    NullableString `json:"email" swaggertype:"string"`
+	// FullName may be null or "" to clear it.
+	FullName NullableString `json:"full_name" swaggertype:"string" extensions:"x-nullable"`
+	// AvatarURL may be null or "" to remove it.
+	AvatarURL NullableString `json:"avatar_url" swaggertype:"string" extensions:"x-nullable"`
+	// Version is the user version the client last read. An update based on
+	// an older version is refused.
+	Version *int `json:"version"`
+
+	// ID and UUID are immutable. They are only captured so that attempts to
+	// change them can be rejected instead of silently ignored.
+	ID   json.RawMessage `json:"id,omitempty" swaggerignore:"true"`
+	UUID json.RawMessage `json:"uuid,omitempty" swaggerignore:"true"`
+}
+
+// ImmutableFields returns the immutable identifiers present in the payload.
+func (r UpdateUser) ImmutableFields() []string {
+	var fields []string
+	if r.ID != nil {
+		fields = append(fields, "id")
+	}
+	if r.UUID != nil {
+		fields = append(fields, "uuid")
+	}
+	return fields
+}
+
+// NullableString is a merge patch member that tells an omitted key (Set is
+// false) apart from an explicit null, which is Set with an empty Value.
+type NullableString struct {
+	Set   bool
+	Value string
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It is only called for keys
+// present in the body, null included.
+func (s *NullableString) UnmarshalJSON(data []byte) error {
+	s.Set = true
+	if string(data) == "null" {
+		s.Value = ""
+		return nil
+	}
+	return json.Unmarshal(data, &s.Value)
+}
+
+// Update returns the value for service.UpdateUserInput: nil when the key was
+// omitted, "" when it was null, and the value otherwise. The service clears
+// a field set to "" where that is allowed and rejects it where it is not.
+func (s NullableString) Update() *string {
+	if !s.Set {
+		return nil
+	}
+	value := s.Value
+	return &value
+}
+
+// BulkDeleteUsers is the body of POST /users/bulk-delete.
+type BulkDeleteUsers struct {
+	UUIDs []string `json:"uuids" binding:"required"`
+}
+
+// BulkUpdateUsers is the body of PATCH /users/bulk: the uuids plus exactly
+// one other key, which names the field to set, as in
+// {"uuids": [...], "status": "suspended"}. A null value binds as "".
+type BulkUpdateUsers struct {
+	UUIDs []string `json:"uuids" binding:"required"`
+	Field string   `json:"-"`
+	Value string   `json:"-"`
+}
+
+// ErrBulkUpdateField is returned when a bulk update body names no field to
+// set, or more than one.
+var ErrBulkUpdateField = errors.New("bulk update must set exactly one field besides uuids")
+
+// UnmarshalJSON implements json.Unmarshaler. Which fields may be set is up
+// to the service; a non-string value is rejected here.
+func (r *BulkUpdateUsers) UnmarshalJSON(data []byte) error {
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(data, &body); err != nil {
+		return err
+	}
+	*r = BulkUpdateUsers{}
+	for key, raw := range body {
+		if key == "uuids" {
+			if err := json.Unmarshal(raw, &r.UUIDs); err != nil {
+				return err
+			}
+			continue
+		}
+		if r.Field != "" {
+			return ErrBulkUpdateField
+		}
+		var value NullableString
+		if err := value.UnmarshalJSON(raw); err != nil {
+			return err
+		}
+		r.Field, r.Value = key, value.Value
+	}
+	if r.Field == "" {
+		return ErrBulkUpdateField
+	}
+	return nil
+}
+
+// BatchGetUsers is the body of POST /users/batch-get.
+type BatchGetUsers struct {
+	UUIDs []string `json:"uuids" binding:"required"`
+}
+
+// VerifyEmail is the body of POST /users/verify.
+type VerifyEmail struct {
+	Token string `json:"token" binding:"required"`
 }
 
 type UUIDParam struct {
@@ -16,5 +141,32 @@ type UUIDParam struct {
 }
 
 type IDParam struct {
-	ID int64 `uri:"id" binding:"required,gt=0"`
+	ID ID `uri:"id" binding:"required,gt=0"`
+}
+
+// ErrIDOutOfRange is returned when an id has more digits than an int64
+// holds. It is rejected rather than wrapped or clamped.
+var ErrIDOutOfRange = errors.New("id out of range")
+
+// ID is a numeric id path parameter. Surrounding whitespace is trimmed and
+// leading zeros are ignored, so " 5 " and "007" bind as 5 and 7; anything
+// else that is not a base-10 integer fails to bind.
+type ID int64
+
+// UnmarshalParam implements binding.BindUnmarshaler.
+func (id *ID) UnmarshalParam(param string) error {
+	n, err := strconv.ParseInt(strings.TrimSpace(param), 10, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return ErrIDOutOfRange
+	}
+	if err != nil {
+		return errors.New("id is not a base-10 integer")
+	}
+	*id = ID(n)
+	return nil
+}
+
+// Int64 returns the id as the int64 the service layer takes.
+func (id ID) Int64() int64 {
+	return int64(id)
 }
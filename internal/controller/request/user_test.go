@@ -0,0 +1,70 @@
+package request
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIDParam_Binding(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    int64
+		wantErr error
+	}{
+		{raw: "5", want: 5},
+		{raw: "007", want: 7},
+		{raw: " 5 ", want: 5},
+		{raw: "9999999999999999999999", wantErr: ErrIDOutOfRange},
+		{raw: "5a"},
+		{raw: "0x1F"},
+		{raw: "0"},
+		{raw: "-1"},
+		{raw: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			var uri IDParam
+			err := binding.Uri.BindUri(map[string][]string{"id": {tt.raw}}, &uri)
+
+			if tt.want == 0 {
+				require.Error(t, err)
+				if tt.wantErr != nil {
+					require.ErrorIs(t, err, tt.wantErr)
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, uri.ID.Int64())
+		})
+	}
+}
+
+func TestUpdateUser_MergePatch(t *testing.T) {
+	var req UpdateUser
+	require.NoError(t, json.Unmarshal([]byte(`{"email":null,"full_name":"Jane Doe"}`), &req))
+
+	require.Equal(t, NullableString{}, req.Username)
+	require.Nil(t, req.Username.Update())
+	require.Equal(t, NullableString{Set: true}, req.Email)
+	require.Equal(t, "", *req.Email.Update())
+	require.Equal(t, NullableString{Set: true, Value: "Jane Doe"}, req.FullName)
+	require.Equal(t, "Jane Doe", *req.FullName.Update())
+
+	require.Error(t, json.Unmarshal([]byte(`{"email":42}`), &req))
+}
+
+func TestBulkUpdateUsers_OneField(t *testing.T) {
+	var req BulkUpdateUsers
+	require.NoError(t, json.Unmarshal([]byte(`{"uuids":["a","b"],"status":"suspended"}`), &req))
+	require.Equal(t, BulkUpdateUsers{UUIDs: []string{"a", "b"}, Field: "status", Value: "suspended"}, req)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"avatar_url":null,"uuids":["a"]}`), &req))
+	require.Equal(t, BulkUpdateUsers{UUIDs: []string{"a"}, Field: "avatar_url"}, req)
+
+	require.ErrorIs(t, json.Unmarshal([]byte(`{"uuids":["a"]}`), &req), ErrBulkUpdateField)
+	require.ErrorIs(t, json.Unmarshal([]byte(`{"uuids":["a"],"status":"active","email":"x"}`), &req), ErrBulkUpdateField)
+	require.Error(t, json.Unmarshal([]byte(`{"uuids":["a"],"status":1}`), &req))
+}
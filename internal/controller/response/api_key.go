@@ -0,0 +1,12 @@
+package response
+
+import "cruder/internal/model"
+
+// APIKey represents an API key listing entry; the key hash is never serialized.
+type APIKey = model.APIKey
+
+// CreatedAPIKey is returned once on key creation and carries the plaintext key.
+type CreatedAPIKey struct {
+	model.APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,104 @@
+package response
+
+import (
+	"fmt"
+	"strings"
+)
+
+// userFieldNames are the user payload keys a client may select with
+// ?fields=, in payload order.
+var userFieldNames = []string{"id", "uuid", "username", "email", "full_name", "avatar_url", "email_verified", "status", "version"}
+
+// UserFields is the projection requested with ?fields=. The zero value
+// selects the whole payload.
+type UserFields []string
+
+// ParseUserFields reads a comma-separated field list, skipping empty entries
+// and duplicates. Unknown names are an error; id is unknown when hideID is
+// set, since UUID-only mode never returns it.
+func ParseUserFields(raw string, hideID bool) (UserFields, error) {
+	var fields UserFields
+	seen := make(map[string]struct{})
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !knownUserField(part, hideID) {
+			return nil, fmt.Errorf("unknown field %q", part)
+		}
+		if _, dup := seen[part]; dup {
+			continue
+		}
+		seen[part] = struct{}{}
+		fields = append(fields, part)
+	}
+	return fields, nil
+}
+
+func knownUserField(name string, hideID bool) bool {
+	if name == "id" && hideID {
+		return false
+	}
+	for _, known := range userFieldNames {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// User returns u restricted to the selected fields, or u itself when no
+// projection was requested.
+func (f UserFields) User(u User) any {
+	if len(f) == 0 {
+		return u
+	}
+	out := make(map[string]any, len(f))
+	for _, name := range f {
+		switch name {
+		case "id":
+			out[name] = u.ID
+		case "uuid":
+			out[name] = u.UUID
+		case "username":
+			out[name] = u.Username
+		case "email":
+			out[name] = u.Email
+		case "full_name":
+			out[name] = u.FullName
+		case "avatar_url":
+			out[name] = u.AvatarURL
+		case "email_verified":
+			out[name] = u.EmailVerified
+		case "status":
+			out[name] = u.Status
+		case "version":
+			out[name] = u.Version
+		}
+	}
+	return out
+}
+
+// Users applies User to every entry.
+func (f UserFields) Users(users []User) any {
+	if len(f) == 0 {
+		return users
+	}
+	out := make([]any, len(users))
+	for i, u := range users {
+		out[i] = f.User(u)
+	}
+	return out
+}
+
+// Page applies User to every entry of p, keeping the cursor.
+func (f UserFields) Page(p UserPage) any {
+	if len(f) == 0 {
+		return p
+	}
+	return struct {
+		Users      any    `json:"users"`
+		NextCursor *int64 `json:"next_cursor"`
+	}{f.Users(p.Users), p.NextCursor}
+}
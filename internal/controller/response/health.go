@@ -0,0 +1,15 @@
+package response
+
+// HealthCheck reports one dependency in GET /health/detail.
+type HealthCheck struct {
+	Status    string `json:"status" example:"ok"`
+	LatencyMS int64  `json:"latency_ms" example:"3"`
+	Critical  bool   `json:"critical" example:"true"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Health check statuses.
+const (
+	HealthStatusOK   = "ok"
+	HealthStatusFail = "fail"
+)
@@ -1,11 +1,159 @@
 package response
 
-import "cruder/internal/model"
+import (
+	"time"
 
-// User represents the user payload returned by controller endpoints.
-type User = model.User
+	"cruder/internal/model"
+)
 
-// Error wraps API error responses in a consistent schema.
+// User represents the user payload returned by controller endpoints. ID is
+// left zero, and so omitted, when the API runs in UUID-only mode.
+type User struct {
+	ID       int    `json:"id,omitempty"`
+	UUID     string `json:"uuid"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	FullName string `json:"full_name"`
+	// AvatarURL is null when the user has no avatar.
+	AvatarURL *string `json:"avatar_url" extensions:"x-nullable"`
+	// EmailVerified reports whether the current email has been verified.
+	EmailVerified bool `json:"email_verified"`
+	// Status is active or suspended.
+	Status string `json:"status" enums:"active,suspended"`
+	// Version goes up with every update; send it back when updating.
+	Version int `json:"version"`
+}
+
+// NewUser builds the payload for u, dropping the numeric id when hideID is set.
+func NewUser(u model.User, hideID bool) User {
+	out := User{
+		ID:            u.ID,
+		UUID:          u.UUID,
+		Username:      u.Username,
+		Email:         u.Email,
+		FullName:      u.FullName,
+		EmailVerified: u.EmailVerified,
+		Status:        u.Status,
+		Version:       u.Version,
+	}
+	if u.AvatarURL != "" {
+		out.AvatarURL = &u.AvatarURL
+	}
+	if hideID {
+		out.ID = 0
+	}
+	return out
+}
+
+// UserPage is returned by cursor-paginated listings. NextCursor is the last
+// id on a full page, to pass back as after; it is null once the end is reached.
+type UserPage struct {
+	Users      []User `json:"users"`
+	NextCursor *int64 `json:"next_cursor"`
+}
+
+// NormalizedUser is returned by create/update endpoints. Normalized lists the
+// submitted fields whose stored value differs from what the client sent.
+type NormalizedUser struct {
+	User
+	Normalized []string `json:"normalized,omitempty"`
+}
+
+// BulkDeleteResult is returned by the bulk delete endpoint. NotFound lists
+// the requested uuids that matched no user.
+type BulkDeleteResult struct {
+	Deleted  int      `json:"deleted"`
+	NotFound []string `json:"not_found"`
+}
+
+// BulkUpdateResult is returned by the bulk update endpoint. Updated counts
+// the users whose field changed; NotFound lists the requested uuids that
+// matched no user.
+type BulkUpdateResult struct {
+	Updated  int      `json:"updated"`
+	NotFound []string `json:"not_found"`
+}
+
+// BatchGetResult is returned by the batch get endpoint. Users are in no
+// particular order; Missing lists the requested uuids that matched no user.
+type BatchGetResult struct {
+	Users   []User   `json:"users"`
+	Missing []string `json:"missing"`
+}
+
+// Error wraps API error responses in a consistent schema. Fields is set for
+// validation failures and maps each rejected field to its reason. Code, Param
+// and Rule are set for bad path parameters. RequestID echoes X-Request-ID on
+// unexpected server errors so they can be matched to the logs.
 type Error struct {
-	Error string `json:"error"`
+	Error     string            `json:"error"`
+	Code      string            `json:"code,omitempty"`
+	Param     string            `json:"param,omitempty"`
+	Rule      string            `json:"rule,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+// ProblemDetails is the RFC 7807 error document returned when the client
+// sends Accept: application/problem+json.
+type ProblemDetails struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Code     string            `json:"code,omitempty"`
+	Param    string            `json:"param,omitempty"`
+	Rule     string            `json:"rule,omitempty"`
+	Fields   map[string]string `json:"fields,omitempty"`
+}
+
+// EmailVerification is returned when a verification token is issued. The
+// token is shown only in this response.
+type EmailVerification struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AuditEntry is one change in a user's history. Changed names the fields the
+// change touched; Before is null for the create and After for the delete.
+type AuditEntry struct {
+	ID        int64              `json:"id"`
+	Action    string             `json:"action"`
+	Actor     string             `json:"actor"`
+	Changed   []string           `json:"changed"`
+	Before    *model.AuditFields `json:"before"`
+	After     *model.AuditFields `json:"after"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// NewAuditEntry builds the payload for e.
+func NewAuditEntry(e model.AuditEntry) AuditEntry {
+	changed := e.Changed()
+	if changed == nil {
+		changed = []string{}
+	}
+	return AuditEntry{
+		ID:        e.ID,
+		Action:    e.Action,
+		Actor:     e.Actor,
+		Changed:   changed,
+		Before:    e.Before,
+		After:     e.After,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+// UserStats are the headline counts from GET /users/stats.
+type UserStats struct {
+	Total        int64 `json:"total"`
+	CreatedToday int64 `json:"created_today"`
+	Suspended    int64 `json:"suspended"`
+}
+
+// AuditPage is a page of a user's history. NextCursor is the last entry id on
+// a full page, to pass back as after; it is null once the end is reached.
+type AuditPage struct {
+	Entries    []AuditEntry `json:"entries"`
+	NextCursor *int64       `json:"next_cursor"`
 }
@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+
+	"cruder/internal/controller/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserStats godoc
+// @Summary      Get user counts
+// @Description  Returns the number of users, those created today (in the database's time zone), and those suspended. The counts are cached briefly (STATS_CACHE_TTL), so they may lag recent changes.
+// @Tags         users
+// @Security     APIKeyAuth
+// @Produce      json
+// @Success      200  {object}  response.UserStats
+// @Failure      500  {object}  response.Error
+// @Router       /api/v1/users/stats [get]
+func (c *UserController) GetUserStats(ctx *gin.Context) {
+	log := c.requestLogger(ctx, "GetUserStats")
+	stats, err := c.service.Stats(ctx.Request.Context())
+	if err != nil {
+		log.Error("failed to get user stats", slog.String("error", err.Error()))
+		writeError(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusOK, response.UserStats{
+		Total:        stats.Total,
+		CreatedToday: stats.CreatedToday,
+		Suspended:    stats.Suspended,
+	})
+}
@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"cruder/internal/controller/mocks"
+	"cruder/internal/model"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserController_GetUserStats(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("Stats", mock.Anything).Return(&model.UserStats{Total: 12, CreatedToday: 3, Suspended: 1}, nil).Once()
+
+	resp := serveUserRequest(router, http.MethodGet, "/api/v1/users/stats")
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.JSONEq(t, `{"total":12,"created_today":3,"suspended":1}`, resp.Body.String())
+}
+
+func TestUserController_GetUserStats_Error(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("Stats", mock.Anything).Return((*model.UserStats)(nil), errors.New("db down")).Once()
+
+	resp := serveUserRequest(router, http.MethodGet, "/api/v1/users/stats")
+
+	require.Equal(t, http.StatusInternalServerError, resp.Code)
+}
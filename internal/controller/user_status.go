@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"cruder/internal/controller/request"
+	"cruder/internal/model"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SuspendUser godoc
+// @Summary      Suspend a user
+// @Description  Sets the user's status to suspended. Suspended users can still be fetched; their status says so. Suspending a suspended user changes nothing.
+// @Tags         users
+// @Security     APIKeyAuth
+// @Produce      json
+// @Param        uuid  path      string  true  "User UUID"
+// @Success      200  {object}  response.User
+// @Header       200  {string}  ETag  "Validator of the updated user"
+// @Failure      400  {object}  response.Error
+// @Failure      404  {object}  response.Error
+// @Failure      500  {object}  response.Error
+// @Failure      503  {object}  response.Error
+// @Router       /api/v1/users/uuid/{uuid}/suspend [post]
+func (c *UserController) SuspendUser(ctx *gin.Context) {
+	c.setUserStatus(ctx, "SuspendUser", model.UserStatusSuspended)
+}
+
+// ActivateUser godoc
+// @Summary      Reactivate a user
+// @Description  Sets the user's status back to active. Activating an active user changes nothing.
+// @Tags         users
+// @Security     APIKeyAuth
+// @Produce      json
+// @Param        uuid  path      string  true  "User UUID"
+// @Success      200  {object}  response.User
+// @Header       200  {string}  ETag  "Validator of the updated user"
+// @Failure      400  {object}  response.Error
+// @Failure      404  {object}  response.Error
+// @Failure      500  {object}  response.Error
+// @Failure      503  {object}  response.Error
+// @Router       /api/v1/users/uuid/{uuid}/activate [post]
+func (c *UserController) ActivateUser(ctx *gin.Context) {
+	c.setUserStatus(ctx, "ActivateUser", model.UserStatusActive)
+}
+
+func (c *UserController) setUserStatus(ctx *gin.Context, handler, status string) {
+	log := c.requestLogger(ctx, handler)
+	var uri request.UUIDParam
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		log.Warn("invalid uuid parameter", slog.String("error", err.Error()))
+		writeParamError(ctx, errInvalidUUID, "uuid", paramRule(err))
+		return
+	}
+
+	parsedUUID, err := uuid.Parse(uri.UUID)
+	if err != nil {
+		log.Warn("failed to parse uuid", slog.String("request.uuid_raw", uri.UUID))
+		writeParamError(ctx, errInvalidUUID, "uuid", "uuid")
+		return
+	}
+
+	log = log.With(slog.String("request.user_uuid", parsedUUID.String()))
+
+	updated, err := c.service.SetStatus(ctx.Request.Context(), parsedUUID, status)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrReservedUUID):
+			log.Warn("reserved uuid", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, service.ErrInvalidUserInput):
+			log.Warn("invalid user input", slog.String("error", err.Error()))
+			c.writeInvalidInput(ctx, err)
+			return
+		case errors.Is(err, service.ErrUserNotFound):
+			log.Warn("user not found", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusNotFound, err.Error())
+			return
+		case errors.Is(err, service.ErrReadOnly):
+			log.Warn("service is read-only", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusServiceUnavailable, err.Error())
+			return
+		default:
+			log.Error("failed to set user status", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	log.Info("user status set", slog.String("user.status", updated.Status))
+	ctx.Header("ETag", userETag(*updated))
+	ctx.JSON(http.StatusOK, c.present(*updated))
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"cruder/internal/controller/mocks"
+	"cruder/internal/model"
+	"cruder/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserController_SetStatus(t *testing.T) {
+	tests := []struct {
+		path   string
+		status string
+	}{
+		{"suspend", model.UserStatusSuspended},
+		{"activate", model.UserStatusActive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			svc := mocks.NewUserServiceMock(t)
+			router := setupUserRouter(svc)
+			id := uuid.New()
+			user := &model.User{ID: 1, UUID: id.String(), Username: "jdoe", Status: tt.status, Version: 2}
+			svc.On("SetStatus", mock.Anything, id, tt.status).Return(user, nil).Once()
+
+			resp := serveUserRequest(router, http.MethodPost, "/api/v1/users/uuid/"+id.String()+"/"+tt.path)
+
+			require.Equal(t, http.StatusOK, resp.Code)
+			require.Equal(t, userETag(*user), resp.Header().Get("ETag"))
+			require.Contains(t, resp.Body.String(), `"status":"`+tt.status+`"`)
+		})
+	}
+}
+
+func TestUserController_SetStatus_Errors(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+	}{
+		{service.ErrUserNotFound, http.StatusNotFound},
+		{service.ErrReservedUUID, http.StatusBadRequest},
+		{service.ErrReadOnly, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			svc := mocks.NewUserServiceMock(t)
+			router := setupUserRouter(svc)
+			id := uuid.New()
+			svc.On("SetStatus", mock.Anything, id, model.UserStatusSuspended).Return((*model.User)(nil), tt.err).Once()
+
+			resp := serveUserRequest(router, http.MethodPost, "/api/v1/users/uuid/"+id.String()+"/suspend")
+
+			require.Equal(t, tt.wantStatus, resp.Code)
+			require.JSONEq(t, `{"error":"`+tt.err.Error()+`"}`, resp.Body.String())
+		})
+	}
+}
+
+func TestUserController_SetStatus_InvalidUUID(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+
+	resp := serveUserRequest(router, http.MethodPost, "/api/v1/users/uuid/not-a-uuid/suspend")
+
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	svc.AssertNotCalled(t, "SetStatus", mock.Anything, mock.Anything, mock.Anything)
+}
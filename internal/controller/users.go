@@ -1,32 +1,130 @@
 package controller
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"cruder/internal/controller/request"
 	"cruder/internal/controller/response"
 	"cruder/internal/middleware"
+	"cruder/internal/model"
 	"cruder/internal/service"
 	"cruder/pkg/logger"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 )
 
 const (
-	errInvalidID   = "invalid id"
-	errInvalidUUID = "invalid uuid"
-	errInvalidBody = "invalid payload"
+	contentTypeNDJSON = "application/x-ndjson"
+	// exportFlushEvery is how many lines ExportUsers writes between flushes.
+	exportFlushEvery = 100
+)
+
+const (
+	errInvalidID       = "invalid id"
+	errInvalidUUID     = "invalid uuid"
+	errInvalidUsername = "invalid username"
+	errInvalidBody     = "invalid payload"
 )
 
 type UserController struct {
 	service service.UserService
+	opts    UserControllerOptions
+}
+
+type UserControllerOptions struct {
+	// UnprocessableEntity answers well-formed bodies that fail validation with
+	// 422 instead of 400. Malformed JSON is always 400.
+	UnprocessableEntity bool
+	// UUIDOnly hides numeric ids: responses omit id and the router skips the
+	// /id/ routes. Ids still exist in the database.
+	UUIDOnly bool
 }
 
 func NewUserController(service service.UserService) *UserController {
-	return &UserController{service: service}
+	return NewUserControllerWithOptions(service, UserControllerOptions{})
+}
+
+func NewUserControllerWithOptions(service service.UserService, opts UserControllerOptions) *UserController {
+	return &UserController{service: service, opts: opts}
+}
+
+// UUIDOnly reports whether the numeric-id routes should stay unregistered.
+func (c *UserController) UUIDOnly() bool {
+	return c.opts.UUIDOnly
+}
+
+func (c *UserController) present(u model.User) response.User {
+	return response.NewUser(u, c.opts.UUIDOnly)
+}
+
+func (c *UserController) presentAll(users []model.User) []response.User {
+	out := make([]response.User, len(users))
+	for i, u := range users {
+		out[i] = c.present(u)
+	}
+	return out
+}
+
+// userFields parses the ?fields= projection shared by the user getters,
+// writing the 400 itself when a field name is unknown.
+func (c *UserController) userFields(ctx *gin.Context, log *logger.Logger) (response.UserFields, bool) {
+	fields, err := response.ParseUserFields(ctx.Query("fields"), c.opts.UUIDOnly)
+	if err != nil {
+		log.Warn("invalid fields parameter", slog.String("error", err.Error()))
+		writeError(ctx, http.StatusBadRequest, "invalid fields: "+err.Error())
+		return nil, false
+	}
+	return fields, true
+}
+
+// validationStatus is the status for a parseable body the service rejected.
+func (c *UserController) validationStatus() int {
+	if c.opts.UnprocessableEntity {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusBadRequest
+}
+
+// writeInvalidInput renders a service validation failure, listing the
+// offending fields when the service reported them.
+func (c *UserController) writeInvalidInput(ctx *gin.Context, err error) {
+	var verr *service.ValidationError
+	if errors.As(err, &verr) {
+		writeFieldErrors(ctx, c.validationStatus(), service.ErrInvalidUserInput.Error(), verr.Fields)
+		return
+	}
+	writeError(ctx, c.validationStatus(), err.Error())
+}
+
+// writeConflict renders a duplicate user as 409, naming the conflicting field
+// when the service could tell which one it was.
+func writeConflict(ctx *gin.Context, err error) {
+	var cerr *service.ConflictError
+	if errors.As(err, &cerr) {
+		writeFieldErrors(ctx, http.StatusConflict, service.ErrUserAlreadyExists.Error(), cerr.Fields)
+		return
+	}
+	writeError(ctx, http.StatusConflict, err.Error())
+}
+
+// bindStatus separates malformed JSON from bodies that decoded but failed
+// binding tags such as required.
+func (c *UserController) bindStatus(err error) int {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return c.validationStatus()
+	}
+	return http.StatusBadRequest
 }
 
 func (c *UserController) requestLogger(ctx *gin.Context, operation string) *logger.Logger {
@@ -39,152 +137,467 @@ func (c *UserController) requestLogger(ctx *gin.Context, operation string) *logg
 
 // GetAllUsers godoc
 // @Summary      List users
+// @Description  Returns every user unless a page is requested with page/per_page or limit/offset (not both).
+// @Description  Users listed in pin come first, in the given order; the rest follow by id.
+// @Description  With after (optionally plus limit) the response is a response.UserPage envelope whose next_cursor feeds the next request.
 // @Tags         users
+// @Security     APIKeyAuth
 // @Produce      json
+// @Param        page      query     int     false  "Page number, starting at 1"
+// @Param        per_page  query     int     false  "Page size"
+// @Param        limit     query     int     false  "Maximum number of users"
+// @Param        offset    query     int     false  "Number of users to skip"
+// @Param        after     query     int     false  "Return users with an id above this cursor"
+// @Param        pin       query     string  false  "Comma-separated user UUIDs to list first"
+// @Param        created_after   query  string  false  "Only users created at or after this RFC 3339 time"
+// @Param        created_before  query  string  false  "Only users created before this RFC 3339 time"
+// @Param        fields    query     string  false  "Comma-separated fields to return (id, uuid, username, email, full_name, avatar_url, email_verified, status, version)"
 // @Success      200  {array}   response.User
+// @Failure      400  {object}  response.Error
 // @Failure      500  {object}  response.Error
 // @Router       /api/v1/users/ [get]
 func (c *UserController) GetAllUsers(ctx *gin.Context) {
 	log := c.requestLogger(ctx, "GetAllUsers")
 
-	users, err := c.service.GetAll()
+	fields, ok := c.userFields(ctx, log)
+	if !ok {
+		return
+	}
+	pinned, err := parsePinned(ctx.Query("pin"))
+	if err != nil {
+		log.Warn("invalid pin parameter", slog.String("error", err.Error()))
+		writeError(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	createdAfter, createdBefore, err := parseCreatedRange(ctx)
+	if err != nil {
+		log.Warn("invalid created_at range", slog.String("error", err.Error()))
+		writeError(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	query := service.ListUsersQuery{Pinned: pinned, CreatedAfter: createdAfter, CreatedBefore: createdBefore}
+	filtered := !createdAfter.IsZero() || !createdBefore.IsZero()
+
+	var users []model.User
+	page, paged := middleware.PageFromContext(ctx)
+	if page.Cursor {
+		c.getUsersAfter(ctx, log, page, query, fields)
+		return
+	}
+	if paged || len(pinned) > 0 || filtered {
+		query.Limit, query.Offset = page.Limit, page.Offset
+		log = log.With(slog.Int("page.limit", page.Limit), slog.Int("page.offset", page.Offset), slog.Int("pinned.count", len(pinned)), slog.Bool("request.created_filter", filtered))
+		users, err = c.service.List(ctx.Request.Context(), query)
+	} else {
+		users, err = c.service.GetAll(ctx.Request.Context())
+	}
+	if errors.Is(err, service.ErrInvalidUserInput) {
+		writeError(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil && ctx.Request.Context().Err() != nil {
+		// the client is gone or the timeout middleware answers for us
+		log.Debug("fetch users abandoned", slog.String("error", err.Error()))
+		ctx.Abort()
+		return
+	}
 	if err != nil {
 		log.Error("failed to fetch users", slog.String("error", err.Error()))
-		ctx.JSON(http.StatusInternalServerError, response.Error{Error: err.Error()})
+		writeError(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	log.Debug("fetched users", slog.Int("users.count", len(users)))
-	ctx.JSON(http.StatusOK, users)
+	ctx.JSON(http.StatusOK, fields.Users(c.presentAll(users)))
+}
+
+// getUsersAfter serves keyset pages, wrapped in an envelope carrying the
+// cursor for the next page.
+func (c *UserController) getUsersAfter(ctx *gin.Context, log *logger.Logger, page middleware.Page, query service.ListUsersQuery, fields response.UserFields) {
+	switch {
+	case len(query.Pinned) > 0:
+		writeError(ctx, http.StatusBadRequest, "pin cannot be combined with after")
+		return
+	case c.opts.UUIDOnly:
+		// cursors are numeric ids, which UUID-only mode keeps private
+		writeError(ctx, http.StatusBadRequest, "after is unavailable in uuid-only mode")
+		return
+	}
+
+	log = log.With(slog.Int64("page.after", page.After), slog.Int("page.limit", page.Limit))
+	query.AfterID, query.Limit = page.After, page.Limit
+	users, err := c.service.List(ctx.Request.Context(), query)
+	if errors.Is(err, service.ErrInvalidUserInput) {
+		writeError(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil && ctx.Request.Context().Err() != nil {
+		// the client is gone or the timeout middleware answers for us
+		log.Debug("fetch users after cursor abandoned", slog.String("error", err.Error()))
+		ctx.Abort()
+		return
+	}
+	if err != nil {
+		log.Error("failed to fetch users after cursor", slog.String("error", err.Error()))
+		writeError(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	result := response.UserPage{Users: c.presentAll(users)}
+	if len(users) == page.Limit {
+		next := int64(users[len(users)-1].ID)
+		result.NextCursor = &next
+	}
+	log.Debug("fetched users after cursor", slog.Int("users.count", len(users)))
+	ctx.JSON(http.StatusOK, fields.Page(result))
+}
+
+// ExportUsers godoc
+// @Summary      Export users
+// @Description  Streams every user as newline-delimited JSON, one response.User object per line, in id order.
+// @Description  Users are read in batches from one snapshot, so memory use does not grow with the table and the export is consistent.
+// @Description  A failure after the first line can only end the stream early; it is logged, not reported to the client.
+// @Tags         users
+// @Security     APIKeyAuth
+// @Produce      application/x-ndjson
+// @Success      200  {object}  response.User
+// @Failure      500  {object}  response.Error
+// @Router       /api/v1/users/export [get]
+func (c *UserController) ExportUsers(ctx *gin.Context) {
+	log := c.requestLogger(ctx, "ExportUsers")
+	reqCtx := ctx.Request.Context()
+
+	// a large export outlasts the server's write timeout, which is sized
+	// for ordinary requests; not every writer supports deadlines, e.g. in tests
+	_ = http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{})
+	ctx.Header("Content-Type", contentTypeNDJSON)
+	enc := json.NewEncoder(ctx.Writer)
+	count := 0
+	err := c.service.Export(reqCtx, service.ExportOptions{Snapshot: true}, func(u model.User) error {
+		if err := enc.Encode(c.present(u)); err != nil {
+			return err
+		}
+		count++
+		if count%exportFlushEvery == 0 {
+			ctx.Writer.Flush()
+		}
+		return nil
+	})
+	log = log.With(slog.Int("users.count", count))
+	if err != nil {
+		if reqCtx.Err() != nil {
+			// an impatient client, not a server fault
+			log.Debug("export cancelled", slog.String("error", err.Error()))
+			ctx.Abort()
+			return
+		}
+		if ctx.Writer.Written() {
+			// the status line is gone; all that is left is to stop writing
+			log.Error("export aborted", slog.String("error", err.Error()))
+			ctx.Abort()
+			return
+		}
+		log.Error("failed to export users", slog.String("error", err.Error()))
+		ctx.Writer.Header().Del("Content-Type")
+		writeError(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+	log.Info("exported users")
+}
+
+// parseCreatedRange reads the optional RFC 3339 created_after and
+// created_before parameters; an absent bound is the zero time.
+func parseCreatedRange(ctx *gin.Context) (after, before time.Time, err error) {
+	for _, bound := range []struct {
+		name string
+		dst  *time.Time
+	}{{"created_after", &after}, {"created_before", &before}} {
+		raw := ctx.Query(bound.name)
+		if raw == "" {
+			continue
+		}
+		if *bound.dst, err = time.Parse(time.RFC3339, raw); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid %s: must be an RFC 3339 timestamp", bound.name)
+		}
+	}
+	return after, before, nil
+}
+
+// parsePinned reads the comma-separated pin parameter, skipping empty entries
+// and duplicates.
+func parsePinned(raw string) ([]uuid.UUID, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var pinned []uuid.UUID
+	seen := make(map[uuid.UUID]struct{})
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := uuid.Parse(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pin uuid %q", part)
+		}
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		pinned = append(pinned, id)
+	}
+	if len(pinned) > service.MaxPinnedUsers {
+		return nil, fmt.Errorf("pin accepts at most %d uuids", service.MaxPinnedUsers)
+	}
+	return pinned, nil
 }
 
 // GetUserByUsername godoc
 // @Summary      Fetch user by username
 // @Tags         users
+// @Security     APIKeyAuth
 // @Param        username  path      string  true  "User username"
+// @Param        fields    query     string  false  "Comma-separated fields to return (id, uuid, username, email, full_name, avatar_url, email_verified, status, version)"
 // @Produce      json
+// @Param        If-None-Match  header  string  false  "ETag from an earlier response; a match returns 304"
 // @Success      200  {object}  response.User
+// @Header       200  {string}  ETag  "Validator for If-None-Match and If-Match"
+// @Success      304  "Not Modified"
+// @Failure      400  {object}  response.Error
 // @Failure      404  {object}  response.Error
 // @Failure      500  {object}  response.Error
 // @Router       /api/v1/users/username/{username} [get]
 func (c *UserController) GetUserByUsername(ctx *gin.Context) {
 	username := ctx.Param("username")
-	log := c.requestLogger(ctx, "GetUserByUsername").With(slog.String("request.username", username))
+	log := c.requestLogger(ctx, "GetUserByUsername")
+	fields, ok := c.userFields(ctx, log)
+	if !ok {
+		return
+	}
+
+	if utf8.RuneCountInString(username) > service.MaxUsernameLength {
+		log.Warn("username parameter too long", slog.Int("request.username_length", len(username)))
+		writeError(ctx, http.StatusBadRequest, errInvalidUsername)
+		return
+	}
+
+	log = log.With(slog.String("request.username", username))
 
-	user, err := c.service.GetByUsername(username)
+	user, err := c.service.GetByUsername(ctx.Request.Context(), username)
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotFound) {
 			log.Warn("user not found")
-			ctx.JSON(http.StatusNotFound, response.Error{Error: err.Error()})
+			writeError(ctx, http.StatusNotFound, err.Error())
 			return
 		}
 		log.Error("failed to fetch user by username", slog.String("error", err.Error()))
-		ctx.JSON(http.StatusInternalServerError, response.Error{Error: err.Error()})
+		writeError(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	log.Debug("fetched user by username")
-	ctx.JSON(http.StatusOK, user)
+	c.writeUser(ctx, *user, fields)
 }
 
 // GetUserByID godoc
 // @Summary      Fetch user by ID
 // @Tags         users
+// @Security     APIKeyAuth
 // @Param        id   path      int  true  "User ID"
+// @Param        fields    query     string  false  "Comma-separated fields to return (id, uuid, username, email, full_name, avatar_url, email_verified, status, version)"
 // @Produce      json
+// @Param        If-None-Match  header  string  false  "ETag from an earlier response; a match returns 304"
 // @Success      200  {object}  response.User
+// @Header       200  {string}  ETag  "Validator for If-None-Match and If-Match"
+// @Success      304  "Not Modified"
 // @Failure      400  {object}  response.Error
 // @Failure      404  {object}  response.Error
 // @Failure      500  {object}  response.Error
 // @Router       /api/v1/users/id/{id} [get]
 func (c *UserController) GetUserByID(ctx *gin.Context) {
 	log := c.requestLogger(ctx, "GetUserByID")
+	fields, ok := c.userFields(ctx, log)
+	if !ok {
+		return
+	}
 	var uri request.IDParam
 	if err := ctx.ShouldBindUri(&uri); err != nil {
 		log.Warn("invalid id parameter", slog.String("error", err.Error()))
-		ctx.JSON(http.StatusBadRequest, response.Error{Error: errInvalidID})
+		writeParamError(ctx, errInvalidID, "id", paramRule(err))
 		return
 	}
 
-	log = log.With(slog.Int64("request.user_id", uri.ID))
+	log = log.With(slog.Int64("request.user_id", uri.ID.Int64()))
 
-	user, err := c.service.GetByID(uri.ID)
+	user, err := c.service.GetByID(ctx.Request.Context(), uri.ID.Int64())
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotFound) {
 			log.Warn("user not found")
-			ctx.JSON(http.StatusNotFound, response.Error{Error: err.Error()})
+			writeError(ctx, http.StatusNotFound, err.Error())
 			return
 		}
 		log.Error("failed to fetch user by id", slog.String("error", err.Error()))
-		ctx.JSON(http.StatusInternalServerError, response.Error{Error: err.Error()})
+		writeError(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	log.Debug("fetched user by id")
-	ctx.JSON(http.StatusOK, user)
+	c.writeUser(ctx, *user, fields)
 }
 
 // GetUserByUUID godoc
 // @Summary      Fetch user by UUID
 // @Tags         users
+// @Security     APIKeyAuth
 // @Param        uuid  path      string  true  "User UUID"
+// @Param        fields    query     string  false  "Comma-separated fields to return (id, uuid, username, email, full_name, avatar_url, email_verified, status, version)"
 // @Produce      json
+// @Param        If-None-Match  header  string  false  "ETag from an earlier response; a match returns 304"
 // @Success      200  {object}  response.User
+// @Header       200  {string}  ETag  "Validator for If-None-Match and If-Match"
+// @Success      304  "Not Modified"
 // @Failure      400  {object}  response.Error
 // @Failure      404  {object}  response.Error
 // @Failure      500  {object}  response.Error
 // @Router       /api/v1/users/uuid/{uuid} [get]
 func (c *UserController) GetUserByUUID(ctx *gin.Context) {
 	log := c.requestLogger(ctx, "GetUserByUUID")
+	fields, ok := c.userFields(ctx, log)
+	if !ok {
+		return
+	}
 	var uri request.UUIDParam
 	if err := ctx.ShouldBindUri(&uri); err != nil {
 		log.Warn("invalid uuid parameter", slog.String("error", err.Error()))
-		ctx.JSON(http.StatusBadRequest, response.Error{Error: errInvalidUUID})
+		writeParamError(ctx, errInvalidUUID, "uuid", paramRule(err))
 		return
 	}
 
 	parsedUUID, err := uuid.Parse(uri.UUID)
 	if err != nil {
 		log.Warn("failed to parse uuid", slog.String("request.uuid_raw", uri.UUID))
-		ctx.JSON(http.StatusBadRequest, response.Error{Error: errInvalidUUID})
+		writeParamError(ctx, errInvalidUUID, "uuid", "uuid")
 		return
 	}
 
 	log = log.With(slog.String("request.user_uuid", parsedUUID.String()))
 
-	user, err := c.service.GetByUUID(parsedUUID)
+	user, err := c.service.GetByUUID(ctx.Request.Context(), parsedUUID)
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotFound) {
 			log.Warn("user not found")
-			ctx.JSON(http.StatusNotFound, response.Error{Error: err.Error()})
+			writeError(ctx, http.StatusNotFound, err.Error())
 			return
 		}
 		log.Error("failed to fetch user by uuid", slog.String("error", err.Error()))
-		ctx.JSON(http.StatusInternalServerError, response.Error{Error: err.Error()})
+		writeError(ctx, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	log.Debug("fetched user by uuid")
-	ctx.JSON(http.StatusOK, user)
+	c.writeUser(ctx, *user, fields)
+}
+
+// HeadUserByUUID godoc
+// @Summary      Check a user exists by UUID
+// @Description  Answers 200 or 404 with no body, without loading the user.
+// @Tags         users
+// @Security     APIKeyAuth
+// @Param        uuid  path  string  true  "User UUID"
+// @Success      200  "User exists"
+// @Failure      400  "Invalid uuid"
+// @Failure      404  "No such user"
+// @Failure      500  "Lookup failed"
+// @Router       /api/v1/users/uuid/{uuid} [head]
+func (c *UserController) HeadUserByUUID(ctx *gin.Context) {
+	log := c.requestLogger(ctx, "HeadUserByUUID")
+	var uri request.UUIDParam
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		log.Warn("invalid uuid parameter", slog.String("error", err.Error()))
+		ctx.Status(http.StatusBadRequest)
+		return
+	}
+	parsedUUID, err := uuid.Parse(uri.UUID)
+	if err != nil {
+		log.Warn("failed to parse uuid", slog.String("request.uuid_raw", uri.UUID))
+		ctx.Status(http.StatusBadRequest)
+		return
+	}
+
+	exists, err := c.service.ExistsByUUID(ctx.Request.Context(), parsedUUID)
+	c.writeExists(ctx, log.With(slog.String("request.user_uuid", parsedUUID.String())), exists, err)
+}
+
+// HeadUserByID godoc
+// @Summary      Check a user exists by ID
+// @Description  Answers 200 or 404 with no body, without loading the user.
+// @Tags         users
+// @Security     APIKeyAuth
+// @Param        id  path  int  true  "User ID"
+// @Success      200  "User exists"
+// @Failure      400  "Invalid id"
+// @Failure      404  "No such user"
+// @Failure      500  "Lookup failed"
+// @Router       /api/v1/users/id/{id} [head]
+func (c *UserController) HeadUserByID(ctx *gin.Context) {
+	log := c.requestLogger(ctx, "HeadUserByID")
+	var uri request.IDParam
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		log.Warn("invalid id parameter", slog.String("error", err.Error()))
+		ctx.Status(http.StatusBadRequest)
+		return
+	}
+
+	exists, err := c.service.ExistsByID(ctx.Request.Context(), uri.ID.Int64())
+	c.writeExists(ctx, log.With(slog.Int64("request.user_id", uri.ID.Int64())), exists, err)
+}
+
+// writeExists answers a HEAD existence check. HEAD responses carry no body,
+// so errors are reported by status alone.
+func (c *UserController) writeExists(ctx *gin.Context, log *logger.Logger, exists bool, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidUserInput):
+		log.Warn("invalid existence check", slog.String("error", err.Error()))
+		ctx.Status(http.StatusBadRequest)
+	case err != nil:
+		log.Error("failed to check user exists", slog.String("error", err.Error()))
+		ctx.Status(http.StatusInternalServerError)
+	case !exists:
+		log.Debug("user does not exist")
+		ctx.Status(http.StatusNotFound)
+	default:
+		log.Debug("user exists")
+		ctx.Status(http.StatusOK)
+	}
 }
 
 // CreateUser godoc
 // @Summary      Create user
+// @Description  full_name is optional and defaults to the username when blank. Retries carrying the same Idempotency-Key replay the original 201 response for 24h; a retry sent while the original is still running gets 409 with Retry-After.
 // @Tags         users
+// @Security     APIKeyAuth
 // @Accept       json
 // @Produce      json
-// @Param        request  body      request.CreateUser  true  "User payload"
-// @Success      201  {object}  response.User
+// @Param        request          body      request.CreateUser  true   "User payload"
+// @Param        Idempotency-Key  header    string              false  "Client-chosen key, scoped to the API key"
+// @Success      201  {object}  response.NormalizedUser
+// @Header       201  {string}  Location  "/api/v1/users/uuid/{uuid}"
 // @Failure      400  {object}  response.Error
 // @Failure      409  {object}  response.Error
+// @Failure      413  {object}  response.Error
+// @Failure      415  {object}  response.Error
+// @Failure      422  {object}  response.Error
 // @Failure      500  {object}  response.Error
+// @Failure      503  {object}  response.Error
 // @Router       /api/v1/users/ [post]
 func (c *UserController) CreateUser(ctx *gin.Context) {
 	log := c.requestLogger(ctx, "CreateUser")
 	var req request.CreateUser
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		log.Warn("invalid request body", slog.String("error", err.Error()))
-		ctx.JSON(http.StatusBadRequest, response.Error{Error: errInvalidBody})
+		writeBindError(ctx, c.bindStatus(err), err)
 		return
 	}
 
@@ -192,143 +605,200 @@ func (c *UserController) CreateUser(ctx *gin.Context) {
 		slog.String("request.username", req.Username),
 		slog.Bool("request.email_provided", req.Email != ""),
 		slog.Bool("request.full_name_provided", req.FullName != ""),
+		slog.Bool("request.avatar_url_provided", req.AvatarURL != ""),
 	)
 
-	user, err := c.service.Create(req.Username, req.Email, req.FullName)
+	user, err := c.service.Create(ctx.Request.Context(), req.Username, req.Email, req.FullName, req.AvatarURL)
 	if err != nil {
 		switch {
 		case errors.Is(err, service.ErrInvalidUserInput):
 			log.Warn("invalid user input", slog.String("error", err.Error()))
-			ctx.JSON(http.StatusBadRequest, response.Error{Error: err.Error()})
+			c.writeInvalidInput(ctx, err)
 			return
 		case errors.Is(err, service.ErrUserAlreadyExists):
 			log.Warn("user already exists", slog.String("error", err.Error()))
-			ctx.JSON(http.StatusConflict, response.Error{Error: err.Error()})
+			writeConflict(ctx, err)
+			return
+		case errors.Is(err, service.ErrReadOnly):
+			log.Warn("service is read-only", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusServiceUnavailable, err.Error())
 			return
 		default:
 			log.Error("failed to create user", slog.String("error", err.Error()))
-			ctx.JSON(http.StatusInternalServerError, response.Error{Error: err.Error()})
+			writeError(ctx, http.StatusInternalServerError, err.Error())
 			return
 		}
 	}
 
 	log.Info("user created", slog.String("user.uuid", user.UUID), slog.Int("user.id", user.ID))
-	ctx.JSON(http.StatusCreated, user)
+	ctx.Header("Location", userLocation(ctx, user.UUID))
+	ctx.JSON(http.StatusCreated, response.NormalizedUser{
+		User:       c.present(*user),
+		Normalized: normalizedFields(user, &req.Username, &req.Email, &req.FullName, &req.AvatarURL),
+	})
+}
+
+// userLocation returns the canonical URL of a user created through the
+// collection route: its uuid route, which survives username changes and
+// UUID-only mode.
+func userLocation(ctx *gin.Context, userUUID string) string {
+	return path.Join(ctx.FullPath(), "uuid", userUUID)
 }
 
 // UpdateUserByUUID godoc
 // @Summary      Update user by UUID
+// @Description  The body must include the version the client last read. A stale version returns 409 with code version_conflict.
 // @Tags         users
+// @Security     APIKeyAuth
 // @Accept       json
 // @Produce      json
 // @Param        uuid     path      string             true  "User UUID"
 // @Param        request  body      request.UpdateUser  true  "User payload"
-// @Success      200  {object}  response.User
+// @Param        If-Match  header   string             false  "Apply only if the user still has this ETag"
+// @Success      200  {object}  response.NormalizedUser
+// @Header       200  {string}  ETag  "Validator of the updated user"
 // @Failure      400  {object}  response.Error
 // @Failure      404  {object}  response.Error
 // @Failure      409  {object}  response.Error
+// @Failure      412  {object}  response.Error
+// @Failure      413  {object}  response.Error
+// @Failure      415  {object}  response.Error
+// @Failure      422  {object}  response.Error
 // @Failure      500  {object}  response.Error
+// @Failure      503  {object}  response.Error
 // @Router       /api/v1/users/uuid/{uuid} [patch]
 func (c *UserController) UpdateUserByUUID(ctx *gin.Context) {
 	log := c.requestLogger(ctx, "UpdateUserByUUID")
 	var uri request.UUIDParam
 	if err := ctx.ShouldBindUri(&uri); err != nil {
 		log.Warn("invalid uuid parameter", slog.String("error", err.Error()))
-		ctx.JSON(http.StatusBadRequest, response.Error{Error: errInvalidUUID})
+		writeParamError(ctx, errInvalidUUID, "uuid", paramRule(err))
 		return
 	}
 
 	parsedUUID, err := uuid.Parse(uri.UUID)
 	if err != nil {
 		log.Warn("failed to parse uuid", slog.String("request.uuid_raw", uri.UUID))
-		ctx.JSON(http.StatusBadRequest, response.Error{Error: errInvalidUUID})
+		writeParamError(ctx, errInvalidUUID, "uuid", "uuid")
 		return
 	}
 
 	var req request.UpdateUser
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		log.Warn("invalid request body", slog.String("error", err.Error()))
-		ctx.JSON(http.StatusBadRequest, response.Error{Error: errInvalidBody})
+		writeBindError(ctx, c.bindStatus(err), err)
 		return
 	}
 
 	log = log.With(
 		slog.String("request.user_uuid", parsedUUID.String()),
-		slog.Bool("request.username_update", req.Username != nil),
-		slog.Bool("request.email_update", req.Email != nil),
-		slog.Bool("request.full_name_update", req.FullName != nil),
+		slog.Bool("request.username_update", req.Username.Set),
+		slog.Bool("request.email_update", req.Email.Set),
+		slog.Bool("request.full_name_update", req.FullName.Set),
+		slog.Bool("request.avatar_url_update", req.AvatarURL.Set),
 	)
 
-	updated, err := c.service.UpdateByUUID(parsedUUID, service.UpdateUserInput{
-		Username: req.Username,
-		Email:    req.Email,
-		FullName: req.FullName,
+	updated, err := c.service.UpdateByUUID(ctx.Request.Context(), parsedUUID, service.UpdateUserInput{
+		Username:     req.Username.Update(),
+		Email:        req.Email.Update(),
+		FullName:     req.FullName.Update(),
+		AvatarURL:    req.AvatarURL.Update(),
+		Version:      req.Version,
+		Immutable:    req.ImmutableFields(),
+		Precondition: ifMatchPrecondition(ctx),
 	})
 	if err != nil {
 		switch {
+		case errors.Is(err, service.ErrReservedUUID):
+			// a bad path parameter, not a body problem, so never 422
+			log.Warn("reserved uuid", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusBadRequest, err.Error())
+			return
 		case errors.Is(err, service.ErrInvalidUserInput):
 			log.Warn("invalid user input", slog.String("error", err.Error()))
-			ctx.JSON(http.StatusBadRequest, response.Error{Error: err.Error()})
+			c.writeInvalidInput(ctx, err)
 			return
 		case errors.Is(err, service.ErrUserNotFound):
 			log.Warn("user not found", slog.String("error", err.Error()))
-			ctx.JSON(http.StatusNotFound, response.Error{Error: err.Error()})
+			writeError(ctx, http.StatusNotFound, err.Error())
 			return
 		case errors.Is(err, service.ErrUserAlreadyExists):
 			log.Warn("user already exists", slog.String("error", err.Error()))
-			ctx.JSON(http.StatusConflict, response.Error{Error: err.Error()})
+			writeConflict(ctx, err)
+			return
+		case errors.Is(err, service.ErrPreconditionFailed):
+			log.Warn("if-match precondition failed", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusPreconditionFailed, err.Error())
+			return
+		case errors.Is(err, service.ErrVersionConflict):
+			log.Warn("stale user version", slog.String("error", err.Error()))
+			writeErrorBody(ctx, http.StatusConflict, response.Error{Error: err.Error(), Code: errCodeVersionConflict})
+			return
+		case errors.Is(err, service.ErrReadOnly):
+			log.Warn("service is read-only", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusServiceUnavailable, err.Error())
 			return
 		default:
 			log.Error("failed to update user by uuid", slog.String("error", err.Error()))
-			ctx.JSON(http.StatusInternalServerError, response.Error{Error: err.Error()})
+			writeError(ctx, http.StatusInternalServerError, err.Error())
 			return
 		}
 	}
 
 	log.Info("user updated by uuid", slog.Int("user.id", updated.ID))
-	ctx.JSON(http.StatusOK, updated)
+	ctx.Header("ETag", userETag(*updated))
+	ctx.JSON(http.StatusOK, response.NormalizedUser{
+		User:       c.present(*updated),
+		Normalized: normalizedFields(updated, req.Username.Update(), req.Email.Update(), req.FullName.Update(), req.AvatarURL.Update()),
+	})
 }
 
 // DeleteUserByUUID godoc
 // @Summary      Delete user by UUID
 // @Tags         users
+// @Security     APIKeyAuth
 // @Param        uuid  path  string  true  "User UUID"
 // @Success      204  "No Content"
 // @Failure      400  {object}  response.Error
 // @Failure      404  {object}  response.Error
 // @Failure      500  {object}  response.Error
+// @Failure      503  {object}  response.Error
 // @Router       /api/v1/users/uuid/{uuid} [delete]
 func (c *UserController) DeleteUserByUUID(ctx *gin.Context) {
 	log := c.requestLogger(ctx, "DeleteUserByUUID")
 	var uri request.UUIDParam
 	if err := ctx.ShouldBindUri(&uri); err != nil {
 		log.Warn("invalid uuid parameter", slog.String("error", err.Error()))
-		ctx.JSON(http.StatusBadRequest, response.Error{Error: errInvalidUUID})
+		writeParamError(ctx, errInvalidUUID, "uuid", paramRule(err))
 		return
 	}
 
 	parsedUUID, err := uuid.Parse(uri.UUID)
 	if err != nil {
 		log.Warn("failed to parse uuid", slog.String("request.uuid_raw", uri.UUID))
-		ctx.JSON(http.StatusBadRequest, response.Error{Error: errInvalidUUID})
+		writeParamError(ctx, errInvalidUUID, "uuid", "uuid")
 		return
 	}
 
 	log = log.With(slog.String("request.user_uuid", parsedUUID.String()))
 
-	if err := c.service.DeleteByUUID(parsedUUID); err != nil {
+	if err := c.service.DeleteByUUID(ctx.Request.Context(), parsedUUID); err != nil {
 		switch {
 		case errors.Is(err, service.ErrInvalidUserInput):
 			log.Warn("invalid user input", slog.String("error", err.Error()))
-			ctx.JSON(http.StatusBadRequest, response.Error{Error: err.Error()})
+			writeError(ctx, http.StatusBadRequest, err.Error())
 			return
 		case errors.Is(err, service.ErrUserNotFound):
 			log.Warn("user not found", slog.String("error", err.Error()))
-			ctx.JSON(http.StatusNotFound, response.Error{Error: err.Error()})
+			writeError(ctx, http.StatusNotFound, err.Error())
+			return
+		case errors.Is(err, service.ErrReadOnly):
+			log.Warn("service is read-only", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusServiceUnavailable, err.Error())
 			return
 		default:
 			log.Error("failed to delete user by uuid", slog.String("error", err.Error()))
-			ctx.JSON(http.StatusInternalServerError, response.Error{Error: err.Error()})
+			writeError(ctx, http.StatusInternalServerError, err.Error())
 			return
 		}
 	}
@@ -337,105 +807,400 @@ func (c *UserController) DeleteUserByUUID(ctx *gin.Context) {
 	ctx.Status(http.StatusNoContent)
 }
 
+// DeleteUserByUsername godoc
+// @Summary      Delete user by username
+// @Tags         users
+// @Security     APIKeyAuth
+// @Param        username  path  string  true  "User username"
+// @Success      204  "No Content"
+// @Failure      400  {object}  response.Error
+// @Failure      404  {object}  response.Error
+// @Failure      500  {object}  response.Error
+// @Failure      503  {object}  response.Error
+// @Router       /api/v1/users/username/{username} [delete]
+func (c *UserController) DeleteUserByUsername(ctx *gin.Context) {
+	username := ctx.Param("username")
+	log := c.requestLogger(ctx, "DeleteUserByUsername").With(slog.String("request.username", username))
+
+	if err := c.service.DeleteByUsername(ctx.Request.Context(), username); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidUserInput):
+			log.Warn("invalid user input", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, service.ErrUserNotFound):
+			log.Warn("user not found", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusNotFound, err.Error())
+			return
+		case errors.Is(err, service.ErrReadOnly):
+			log.Warn("service is read-only", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusServiceUnavailable, err.Error())
+			return
+		default:
+			log.Error("failed to delete user by username", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	log.Info("user deleted by username")
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetUsersBatch godoc
+// @Summary      Get users by UUID in bulk
+// @Description  Loads every listed user in one query. Pass the uuids either as a JSON body or as a comma-separated uuids query parameter, not both. Duplicate uuids are ignored, users come back in no particular order, and uuids that match no user are listed in missing. A malformed uuid rejects the whole batch.
+// @Tags         users
+// @Security     APIKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        uuids    query     string                 false  "Comma-separated UUIDs to fetch"
+// @Param        request  body      request.BatchGetUsers  false  "UUIDs to fetch"
+// @Success      200  {object}  response.BatchGetResult
+// @Failure      400  {object}  response.Error
+// @Failure      413  {object}  response.Error
+// @Failure      415  {object}  response.Error
+// @Failure      422  {object}  response.Error
+// @Failure      500  {object}  response.Error
+// @Router       /api/v1/users/batch-get [post]
+func (c *UserController) GetUsersBatch(ctx *gin.Context) {
+	log := c.requestLogger(ctx, "GetUsersBatch")
+
+	var raw []string
+	if query, ok := ctx.GetQuery("uuids"); ok {
+		if ctx.Request.ContentLength > 0 {
+			log.Warn("uuids given in both query and body")
+			writeError(ctx, http.StatusBadRequest, "pass uuids in the query or the body, not both")
+			return
+		}
+		for _, item := range strings.Split(query, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				raw = append(raw, item)
+			}
+		}
+	} else {
+		var req request.BatchGetUsers
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			log.Warn("invalid request body", slog.String("error", err.Error()))
+			writeBindError(ctx, c.bindStatus(err), err)
+			return
+		}
+		raw = req.UUIDs
+	}
+
+	uuids, invalid := parseUUIDList(raw)
+	if len(invalid) > 0 {
+		log.Warn("invalid uuids in batch get", slog.Int("request.invalid_count", len(invalid)))
+		writeFieldErrors(ctx, http.StatusBadRequest, errInvalidUUID, invalid)
+		return
+	}
+
+	log = log.With(slog.Int("request.uuid_count", len(uuids)))
+
+	result, err := c.service.GetByUUIDs(ctx.Request.Context(), uuids)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidUserInput) {
+			log.Warn("invalid batch get", slog.String("error", err.Error()))
+			c.writeInvalidInput(ctx, err)
+			return
+		}
+		log.Error("failed to batch get users", slog.String("error", err.Error()))
+		writeError(ctx, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	missing := make([]string, len(result.Missing))
+	for i, id := range result.Missing {
+		missing[i] = id.String()
+	}
+	log.Info("users batch fetched", slog.Int("users.found", len(result.Users)), slog.Int("users.missing", len(missing)))
+	ctx.JSON(http.StatusOK, response.BatchGetResult{Users: c.presentAll(result.Users), Missing: missing})
+}
+
+// parseUUIDList parses a request's uuid list, reporting each malformed entry
+// under its index.
+func parseUUIDList(raw []string) ([]uuid.UUID, map[string]string) {
+	uuids := make([]uuid.UUID, len(raw))
+	invalid := map[string]string{}
+	for i, item := range raw {
+		parsed, err := uuid.Parse(item)
+		if err != nil {
+			invalid[fmt.Sprintf("uuids[%d]", i)] = "not a valid uuid"
+			continue
+		}
+		uuids[i] = parsed
+	}
+	return uuids, invalid
+}
+
+// DeleteUsersBulk godoc
+// @Summary      Delete users by UUID in bulk
+// @Description  Deletes every listed user in one statement. Duplicate uuids are ignored; a malformed or reserved uuid rejects the whole batch.
+// @Tags         users
+// @Security     APIKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      request.BulkDeleteUsers  true  "UUIDs to delete"
+// @Success      200  {object}  response.BulkDeleteResult
+// @Failure      400  {object}  response.Error
+// @Failure      413  {object}  response.Error
+// @Failure      415  {object}  response.Error
+// @Failure      422  {object}  response.Error
+// @Failure      500  {object}  response.Error
+// @Failure      503  {object}  response.Error
+// @Router       /api/v1/users/bulk-delete [post]
+func (c *UserController) DeleteUsersBulk(ctx *gin.Context) {
+	log := c.requestLogger(ctx, "DeleteUsersBulk")
+	var req request.BulkDeleteUsers
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Warn("invalid request body", slog.String("error", err.Error()))
+		writeBindError(ctx, c.bindStatus(err), err)
+		return
+	}
+
+	uuids, invalid := parseUUIDList(req.UUIDs)
+	if len(invalid) > 0 {
+		log.Warn("invalid uuids in bulk delete", slog.Int("request.invalid_count", len(invalid)))
+		writeFieldErrors(ctx, http.StatusBadRequest, errInvalidUUID, invalid)
+		return
+	}
+
+	log = log.With(slog.Int("request.uuid_count", len(uuids)))
+
+	result, err := c.service.DeleteManyByUUID(ctx.Request.Context(), uuids)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrReservedUUID):
+			log.Warn("reserved uuid", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, service.ErrInvalidUserInput):
+			log.Warn("invalid bulk delete", slog.String("error", err.Error()))
+			c.writeInvalidInput(ctx, err)
+			return
+		case errors.Is(err, service.ErrReadOnly):
+			log.Warn("service is read-only", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusServiceUnavailable, err.Error())
+			return
+		default:
+			log.Error("failed to bulk delete users", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	notFound := make([]string, len(result.NotFound))
+	for i, id := range result.NotFound {
+		notFound[i] = id.String()
+	}
+	log.Info("users bulk deleted", slog.Int("users.deleted", result.Deleted), slog.Int("users.not_found", len(notFound)))
+	ctx.JSON(http.StatusOK, response.BulkDeleteResult{Deleted: result.Deleted, NotFound: notFound})
+}
+
+// UpdateUsersBulk godoc
+// @Summary      Set one field on users by UUID in bulk
+// @Description  Sets a single field on every listed user in one transaction. Besides uuids the body must hold exactly one key, naming the field: status (active or suspended) or avatar_url (null or "" removes it). Duplicate uuids are ignored; a malformed or reserved uuid, or an invalid field or value, rejects the whole batch.
+// @Description  Users that already have the value are not counted in updated.
+// @Tags         users
+// @Security     APIKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      request.BulkUpdateUsers  true  "UUIDs to update and the field to set"
+// @Success      200  {object}  response.BulkUpdateResult
+// @Failure      400  {object}  response.Error
+// @Failure      413  {object}  response.Error
+// @Failure      415  {object}  response.Error
+// @Failure      422  {object}  response.Error
+// @Failure      500  {object}  response.Error
+// @Failure      503  {object}  response.Error
+// @Router       /api/v1/users/bulk [patch]
+func (c *UserController) UpdateUsersBulk(ctx *gin.Context) {
+	log := c.requestLogger(ctx, "UpdateUsersBulk")
+	var req request.BulkUpdateUsers
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Warn("invalid request body", slog.String("error", err.Error()))
+		if errors.Is(err, request.ErrBulkUpdateField) {
+			writeError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeBindError(ctx, c.bindStatus(err), err)
+		return
+	}
+
+	uuids, invalid := parseUUIDList(req.UUIDs)
+	if len(invalid) > 0 {
+		log.Warn("invalid uuids in bulk update", slog.Int("request.invalid_count", len(invalid)))
+		writeFieldErrors(ctx, http.StatusBadRequest, errInvalidUUID, invalid)
+		return
+	}
+
+	log = log.With(slog.Int("request.uuid_count", len(uuids)), slog.String("request.field", req.Field))
+
+	result, err := c.service.UpdateManyByUUID(ctx.Request.Context(), uuids, req.Field, req.Value)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrReservedUUID):
+			log.Warn("reserved uuid", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, service.ErrInvalidUserInput):
+			log.Warn("invalid bulk update", slog.String("error", err.Error()))
+			c.writeInvalidInput(ctx, err)
+			return
+		case errors.Is(err, service.ErrReadOnly):
+			log.Warn("service is read-only", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusServiceUnavailable, err.Error())
+			return
+		default:
+			log.Error("failed to bulk update users", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	notFound := make([]string, len(result.NotFound))
+	for i, id := range result.NotFound {
+		notFound[i] = id.String()
+	}
+	log.Info("users bulk updated", slog.Int("users.updated", result.Updated), slog.Int("users.not_found", len(notFound)))
+	ctx.JSON(http.StatusOK, response.BulkUpdateResult{Updated: result.Updated, NotFound: notFound})
+}
+
 // UpdateUserByID godoc
 // @Summary      Update user by ID
+// @Description  The body must include the version the client last read. A stale version returns 409 with code version_conflict.
 // @Tags         users
+// @Security     APIKeyAuth
 // @Accept       json
 // @Produce      json
 // @Param        id       path      int               true  "User ID"
 // @Param        request  body      request.UpdateUser  true  "User payload"
-// @Success      200  {object}  response.User
+// @Param        If-Match  header   string             false  "Apply only if the user still has this ETag"
+// @Success      200  {object}  response.NormalizedUser
+// @Header       200  {string}  ETag  "Validator of the updated user"
 // @Failure      400  {object}  response.Error
 // @Failure      404  {object}  response.Error
 // @Failure      409  {object}  response.Error
+// @Failure      412  {object}  response.Error
+// @Failure      413  {object}  response.Error
+// @Failure      415  {object}  response.Error
+// @Failure      422  {object}  response.Error
 // @Failure      500  {object}  response.Error
+// @Failure      503  {object}  response.Error
 // @Router       /api/v1/users/id/{id} [patch]
 func (c *UserController) UpdateUserByID(ctx *gin.Context) {
 	log := c.requestLogger(ctx, "UpdateUserByID")
 	var uri request.IDParam
 	if err := ctx.ShouldBindUri(&uri); err != nil {
 		log.Warn("invalid id parameter", slog.String("error", err.Error()))
-		ctx.JSON(http.StatusBadRequest, response.Error{Error: errInvalidID})
+		writeParamError(ctx, errInvalidID, "id", paramRule(err))
 		return
 	}
 
 	var req request.UpdateUser
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		log.Warn("invalid request body", slog.String("error", err.Error()))
-		ctx.JSON(http.StatusBadRequest, response.Error{Error: errInvalidBody})
+		writeBindError(ctx, c.bindStatus(err), err)
 		return
 	}
 
 	log = log.With(
-		slog.Int64("request.user_id", uri.ID),
-		slog.Bool("request.username_update", req.Username != nil),
-		slog.Bool("request.email_update", req.Email != nil),
-		slog.Bool("request.full_name_update", req.FullName != nil),
+		slog.Int64("request.user_id", uri.ID.Int64()),
+		slog.Bool("request.username_update", req.Username.Set),
+		slog.Bool("request.email_update", req.Email.Set),
+		slog.Bool("request.full_name_update", req.FullName.Set),
+		slog.Bool("request.avatar_url_update", req.AvatarURL.Set),
 	)
 
-	updated, err := c.service.UpdateByID(uri.ID, service.UpdateUserInput{
-		Username: req.Username,
-		Email:    req.Email,
-		FullName: req.FullName,
+	updated, err := c.service.UpdateByID(ctx.Request.Context(), uri.ID.Int64(), service.UpdateUserInput{
+		Username:     req.Username.Update(),
+		Email:        req.Email.Update(),
+		FullName:     req.FullName.Update(),
+		AvatarURL:    req.AvatarURL.Update(),
+		Version:      req.Version,
+		Immutable:    req.ImmutableFields(),
+		Precondition: ifMatchPrecondition(ctx),
 	})
 	if err != nil {
 		switch {
 		case errors.Is(err, service.ErrInvalidUserInput):
 			log.Warn("invalid user input", slog.String("error", err.Error()))
-			ctx.JSON(http.StatusBadRequest, response.Error{Error: err.Error()})
+			c.writeInvalidInput(ctx, err)
 			return
 		case errors.Is(err, service.ErrUserNotFound):
 			log.Warn("user not found", slog.String("error", err.Error()))
-			ctx.JSON(http.StatusNotFound, response.Error{Error: err.Error()})
+			writeError(ctx, http.StatusNotFound, err.Error())
 			return
 		case errors.Is(err, service.ErrUserAlreadyExists):
 			log.Warn("user already exists", slog.String("error", err.Error()))
-			ctx.JSON(http.StatusConflict, response.Error{Error: err.Error()})
+			writeConflict(ctx, err)
+			return
+		case errors.Is(err, service.ErrPreconditionFailed):
+			log.Warn("if-match precondition failed", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusPreconditionFailed, err.Error())
+			return
+		case errors.Is(err, service.ErrVersionConflict):
+			log.Warn("stale user version", slog.String("error", err.Error()))
+			writeErrorBody(ctx, http.StatusConflict, response.Error{Error: err.Error(), Code: errCodeVersionConflict})
+			return
+		case errors.Is(err, service.ErrReadOnly):
+			log.Warn("service is read-only", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusServiceUnavailable, err.Error())
 			return
 		default:
 			log.Error("failed to update user by id", slog.String("error", err.Error()))
-			ctx.JSON(http.StatusInternalServerError, response.Error{Error: err.Error()})
+			writeError(ctx, http.StatusInternalServerError, err.Error())
 			return
 		}
 	}
 
 	log.Info("user updated by id", slog.String("user.uuid", updated.UUID))
-	ctx.JSON(http.StatusOK, updated)
+	ctx.Header("ETag", userETag(*updated))
+	ctx.JSON(http.StatusOK, response.NormalizedUser{
+		User:       c.present(*updated),
+		Normalized: normalizedFields(updated, req.Username.Update(), req.Email.Update(), req.FullName.Update(), req.AvatarURL.Update()),
+	})
 }
 
 // DeleteUserByID godoc
 // @Summary      Delete user by ID
 // @Tags         users
+// @Security     APIKeyAuth
 // @Param        id  path  int  true  "User ID"
 // @Success      204  "No Content"
 // @Failure      400  {object}  response.Error
 // @Failure      404  {object}  response.Error
 // @Failure      500  {object}  response.Error
+// @Failure      503  {object}  response.Error
 // @Router       /api/v1/users/id/{id} [delete]
 func (c *UserController) DeleteUserByID(ctx *gin.Context) {
 	log := c.requestLogger(ctx, "DeleteUserByID")
 	var uri request.IDParam
 	if err := ctx.ShouldBindUri(&uri); err != nil {
 		log.Warn("invalid id parameter", slog.String("error", err.Error()))
-		ctx.JSON(http.StatusBadRequest, response.Error{Error: errInvalidID})
+		writeParamError(ctx, errInvalidID, "id", paramRule(err))
 		return
 	}
 
-	log = log.With(slog.Int64("request.user_id", uri.ID))
+	log = log.With(slog.Int64("request.user_id", uri.ID.Int64()))
 
-	if err := c.service.DeleteByID(uri.ID); err != nil {
+	if err := c.service.DeleteByID(ctx.Request.Context(), uri.ID.Int64()); err != nil {
 		switch {
 		case errors.Is(err, service.ErrInvalidUserInput):
 			log.Warn("invalid user input", slog.String("error", err.Error()))
-			ctx.JSON(http.StatusBadRequest, response.Error{Error: err.Error()})
+			writeError(ctx, http.StatusBadRequest, err.Error())
 			return
 		case errors.Is(err, service.ErrUserNotFound):
 			log.Warn("user not found", slog.String("error", err.Error()))
-			ctx.JSON(http.StatusNotFound, response.Error{Error: err.Error()})
+			writeError(ctx, http.StatusNotFound, err.Error())
+			return
+		case errors.Is(err, service.ErrReadOnly):
+			log.Warn("service is read-only", slog.String("error", err.Error()))
+			writeError(ctx, http.StatusServiceUnavailable, err.Error())
 			return
 		default:
 			log.Error("failed to delete user by id", slog.String("error", err.Error()))
-			ctx.JSON(http.StatusInternalServerError, response.Error{Error: err.Error()})
+			writeError(ctx, http.StatusInternalServerError, err.Error())
 			return
 		}
 	}
@@ -443,3 +1208,22 @@ func (c *UserController) DeleteUserByID(ctx *gin.Context) {
 	log.Info("user deleted by id")
 	ctx.Status(http.StatusNoContent)
 }
+
+// normalizedFields lists the submitted fields whose stored value differs from
+// the submitted one (e.g. trimmed whitespace). Nil inputs were not submitted.
+func normalizedFields(user *model.User, username, email, fullName, avatarURL *string) []string {
+	var fields []string
+	if username != nil && *username != user.Username {
+		fields = append(fields, "username")
+	}
+	if email != nil && *email != user.Email {
+		fields = append(fields, "email")
+	}
+	if fullName != nil && *fullName != user.FullName {
+		fields = append(fields, "full_name")
+	}
+	if avatarURL != nil && *avatarURL != user.AvatarURL {
+		fields = append(fields, "avatar_url")
+	}
+	return fields
+}
@@ -0,0 +1,931 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cruder/internal/controller/mocks"
+	"cruder/internal/controller/response"
+	"cruder/internal/middleware"
+	"cruder/internal/model"
+	"cruder/internal/service"
+	"cruder/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserController_GetUserByUsername_TooLong(t *testing.T) {
+	// Given: a controller backed by a mock service
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+
+	// When: requesting a username longer than the create-time limit
+	username := strings.Repeat("a", service.MaxUsernameLength+1)
+	resp := serveUserRequest(router, http.MethodGet, "/api/v1/users/username/"+username)
+
+	// Then: the request is rejected before reaching the service
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.JSONEq(t, `{"error":"invalid username"}`, resp.Body.String())
+	svc.AssertNotCalled(t, "GetByUsername", mock.Anything, mock.Anything)
+}
+
+func TestUserController_GetUserByUsername_MaxLength(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	username := strings.Repeat("a", service.MaxUsernameLength)
+	svc.On("GetByUsername", mock.Anything, username).Return(&model.User{Username: username}, nil).Once()
+
+	resp := serveUserRequest(router, http.MethodGet, "/api/v1/users/username/"+username)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestUserController_DeleteUserByUsername(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("DeleteByUsername", mock.Anything, "jdoe").Return(nil).Once()
+	svc.On("DeleteByUsername", mock.Anything, "ghost").Return(service.ErrUserNotFound).Once()
+
+	resp := serveUserRequest(router, http.MethodDelete, "/api/v1/users/username/jdoe")
+	require.Equal(t, http.StatusNoContent, resp.Code)
+
+	resp = serveUserRequest(router, http.MethodDelete, "/api/v1/users/username/ghost")
+	require.Equal(t, http.StatusNotFound, resp.Code)
+	require.JSONEq(t, `{"error":"user not found"}`, resp.Body.String())
+}
+
+func TestUserController_CreateUser_ReportsNormalizedFields(t *testing.T) {
+	// Given: the service normalizes the submitted email
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("Create", mock.Anything, "jdoe", "JDoe@Example.com", "John Doe", "").
+		Return(&model.User{ID: 1, Username: "jdoe", Email: "jdoe@example.com", FullName: "John Doe"}, nil).Once()
+
+	// When: creating a user with a mixed-case email
+	resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/",
+		`{"username":"jdoe","email":"JDoe@Example.com","full_name":"John Doe"}`)
+
+	// Then: the response reports the email as normalized
+	require.Equal(t, http.StatusCreated, resp.Code)
+	var body response.NormalizedUser
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	require.Equal(t, []string{"email"}, body.Normalized)
+	require.Equal(t, "jdoe@example.com", body.Email)
+}
+
+func TestUserController_CreateUser_SetsLocation(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	id := uuid.NewString()
+	svc.On("Create", mock.Anything, "jdoe", "jdoe@example.com", "John Doe", "").
+		Return(&model.User{ID: 1, UUID: id, Username: "jdoe", Email: "jdoe@example.com", FullName: "John Doe"}, nil).Once()
+
+	resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/",
+		`{"username":"jdoe","email":"jdoe@example.com","full_name":"John Doe"}`)
+
+	require.Equal(t, http.StatusCreated, resp.Code)
+	require.Equal(t, "/api/v1/users/uuid/"+id, resp.Header().Get("Location"))
+}
+
+func TestUserController_CreateUser_CanonicalInputHasNoReport(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("Create", mock.Anything, "jdoe", "jdoe@example.com", "John Doe", "").
+		Return(&model.User{ID: 1, Username: "jdoe", Email: "jdoe@example.com", FullName: "John Doe"}, nil).Once()
+
+	resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/",
+		`{"username":"jdoe","email":"jdoe@example.com","full_name":"John Doe"}`)
+
+	require.Equal(t, http.StatusCreated, resp.Code)
+	require.NotContains(t, resp.Body.String(), "normalized")
+}
+
+func TestUserController_UpdateUserByID_ReportsOnlySubmittedFields(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("UpdateByID", mock.Anything, int64(3), mock.AnythingOfType("service.UpdateUserInput")).
+		Return(&model.User{ID: 3, Username: "jdoe", Email: "jdoe@example.com", FullName: "John Doe"}, nil).Once()
+
+	resp := serveUserJSON(router, http.MethodPatch, "/api/v1/users/id/3", `{"full_name":"  John Doe "}`)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	var body response.NormalizedUser
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	require.Equal(t, []string{"full_name"}, body.Normalized)
+}
+
+func TestUserController_UpdateUserByUUID_MergePatch(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+	tests := []struct {
+		name          string
+		body          string
+		wantEmail     *string
+		wantFullName  *string
+		wantAvatarURL *string
+	}{
+		{name: "omitted keys are left alone", body: `{"version":1}`},
+		{name: "null clears", body: `{"email":null,"full_name":null,"avatar_url":null,"version":1}`, wantEmail: ptr(""), wantFullName: ptr(""), wantAvatarURL: ptr("")},
+		{name: "values set", body: `{"email":"jane@example.com","full_name":"Jane Doe","avatar_url":"https://cdn.example.com/jane.png","version":1}`, wantEmail: ptr("jane@example.com"), wantFullName: ptr("Jane Doe"), wantAvatarURL: ptr("https://cdn.example.com/jane.png")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: a service that records the update it was asked for
+			svc := mocks.NewUserServiceMock(t)
+			router := setupUserRouter(svc)
+			id := uuid.New()
+			var got service.UpdateUserInput
+			svc.On("UpdateByUUID", mock.Anything, id, mock.AnythingOfType("service.UpdateUserInput")).
+				Run(func(args mock.Arguments) { got = args.Get(2).(service.UpdateUserInput) }).
+				Return(&model.User{ID: 1, UUID: id.String(), Username: "jdoe", Email: "jdoe@example.com", Version: 2}, nil).Once()
+
+			// When: patching
+			resp := serveUserJSON(router, http.MethodPatch, "/api/v1/users/uuid/"+id.String(), tt.body)
+
+			// Then: omitted, null, and values reach the service as nil, "" and the value
+			require.Equal(t, http.StatusOK, resp.Code)
+			require.Nil(t, got.Username)
+			require.Equal(t, tt.wantEmail, got.Email)
+			require.Equal(t, tt.wantFullName, got.FullName)
+			require.Equal(t, tt.wantAvatarURL, got.AvatarURL)
+		})
+	}
+}
+
+func TestUserController_CreateUser_AvatarURL(t *testing.T) {
+	// Given: a service that stores one avatar and rejects another
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("Create", mock.Anything, "jdoe", "jdoe@example.com", "John Doe", "https://cdn.example.com/jdoe.png").
+		Return(&model.User{ID: 1, Username: "jdoe", Email: "jdoe@example.com", FullName: "John Doe", AvatarURL: "https://cdn.example.com/jdoe.png"}, nil).Once()
+	svc.On("Create", mock.Anything, "jdoe", "jdoe@example.com", "John Doe", "javascript:alert(1)").
+		Return((*model.User)(nil), &service.ValidationError{Fields: map[string]string{"avatar_url": "must be an http or https URL"}}).Once()
+
+	// When: creating with a valid avatar URL
+	resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/",
+		`{"username":"jdoe","email":"jdoe@example.com","full_name":"John Doe","avatar_url":"https://cdn.example.com/jdoe.png"}`)
+
+	// Then: it is returned with the user
+	require.Equal(t, http.StatusCreated, resp.Code)
+	var body response.NormalizedUser
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	require.Equal(t, "https://cdn.example.com/jdoe.png", *body.AvatarURL)
+
+	// When: creating with a script URL
+	resp = serveUserJSON(router, http.MethodPost, "/api/v1/users/",
+		`{"username":"jdoe","email":"jdoe@example.com","full_name":"John Doe","avatar_url":"javascript:alert(1)"}`)
+
+	// Then: the field is named in the 400
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.JSONEq(t, `{"error":"invalid user input","fields":{"avatar_url":"must be an http or https URL"}}`, resp.Body.String())
+}
+
+func TestUserController_CreateUser_ReadOnly(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("Create", mock.Anything, "jdoe", "jdoe@example.com", "John Doe", "").
+		Return((*model.User)(nil), service.ErrReadOnly).Once()
+
+	resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/",
+		`{"username":"jdoe","email":"jdoe@example.com","full_name":"John Doe"}`)
+
+	require.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	require.JSONEq(t, `{"error":"service is read-only"}`, resp.Body.String())
+}
+
+func TestUserController_CreateUser_UnprocessableEntity(t *testing.T) {
+	// Given: a controller configured to answer semantic errors with 422
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouterWithOptions(svc, UserControllerOptions{UnprocessableEntity: true})
+	svc.On("Create", mock.Anything, "jdoe", "not-an-email", "John Doe", "").
+		Return((*model.User)(nil), service.ErrInvalidUserInput).Once()
+
+	// When: the body parses but the service rejects the email
+	resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/",
+		`{"username":"jdoe","email":"not-an-email","full_name":"John Doe"}`)
+
+	// Then: the response is 422
+	require.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	require.JSONEq(t, `{"error":"invalid user input"}`, resp.Body.String())
+}
+
+func TestUserController_CreateUser_UnprocessableEntity_MissingField(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouterWithOptions(svc, UserControllerOptions{UnprocessableEntity: true})
+
+	resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/", `{"username":"jdoe"}`)
+
+	require.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	svc.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserController_CreateUser_MalformedJSONStays400(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouterWithOptions(svc, UserControllerOptions{UnprocessableEntity: true})
+
+	resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/", `{"username":`)
+
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.JSONEq(t, `{"error":"invalid payload"}`, resp.Body.String())
+}
+
+func TestUserController_UpdateUserByID_ValidationDefaultsTo400(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("UpdateByID", mock.Anything, int64(3), mock.AnythingOfType("service.UpdateUserInput")).
+		Return((*model.User)(nil), service.ErrInvalidUserInput).Once()
+
+	resp := serveUserJSON(router, http.MethodPatch, "/api/v1/users/id/3", `{"email":"not-an-email"}`)
+
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestUserController_UpdateUserByUUID_VersionConflict(t *testing.T) {
+	// Given: the service refuses the client's version
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	id := uuid.New()
+	svc.On("UpdateByUUID", mock.Anything, id, mock.MatchedBy(func(input service.UpdateUserInput) bool {
+		return input.Version != nil && *input.Version == 2
+	})).Return((*model.User)(nil), service.ErrVersionConflict).Once()
+
+	// When: updating from version 2
+	resp := serveUserJSON(router, http.MethodPatch, "/api/v1/users/uuid/"+id.String(), `{"full_name":"Jane Doe","version":2}`)
+
+	// Then: the 409 carries a code that sets it apart from a duplicate
+	require.Equal(t, http.StatusConflict, resp.Code)
+	require.JSONEq(t, `{"error":"user version is out of date","code":"version_conflict"}`, resp.Body.String())
+}
+
+func TestUserController_CreateUser_ConflictNamesField(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("Create", mock.Anything, "jdoe", "jdoe@example.com", "John Doe", "").
+		Return((*model.User)(nil), &service.ConflictError{Fields: map[string]string{"username": "already taken"}}).Once()
+
+	resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/",
+		`{"username":"jdoe","email":"jdoe@example.com","full_name":"John Doe"}`)
+
+	require.Equal(t, http.StatusConflict, resp.Code)
+	require.JSONEq(t, `{"error":"user already exists","fields":{"username":"already taken"}}`, resp.Body.String())
+}
+
+func TestUserController_ProblemDetails(t *testing.T) {
+	// Given: a client that asks for RFC 7807 documents
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("Create", mock.Anything, "jdoe", "jdoe@example.com", "John Doe", "").
+		Return((*model.User)(nil), service.ErrUserAlreadyExists).Once()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users/",
+		strings.NewReader(`{"username":"jdoe","email":"jdoe@example.com","full_name":"John Doe"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/problem+json")
+	req.Header.Set("X-Request-ID", "req-123")
+	resp := httptest.NewRecorder()
+
+	// When: the request fails
+	router.ServeHTTP(resp, req)
+
+	// Then: the error is a problem document carrying the request id
+	require.Equal(t, http.StatusConflict, resp.Code)
+	require.Equal(t, "application/problem+json", resp.Header().Get("Content-Type"))
+	require.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Conflict",
+		"status": 409,
+		"detail": "user already exists",
+		"instance": "req-123"
+	}`, resp.Body.String())
+}
+
+func TestUserController_ProblemDetails_InstanceFallsBackToPath(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/username/"+strings.Repeat("a", service.MaxUsernameLength+1), nil)
+	req.Header.Set("Accept", "application/problem+json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	var problem response.ProblemDetails
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &problem))
+	require.Equal(t, http.StatusBadRequest, problem.Status)
+	require.Equal(t, req.URL.Path, problem.Instance)
+}
+
+func TestUserController_BadPathParams(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{
+			name: "non-numeric id",
+			path: "/api/v1/users/id/abc",
+			want: `{"error":"invalid id","code":"invalid_param","param":"id","rule":"type"}`,
+		},
+		{
+			name: "non-positive id",
+			path: "/api/v1/users/id/-1",
+			want: `{"error":"invalid id","code":"invalid_param","param":"id","rule":"gt"}`,
+		},
+		{
+			name: "id beyond int64",
+			path: "/api/v1/users/id/9999999999999999999999",
+			want: `{"error":"invalid id","code":"invalid_param","param":"id","rule":"range"}`,
+		},
+		{
+			name: "malformed uuid",
+			path: "/api/v1/users/uuid/not-a-uuid",
+			want: `{"error":"invalid uuid","code":"invalid_param","param":"uuid","rule":"uuid"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: a controller backed by a mock service
+			svc := mocks.NewUserServiceMock(t)
+			router := setupUserRouter(svc)
+
+			// When: the path parameter fails binding
+			resp := serveUserRequest(router, http.MethodGet, tt.path)
+
+			// Then: the error names the parameter and rule, not the raw value
+			require.Equal(t, http.StatusBadRequest, resp.Code)
+			require.JSONEq(t, tt.want, resp.Body.String())
+		})
+	}
+}
+
+func TestUserController_LenientIDForms(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("GetByID", mock.Anything, int64(7)).Return(&model.User{ID: 7, UUID: uuid.NewString()}, nil).Twice()
+
+	for _, path := range []string{"/api/v1/users/id/007", "/api/v1/users/id/%207%20"} {
+		resp := serveUserRequest(router, http.MethodGet, path)
+		require.Equal(t, http.StatusOK, resp.Code, path)
+	}
+}
+
+func TestUserController_BadPathParam_ProblemDetails(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/uuid/%3Cscript%3E", nil)
+	req.Header.Set("Accept", "application/problem+json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	var problem response.ProblemDetails
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &problem))
+	require.Equal(t, "invalid_param", problem.Code)
+	require.Equal(t, "uuid", problem.Param)
+	require.Equal(t, "uuid", problem.Rule)
+	require.Equal(t, "/api/v1/users/uuid/:uuid", problem.Instance)
+	require.NotContains(t, resp.Body.String(), "script")
+}
+
+func TestUserController_CreateUser_ValidationFields(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("Create", mock.Anything, "jdoe", "not-an-email", "John Doe", "").
+		Return((*model.User)(nil), &service.ValidationError{Fields: map[string]string{"email": "not a valid address"}}).Once()
+
+	resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/",
+		`{"username":"jdoe","email":"not-an-email","full_name":"John Doe"}`)
+
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.JSONEq(t, `{"error":"invalid user input","fields":{"email":"not a valid address"}}`, resp.Body.String())
+}
+
+func TestUserController_GetAllUsers_Pinned(t *testing.T) {
+	// Given: two pinned UUIDs, one repeated, alongside a page
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	first, second := uuid.New(), uuid.New()
+	query := service.ListUsersQuery{Limit: 5, Pinned: []uuid.UUID{first, second}}
+	svc.On("List", mock.Anything, query).Return([]model.User{}, nil).Once()
+
+	// When: listing users
+	resp := serveUserRequest(router, http.MethodGet, "/api/v1/users/?limit=5&pin="+first.String()+","+second.String()+","+first.String())
+
+	// Then: the service receives the pins in order, deduplicated
+	require.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestUserController_GetAllUsers_InvalidPin(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+
+	resp := serveUserRequest(router, http.MethodGet, "/api/v1/users/?pin=not-a-uuid")
+
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.JSONEq(t, `{"error":"invalid pin uuid \"not-a-uuid\""}`, resp.Body.String())
+	svc.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+}
+
+func TestUserController_GetAllUsers_Cursor(t *testing.T) {
+	// Given: a full page of two users after cursor 10
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("List", mock.Anything, service.ListUsersQuery{AfterID: 10, Limit: 2}).Return([]model.User{{ID: 11}, {ID: 14}}, nil).Once()
+	svc.On("List", mock.Anything, service.ListUsersQuery{AfterID: 14, Limit: 2}).Return([]model.User{{ID: 15}}, nil).Once()
+
+	// When: paging forward twice
+	first := serveUserRequest(router, http.MethodGet, "/api/v1/users/?after=10&limit=2")
+	last := serveUserRequest(router, http.MethodGet, "/api/v1/users/?after=14&limit=2")
+
+	// Then: the envelope carries the last id until a short page ends the scan
+	require.Equal(t, http.StatusOK, first.Code)
+	var page response.UserPage
+	require.NoError(t, json.Unmarshal(first.Body.Bytes(), &page))
+	require.Len(t, page.Users, 2)
+	require.NotNil(t, page.NextCursor)
+	require.EqualValues(t, 14, *page.NextCursor)
+
+	require.NoError(t, json.Unmarshal(last.Body.Bytes(), &page))
+	require.Len(t, page.Users, 1)
+	require.Nil(t, page.NextCursor)
+}
+
+func TestUserController_GetAllUsers_CreatedRange(t *testing.T) {
+	// Given: a created_at window, once alone and once with a cursor
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	after := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2026, 2, 1, 0, 0, 0, 0, time.FixedZone("", 2*60*60))
+	svc.On("List", mock.Anything, mock.MatchedBy(func(q service.ListUsersQuery) bool {
+		return q.CreatedAfter.Equal(after) && q.CreatedBefore.Equal(before) && q.AfterID == 0 && q.Limit == 0
+	})).Return([]model.User{}, nil).Once()
+	svc.On("List", mock.Anything, mock.MatchedBy(func(q service.ListUsersQuery) bool {
+		return q.CreatedAfter.Equal(after) && q.CreatedBefore.IsZero() && q.AfterID == 5 && q.Limit == 2
+	})).Return([]model.User{}, nil).Once()
+
+	// When: listing with the filters
+	plain := serveUserRequest(router, http.MethodGet, "/api/v1/users/?created_after=2026-01-01T00:00:00Z&created_before=2026-02-01T00:00:00%2B02:00")
+	cursor := serveUserRequest(router, http.MethodGet, "/api/v1/users/?created_after=2026-01-01T00:00:00Z&after=5&limit=2")
+
+	// Then: both reach the service with the parsed bounds
+	require.Equal(t, http.StatusOK, plain.Code)
+	require.Equal(t, http.StatusOK, cursor.Code)
+}
+
+func TestUserController_GetAllUsers_InvalidCreatedRange(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+
+	resp := serveUserRequest(router, http.MethodGet, "/api/v1/users/?created_before=yesterday")
+
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.JSONEq(t, `{"error":"invalid created_before: must be an RFC 3339 timestamp"}`, resp.Body.String())
+	svc.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+}
+
+func TestUserController_GetAllUsers_CursorRejectsPin(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+
+	resp := serveUserRequest(router, http.MethodGet, "/api/v1/users/?after=1&pin="+uuid.NewString())
+
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	svc.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+}
+
+func TestUserController_Fields(t *testing.T) {
+	// Given: one user served by every getter
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	user := model.User{ID: 7, UUID: uuid.NewString(), Username: "jdoe", Email: "jdoe@example.com", FullName: "John Doe"}
+	svc.On("GetByUsername", mock.Anything, "jdoe").Return(&user, nil).Once()
+	svc.On("GetByID", mock.Anything, int64(7)).Return(&user, nil).Once()
+	svc.On("GetByUUID", mock.Anything, uuid.MustParse(user.UUID)).Return(&user, nil).Once()
+	svc.On("GetAll", mock.Anything).Return([]model.User{user}, nil).Once()
+	svc.On("List", mock.Anything, service.ListUsersQuery{Limit: 1}).Return([]model.User{user}, nil).Once()
+
+	// When: each asks for id and username only, one with a repeat and blanks
+	username := serveUserRequest(router, http.MethodGet, "/api/v1/users/username/jdoe?fields=id,username")
+	byID := serveUserRequest(router, http.MethodGet, "/api/v1/users/id/7?fields=id,username")
+	byUUID := serveUserRequest(router, http.MethodGet, "/api/v1/users/uuid/"+user.UUID+"?fields=id,,username,id")
+	list := serveUserRequest(router, http.MethodGet, "/api/v1/users/?fields=id,username")
+	page := serveUserRequest(router, http.MethodGet, "/api/v1/users/?after=0&limit=1&fields=id,username")
+
+	// Then: every payload holds just those keys
+	for _, resp := range []*httptest.ResponseRecorder{username, byID, byUUID} {
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.JSONEq(t, `{"id":7,"username":"jdoe"}`, resp.Body.String())
+	}
+	require.JSONEq(t, `[{"id":7,"username":"jdoe"}]`, list.Body.String())
+	require.JSONEq(t, `{"users":[{"id":7,"username":"jdoe"}],"next_cursor":7}`, page.Body.String())
+}
+
+func TestUserController_FieldsUnknown(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+
+	list := serveUserRequest(router, http.MethodGet, "/api/v1/users/?fields=username,password")
+	single := serveUserRequest(router, http.MethodGet, "/api/v1/users/username/jdoe?fields=nope")
+
+	require.Equal(t, http.StatusBadRequest, list.Code)
+	require.JSONEq(t, `{"error":"invalid fields: unknown field \"password\""}`, list.Body.String())
+	require.Equal(t, http.StatusBadRequest, single.Code)
+	svc.AssertNotCalled(t, "GetAll", mock.Anything)
+	svc.AssertNotCalled(t, "GetByUsername", mock.Anything, mock.Anything)
+}
+
+func TestUserController_FieldsUUIDOnlyRejectsID(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouterWithOptions(svc, UserControllerOptions{UUIDOnly: true})
+
+	resp := serveUserRequest(router, http.MethodGet, "/api/v1/users/username/jdoe?fields=id")
+
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.JSONEq(t, `{"error":"invalid fields: unknown field \"id\""}`, resp.Body.String())
+}
+
+func TestUserController_ExportUsers(t *testing.T) {
+	// Given: a service exporting two users from a snapshot
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("Export", mock.Anything, service.ExportOptions{Snapshot: true}, mock.Anything).
+		Run(func(args mock.Arguments) {
+			emit := args.Get(2).(func(model.User) error)
+			require.NoError(t, emit(model.User{ID: 1, Username: "alice", Status: model.UserStatusActive}))
+			require.NoError(t, emit(model.User{ID: 2, Username: "bob", Status: model.UserStatusSuspended}))
+		}).Return(nil).Once()
+
+	// When: exporting
+	resp := serveUserRequest(router, http.MethodGet, "/api/v1/users/export")
+
+	// Then: each user is one JSON line
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, "application/x-ndjson", resp.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSuffix(resp.Body.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	require.JSONEq(t, `{"id":1,"uuid":"","username":"alice","email":"","full_name":"","avatar_url":null,"email_verified":false,"status":"active","version":0}`, lines[0])
+	require.JSONEq(t, `{"id":2,"uuid":"","username":"bob","email":"","full_name":"","avatar_url":null,"email_verified":false,"status":"suspended","version":0}`, lines[1])
+}
+
+func TestUserController_ExportUsers_FailsBeforeFirstLine(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("Export", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("db down")).Once()
+
+	resp := serveUserRequest(router, http.MethodGet, "/api/v1/users/export")
+
+	require.Equal(t, http.StatusInternalServerError, resp.Code)
+	require.Contains(t, resp.Header().Get("Content-Type"), "application/json")
+	require.JSONEq(t, `{"error":"db down"}`, resp.Body.String())
+}
+
+func TestUserController_GetAllUsers_CursorClientGone(t *testing.T) {
+	// Given: a keyset page whose scan is cut short by the client leaving
+	path := filepath.Join(t.TempDir(), "app.log")
+	_, err := logger.Configure(logger.Options{Output: logger.OutputFile, FilePath: path, Level: "debug"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = logger.Configure(logger.DefaultOptions()) })
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("List", mock.Anything, service.ListUsersQuery{AfterID: 10, Limit: 2}).Return(nil, context.Canceled).Once()
+	reqCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// When: serving the page
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/?after=10&limit=2", nil).WithContext(reqCtx)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	// Then: no 500 is written and the scan is logged at debug, not error
+	require.Empty(t, resp.Body.String())
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(out), `"level":"DEBUG","message":"fetch users after cursor abandoned"`)
+	require.NotContains(t, string(out), `"level":"ERROR"`)
+}
+
+func TestUserController_ExportUsers_ClientGone(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("Export", mock.Anything, mock.Anything, mock.Anything).Return(context.Canceled).Once()
+	reqCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/export", nil).WithContext(reqCtx)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	// nobody is listening, so no error body is written
+	require.Empty(t, resp.Body.String())
+}
+
+func TestUserController_ExportUsers_FailsMidStream(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("Export", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			emit := args.Get(2).(func(model.User) error)
+			require.NoError(t, emit(model.User{ID: 1, Username: "alice", Status: model.UserStatusActive}))
+		}).Return(errors.New("db down")).Once()
+
+	resp := serveUserRequest(router, http.MethodGet, "/api/v1/users/export")
+
+	// the 200 is already on the wire, so the stream just stops
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, 1, strings.Count(resp.Body.String(), "\n"))
+	require.NotContains(t, resp.Body.String(), "db down")
+}
+
+func TestUserController_DeleteUsersBulk(t *testing.T) {
+	// Given: two uuids, one of which is unknown
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	present, missing := uuid.New(), uuid.New()
+	svc.On("DeleteManyByUUID", mock.Anything, []uuid.UUID{present, missing}).
+		Return(&service.BulkDeleteResult{Deleted: 1, NotFound: []uuid.UUID{missing}}, nil).Once()
+
+	// When: bulk deleting them
+	resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/bulk-delete",
+		`{"uuids":["`+present.String()+`","`+missing.String()+`"]}`)
+
+	// Then: the count and the unknown uuid come back
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.JSONEq(t, `{"deleted":1,"not_found":["`+missing.String()+`"]}`, resp.Body.String())
+}
+
+func TestUserController_DeleteUsersBulk_MalformedUUID(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouterWithOptions(svc, UserControllerOptions{UnprocessableEntity: true})
+
+	resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/bulk-delete",
+		`{"uuids":["`+uuid.NewString()+`","nope"]}`)
+
+	// malformed ids are always 400, even with 422 enabled
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.JSONEq(t, `{"error":"invalid uuid","fields":{"uuids[1]":"not a valid uuid"}}`, resp.Body.String())
+	svc.AssertNotCalled(t, "DeleteManyByUUID", mock.Anything, mock.Anything)
+}
+
+func TestUserController_DeleteUsersBulk_OverCap(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("DeleteManyByUUID", mock.Anything, mock.Anything).
+		Return(nil, fmt.Errorf("%w: at most 1 uuids per request", service.ErrInvalidUserInput)).Once()
+
+	resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/bulk-delete",
+		`{"uuids":["`+uuid.NewString()+`","`+uuid.NewString()+`"]}`)
+
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.JSONEq(t, `{"error":"invalid user input: at most 1 uuids per request"}`, resp.Body.String())
+}
+
+func TestUserController_UpdateUsersBulk(t *testing.T) {
+	// Given: two uuids, one of which is unknown
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	present, missing := uuid.New(), uuid.New()
+	svc.On("UpdateManyByUUID", mock.Anything, []uuid.UUID{present, missing}, "status", "suspended").
+		Return(&service.BulkUpdateResult{Updated: 1, NotFound: []uuid.UUID{missing}}, nil).Once()
+
+	// When: suspending both
+	resp := serveUserJSON(router, http.MethodPatch, "/api/v1/users/bulk",
+		`{"uuids":["`+present.String()+`","`+missing.String()+`"],"status":"suspended"}`)
+
+	// Then: the count and the unknown uuid come back
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.JSONEq(t, `{"updated":1,"not_found":["`+missing.String()+`"]}`, resp.Body.String())
+}
+
+func TestUserController_UpdateUsersBulk_RejectsBody(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	id := uuid.NewString()
+
+	noField := serveUserJSON(router, http.MethodPatch, "/api/v1/users/bulk", `{"uuids":["`+id+`"]}`)
+	twoFields := serveUserJSON(router, http.MethodPatch, "/api/v1/users/bulk", `{"uuids":["`+id+`"],"status":"active","avatar_url":null}`)
+	malformed := serveUserJSON(router, http.MethodPatch, "/api/v1/users/bulk", `{"uuids":["`+id+`","nope"],"status":"active"}`)
+
+	require.Equal(t, http.StatusBadRequest, noField.Code)
+	require.JSONEq(t, `{"error":"bulk update must set exactly one field besides uuids"}`, noField.Body.String())
+	require.Equal(t, http.StatusBadRequest, twoFields.Code)
+	require.Equal(t, http.StatusBadRequest, malformed.Code)
+	require.JSONEq(t, `{"error":"invalid uuid","fields":{"uuids[1]":"not a valid uuid"}}`, malformed.Body.String())
+	svc.AssertNotCalled(t, "UpdateManyByUUID", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserController_UpdateUsersBulk_FieldNotAllowed(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouterWithOptions(svc, UserControllerOptions{UnprocessableEntity: true})
+	svc.On("UpdateManyByUUID", mock.Anything, mock.Anything, "email", "x@example.com").
+		Return(nil, &service.ValidationError{Fields: map[string]string{"email": "cannot be bulk updated; use one of avatar_url, status"}}).Once()
+
+	resp := serveUserJSON(router, http.MethodPatch, "/api/v1/users/bulk",
+		`{"uuids":["`+uuid.NewString()+`"],"email":"x@example.com"}`)
+
+	require.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	require.Contains(t, resp.Body.String(), `"email":"cannot be bulk updated; use one of avatar_url, status"`)
+}
+
+func TestUserController_GetUsersBatch(t *testing.T) {
+	// Given: two uuids, one of which is unknown
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	present, missing := uuid.New(), uuid.New()
+	svc.On("GetByUUIDs", mock.Anything, []uuid.UUID{present, missing}).Return(&service.BatchGetResult{
+		Users:   []model.User{{ID: 1, UUID: present.String(), Username: "jdoe", Email: "jdoe@example.com", FullName: "John Doe", Status: model.UserStatusActive, Version: 1}},
+		Missing: []uuid.UUID{missing},
+	}, nil).Twice()
+
+	// When: fetching them through the body and through the query
+	fromBody := serveUserJSON(router, http.MethodPost, "/api/v1/users/batch-get",
+		`{"uuids":["`+present.String()+`","`+missing.String()+`"]}`)
+	fromQuery := serveUserRequest(router, http.MethodPost, "/api/v1/users/batch-get?uuids="+present.String()+","+missing.String())
+
+	// Then: both return the found user and the unknown uuid
+	want := `{"users":[{"id":1,"uuid":"` + present.String() + `","username":"jdoe","email":"jdoe@example.com","full_name":"John Doe","avatar_url":null,"email_verified":false,"status":"active","version":1}],"missing":["` + missing.String() + `"]}`
+	require.Equal(t, http.StatusOK, fromBody.Code)
+	require.JSONEq(t, want, fromBody.Body.String())
+	require.Equal(t, http.StatusOK, fromQuery.Code)
+	require.JSONEq(t, want, fromQuery.Body.String())
+}
+
+func TestUserController_GetUsersBatch_MalformedUUID(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+
+	resp := serveUserRequest(router, http.MethodPost, "/api/v1/users/batch-get?uuids="+uuid.NewString()+",nope")
+
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.JSONEq(t, `{"error":"invalid uuid","fields":{"uuids[1]":"not a valid uuid"}}`, resp.Body.String())
+	svc.AssertNotCalled(t, "GetByUUIDs", mock.Anything, mock.Anything)
+}
+
+func TestUserController_GetUsersBatch_QueryAndBody(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+
+	resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/batch-get?uuids="+uuid.NewString(),
+		`{"uuids":["`+uuid.NewString()+`"]}`)
+
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	svc.AssertNotCalled(t, "GetByUUIDs", mock.Anything, mock.Anything)
+}
+
+func TestUserController_HeadUser(t *testing.T) {
+	// Given: one existing and one unknown user
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	present, missing := uuid.New(), uuid.New()
+	svc.On("ExistsByUUID", mock.Anything, present).Return(true, nil).Once()
+	svc.On("ExistsByUUID", mock.Anything, missing).Return(false, nil).Once()
+	svc.On("ExistsByID", mock.Anything, int64(7)).Return(true, nil).Once()
+	svc.On("ExistsByID", mock.Anything, int64(8)).Return(false, errors.New("db down")).Once()
+
+	// When / Then: each check answers by status alone
+	cases := []struct {
+		path string
+		want int
+	}{
+		{"/api/v1/users/uuid/" + present.String(), http.StatusOK},
+		{"/api/v1/users/uuid/" + missing.String(), http.StatusNotFound},
+		{"/api/v1/users/uuid/not-a-uuid", http.StatusBadRequest},
+		{"/api/v1/users/id/7", http.StatusOK},
+		{"/api/v1/users/id/8", http.StatusInternalServerError},
+		{"/api/v1/users/id/-1", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		resp := serveUserRequest(router, http.MethodHead, tc.path)
+		require.Equal(t, tc.want, resp.Code, tc.path)
+		require.Empty(t, resp.Body.String(), tc.path)
+	}
+	svc.AssertNotCalled(t, "GetByUUID", mock.Anything, mock.Anything)
+}
+
+func TestUserController_CreateUser_BodyTooLarge(t *testing.T) {
+	// Given: a router capping bodies at 16 bytes
+	svc := mocks.NewUserServiceMock(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.BodyLimit(16))
+	router.POST("/api/v1/users/", NewUserController(svc).CreateUser)
+
+	// When: posting a larger payload
+	resp := serveUserJSON(router, http.MethodPost, "/api/v1/users/", `{"username":"jdoe","email":"jdoe@example.com","full_name":"John Doe"}`)
+
+	// Then: the bind failure is reported as 413 in the standard error shape
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+	require.JSONEq(t, `{"error":"request body too large"}`, resp.Body.String())
+	svc.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserController_UUIDOnlyOmitsID(t *testing.T) {
+	// Given: a controller in UUID-only mode
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouterWithOptions(svc, UserControllerOptions{UUIDOnly: true})
+	user := model.User{ID: 7, UUID: uuid.NewString(), Username: "jdoe", Email: "jdoe@example.com", FullName: "John Doe"}
+	svc.On("GetByUsername", mock.Anything, "jdoe").Return(&user, nil).Once()
+	svc.On("GetAll", mock.Anything).Return([]model.User{user}, nil).Once()
+
+	// When: fetching one user and the list
+	single := serveUserRequest(router, http.MethodGet, "/api/v1/users/username/jdoe")
+	list := serveUserRequest(router, http.MethodGet, "/api/v1/users/")
+
+	// Then: neither payload carries the numeric id
+	require.Equal(t, http.StatusOK, single.Code)
+	require.NotContains(t, decodeJSONObject(t, single.Body.Bytes()), "id")
+	require.Equal(t, user.UUID, decodeJSONObject(t, single.Body.Bytes())["uuid"])
+	var users []map[string]any
+	require.NoError(t, json.Unmarshal(list.Body.Bytes(), &users))
+	require.Len(t, users, 1)
+	require.NotContains(t, users[0], "id")
+}
+
+func TestUserController_ResponsesIncludeIDByDefault(t *testing.T) {
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouter(svc)
+	svc.On("GetByUsername", mock.Anything, "jdoe").Return(&model.User{ID: 7, Username: "jdoe"}, nil).Once()
+
+	resp := serveUserRequest(router, http.MethodGet, "/api/v1/users/username/jdoe")
+
+	require.EqualValues(t, 7, decodeJSONObject(t, resp.Body.Bytes())["id"])
+}
+
+func decodeJSONObject(t *testing.T, body []byte) map[string]any {
+	t.Helper()
+	var out map[string]any
+	require.NoError(t, json.Unmarshal(body, &out))
+	return out
+}
+
+func TestUserController_UpdateUserByUUID_NilUUID(t *testing.T) {
+	// Given: the service refuses the nil UUID, even with 422 enabled
+	svc := mocks.NewUserServiceMock(t)
+	router := setupUserRouterWithOptions(svc, UserControllerOptions{UnprocessableEntity: true})
+	svc.On("UpdateByUUID", mock.Anything, uuid.Nil, mock.Anything).Return(nil, service.ErrReservedUUID).Once()
+
+	// When: patching /uuid/00000000-0000-0000-0000-000000000000
+	resp := serveUserJSON(router, http.MethodPatch, "/api/v1/users/uuid/"+uuid.Nil.String(), `{"full_name":"New Name"}`)
+
+	// Then: the client gets a plain 400 naming the problem
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.JSONEq(t, `{"error":"invalid user input: uuid is reserved"}`, resp.Body.String())
+}
+
+func setupUserRouter(svc service.UserService) *gin.Engine {
+	return setupUserRouterWithOptions(svc, UserControllerOptions{})
+}
+
+func setupUserRouterWithOptions(svc service.UserService, opts UserControllerOptions) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	controller := NewUserControllerWithOptions(svc, opts)
+
+	router := gin.New()
+	users := router.Group("/api/v1/users")
+	users.GET("/", middleware.Pagination(middleware.PaginationOptions{}), controller.GetAllUsers)
+	users.GET("/export", controller.ExportUsers)
+	users.GET("/stats", controller.GetUserStats)
+	users.GET("/id/:id", controller.GetUserByID)
+	users.GET("/uuid/:uuid", controller.GetUserByUUID)
+	users.HEAD("/uuid/:uuid", controller.HeadUserByUUID)
+	users.HEAD("/id/:id", controller.HeadUserByID)
+	users.GET("/username/:username", controller.GetUserByUsername)
+	users.DELETE("/username/:username", controller.DeleteUserByUsername)
+	users.POST("/batch-get", controller.GetUsersBatch)
+	users.POST("/bulk-delete", controller.DeleteUsersBulk)
+	users.PATCH("/bulk", controller.UpdateUsersBulk)
+	users.POST("/", controller.CreateUser)
+	users.PATCH("/id/:id", controller.UpdateUserByID)
+	users.PATCH("/uuid/:uuid", controller.UpdateUserByUUID)
+	users.POST("/uuid/:uuid/verification", controller.IssueEmailVerification)
+	users.POST("/verify", controller.VerifyEmail)
+	users.POST("/uuid/:uuid/suspend", controller.SuspendUser)
+	users.POST("/uuid/:uuid/activate", controller.ActivateUser)
+	users.GET("/uuid/:uuid/history", middleware.Pagination(middleware.PaginationOptions{}), controller.GetUserHistory)
+	return router
+}
+
+func serveUserRequest(router *gin.Engine, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func serveUserJSON(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
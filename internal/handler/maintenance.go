@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+
+	"cruder/internal/controller/response"
+	"cruder/internal/middleware"
+	"cruder/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenancePath is where RegisterMaintenance mounts the switch.
+const MaintenancePath = "/admin/maintenance"
+
+// MaintenanceState is the body of the maintenance switch requests and
+// responses.
+type MaintenanceState struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// RegisterMaintenance mounts the switch for middleware.Maintenance behind
+// auth: GET reports whether maintenance mode is on and POST with
+// {"enabled":true} or false flips it. Register it before the middleware so
+// the switch itself is never refused.
+func RegisterMaintenance(router gin.IRouter, auth gin.HandlerFunc, enabled *atomic.Bool, log *logger.Logger) {
+	router.GET(MaintenancePath, auth, func(c *gin.Context) {
+		state := enabled.Load()
+		c.JSON(http.StatusOK, MaintenanceState{Enabled: &state})
+	})
+	router.POST(MaintenancePath, auth, func(c *gin.Context) {
+		var req MaintenanceState
+		if err := c.ShouldBindJSON(&req); err != nil {
+			middleware.WriteError(c, http.StatusBadRequest, response.Error{Error: "invalid payload", Fields: map[string]string{"enabled": "required"}})
+			return
+		}
+		if enabled.Swap(*req.Enabled) != *req.Enabled {
+			log.Warn("maintenance mode changed", slog.Bool("maintenance.enabled", *req.Enabled))
+		}
+		c.JSON(http.StatusOK, req)
+	})
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"net/http/pprof"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PprofPrefix is where RegisterPprof mounts the profiling handlers. The
+// net/http/pprof index resolves profile names relative to it.
+const PprofPrefix = "/debug/pprof"
+
+// RegisterPprof mounts the net/http/pprof handlers under PprofPrefix, every
+// one behind auth. Importing net/http/pprof also registers them on
+// http.DefaultServeMux, which the server never uses.
+func RegisterPprof(router gin.IRouter, auth gin.HandlerFunc) {
+	group := router.Group(PprofPrefix, auth)
+	group.GET("/", gin.WrapF(pprof.Index))
+	group.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	group.GET("/profile", gin.WrapF(pprof.Profile))
+	group.GET("/symbol", gin.WrapF(pprof.Symbol))
+	group.POST("/symbol", gin.WrapF(pprof.Symbol))
+	group.GET("/trace", gin.WrapF(pprof.Trace))
+	// named profiles: goroutine, heap, allocs, block, mutex, threadcreate
+	group.GET("/:name", gin.WrapF(pprof.Index))
+}
@@ -1,26 +1,93 @@
 package handler
 
 import (
+	"log/slog"
+	"net/http"
+
 	"cruder/internal/controller"
+	"cruder/internal/controller/response"
+	"cruder/internal/middleware"
+	"cruder/internal/model"
+	"cruder/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
-func New(router *gin.Engine, userController *controller.UserController) *gin.Engine {
+// StreamingRoutes are route patterns that write their response
+// incrementally. Middleware that buffers whole responses must pass them
+// through untouched.
+var StreamingRoutes = []string{"/api/v1/users/export"}
+
+// New registers the API routes. createIdempotency wraps user creation so
+// retried requests carrying an Idempotency-Key are not applied twice, and
+// bulkBodyLimit replaces the global body cap on the routes that take many
+// users in one body.
+func New(router *gin.Engine, controllers *controller.Controller, adminAuth, createIdempotency, bulkBodyLimit gin.HandlerFunc) *gin.Engine {
+	userController := controllers.Users
+	apiKeyController := controllers.APIKeys
+
 	v1 := router.Group("/api/v1")
 	{
 		userGroup := v1.Group("/users")
 		{
-			userGroup.GET("/", userController.GetAllUsers)
-			userGroup.GET("/username/:username", userController.GetUserByUsername)
-			userGroup.GET("/id/:id", userController.GetUserByID)
-			userGroup.GET("/uuid/:uuid", userController.GetUserByUUID)
-			userGroup.POST("/", userController.CreateUser)
-			userGroup.PATCH("/uuid/:uuid", userController.UpdateUserByUUID)
-			userGroup.PATCH("/id/:id", userController.UpdateUserByID)
-			userGroup.DELETE("/uuid/:uuid", userController.DeleteUserByUUID)
-			userGroup.DELETE("/id/:id", userController.DeleteUserByID)
+			// scope checks run per route, after authentication; write routes
+			// then refuse non-JSON bodies before binding them
+			read := userGroup.Group("", middleware.RequireScope(model.ScopeUsersRead))
+			write := userGroup.Group("", middleware.RequireScope(model.ScopeUsersWrite), middleware.RequireJSON())
+
+			read.GET("/", middleware.Pagination(middleware.PaginationOptions{}), userController.GetAllUsers)
+			read.GET("/export", userController.ExportUsers)
+			read.GET("/stats", userController.GetUserStats)
+			read.POST("/batch-get", bulkBodyLimit, middleware.RequireJSON(), userController.GetUsersBatch)
+			read.GET("/username/:username", userController.GetUserByUsername)
+			write.DELETE("/username/:username", userController.DeleteUserByUsername)
+			read.GET("/uuid/:uuid", userController.GetUserByUUID)
+			read.HEAD("/uuid/:uuid", userController.HeadUserByUUID)
+			write.POST("/", createIdempotency, userController.CreateUser)
+			write.PATCH("/uuid/:uuid", userController.UpdateUserByUUID)
+			write.DELETE("/uuid/:uuid", userController.DeleteUserByUUID)
+			write.POST("/uuid/:uuid/verification", userController.IssueEmailVerification)
+			write.POST("/verify", userController.VerifyEmail)
+			write.POST("/uuid/:uuid/suspend", userController.SuspendUser)
+			write.POST("/uuid/:uuid/activate", userController.ActivateUser)
+			write.POST("/bulk-delete", bulkBodyLimit, userController.DeleteUsersBulk)
+			write.PATCH("/bulk", bulkBodyLimit, userController.UpdateUsersBulk)
+			// admin-only: support staff read it on behalf of users
+			userGroup.GET("/uuid/:uuid/history", adminAuth, middleware.Pagination(middleware.PaginationOptions{}), userController.GetUserHistory)
+			if !userController.UUIDOnly() {
+				read.GET("/id/:id", userController.GetUserByID)
+				read.HEAD("/id/:id", userController.HeadUserByID)
+				write.PATCH("/id/:id", userController.UpdateUserByID)
+				write.DELETE("/id/:id", userController.DeleteUserByID)
+			}
+		}
+
+		apiKeyGroup := v1.Group("/apikeys", adminAuth, middleware.RequireJSON())
+		{
+			apiKeyGroup.POST("/", apiKeyController.CreateAPIKey)
+			apiKeyGroup.GET("/", middleware.Pagination(middleware.PaginationOptions{}), apiKeyController.ListAPIKeys)
+			apiKeyGroup.DELETE("/:id", apiKeyController.RevokeAPIKey)
 		}
 	}
+
+	// unmatched requests still pass through the global middleware, so an
+	// unauthenticated probe gets the auth error rather than these. Before
+	// NoMethod runs, gin sets the Allow header from the routes matching the
+	// path under other methods.
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) {
+		log := middleware.LoggerFromContext(c, nil)
+		if log == nil {
+			log = logger.Get().With(
+				slog.String("http.request.method", c.Request.Method),
+				slog.String("http.request.path", c.Request.URL.Path),
+			)
+		}
+		log.Debug("no route matched")
+		middleware.WriteError(c, http.StatusNotFound, response.Error{Error: "not found"})
+	})
+	router.NoMethod(func(c *gin.Context) {
+		middleware.WriteError(c, http.StatusMethodNotAllowed, response.Error{Error: "method not allowed"})
+	})
 	return router
 }
@@ -0,0 +1,361 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cruder/internal/controller"
+	"cruder/internal/controller/response"
+	"cruder/internal/middleware"
+	"cruder/internal/model"
+	"cruder/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_UUIDOnlyOmitsIDRoutes(t *testing.T) {
+	require.Contains(t, routePaths(t, false), "GET /api/v1/users/id/:id")
+
+	paths := routePaths(t, true)
+	for _, route := range []string{
+		"GET /api/v1/users/id/:id",
+		"PATCH /api/v1/users/id/:id",
+		"DELETE /api/v1/users/id/:id",
+	} {
+		require.NotContains(t, paths, route)
+	}
+	require.Contains(t, paths, "GET /api/v1/users/uuid/:uuid")
+}
+
+func TestNew_ErrorsShareOneShape(t *testing.T) {
+	// Given: the API behind a global auth middleware and a request timeout,
+	// plus a handler that outlives it
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.AdminAuth("secret", logger.Get()))
+	router.Use(middleware.Timeout(20 * time.Millisecond))
+	controllers := &controller.Controller{
+		Users:   controller.NewUserController(nil),
+		APIKeys: controller.NewAPIKeyController(nil),
+	}
+	noop := func(c *gin.Context) { c.Status(http.StatusOK) }
+	New(router, controllers, noop, noop, noop)
+	router.GET("/slow", func(c *gin.Context) { <-c.Request.Context().Done() })
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		authed  bool
+		status  int
+		message string
+	}{
+		{"unauthenticated probe", http.MethodGet, "/admin", false, http.StatusUnauthorized, "missing admin key"},
+		{"unknown route", http.MethodGet, "/admin", true, http.StatusNotFound, "not found"},
+		{"wrong method", http.MethodPut, "/api/v1/users/uuid/x", true, http.StatusMethodNotAllowed, "method not allowed"},
+		{"timed out handler", http.MethodGet, "/slow", true, http.StatusServiceUnavailable, "request timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serve := func(accept string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(tt.method, tt.path, nil)
+				if tt.authed {
+					req.Header.Set(middleware.HeaderAdminKey, "secret")
+				}
+				if accept != "" {
+					req.Header.Set("Accept", accept)
+				}
+				resp := httptest.NewRecorder()
+				router.ServeHTTP(resp, req)
+				return resp
+			}
+
+			// When: the request fails outside any controller
+			resp := serve("")
+
+			// Then: the body is the same JSON error document controllers use
+			require.Equal(t, tt.status, resp.Code)
+			require.Contains(t, resp.Header().Get("Content-Type"), "application/json")
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+			require.Equal(t, map[string]any{"error": tt.message}, body)
+
+			// And: a client asking for problem documents gets one
+			resp = serve("application/problem+json")
+			require.Equal(t, tt.status, resp.Code)
+			require.Equal(t, "application/problem+json", resp.Header().Get("Content-Type"))
+			var problem response.ProblemDetails
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &problem))
+			require.Equal(t, tt.status, problem.Status)
+			require.Equal(t, tt.message, problem.Detail)
+		})
+	}
+}
+
+func TestNew_MethodNotAllowedListsAllowedMethods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controllers := &controller.Controller{
+		Users:   controller.NewUserController(nil),
+		APIKeys: controller.NewAPIKeyController(nil),
+	}
+	noop := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router := New(gin.New(), controllers, noop, noop, noop)
+
+	// When: PUTting to a user, which only supports reads, PATCH, and DELETE
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodPut, "/api/v1/users/id/1", nil))
+
+	// Then: the 405 names every method the path does accept
+	require.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+	allowed := strings.Split(resp.Header().Get("Allow"), ", ")
+	require.ElementsMatch(t, []string{http.MethodGet, http.MethodHead, http.MethodPatch, http.MethodDelete}, allowed)
+
+	// And: a path that exists for no method is still a 404
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodPut, "/api/v1/users/nope/1", nil))
+	require.Equal(t, http.StatusNotFound, resp.Code)
+	require.Empty(t, resp.Header().Get("Allow"))
+}
+
+func TestNew_RoutesRequireScopes(t *testing.T) {
+	// Given: the API behind a key that may only read users
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(middleware.ContextAPIClientKey, &model.APIKey{ID: 1, Scopes: []string{model.ScopeUsersRead}})
+	})
+	controllers := &controller.Controller{
+		Users:   controller.NewUserController(nil),
+		APIKeys: controller.NewAPIKeyController(nil),
+	}
+	noop := func(c *gin.Context) { c.Status(http.StatusOK) }
+	New(router, controllers, noop, noop, noop)
+
+	for _, tt := range []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPost, "/api/v1/users/"},
+		{http.MethodPatch, "/api/v1/users/uuid/x"},
+		{http.MethodDelete, "/api/v1/users/uuid/x"},
+		{http.MethodDelete, "/api/v1/users/username/x"},
+		{http.MethodPost, "/api/v1/users/bulk-delete"},
+		{http.MethodPatch, "/api/v1/users/bulk"},
+		{http.MethodPatch, "/api/v1/users/id/1"},
+		{http.MethodDelete, "/api/v1/users/id/1"},
+	} {
+		// When: it calls a write route
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(tt.method, tt.path, nil))
+
+		// Then: the scope check rejects it before the controller runs
+		require.Equal(t, http.StatusForbidden, resp.Code, "%s %s", tt.method, tt.path)
+	}
+}
+
+func TestNew_WriteRoutesRequireJSON(t *testing.T) {
+	// Given: the API behind a key that may read and write users
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(middleware.ContextAPIClientKey, &model.APIKey{ID: 1, Scopes: []string{model.ScopeUsersRead, model.ScopeUsersWrite}})
+	})
+	controllers := &controller.Controller{
+		Users:   controller.NewUserController(nil),
+		APIKeys: controller.NewAPIKeyController(nil),
+	}
+	noop := func(c *gin.Context) { c.Status(http.StatusOK) }
+	New(router, controllers, noop, noop, noop)
+
+	for _, path := range []string{"/api/v1/users/", "/api/v1/users/bulk-delete", "/api/v1/users/batch-get", "/api/v1/apikeys/"} {
+		// When: it posts a form-encoded body
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader("username=jdoe"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		// Then: it is refused before the controller tries to bind it
+		require.Equal(t, http.StatusUnsupportedMediaType, resp.Code, path)
+		require.JSONEq(t, `{"error":"Content-Type must be application/json"}`, resp.Body.String())
+	}
+}
+
+func TestNew_HistoryRequiresAdminKey(t *testing.T) {
+	// Given: the API with an admin key configured
+	gin.SetMode(gin.TestMode)
+	controllers := &controller.Controller{
+		Users:   controller.NewUserController(nil),
+		APIKeys: controller.NewAPIKeyController(nil),
+	}
+	noop := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router := New(gin.New(), controllers, middleware.AdminAuth("secret", logger.Get()), noop, noop)
+
+	// When: reading a user's history without the admin key
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/api/v1/users/uuid/x/history", nil))
+
+	// Then: the admin check rejects it before the controller runs
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestNew_NotFoundHonorsProblemJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controllers := &controller.Controller{
+		Users:   controller.NewUserController(nil),
+		APIKeys: controller.NewAPIKeyController(nil),
+	}
+	noop := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router := New(gin.New(), controllers, noop, noop, noop)
+	req := httptest.NewRequest(http.MethodGet, "/nope", nil)
+	req.Header.Set("Accept", "application/problem+json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusNotFound, resp.Code)
+	require.Equal(t, "application/problem+json", resp.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"type":"about:blank","title":"Not Found","status":404,"detail":"not found","instance":"/nope"}`, resp.Body.String())
+}
+
+func TestNew_NotFoundLogsPathAtDebug(t *testing.T) {
+	// Given: a debug-level log file and no request logger in the chain
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "app.log")
+	_, err := logger.Configure(logger.Options{Output: logger.OutputFile, FilePath: path, Level: "debug"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = logger.Configure(logger.DefaultOptions()) })
+	controllers := &controller.Controller{
+		Users:   controller.NewUserController(nil),
+		APIKeys: controller.NewAPIKeyController(nil),
+	}
+	noop := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router := New(gin.New(), controllers, noop, noop, noop)
+
+	// When: requesting a path outside /api/v1
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/wp-admin", nil))
+
+	// Then: the JSON 404 is logged with the attempted path
+	require.Equal(t, http.StatusNotFound, resp.Code)
+	require.JSONEq(t, `{"error":"not found"}`, resp.Body.String())
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(out), `"message":"no route matched"`)
+	require.Contains(t, string(out), `"http.request.path":"/wp-admin"`)
+}
+
+func TestNew_BulkRoutesTakeTheBulkBodyLimit(t *testing.T) {
+	// Given: a bulk limit that marks the requests it sees
+	gin.SetMode(gin.TestMode)
+	controllers := &controller.Controller{
+		Users:   controller.NewUserController(nil),
+		APIKeys: controller.NewAPIKeyController(nil),
+	}
+	noop := func(c *gin.Context) { c.Status(http.StatusOK) }
+	bulkLimit := func(c *gin.Context) { c.AbortWithStatus(http.StatusTeapot) }
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set(middleware.ContextAPIClientKey, &model.APIKey{Scopes: []string{model.ScopeAll}})
+	})
+	router := New(engine, controllers, noop, noop, bulkLimit)
+	serve := func(method, path string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	// Then: every route taking many users gets it
+	require.Equal(t, http.StatusTeapot, serve(http.MethodPost, "/api/v1/users/batch-get"))
+	require.Equal(t, http.StatusTeapot, serve(http.MethodPost, "/api/v1/users/bulk-delete"))
+	require.Equal(t, http.StatusTeapot, serve(http.MethodPatch, "/api/v1/users/bulk"))
+
+	// And: single-user writes keep the global limit
+	require.NotEqual(t, http.StatusTeapot, serve(http.MethodPost, "/api/v1/users/verify"))
+}
+
+func routePaths(t *testing.T, uuidOnly bool) []string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	controllers := &controller.Controller{
+		Users:   controller.NewUserControllerWithOptions(nil, controller.UserControllerOptions{UUIDOnly: uuidOnly}),
+		APIKeys: controller.NewAPIKeyController(nil),
+	}
+	noop := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router := New(gin.New(), controllers, noop, noop, noop)
+
+	var paths []string
+	for _, route := range router.Routes() {
+		paths = append(paths, route.Method+" "+route.Path)
+	}
+	return paths
+}
+
+func TestRegisterPprof_RequiresAdminKey(t *testing.T) {
+	// Given: pprof mounted behind the admin key
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterPprof(router, middleware.AdminAuth("secret", logger.Get()))
+
+	// When / Then: anonymous requests are refused
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine", nil))
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	// And: the admin gets the index and named profiles
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/goroutine?debug=1"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(middleware.HeaderAdminKey, "secret")
+		resp = httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusOK, resp.Code, path)
+		require.Contains(t, resp.Body.String(), "goroutine", path)
+	}
+}
+
+func TestRegisterMaintenance_FlipsTheSwitch(t *testing.T) {
+	// Given: the switch mounted behind the admin key
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	enabled := new(atomic.Bool)
+	RegisterMaintenance(router, middleware.AdminAuth("secret", logger.Get()), enabled, logger.Get())
+
+	serve := func(method, body string, admin bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, MaintenancePath, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if admin {
+			req.Header.Set(middleware.HeaderAdminKey, "secret")
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	// When / Then: anonymous requests are refused
+	require.Equal(t, http.StatusUnauthorized, serve(http.MethodPost, `{"enabled":true}`, false).Code)
+	require.False(t, enabled.Load())
+
+	// And: the admin turns it on and reads it back
+	resp := serve(http.MethodPost, `{"enabled":true}`, true)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.JSONEq(t, `{"enabled":true}`, resp.Body.String())
+	require.True(t, enabled.Load())
+	require.JSONEq(t, `{"enabled":true}`, serve(http.MethodGet, "", true).Body.String())
+
+	// And: a body without enabled changes nothing
+	resp = serve(http.MethodPost, `{}`, true)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.True(t, enabled.Load())
+
+	require.Equal(t, http.StatusOK, serve(http.MethodPost, `{"enabled":false}`, true).Code)
+	require.False(t, enabled.Load())
+}
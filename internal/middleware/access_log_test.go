@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLog_Formats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := map[AccessLogFormat]*regexp.Regexp{
+		AccessLogCommon: regexp.MustCompile(
+			`^192\.0\.2\.1 - client-a \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /users\?limit=5 HTTP/1\.1" 200 11\n$`),
+		AccessLogCombined: regexp.MustCompile(
+			`^192\.0\.2\.1 - client-a \[[^\]]+\] "GET /users\?limit=5 HTTP/1\.1" 200 11 "https://ref\.example/" "curl/8\.0"\n$`),
+		AccessLogCombinedDuration: regexp.MustCompile(
+			`^192\.0\.2\.1 - client-a \[[^\]]+\] "GET /users\?limit=5 HTTP/1\.1" 200 11 "https://ref\.example/" "curl/8\.0" \d+\n$`),
+	}
+	for format, want := range cases {
+		t.Run(string(format), func(t *testing.T) {
+			// Given: an access-logged route behind something that names the client
+			var out bytes.Buffer
+			router := gin.New()
+			router.Use(AccessLog(&out, format), func(c *gin.Context) {
+				c.Request = c.Request.WithContext(service.ContextWithActor(c.Request.Context(), "client-a"))
+			})
+			router.GET("/users", func(c *gin.Context) { c.String(http.StatusOK, "hello world") })
+
+			// When: a request is served
+			req := httptest.NewRequest(http.MethodGet, "/users?limit=5", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			req.Header.Set("Referer", "https://ref.example/")
+			req.Header.Set("User-Agent", "curl/8.0")
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			// Then: one line in the chosen layout
+			require.Regexp(t, want, out.String())
+		})
+	}
+}
+
+func TestAccessLog_EmptyFieldsAndEscaping(t *testing.T) {
+	// Given: an anonymous request with no body and a hostile user agent
+	gin.SetMode(gin.TestMode)
+	var out bytes.Buffer
+	router := gin.New()
+	router.Use(AccessLog(&out, AccessLogCombined))
+	router.DELETE("/users/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	req := httptest.NewRequest(http.MethodDelete, "/users/1", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("User-Agent", "evil\" \n192.0.2.9 - - [forged]")
+
+	// When: it is served
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Then: missing values are dashes and the agent stays on one line, inside its quotes
+	line := out.String()
+	require.Equal(t, 1, strings.Count(line, "\n"))
+	require.Contains(t, line, `192.0.2.1 - - [`)
+	require.Contains(t, line, `"DELETE /users/1 HTTP/1.1" 204 - "-" "evil\" \x0a192.0.2.9 - - [forged]"`)
+}
+
+func TestParseAccessLogFormat(t *testing.T) {
+	format, err := ParseAccessLogFormat(" Combined ")
+	require.NoError(t, err)
+	require.Equal(t, AccessLogCombined, format)
+
+	_, err = ParseAccessLogFormat("json")
+	require.EqualError(t, err, `unknown access log format "json"; use common, combined, or combined_duration`)
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"cruder/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const HeaderAdminKey = "X-Admin-Key" // #nosec G101: header name only
+
+// AdminAuth guards admin-only routes with a super-admin key that is separate
+// from the per-client API keys. When adminKey is empty, admin routes are disabled.
+func AdminAuth(adminKey string, log *logger.Logger) gin.HandlerFunc {
+	adminKey = strings.TrimSpace(adminKey)
+	expected := sha256.Sum256([]byte(adminKey))
+
+	return func(c *gin.Context) {
+		if adminKey == "" {
+			log.WarnContext(c.Request.Context(), "admin access attempted while disabled", loggerRequestAttrs(c)...)
+			abortWithError(c, http.StatusForbidden, "admin access disabled")
+			return
+		}
+
+		provided := strings.TrimSpace(c.GetHeader(HeaderAdminKey))
+		if provided == "" {
+			log.WarnContext(c.Request.Context(), "request missing admin key", loggerRequestAttrs(c)...)
+			abortWithError(c, http.StatusUnauthorized, "missing admin key")
+			return
+		}
+
+		actual := sha256.Sum256([]byte(provided))
+		if subtle.ConstantTimeCompare(expected[:], actual[:]) != 1 {
+			log.WarnContext(c.Request.Context(), "request with invalid admin key", loggerRequestAttrs(c)...)
+			abortWithError(c, http.StatusForbidden, "invalid admin key")
+			return
+		}
+
+		c.Next()
+	}
+}
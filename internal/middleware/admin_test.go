@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cruder/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminAuth_Disabled(t *testing.T) {
+	router := setupAdminRouter("")
+
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set(HeaderAdminKey, "anything")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+func TestAdminAuth_MissingKey(t *testing.T) {
+	router := setupAdminRouter("super-secret")
+
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestAdminAuth_InvalidKey(t *testing.T) {
+	router := setupAdminRouter("super-secret")
+
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set(HeaderAdminKey, "wrong")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+func TestAdminAuth_Success(t *testing.T) {
+	router := setupAdminRouter("super-secret")
+
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set(HeaderAdminKey, "super-secret")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+}
+
+func setupAdminRouter(adminKey string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	_, _ = logger.Configure(logger.DefaultOptions())
+
+	router := gin.New()
+	router.Use(AdminAuth(adminKey, logger.Get()))
+	router.GET("/admin", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
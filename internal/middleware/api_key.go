@@ -2,7 +2,10 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
+	"cruder/internal/model"
 	"cruder/internal/service"
 	"cruder/pkg/logger"
 
@@ -16,35 +19,85 @@ const (
 	ContextAPIClientKey = "api.client"
 )
 
+// APIKeyAuthOptions configures APIKeyAuthWithOptions.
+type APIKeyAuthOptions struct {
+	Lockout LockoutOptions
+}
+
 func APIKeyAuth(apiKeys service.APIKeyService, log *logger.Logger) gin.HandlerFunc {
+	return APIKeyAuthWithOptions(apiKeys, log, APIKeyAuthOptions{})
+}
+
+// APIKeyAuthWithOptions is APIKeyAuth with an optional lockout: once an IP
+// sends Lockout.Threshold invalid keys in a row, its requests are answered
+// with 429 without a lookup until the lockout ends. A valid key resets the
+// count.
+func APIKeyAuthWithOptions(apiKeys service.APIKeyService, log *logger.Logger, opts APIKeyAuthOptions) gin.HandlerFunc {
+	var locked *lockouts
+	if opts.Lockout.Threshold > 0 {
+		locked = newLockouts(opts.Lockout)
+	}
+
 	return func(c *gin.Context) {
+		if locked != nil {
+			if wait := locked.lockedFor(c.ClientIP(), time.Now()); wait > 0 {
+				log.WarnContext(c.Request.Context(), "request from locked out client", loggerRequestAttrs(c)...)
+				c.Header("Retry-After", strconv.Itoa(retryAfterSeconds(wait)))
+				abortWithError(c, http.StatusTooManyRequests, "too many invalid api keys")
+				return
+			}
+		}
+
 		apiKey := c.GetHeader(HeaderAPIKey)
 		client, err := apiKeys.Validate(c.Request.Context(), apiKey)
 		if err != nil {
 			switch err {
 			case service.ErrAPIKeyMissing:
-				log.Warn("request missing api key", loggerRequestAttrs(c)...)
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing api key"})
+				log.WarnContext(c.Request.Context(), "request missing api key", loggerRequestAttrs(c)...)
+				abortWithError(c, http.StatusUnauthorized, "missing api key")
 				return
 			case service.ErrAPIKeyInvalid:
-				log.Warn("request with invalid api key", loggerRequestAttrs(c)...)
-				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "invalid api key"})
+				log.WarnContext(c.Request.Context(), "request with invalid api key", loggerRequestAttrs(c)...)
+				if locked != nil {
+					if wait := locked.fail(c.ClientIP(), time.Now()); wait > 0 {
+						log.WarnContext(c.Request.Context(), "client locked out after invalid api keys", append(loggerRequestAttrs(c), slog.Duration("lockout", wait))...)
+					}
+				}
+				abortWithError(c, http.StatusForbidden, "invalid api key")
+				return
+			case service.ErrAPIKeyExpired:
+				log.WarnContext(c.Request.Context(), "request with expired api key", loggerRequestAttrs(c)...)
+				abortWithError(c, http.StatusForbidden, "api key expired")
 				return
 			default:
 				attrs := append(loggerRequestAttrs(c), slog.String("error", err.Error()))
-				log.Error("failed to validate api key", attrs...)
-				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+				log.ErrorContext(c.Request.Context(), "failed to validate api key", attrs...)
+				abortWithError(c, http.StatusInternalServerError, "internal server error")
 				return
 			}
 		}
+		if locked != nil {
+			locked.succeed(c.ClientIP())
+		}
 		if client != nil {
 			c.Set(ContextAPIClientKey, client)
-			log.Debug("api key accepted", append(loggerRequestAttrs(c), slog.String("client_name", client.ClientName))...)
+			c.Request = c.Request.WithContext(service.ContextWithActor(c.Request.Context(), client.ClientName))
+			log.DebugContext(c.Request.Context(), "api key accepted", append(loggerRequestAttrs(c), slog.String("client_name", client.ClientName), slog.String("api_key.label", client.Label))...)
 		}
 		c.Next()
 	}
 }
 
+// apiClient returns the key APIKeyAuth authenticated the request with, or nil.
+func apiClient(c *gin.Context) *model.APIKey {
+	value, ok := c.Get(ContextAPIClientKey)
+	if !ok {
+		return nil
+	}
+	key, _ := value.(*model.APIKey)
+	return key
+}
+
 func loggerRequestAttrs(c *gin.Context) []any {
 	route := c.FullPath()
 	if route == "" {
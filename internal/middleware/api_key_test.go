@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
@@ -39,6 +40,21 @@ func TestAPIKeyAuth_InvalidKey(t *testing.T) {
 	require.Equal(t, http.StatusForbidden, resp.Code)
 }
 
+func TestAPIKeyAuth_ExpiredKey(t *testing.T) {
+	router, stub := setupAPIKeyRouter(t)
+	stub.reset()
+	stub.err = service.ErrAPIKeyExpired
+
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set(HeaderAPIKey, "expired")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusForbidden, resp.Code)
+	require.Contains(t, resp.Body.String(), "api key expired")
+}
+
 func TestAPIKeyAuth_InternalError(t *testing.T) {
 	router, stub := setupAPIKeyRouter(t)
 	stub.reset()
@@ -68,6 +84,64 @@ func TestAPIKeyAuth_Success(t *testing.T) {
 	require.Contains(t, resp.Body.String(), "Test Client")
 }
 
+func TestAPIKeyAuth_LocksOutRepeatedInvalidKeys(t *testing.T) {
+	// Given: a lockout after two invalid keys
+	gin.SetMode(gin.TestMode)
+	stub := &stubAPIKeyService{validKey: "secret"}
+	router := gin.New()
+	router.Use(APIKeyAuthWithOptions(stub, logger.Get(), APIKeyAuthOptions{
+		Lockout: LockoutOptions{Threshold: 2, BaseDelay: time.Minute},
+	}))
+	router.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
+	serve := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+		req.Header.Set(HeaderAPIKey, key)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	// When: a valid key in between resets the count
+	require.Equal(t, http.StatusForbidden, serve("guess-1").Code)
+	require.Equal(t, http.StatusOK, serve("secret").Code)
+	require.Equal(t, http.StatusForbidden, serve("guess-2").Code)
+	require.Equal(t, http.StatusForbidden, serve("guess-3").Code)
+
+	// Then: the second failure in a row locks the client out, valid key or not
+	resp := serve("secret")
+	require.Equal(t, http.StatusTooManyRequests, resp.Code)
+	require.Equal(t, "60", resp.Header().Get("Retry-After"))
+	require.JSONEq(t, `{"error":"too many invalid api keys"}`, resp.Body.String())
+}
+
+func TestAPIKeyAuth_LockoutIgnoresForwardedForFromUntrustedPeers(t *testing.T) {
+	// Given: a lockout after one invalid key, trusting no proxies as the
+	// app does unless TRUSTED_PROXIES is set
+	gin.SetMode(gin.TestMode)
+	stub := &stubAPIKeyService{validKey: "secret"}
+	router := gin.New()
+	require.NoError(t, router.SetTrustedProxies(nil))
+	router.Use(APIKeyAuthWithOptions(stub, logger.Get(), APIKeyAuthOptions{
+		Lockout: LockoutOptions{Threshold: 1, BaseDelay: time.Minute},
+	}))
+	router.GET("/protected", func(c *gin.Context) { c.Status(http.StatusOK) })
+	serve := func(key, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+		req.RemoteAddr = "203.0.113.7:51000"
+		req.Header.Set(HeaderAPIKey, key)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	// When: each guess claims a different forwarded address
+	require.Equal(t, http.StatusForbidden, serve("guess-1", "198.51.100.1"))
+
+	// Then: the peer is still locked out
+	require.Equal(t, http.StatusTooManyRequests, serve("guess-2", "198.51.100.2"))
+}
+
 func setupAPIKeyRouter(t *testing.T) (*gin.Engine, *stubAPIKeyService) {
 	gin.SetMode(gin.TestMode)
 	_, _ = logger.Configure(logger.DefaultOptions())
@@ -109,3 +183,23 @@ func (s *stubAPIKeyService) Validate(_ context.Context, key string) (*model.APIK
 	}
 	return &model.APIKey{ClientName: "Test Client"}, nil
 }
+
+func (s *stubAPIKeyService) Create(context.Context, string, string, *time.Time, []string) (*model.APIKey, string, error) {
+	return nil, "", errors.New("not implemented")
+}
+
+func (s *stubAPIKeyService) List(context.Context, string, int, int) ([]model.APIKey, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *stubAPIKeyService) Revoke(context.Context, int64) error {
+	return errors.New("not implemented")
+}
+
+func (s *stubAPIKeyService) Invalidate(string)     {}
+func (s *stubAPIKeyService) InvalidateHash(string) {}
+func (s *stubAPIKeyService) InvalidateAll()        {}
+
+func (s *stubAPIKeyService) Close() error {
+	return nil
+}
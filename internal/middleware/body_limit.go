@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	contextBodyLimitKey = "body_limit.original"
+
+	// ErrBodyTooLarge is the error message for requests rejected by BodyLimit.
+	ErrBodyTooLarge = "request body too large"
+)
+
+// BodyLimit caps the request body at maxBytes with http.MaxBytesReader.
+// Reading past the cap fails with *http.MaxBytesError, which handlers report
+// as 413 (see IsBodyTooLarge). A later BodyLimit on a route replaces an
+// earlier one, so a global default can be raised for endpoints that take
+// larger payloads; for that reason oversized Content-Length values are not
+// rejected up front.
+func BodyLimit(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxBytes <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		original := c.Request.Body
+		if value, ok := c.Get(contextBodyLimitKey); ok {
+			if body, ok := value.(io.ReadCloser); ok {
+				original = body
+			}
+		} else {
+			c.Set(contextBodyLimitKey, original)
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, original, maxBytes)
+		c.Next()
+	}
+}
+
+// IsBodyTooLarge reports whether err came from reading past a BodyLimit.
+func IsBodyTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
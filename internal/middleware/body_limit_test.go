@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func serveBodyLimit(t *testing.T, body io.Reader, contentLength int64, handlers ...gin.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(BodyLimit(8))
+	router.POST("/items", append(handlers, func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			require.True(t, IsBodyTooLarge(err))
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": ErrBodyTooLarge})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})...)
+
+	req := httptest.NewRequest(http.MethodPost, "/items", body)
+	req.ContentLength = contentLength
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestBodyLimit_RejectsOversizedBody(t *testing.T) {
+	resp := serveBodyLimit(t, strings.NewReader("0123456789"), 10)
+
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+	require.JSONEq(t, `{"error":"request body too large"}`, resp.Body.String())
+}
+
+func TestBodyLimit_CutsOffUndeclaredLength(t *testing.T) {
+	// Given: a chunked body with no Content-Length
+	resp := serveBodyLimit(t, strings.NewReader("0123456789"), -1)
+
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+}
+
+func TestBodyLimit_AllowsSmallBody(t *testing.T) {
+	resp := serveBodyLimit(t, strings.NewReader("0123"), 4)
+
+	require.Equal(t, http.StatusNoContent, resp.Code)
+}
+
+func TestBodyLimit_RouteLimitReplacesGlobal(t *testing.T) {
+	resp := serveBodyLimit(t, strings.NewReader("0123456789"), 10, BodyLimit(64))
+
+	require.Equal(t, http.StatusNoContent, resp.Code)
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrUnsupportedMediaType is the error message for requests rejected by
+// RequireJSON.
+const ErrUnsupportedMediaType = "Content-Type must be application/json"
+
+// jsonMediaTypes are the request media types RequireJSON accepts. Merge
+// patch is the JSON Merge Patch (RFC 7396) type the PATCH endpoints follow.
+var jsonMediaTypes = map[string]struct{}{
+	"application/json":             {},
+	"application/merge-patch+json": {},
+}
+
+// RequireJSON rejects a request whose Content-Type is not JSON with 415
+// before the handler tries to bind its body. Requests without a
+// Content-Type pass, as do GET, HEAD, DELETE, and OPTIONS, which carry no
+// body. Parameters such as charset are ignored.
+func RequireJSON() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions:
+			c.Next()
+			return
+		}
+		contentType := c.GetHeader("Content-Type")
+		if contentType == "" {
+			c.Next()
+			return
+		}
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if _, ok := jsonMediaTypes[mediaType]; err != nil || !ok {
+			abortWithError(c, http.StatusUnsupportedMediaType, ErrUnsupportedMediaType)
+			return
+		}
+		c.Next()
+	}
+}
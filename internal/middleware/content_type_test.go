@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func serveRequireJSON(t *testing.T, method, contentType string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequireJSON())
+	router.Handle(method, "/items", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req := httptest.NewRequest(method, "/items", strings.NewReader(`name=x`))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestRequireJSON_RejectsOtherTypes(t *testing.T) {
+	for _, contentType := range []string{"application/x-www-form-urlencoded", "text/plain; charset=utf-8", "application/jsonx", "not a media type"} {
+		resp := serveRequireJSON(t, http.MethodPost, contentType)
+
+		require.Equal(t, http.StatusUnsupportedMediaType, resp.Code, contentType)
+		require.JSONEq(t, `{"error":"Content-Type must be application/json"}`, resp.Body.String())
+	}
+}
+
+func TestRequireJSON_AllowsJSONAndMissingType(t *testing.T) {
+	for _, contentType := range []string{"", "application/json", "Application/JSON; charset=utf-8", "application/merge-patch+json"} {
+		resp := serveRequireJSON(t, http.MethodPatch, contentType)
+
+		require.Equal(t, http.StatusNoContent, resp.Code, contentType)
+	}
+}
+
+func TestRequireJSON_SkipsBodilessMethods(t *testing.T) {
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		resp := serveRequireJSON(t, method, "text/plain")
+
+		require.Equal(t, http.StatusNoContent, resp.Code, method)
+	}
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete, http.MethodOptions}
+	defaultCORSHeaders = []string{"Content-Type", HeaderAPIKey, HeaderAdminKey, "X-Request-ID", HeaderIdempotencyKey, "If-Match", "If-None-Match"}
+	// corsExposeHeaders are response headers browser clients need to read
+	// beyond the CORS-safelisted ones.
+	corsExposeHeaders = strings.Join([]string{"ETag", "Location"}, ", ")
+)
+
+type CORSOptions struct {
+	// AllowedOrigins lists exact origins, or "*" to allow any origin.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// AllowCredentials lets browsers send cookies and read the response.
+	// Browsers ignore it next to "Access-Control-Allow-Origin: *", so the
+	// matching origin is echoed instead; configuration refuses to pair it
+	// with "*" in AllowedOrigins.
+	AllowCredentials bool
+	// MaxAge lets browsers cache a preflight answer for this long. Zero
+	// leaves the header out and browsers fall back to their own default.
+	MaxAge time.Duration
+}
+
+// CORS adds cross-origin headers for allowed origins and answers preflight
+// requests with 204. It must run before APIKeyAuth so preflights don't need a key.
+func CORS(opts CORSOptions) gin.HandlerFunc {
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	headers := opts.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(headers, ", ")
+	maxAge := ""
+	if opts.MaxAge > 0 {
+		maxAge = strconv.Itoa(int(opts.MaxAge / time.Second))
+	}
+
+	allowAny := false
+	origins := make(map[string]struct{}, len(opts.AllowedOrigins))
+	for _, origin := range opts.AllowedOrigins {
+		if origin == "*" {
+			allowAny = true
+			continue
+		}
+		origins[origin] = struct{}{}
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Origin")
+		_, allowed := origins[origin]
+		if !allowed && !allowAny {
+			c.Next()
+			return
+		}
+
+		if allowAny && !opts.AllowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if opts.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", allowMethods)
+			c.Header("Access-Control-Allow-Headers", allowHeaders)
+			if maxAge != "" {
+				c.Header("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Header("Access-Control-Expose-Headers", corsExposeHeaders)
+		c.Next()
+	}
+}
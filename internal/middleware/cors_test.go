@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS_PreflightAllowedOrigin(t *testing.T) {
+	router := setupCORSRouter(CORSOptions{AllowedOrigins: []string{"https://admin.example.com"}})
+
+	req := httptest.NewRequest(http.MethodOptions, "/resource", nil)
+	req.Header.Set("Origin", "https://admin.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusNoContent, resp.Code)
+	require.Equal(t, "https://admin.example.com", resp.Header().Get("Access-Control-Allow-Origin"))
+	require.Contains(t, resp.Header().Get("Access-Control-Allow-Methods"), http.MethodPatch)
+	require.Contains(t, resp.Header().Get("Access-Control-Allow-Headers"), HeaderAPIKey)
+	require.Contains(t, resp.Header().Get("Access-Control-Allow-Headers"), "If-Match")
+}
+
+func TestCORS_ExposesETag(t *testing.T) {
+	router := setupCORSRouter(CORSOptions{AllowedOrigins: []string{"*"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/resource", nil)
+	req.Header.Set("Origin", "https://any.example.com")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, "ETag, Location", resp.Header().Get("Access-Control-Expose-Headers"))
+}
+
+func TestCORS_PreflightSkipsAuth(t *testing.T) {
+	router := setupCORSRouter(CORSOptions{AllowedOrigins: []string{"*"}})
+
+	req := httptest.NewRequest(http.MethodOptions, "/resource", nil)
+	req.Header.Set("Origin", "https://any.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusNoContent, resp.Code)
+	require.Equal(t, "*", resp.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_DisallowedOrigin(t *testing.T) {
+	router := setupCORSRouter(CORSOptions{AllowedOrigins: []string{"https://admin.example.com"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/resource", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+	require.Empty(t, resp.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_SimpleRequestGetsHeaders(t *testing.T) {
+	router := setupCORSRouter(CORSOptions{AllowedOrigins: []string{"https://admin.example.com"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/resource", nil)
+	req.Header.Set("Origin", "https://admin.example.com")
+	req.Header.Set(HeaderAPIKey, "key")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, "https://admin.example.com", resp.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_CredentialsAndMaxAge(t *testing.T) {
+	// Given: credentials enabled for the dashboard and a one-hour preflight cache
+	router := setupCORSRouter(CORSOptions{
+		AllowedOrigins:   []string{"https://dashboard.example.com", "https://admin.example.com"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	})
+
+	// When: the dashboard sends a preflight
+	req := httptest.NewRequest(http.MethodOptions, "/resource", nil)
+	req.Header.Set("Origin", "https://dashboard.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	// Then: the answer names that one origin, allows credentials, and may be cached
+	require.Equal(t, http.StatusNoContent, resp.Code)
+	require.Equal(t, "https://dashboard.example.com", resp.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "true", resp.Header().Get("Access-Control-Allow-Credentials"))
+	require.Equal(t, "3600", resp.Header().Get("Access-Control-Max-Age"))
+
+	// And: the actual request allows credentials too
+	req = httptest.NewRequest(http.MethodGet, "/resource", nil)
+	req.Header.Set("Origin", "https://dashboard.example.com")
+	req.Header.Set(HeaderAPIKey, "key")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, "true", resp.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestCORS_CredentialsNeverSendWildcard(t *testing.T) {
+	router := setupCORSRouter(CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+
+	req := httptest.NewRequest(http.MethodOptions, "/resource", nil)
+	req.Header.Set("Origin", "https://any.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, "https://any.example.com", resp.Header().Get("Access-Control-Allow-Origin"))
+	require.Empty(t, resp.Header().Get("Access-Control-Max-Age"))
+}
+
+func setupCORSRouter(opts CORSOptions) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(CORS(opts))
+	// stand-in for APIKeyAuth: anything reaching it without a key is rejected
+	router.Use(func(c *gin.Context) {
+		if c.GetHeader(HeaderAPIKey) == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	})
+	router.GET("/resource", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
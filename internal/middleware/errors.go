@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"cruder/internal/controller/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	mimeProblemJSON = "application/problem+json"
+	problemTypeBase = "about:blank"
+)
+
+// WriteError renders body as the API's error response. Clients that accept
+// application/problem+json get an RFC 7807 document whose instance is the
+// request ID (or the request path when none was sent); everyone else gets
+// response.Error as is. Middleware, controllers and the router's fallback
+// handlers all render errors through here so every error has one shape.
+func WriteError(c *gin.Context, status int, body response.Error) {
+	writeError(c.Writer, c.Request, c.FullPath(), status, body)
+}
+
+// writeError is WriteError for a request whose route is route, written to w
+// rather than the gin.Context's writer. Timeout uses it once it has taken
+// the response away from the handler.
+func writeError(w http.ResponseWriter, req *http.Request, route string, status int, body response.Error) {
+	if !strings.Contains(req.Header.Get("Accept"), mimeProblemJSON) {
+		writeJSON(w, "application/json; charset=utf-8", status, body)
+		return
+	}
+
+	instance := req.Header.Get(HeaderRequestID)
+	if instance == "" {
+		instance = req.URL.Path
+		if body.Param != "" {
+			// the path holds the rejected value; report the route instead
+			instance = route
+		}
+	}
+	writeJSON(w, mimeProblemJSON, status, response.ProblemDetails{
+		Type:     problemTypeBase,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   body.Error,
+		Instance: instance,
+		Code:     body.Code,
+		Param:    body.Param,
+		Rule:     body.Rule,
+		Fields:   body.Fields,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, contentType string, status int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		// both documents are plain structs, so this is a programming error
+		panic(err)
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+// abortWithError stops the handler chain and renders message through
+// WriteError.
+func abortWithError(c *gin.Context, status int, message string) {
+	c.Abort()
+	WriteError(c, status, response.Error{Error: message})
+}
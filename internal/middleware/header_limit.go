@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultMaxHeaderBytes = 1024
+
+// negotiationHeaders are parsed by content negotiation and error rendering.
+var negotiationHeaders = []string{"Accept", "Content-Type"}
+
+// HeaderLimit rejects requests whose Accept or Content-Type header is longer
+// than maxBytes with 400, before any handler parses them. A non-positive
+// maxBytes uses the 1KB default.
+func HeaderLimit(maxBytes int) gin.HandlerFunc {
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxHeaderBytes
+	}
+	return func(c *gin.Context) {
+		for _, name := range negotiationHeaders {
+			total := 0
+			for _, value := range c.Request.Header.Values(name) {
+				total += len(value)
+			}
+			if total > maxBytes {
+				abortWithError(c, http.StatusBadRequest, name+" header too long")
+				return
+			}
+		}
+		c.Next()
+	}
+}
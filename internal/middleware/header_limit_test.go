@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func serveHeaderLimit(t *testing.T, header, value string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(HeaderLimit(64))
+	router.GET("/items", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	req.Header.Set(header, value)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestHeaderLimit_RejectsLongAccept(t *testing.T) {
+	resp := serveHeaderLimit(t, "Accept", strings.Repeat("text/html;q=0.9,", 10))
+
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.JSONEq(t, `{"error":"Accept header too long"}`, resp.Body.String())
+}
+
+func TestHeaderLimit_RejectsLongContentType(t *testing.T) {
+	resp := serveHeaderLimit(t, "Content-Type", "application/json; charset="+strings.Repeat("x", 64))
+
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestHeaderLimit_AllowsNormalAccept(t *testing.T) {
+	resp := serveHeaderLimit(t, "Accept", "application/problem+json, application/json")
+
+	require.Equal(t, http.StatusNoContent, resp.Code)
+}
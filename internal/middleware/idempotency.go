@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"cruder/internal/service"
+	"cruder/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotency replays the stored response when an authenticated client
+// repeats an Idempotency-Key, instead of running the handler again. Keys are
+// scoped per API key, and only responses with successStatus are stored so a
+// failed attempt can be retried with the same key. The key is reserved
+// before the handler runs, so a repeat that arrives while the first request
+// is still running gets 409 with Retry-After rather than running it twice.
+// Reusing a key with a different body returns 422. It must run after
+// APIKeyAuth; requests without the header or without a client pass through.
+func Idempotency(svc service.IdempotencyService, successStatus int, log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderIdempotencyKey)
+		client := apiClient(c)
+		if key == "" || client == nil {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			abortWithError(c, http.StatusBadRequest, "idempotency key too long")
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if IsBodyTooLarge(err) {
+			abortWithError(c, http.StatusRequestEntityTooLarge, ErrBodyTooLarge)
+			return
+		}
+		if err != nil {
+			abortWithError(c, http.StatusBadRequest, "failed to read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		requestHash := hashRequest(c.Request.Method, c.FullPath(), body)
+
+		ctx := c.Request.Context()
+		stored, err := svc.Reserve(ctx, client.ID, key, requestHash)
+		switch {
+		case errors.Is(err, service.ErrIdempotencyKeyReused):
+			abortWithError(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		case errors.Is(err, service.ErrIdempotencyInProgress):
+			log.DebugContext(c.Request.Context(), "idempotency key in use", append(loggerRequestAttrs(c), slog.Int("api_key.id", client.ID))...)
+			c.Header("Retry-After", "1")
+			abortWithError(c, http.StatusConflict, err.Error())
+			return
+		case err != nil:
+			log.ErrorContext(c.Request.Context(), "idempotency lookup failed", append(loggerRequestAttrs(c), slog.String("error", err.Error()))...)
+			abortWithError(c, http.StatusInternalServerError, "internal server error")
+			return
+		case stored != nil:
+			log.DebugContext(c.Request.Context(), "replaying idempotent response", append(loggerRequestAttrs(c), slog.Int("api_key.id", client.ID))...)
+			c.Header(HeaderIdempotentReplayed, "true")
+			if stored.Location != "" {
+				c.Header("Location", stored.Location)
+			}
+			c.Data(stored.StatusCode, "application/json; charset=utf-8", stored.Body)
+			c.Abort()
+			return
+		}
+
+		// the reservation is settled even when the client has gone away or
+		// the handler panics, so it doesn't hold the key until it expires
+		settleCtx := context.WithoutCancel(ctx)
+		settled := false
+		defer func() {
+			if settled {
+				return
+			}
+			if err := svc.Release(settleCtx, client.ID, key, requestHash); err != nil {
+				log.WarnContext(c.Request.Context(), "failed to release idempotency key", append(loggerRequestAttrs(c), slog.String("error", err.Error()))...)
+			}
+		}()
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		c.Writer = recorder.ResponseWriter
+
+		// the status the handler set, not the writer's: past the Timeout
+		// deadline the client got 503 and the wrapper ignores the handler
+		if recorder.HandlerStatus() != successStatus {
+			if ctx.Err() != nil {
+				// the handler ran past its deadline or the client left, so
+				// its writes may have committed; the key stays reserved
+				// until it expires rather than letting a retry repeat them
+				settled = true
+				log.DebugContext(c.Request.Context(), "idempotency key kept after an abandoned request", append(loggerRequestAttrs(c), slog.Int("api_key.id", client.ID))...)
+			}
+			return
+		}
+		settled = true
+		if err := svc.Store(settleCtx, client.ID, key, requestHash, successStatus, recorder.Header().Get("Location"), recorder.body.Bytes()); err != nil {
+			// the request already succeeded; retries get 409 until the
+			// reservation expires and then are not deduplicated
+			log.WarnContext(c.Request.Context(), "failed to store idempotent response", append(loggerRequestAttrs(c), slog.String("error", err.Error()))...)
+		}
+	}
+}
+
+func hashRequest(method, route string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + route + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// bodyRecorder copies the response body and the status the handler set
+// while passing them through. Like gin's writer, the status can change until
+// the first write.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body    bytes.Buffer
+	status  int
+	written bool
+}
+
+func (w *bodyRecorder) WriteHeader(code int) {
+	if !w.written {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *bodyRecorder) WriteHeaderNow() {
+	w.written = true
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *bodyRecorder) Write(p []byte) (int, error) {
+	w.written = true
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.written = true
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// HandlerStatus is the status the handler set, or 200 if it set none.
+func (w *bodyRecorder) HandlerStatus() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
@@ -0,0 +1,274 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cruder/internal/model"
+	"cruder/internal/service"
+	"cruder/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotency_ReplaysStoredResponse(t *testing.T) {
+	// Given: a create handler guarded by the idempotency middleware
+	router, calls := setupIdempotencyRouter(t, 1)
+
+	// When: the same client sends the same key twice
+	first := serveIdempotent(router, "key-1", `{"name":"a"}`)
+	second := serveIdempotent(router, "key-1", `{"name":"a"}`)
+
+	// Then: the handler ran once and the replay carries the original response
+	require.Equal(t, http.StatusCreated, first.Code)
+	require.Equal(t, http.StatusCreated, second.Code)
+	require.Equal(t, first.Body.String(), second.Body.String())
+	require.Equal(t, "true", second.Header().Get(HeaderIdempotentReplayed))
+	require.Equal(t, "/users/1", second.Header().Get("Location"))
+	require.Equal(t, 1, *calls)
+}
+
+func TestIdempotency_DifferentBodyIsRejected(t *testing.T) {
+	router, calls := setupIdempotencyRouter(t, 1)
+
+	serveIdempotent(router, "key-1", `{"name":"a"}`)
+	resp := serveIdempotent(router, "key-1", `{"name":"b"}`)
+
+	require.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	require.Equal(t, 1, *calls)
+}
+
+func TestIdempotency_WithoutHeaderAlwaysRuns(t *testing.T) {
+	router, calls := setupIdempotencyRouter(t, 1)
+
+	serveIdempotent(router, "", `{"name":"a"}`)
+	serveIdempotent(router, "", `{"name":"a"}`)
+
+	require.Equal(t, 2, *calls)
+}
+
+func TestIdempotency_KeysAreScopedPerClient(t *testing.T) {
+	svc := service.NewIdempotencyService(&memoryIdempotencyRepository{}, service.IdempotencyServiceOptions{})
+	routerA, callsA := setupIdempotencyRouterWith(t, svc, 1)
+	routerB, callsB := setupIdempotencyRouterWith(t, svc, 2)
+
+	serveIdempotent(routerA, "shared", `{"name":"a"}`)
+	resp := serveIdempotent(routerB, "shared", `{"name":"a"}`)
+
+	require.Equal(t, http.StatusCreated, resp.Code)
+	require.Empty(t, resp.Header().Get(HeaderIdempotentReplayed))
+	require.Equal(t, 1, *callsA)
+	require.Equal(t, 1, *callsB)
+}
+
+func TestIdempotency_RetryWhileRunningIsRefused(t *testing.T) {
+	// Given: a create handler that is still running for the first request
+	gin.SetMode(gin.TestMode)
+	svc := service.NewIdempotencyService(&memoryIdempotencyRepository{}, service.IdempotencyServiceOptions{})
+	started, release := make(chan struct{}), make(chan struct{})
+	var calls atomic.Int32
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(ContextAPIClientKey, &model.APIKey{ID: 1})
+	})
+	router.POST("/users", Idempotency(svc, http.StatusCreated, logger.Get()), func(c *gin.Context) {
+		calls.Add(1)
+		close(started)
+		<-release
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- serveIdempotent(router, "key-1", `{"name":"a"}`) }()
+	<-started
+
+	// When: the importer retries with the same key meanwhile
+	retry := serveIdempotent(router, "key-1", `{"name":"a"}`)
+
+	// Then: the retry is told to come back instead of creating a duplicate
+	require.Equal(t, http.StatusConflict, retry.Code)
+	require.Equal(t, "1", retry.Header().Get("Retry-After"))
+	close(release)
+	require.Equal(t, http.StatusCreated, (<-first).Code)
+
+	// and once the first finishes, the retry replays its response
+	replay := serveIdempotent(router, "key-1", `{"name":"a"}`)
+	require.Equal(t, http.StatusCreated, replay.Code)
+	require.Equal(t, "true", replay.Header().Get(HeaderIdempotentReplayed))
+	require.EqualValues(t, 1, calls.Load())
+}
+
+func TestIdempotency_FailedAttemptReleasesKey(t *testing.T) {
+	// Given: a handler that fails the first time
+	gin.SetMode(gin.TestMode)
+	svc := service.NewIdempotencyService(&memoryIdempotencyRepository{}, service.IdempotencyServiceOptions{})
+	calls := 0
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(ContextAPIClientKey, &model.APIKey{ID: 1})
+	})
+	router.POST("/users", Idempotency(svc, http.StatusCreated, logger.Get()), func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "boom"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	// When: retrying after the failure
+	require.Equal(t, http.StatusInternalServerError, serveIdempotent(router, "key-1", `{}`).Code)
+	resp := serveIdempotent(router, "key-1", `{}`)
+
+	// Then: the handler runs again
+	require.Equal(t, http.StatusCreated, resp.Code)
+	require.Empty(t, resp.Header().Get(HeaderIdempotentReplayed))
+	require.Equal(t, 2, calls)
+}
+
+func TestIdempotency_CreateThatOutlivesTimeoutIsNotRepeated(t *testing.T) {
+	// Given: a create behind Timeout that commits after the deadline
+	gin.SetMode(gin.TestMode)
+	svc := service.NewIdempotencyService(&memoryIdempotencyRepository{}, service.IdempotencyServiceOptions{})
+	var calls atomic.Int32
+	router := gin.New()
+	router.Use(Timeout(20 * time.Millisecond))
+	router.Use(func(c *gin.Context) {
+		c.Set(ContextAPIClientKey, &model.APIKey{ID: 1})
+	})
+	router.POST("/users", Idempotency(svc, http.StatusCreated, logger.Get()), func(c *gin.Context) {
+		calls.Add(1)
+		time.Sleep(60 * time.Millisecond)
+		c.Header("Location", "/users/1")
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	// When: the client gets the 503 and retries with the same key
+	first := serveIdempotent(router, "key-1", `{"name":"a"}`)
+	retry := serveIdempotent(router, "key-1", `{"name":"a"}`)
+
+	// Then: the retry replays the create instead of running it again
+	require.Equal(t, http.StatusServiceUnavailable, first.Code)
+	require.Equal(t, http.StatusCreated, retry.Code)
+	require.Equal(t, "true", retry.Header().Get(HeaderIdempotentReplayed))
+	require.JSONEq(t, `{"id":1}`, retry.Body.String())
+	require.EqualValues(t, 1, calls.Load())
+}
+
+func TestIdempotency_FailureAfterTimeoutKeepsKeyReserved(t *testing.T) {
+	// Given: a create behind Timeout that fails only after the deadline,
+	// when it can't tell whether its writes committed
+	gin.SetMode(gin.TestMode)
+	svc := service.NewIdempotencyService(&memoryIdempotencyRepository{}, service.IdempotencyServiceOptions{})
+	var calls atomic.Int32
+	router := gin.New()
+	router.Use(Timeout(20 * time.Millisecond))
+	router.Use(func(c *gin.Context) {
+		c.Set(ContextAPIClientKey, &model.APIKey{ID: 1})
+	})
+	router.POST("/users", Idempotency(svc, http.StatusCreated, logger.Get()), func(c *gin.Context) {
+		calls.Add(1)
+		<-c.Request.Context().Done()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	})
+
+	// When: the client retries with the same key
+	first := serveIdempotent(router, "key-1", `{"name":"a"}`)
+	retry := serveIdempotent(router, "key-1", `{"name":"a"}`)
+
+	// Then: the key stays reserved rather than letting the retry run it again
+	require.Equal(t, http.StatusServiceUnavailable, first.Code)
+	require.Equal(t, http.StatusConflict, retry.Code)
+	require.EqualValues(t, 1, calls.Load())
+}
+
+func setupIdempotencyRouter(t *testing.T, clientID int) (*gin.Engine, *int) {
+	svc := service.NewIdempotencyService(&memoryIdempotencyRepository{}, service.IdempotencyServiceOptions{})
+	return setupIdempotencyRouterWith(t, svc, clientID)
+}
+
+func setupIdempotencyRouterWith(t *testing.T, svc service.IdempotencyService, clientID int) (*gin.Engine, *int) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	_, _ = logger.Configure(logger.DefaultOptions())
+
+	calls := 0
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(ContextAPIClientKey, &model.APIKey{ID: clientID})
+	})
+	router.POST("/users", Idempotency(svc, http.StatusCreated, logger.Get()), func(c *gin.Context) {
+		calls++
+		c.Header("Location", "/users/1")
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+	return router, &calls
+}
+
+func serveIdempotent(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+type memoryIdempotencyRepository struct {
+	mu   sync.Mutex
+	rows []model.IdempotentResponse
+}
+
+func (r *memoryIdempotencyRepository) find(apiKeyID int, key string) int {
+	for i, row := range r.rows {
+		if row.APIKeyID == apiKeyID && row.Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *memoryIdempotencyRepository) Get(_ context.Context, apiKeyID int, key string) (*model.IdempotentResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i := r.find(apiKeyID, key); i >= 0 {
+		row := r.rows[i]
+		return &row, nil
+	}
+	return nil, nil
+}
+
+func (r *memoryIdempotencyRepository) Reserve(_ context.Context, resp *model.IdempotentResponse) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.find(resp.APIKeyID, resp.Key) >= 0 {
+		return false, nil
+	}
+	r.rows = append(r.rows, *resp)
+	return true, nil
+}
+
+func (r *memoryIdempotencyRepository) Save(_ context.Context, resp *model.IdempotentResponse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i := r.find(resp.APIKeyID, resp.Key); i >= 0 && r.rows[i].Pending() {
+		r.rows[i] = *resp
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Release(_ context.Context, apiKeyID int, key, _ string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i := r.find(apiKeyID, key); i >= 0 && r.rows[i].Pending() {
+		r.rows = append(r.rows[:i], r.rows[i+1:]...)
+	}
+	return nil
+}
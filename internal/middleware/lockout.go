@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultLockoutBaseDelay       = time.Second
+	defaultLockoutMaxDelay        = 15 * time.Minute
+	defaultLockoutCleanupInterval = time.Minute
+)
+
+// LockoutOptions throttle clients that keep presenting invalid API keys.
+type LockoutOptions struct {
+	// Threshold is how many consecutive invalid keys one IP may send before
+	// it is locked out. Zero disables the lockout.
+	Threshold int
+	// BaseDelay is the first lockout. Every further invalid key doubles it,
+	// up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// CleanupInterval is the minimum time between sweeps of forgotten IPs.
+	CleanupInterval time.Duration
+}
+
+type lockoutEntry struct {
+	failures    int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+// lockouts counts consecutive authentication failures per client IP.
+type lockouts struct {
+	mu          sync.Mutex
+	entries     map[string]*lockoutEntry
+	threshold   int
+	base        time.Duration
+	max         time.Duration
+	interval    time.Duration
+	lastCleanup time.Time
+}
+
+func newLockouts(opts LockoutOptions) *lockouts {
+	base := opts.BaseDelay
+	if base <= 0 {
+		base = defaultLockoutBaseDelay
+	}
+	maxDelay := opts.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultLockoutMaxDelay
+	}
+	maxDelay = max(maxDelay, base)
+	interval := opts.CleanupInterval
+	if interval <= 0 {
+		interval = defaultLockoutCleanupInterval
+	}
+	return &lockouts{
+		entries:     make(map[string]*lockoutEntry),
+		threshold:   opts.Threshold,
+		base:        base,
+		max:         maxDelay,
+		interval:    interval,
+		lastCleanup: time.Now(),
+	}
+}
+
+// lockedFor returns how long client remains locked out at now, or zero.
+func (l *lockouts) lockedFor(client string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if entry, ok := l.entries[client]; ok && now.Before(entry.lockedUntil) {
+		return entry.lockedUntil.Sub(now)
+	}
+	return 0
+}
+
+// fail records an invalid key from client and returns the lockout it
+// triggered, or zero while the client is still under the threshold.
+func (l *lockouts) fail(client string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastCleanup) >= l.interval {
+		l.cleanup(now)
+	}
+
+	entry, ok := l.entries[client]
+	if !ok {
+		entry = &lockoutEntry{}
+		l.entries[client] = entry
+	}
+	entry.failures++
+	entry.lastFailure = now
+	if entry.failures < l.threshold {
+		return 0
+	}
+	delay := l.delay(entry.failures - l.threshold)
+	entry.lockedUntil = now.Add(delay)
+	return delay
+}
+
+// delay is the lockout after over failures past the threshold: base doubled
+// over times, capped at max. It stops doubling at the cap, so a large base
+// or a persistent client can't overflow into a negative lockout.
+func (l *lockouts) delay(over int) time.Duration {
+	delay := l.base
+	for ; over > 0; over-- {
+		if delay > l.max/2 {
+			return l.max
+		}
+		delay *= 2
+	}
+	return min(delay, l.max)
+}
+
+// succeed forgets client's failures.
+func (l *lockouts) succeed(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, client)
+}
+
+// cleanup drops clients whose lockout is over and who have not failed for
+// the longest lockout, so their next failure starts the count afresh.
+func (l *lockouts) cleanup(now time.Time) {
+	for client, entry := range l.entries {
+		if !now.Before(entry.lockedUntil) && now.Sub(entry.lastFailure) > l.max {
+			delete(l.entries, client)
+		}
+	}
+	l.lastCleanup = now
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockouts_BackoffDoublesUpToMax(t *testing.T) {
+	l := newLockouts(LockoutOptions{Threshold: 3, BaseDelay: time.Second, MaxDelay: 5 * time.Second})
+	now := time.Now()
+
+	// under the threshold nothing is locked
+	require.Zero(t, l.fail("1.2.3.4", now))
+	require.Zero(t, l.fail("1.2.3.4", now))
+	require.Zero(t, l.lockedFor("1.2.3.4", now))
+
+	// each failure from the threshold on doubles the lockout, capped at max
+	var delays []time.Duration
+	for range 5 {
+		delays = append(delays, l.fail("1.2.3.4", now))
+	}
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, delays)
+	require.Equal(t, 5*time.Second, l.lockedFor("1.2.3.4", now))
+	require.Zero(t, l.lockedFor("5.6.7.8", now), "other clients are unaffected")
+
+	// a success forgets the history
+	l.succeed("1.2.3.4")
+	require.Zero(t, l.lockedFor("1.2.3.4", now))
+	require.Zero(t, l.fail("1.2.3.4", now))
+}
+
+func TestLockouts_BackoffNeverOverflows(t *testing.T) {
+	// Given: a one-minute base and a cap too high to stop the doubling in time
+	l := newLockouts(LockoutOptions{Threshold: 1, BaseDelay: time.Minute, MaxDelay: math.MaxInt64})
+	now := time.Now()
+
+	// When: a client keeps failing well past where base<<n overflows
+	var last time.Duration
+	for i := range 40 {
+		delay := l.fail("1.2.3.4", now)
+
+		// Then: the lockout only ever grows, until it sits at the cap
+		require.GreaterOrEqual(t, delay, last, "failure %d", i+1)
+		last = delay
+	}
+	require.Equal(t, time.Duration(math.MaxInt64), last)
+	require.Positive(t, l.lockedFor("1.2.3.4", now))
+}
+
+func TestLockouts_CleanupForgetsQuietClients(t *testing.T) {
+	l := newLockouts(LockoutOptions{Threshold: 1, BaseDelay: time.Second, MaxDelay: time.Minute, CleanupInterval: time.Second})
+	start := time.Now()
+	l.fail("1.2.3.4", start)
+
+	// the next failure after a quiet spell longer than MaxDelay triggers a sweep
+	l.fail("5.6.7.8", start.Add(2*time.Minute))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	require.NotContains(t, l.entries, "1.2.3.4")
+	require.Contains(t, l.entries, "5.6.7.8")
+}
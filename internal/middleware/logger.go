@@ -2,16 +2,36 @@ package middleware
 
 import (
 	"log/slog"
+	"sync"
 	"time"
 
+	"cruder/internal/repository"
+	"cruder/internal/service"
 	"cruder/pkg/logger"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/propagation"
 )
 
-const requestLoggerKey = "request.logger"
+const (
+	requestLoggerKey = "request.logger"
+	HeaderRequestID  = "X-Request-ID"
+)
+
+// RequestLoggerOptions configures RequestLoggerWithOptions.
+type RequestLoggerOptions struct {
+	// SamplePerSecond caps "request handled" lines per route per second.
+	// Responses other than 2xx and requests with errors are always logged.
+	// Zero logs every request.
+	SamplePerSecond int
+}
 
 func RequestLogger(base *logger.Logger) gin.HandlerFunc {
+	return RequestLoggerWithOptions(base, RequestLoggerOptions{})
+}
+
+func RequestLoggerWithOptions(base *logger.Logger, opts RequestLoggerOptions) gin.HandlerFunc {
+	sampler := newLogSampler(opts.SamplePerSecond)
 	return func(c *gin.Context) {
 		start := time.Now()
 
@@ -25,12 +45,20 @@ func RequestLogger(base *logger.Logger) gin.HandlerFunc {
 		if route := c.FullPath(); route != "" {
 			reqLogger = reqLogger.With(slog.String("http.route", route))
 		}
-		if rid := c.GetHeader("X-Request-ID"); rid != "" {
+		// a caller's W3C traceparent becomes the request's span, which
+		// every record logged through reqLogger carries
+		ctx := propagation.TraceContext{}.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		if rid := c.GetHeader(HeaderRequestID); rid != "" {
 			reqLogger = reqLogger.With(slog.String("http.request.id", rid))
+			ctx = service.ContextWithRequestID(ctx, rid)
 		}
+		reqLogger = reqLogger.BindContext(ctx)
 
 		c.Set(requestLoggerKey, reqLogger)
-		ctx := logger.ContextWithLogger(c.Request.Context(), reqLogger)
+		ctx = logger.ContextWithLogger(ctx, reqLogger)
+		// handlers derive their contexts from this one, so every repository
+		// operation they run lands on the same counter
+		ctx = repository.ContextWithQueryCounter(ctx)
 		c.Request = c.Request.WithContext(ctx)
 
 		c.Next()
@@ -38,9 +66,11 @@ func RequestLogger(base *logger.Logger) gin.HandlerFunc {
 		duration := time.Since(start)
 		status := c.Writer.Status()
 
+		queries, _ := repository.QueryCount(ctx)
 		attrs := []any{
 			slog.Int("http.response.status_code", status),
 			slog.Duration("http.server.request.duration", duration),
+			slog.Int64("db.query_count", queries),
 		}
 
 		if len(c.Errors) > 0 {
@@ -49,8 +79,49 @@ func RequestLogger(base *logger.Logger) gin.HandlerFunc {
 			return
 		}
 
-		reqLogger.Info("request handled", attrs...)
+		if status/100 != 2 || sampler.allow(c.Request.Method+" "+c.FullPath(), start) {
+			reqLogger.Info("request handled", attrs...)
+		}
+	}
+}
+
+// logSampler admits the first limit events per key in each wall-clock
+// second.
+type logSampler struct {
+	limit int
+
+	mu      sync.Mutex
+	windows map[string]*sampleWindow
+}
+
+type sampleWindow struct {
+	second int64
+	count  int
+}
+
+func newLogSampler(limit int) *logSampler {
+	return &logSampler{limit: limit, windows: make(map[string]*sampleWindow)}
+}
+
+func (s *logSampler) allow(key string, now time.Time) bool {
+	if s.limit <= 0 {
+		return true
+	}
+	second := now.Unix()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, ok := s.windows[key]
+	if !ok {
+		// keys are method plus route pattern (empty for unmatched paths), so
+		// the map stays as small as the route table
+		w = &sampleWindow{}
+		s.windows[key] = w
+	}
+	if w.second != second {
+		w.second, w.count = second, 0
 	}
+	w.count++
+	return w.count <= s.limit
 }
 
 func LoggerFromContext(c *gin.Context, fallback *logger.Logger) *logger.Logger {
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cruder/internal/repository"
+	"cruder/pkg/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+func TestLogSampler_LimitsPerKeyPerSecond(t *testing.T) {
+	sampler := newLogSampler(2)
+	now := time.Unix(1_700_000_000, 0)
+
+	require.True(t, sampler.allow("GET /users", now))
+	require.True(t, sampler.allow("GET /users", now.Add(100*time.Millisecond)))
+	require.False(t, sampler.allow("GET /users", now.Add(900*time.Millisecond)))
+	require.True(t, sampler.allow("POST /users", now), "routes are sampled independently")
+	require.True(t, sampler.allow("GET /users", now.Add(time.Second)), "the budget resets each second")
+}
+
+func TestLogSampler_Concurrent(t *testing.T) {
+	sampler := newLogSampler(10)
+	now := time.Unix(1_700_000_000, 0)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if sampler.allow("GET /users", now) {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 10, allowed)
+}
+
+func TestRequestLogger_SamplesSuccessesButKeepsFailures(t *testing.T) {
+	// Given: a request logger sampling one success per route per second
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "app.log")
+	log, err := logger.Configure(logger.Options{Output: logger.OutputFile, FilePath: path})
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = logger.Configure(logger.DefaultOptions()) })
+	router := gin.New()
+	router.Use(RequestLoggerWithOptions(log, RequestLoggerOptions{SamplePerSecond: 1}))
+	router.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	// When: both routes are hit several times within a second
+	for range 5 {
+		for _, target := range []string{"/ok", "/fail"} {
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+		}
+	}
+
+	// Then: successes are sampled (two if the loop straddled a second) and
+	// every failure is logged
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.LessOrEqual(t, strings.Count(string(out), `"http.route":"/ok"`), 2)
+	require.Equal(t, 5, strings.Count(string(out), `"http.route":"/fail"`))
+}
+
+func TestRequestLogger_CountsQueries(t *testing.T) {
+	// Given: a handler that runs three repository operations, two of them
+	// side by side
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "app.log")
+	log, err := logger.Configure(logger.Options{Output: logger.OutputFile, FilePath: path})
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = logger.Configure(logger.DefaultOptions()) })
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.MatchExpectationsInOrder(false)
+	for range 3 {
+		mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	}
+	repo := repository.NewUserRepository(db)
+	router := gin.New()
+	router.Use(RequestLogger(log))
+	router.GET("/users", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var wg sync.WaitGroup
+		for id := range 2 {
+			wg.Go(func() { _, _ = repo.ExistsByID(ctx, int64(id)) })
+		}
+		wg.Wait()
+		_, _ = repo.ExistsByID(ctx, 3)
+		c.Status(http.StatusOK)
+	})
+
+	// When: serving a request
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	// Then: the request line reports every query
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(out), `"db.query_count":3`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+type recordExporter struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (e *recordExporter) Export(_ context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, record := range records {
+		e.records = append(e.records, record.Clone())
+	}
+	return nil
+}
+
+func (e *recordExporter) Shutdown(context.Context) error   { return nil }
+func (e *recordExporter) ForceFlush(context.Context) error { return nil }
+
+func TestRequestLogger_RecordsCarryTheCallersTrace(t *testing.T) {
+	// Given: a request logger exporting to OTel and a handler that logs
+	// through it
+	gin.SetMode(gin.TestMode)
+	exporter := &recordExporter{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	log, err := logger.New(logger.Options{Output: logger.OutputOTel, OTelProvider: provider})
+	require.NoError(t, err)
+	router := gin.New()
+	router.Use(RequestLogger(log))
+	router.GET("/users", func(c *gin.Context) {
+		LoggerFromContext(c, log).Info("listing users")
+		c.Status(http.StatusOK)
+	})
+
+	// When: serving a request with a W3C traceparent header
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Then: the handler's record and the request line both carry its trace
+	exporter.mu.Lock()
+	defer exporter.mu.Unlock()
+	require.Len(t, exporter.records, 2)
+	for _, record := range exporter.records {
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", record.TraceID().String(), record.Body().AsString())
+		require.Equal(t, "00f067aa0ba902b7", record.SpanID().String())
+	}
+}
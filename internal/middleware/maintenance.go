@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"cruder/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Maintenance answers 503 with Retry-After to every mutating request while
+// enabled is set, and lets GET, HEAD, and OPTIONS through so reads keep
+// working during online schema changes. Routes registered before it, such as
+// the switch itself, are unaffected.
+func Maintenance(enabled *atomic.Bool, retryAfter time.Duration, log *logger.Logger) gin.HandlerFunc {
+	retry := strconv.Itoa(retryAfterSeconds(retryAfter))
+	return func(c *gin.Context) {
+		if !enabled.Load() {
+			c.Next()
+			return
+		}
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		log.DebugContext(c.Request.Context(), "request rejected: maintenance", loggerRequestAttrs(c)...)
+		c.Header("Retry-After", retry)
+		abortWithError(c, http.StatusServiceUnavailable, "service is under maintenance")
+	}
+}
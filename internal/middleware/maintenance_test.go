@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cruder/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenance(t *testing.T) {
+	// Given: a router behind the maintenance switch
+	gin.SetMode(gin.TestMode)
+	_, _ = logger.Configure(logger.DefaultOptions())
+	var enabled atomic.Bool
+	router := gin.New()
+	router.Use(Maintenance(&enabled, 30*time.Second, logger.Get()))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/users", ok)
+	router.HEAD("/users", ok)
+	router.POST("/users", ok)
+	router.PATCH("/users", ok)
+	router.PUT("/users", ok)
+	router.DELETE("/users", ok)
+	serve := func(method string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(method, "/users", nil))
+		return resp
+	}
+
+	// When: switched off, every method goes through
+	require.Equal(t, http.StatusOK, serve(http.MethodPost).Code)
+
+	// When: switched on
+	enabled.Store(true)
+
+	// Then: reads still go through and writes are turned away
+	require.Equal(t, http.StatusOK, serve(http.MethodGet).Code)
+	require.Equal(t, http.StatusOK, serve(http.MethodHead).Code)
+	for _, method := range []string{http.MethodPost, http.MethodPatch, http.MethodPut, http.MethodDelete} {
+		resp := serve(method)
+		require.Equal(t, http.StatusServiceUnavailable, resp.Code, method)
+		require.Equal(t, "30", resp.Header().Get("Retry-After"))
+		require.JSONEq(t, `{"error":"service is under maintenance"}`, resp.Body.String())
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics_RecordsRequestsByRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Metrics())
+	router.GET("/items/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	okCounter := httpRequestsTotal.WithLabelValues(http.MethodGet, "/items/:id", "200")
+	unmatchedCounter := httpRequestsTotal.WithLabelValues(http.MethodGet, unmatchedRoute, "404")
+	okBefore := testutil.ToFloat64(okCounter)
+	unmatchedBefore := testutil.ToFloat64(unmatchedCounter)
+
+	for _, path := range []string{"/items/1", "/items/2", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	require.Equal(t, okBefore+2, testutil.ToFloat64(okCounter), "route template is used, not the raw path")
+	require.Equal(t, unmatchedBefore+1, testutil.ToFloat64(unmatchedCounter))
+	require.Equal(t, float64(0), testutil.ToFloat64(httpRequestsInFlight.WithLabelValues(http.MethodGet, "/items/:id")))
+}
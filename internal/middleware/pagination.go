@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	contextPaginationKey = "pagination.page"
+
+	defaultPageLimit = 20
+	defaultMaxLimit  = 100
+)
+
+type PaginationOptions struct {
+	// DefaultLimit applies when the client picks a page or offset but no size.
+	DefaultLimit int
+	// MaxLimit caps per_page and limit.
+	MaxLimit int
+}
+
+// Page is the normalized window requested by the client. Cursor pages carry
+// After, the last id the client has seen, instead of an Offset.
+type Page struct {
+	Limit  int
+	Offset int
+	After  int64
+	Cursor bool
+}
+
+// Pagination accepts page/per_page, limit/offset, or after/limit (keyset)
+// query parameters, converts them to a Page, and stores it for
+// PageFromContext. Requests without any pagination parameters pass through
+// untouched. Mixing styles or passing out-of-range values aborts with 400.
+func Pagination(opts PaginationOptions) gin.HandlerFunc {
+	if opts.DefaultLimit <= 0 {
+		opts.DefaultLimit = defaultPageLimit
+	}
+	if opts.MaxLimit <= 0 {
+		opts.MaxLimit = defaultMaxLimit
+	}
+	if opts.DefaultLimit > opts.MaxLimit {
+		opts.DefaultLimit = opts.MaxLimit
+	}
+
+	return func(c *gin.Context) {
+		page, ok, err := parsePage(c, opts)
+		if err != nil {
+			abortWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if ok {
+			c.Set(contextPaginationKey, page)
+		}
+		c.Next()
+	}
+}
+
+// PageFromContext returns the page normalized by Pagination, if the client asked for one.
+func PageFromContext(c *gin.Context) (Page, bool) {
+	value, exists := c.Get(contextPaginationKey)
+	if !exists {
+		return Page{}, false
+	}
+	page, ok := value.(Page)
+	return page, ok
+}
+
+func parsePage(c *gin.Context, opts PaginationOptions) (Page, bool, error) {
+	pageNum, hasPage, err := queryInt(c, "page", 1)
+	if err != nil {
+		return Page{}, false, err
+	}
+	perPage, hasPerPage, err := queryInt(c, "per_page", 1)
+	if err != nil {
+		return Page{}, false, err
+	}
+	limit, hasLimit, err := queryInt(c, "limit", 1)
+	if err != nil {
+		return Page{}, false, err
+	}
+	offset, hasOffset, err := queryInt(c, "offset", 0)
+	if err != nil {
+		return Page{}, false, err
+	}
+	after, hasAfter, err := queryInt64(c, "after", 0)
+	if err != nil {
+		return Page{}, false, err
+	}
+
+	pageStyle := hasPage || hasPerPage
+	offsetStyle := hasLimit || hasOffset
+	switch {
+	case hasAfter && (pageStyle || hasOffset):
+		return Page{}, false, fmt.Errorf("after can only be combined with limit")
+	case hasAfter:
+		if !hasLimit {
+			limit = opts.DefaultLimit
+		}
+		if limit > opts.MaxLimit {
+			return Page{}, false, fmt.Errorf("limit must be at most %d", opts.MaxLimit)
+		}
+		return Page{Limit: limit, After: after, Cursor: true}, true, nil
+	case pageStyle && offsetStyle:
+		return Page{}, false, fmt.Errorf("use either page/per_page or limit/offset, not both")
+	case pageStyle:
+		if !hasPage {
+			pageNum = 1
+		}
+		if !hasPerPage {
+			perPage = opts.DefaultLimit
+		}
+		if perPage > opts.MaxLimit {
+			return Page{}, false, fmt.Errorf("per_page must be at most %d", opts.MaxLimit)
+		}
+		if pageNum-1 > math.MaxInt/perPage {
+			return Page{}, false, fmt.Errorf("page is out of range")
+		}
+		return Page{Limit: perPage, Offset: (pageNum - 1) * perPage}, true, nil
+	case offsetStyle:
+		if !hasLimit {
+			limit = opts.DefaultLimit
+		}
+		if limit > opts.MaxLimit {
+			return Page{}, false, fmt.Errorf("limit must be at most %d", opts.MaxLimit)
+		}
+		return Page{Limit: limit, Offset: offset}, true, nil
+	default:
+		return Page{}, false, nil
+	}
+}
+
+func queryInt64(c *gin.Context, name string, min int64) (int64, bool, error) {
+	raw, ok := c.GetQuery(name)
+	if !ok {
+		return 0, false, nil
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || value < min {
+		return 0, false, fmt.Errorf("%s must be an integer >= %d", name, min)
+	}
+	return value, true, nil
+}
+
+func queryInt(c *gin.Context, name string, min int) (int, bool, error) {
+	raw, ok := c.GetQuery(name)
+	if !ok {
+		return 0, false, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < min {
+		return 0, false, fmt.Errorf("%s must be an integer >= %d", name, min)
+	}
+	return value, true, nil
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type pageResult struct {
+	Set    bool  `json:"set"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+	After  int64 `json:"after"`
+	Cursor bool  `json:"cursor"`
+}
+
+func servePagination(t *testing.T, query string) (*httptest.ResponseRecorder, pageResult) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/items", Pagination(PaginationOptions{DefaultLimit: 20, MaxLimit: 100}), func(c *gin.Context) {
+		page, ok := PageFromContext(c)
+		c.JSON(http.StatusOK, pageResult{Set: ok, Limit: page.Limit, Offset: page.Offset, After: page.After, Cursor: page.Cursor})
+	})
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/items"+query, nil))
+
+	var result pageResult
+	if resp.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &result))
+	}
+	return resp, result
+}
+
+func TestPagination_PageStyle(t *testing.T) {
+	resp, page := servePagination(t, "?page=3&per_page=25")
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, pageResult{Set: true, Limit: 25, Offset: 50}, page)
+}
+
+func TestPagination_OffsetStyle(t *testing.T) {
+	resp, page := servePagination(t, "?limit=10&offset=40")
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, pageResult{Set: true, Limit: 10, Offset: 40}, page)
+}
+
+func TestPagination_Defaults(t *testing.T) {
+	_, page := servePagination(t, "?page=2")
+	require.Equal(t, pageResult{Set: true, Limit: 20, Offset: 20}, page)
+
+	_, page = servePagination(t, "?offset=5")
+	require.Equal(t, pageResult{Set: true, Limit: 20, Offset: 5}, page)
+
+	_, page = servePagination(t, "")
+	require.Equal(t, pageResult{}, page, "no parameters means no pagination")
+}
+
+func TestPagination_ConflictingStyles(t *testing.T) {
+	resp, _ := servePagination(t, "?page=2&offset=10")
+
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.JSONEq(t, `{"error":"use either page/per_page or limit/offset, not both"}`, resp.Body.String())
+}
+
+func TestPagination_InvalidValues(t *testing.T) {
+	for _, query := range []string{"?page=0", "?per_page=abc", "?limit=101", "?offset=-1", "?page=9223372036854775807&per_page=100"} {
+		resp, _ := servePagination(t, query)
+		require.Equal(t, http.StatusBadRequest, resp.Code, query)
+	}
+}
+
+func TestPagination_CursorStyle(t *testing.T) {
+	resp, page := servePagination(t, "?after=42&limit=10")
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, pageResult{Set: true, Limit: 10, After: 42, Cursor: true}, page)
+
+	_, page = servePagination(t, "?after=0")
+	require.Equal(t, pageResult{Set: true, Limit: 20, Cursor: true}, page)
+}
+
+func TestPagination_CursorRejectsOffsetStyles(t *testing.T) {
+	for _, query := range []string{"?after=5&offset=10", "?after=5&page=2", "?after=-1", "?after=x"} {
+		resp, _ := servePagination(t, query)
+		require.Equal(t, http.StatusBadRequest, resp.Code, query)
+	}
+}
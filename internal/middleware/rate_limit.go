@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"cruder/internal/model"
+	"cruder/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultRateLimitIdleTTL         = 10 * time.Minute
+	defaultRateLimitCleanupInterval = time.Minute
+)
+
+type RateLimitOptions struct {
+	RequestsPerSecond float64
+	Burst             int
+	// IdleTTL drops limiters for clients not seen within this window.
+	IdleTTL time.Duration
+	// CleanupInterval is the minimum time between idle-client sweeps.
+	CleanupInterval time.Duration
+}
+
+// RateLimit applies a token-bucket limit per authenticated API key, falling back
+// to the client IP when no key is present. It must run after APIKeyAuth.
+func RateLimit(opts RateLimitOptions, log *logger.Logger) gin.HandlerFunc {
+	limiters := newClientLimiters(opts)
+
+	return func(c *gin.Context) {
+		client := rateLimitClient(c)
+		allowed, retryAfter := limiters.allow(client, time.Now())
+		if !allowed {
+			log.WarnContext(c.Request.Context(), "rate limit exceeded", append(loggerRequestAttrs(c), slog.String("rate_limit.client", client))...)
+			c.Header("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+			abortWithError(c, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		c.Next()
+	}
+}
+
+// retryAfterSeconds rounds d up to whole seconds for a Retry-After header,
+// never below one.
+func retryAfterSeconds(d time.Duration) int {
+	return max(1, int(math.Ceil(d.Seconds())))
+}
+
+func rateLimitClient(c *gin.Context) string {
+	if value, ok := c.Get(ContextAPIClientKey); ok {
+		if key, ok := value.(*model.APIKey); ok && key != nil {
+			return "key:" + strconv.Itoa(key.ID)
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+type clientLimiters struct {
+	mu          sync.Mutex
+	limiters    map[string]*clientLimiter
+	limit       rate.Limit
+	burst       int
+	idleTTL     time.Duration
+	interval    time.Duration
+	lastCleanup time.Time
+}
+
+func newClientLimiters(opts RateLimitOptions) *clientLimiters {
+	burst := opts.Burst
+	if burst <= 0 {
+		burst = int(math.Max(1, math.Ceil(opts.RequestsPerSecond)))
+	}
+	idleTTL := opts.IdleTTL
+	if idleTTL <= 0 {
+		idleTTL = defaultRateLimitIdleTTL
+	}
+	interval := opts.CleanupInterval
+	if interval <= 0 {
+		interval = defaultRateLimitCleanupInterval
+	}
+	return &clientLimiters{
+		limiters:    make(map[string]*clientLimiter),
+		limit:       rate.Limit(opts.RequestsPerSecond),
+		burst:       burst,
+		idleTTL:     idleTTL,
+		interval:    interval,
+		lastCleanup: time.Now(),
+	}
+}
+
+// allow reports whether client may proceed at now and, if not, how long until
+// a token becomes available.
+func (l *clientLimiters) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastCleanup) >= l.interval {
+		l.cleanup(now)
+	}
+
+	entry, ok := l.limiters[client]
+	if !ok {
+		entry = &clientLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.limiters[client] = entry
+	}
+	entry.lastSeen = now
+
+	if entry.limiter.AllowN(now, 1) {
+		return true, 0
+	}
+	reservation := entry.limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	reservation.CancelAt(now)
+	return false, delay
+}
+
+func (l *clientLimiters) cleanup(now time.Time) {
+	for client, entry := range l.limiters {
+		if now.Sub(entry.lastSeen) > l.idleTTL {
+			delete(l.limiters, client)
+		}
+	}
+	l.lastCleanup = now
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"cruder/internal/model"
+	"cruder/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit_ExceededReturns429(t *testing.T) {
+	router := setupRateLimitRouter(RateLimitOptions{RequestsPerSecond: 0.5, Burst: 2})
+
+	for i := 0; i < 2; i++ {
+		resp := serveRateLimited(router, 1, "10.0.0.1")
+		require.Equal(t, http.StatusOK, resp.Code)
+	}
+
+	resp := serveRateLimited(router, 1, "10.0.0.1")
+	require.Equal(t, http.StatusTooManyRequests, resp.Code)
+	require.Equal(t, "2", resp.Header().Get("Retry-After"))
+}
+
+func TestRateLimit_KeyedPerClient(t *testing.T) {
+	router := setupRateLimitRouter(RateLimitOptions{RequestsPerSecond: 0.5, Burst: 1})
+
+	require.Equal(t, http.StatusOK, serveRateLimited(router, 1, "10.0.0.1").Code)
+	require.Equal(t, http.StatusTooManyRequests, serveRateLimited(router, 1, "10.0.0.2").Code, "same key from another IP shares the bucket")
+	require.Equal(t, http.StatusOK, serveRateLimited(router, 2, "10.0.0.1").Code, "another key has its own bucket")
+}
+
+func TestRateLimit_FallsBackToClientIP(t *testing.T) {
+	router := setupRateLimitRouter(RateLimitOptions{RequestsPerSecond: 0.5, Burst: 1})
+
+	require.Equal(t, http.StatusOK, serveRateLimited(router, 0, "10.0.0.1").Code)
+	require.Equal(t, http.StatusTooManyRequests, serveRateLimited(router, 0, "10.0.0.1").Code)
+	require.Equal(t, http.StatusOK, serveRateLimited(router, 0, "10.0.0.2").Code)
+}
+
+func TestClientLimiters_CleanupDropsIdleClients(t *testing.T) {
+	limiters := newClientLimiters(RateLimitOptions{
+		RequestsPerSecond: 1,
+		IdleTTL:           time.Minute,
+		CleanupInterval:   time.Second,
+	})
+	start := time.Now()
+
+	allowed, _ := limiters.allow("ip:idle", start)
+	require.True(t, allowed)
+	allowed, _ = limiters.allow("ip:active", start.Add(90*time.Second))
+	require.True(t, allowed)
+
+	require.NotContains(t, limiters.limiters, "ip:idle")
+	require.Contains(t, limiters.limiters, "ip:active")
+}
+
+func setupRateLimitRouter(opts RateLimitOptions) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	_, _ = logger.Configure(logger.DefaultOptions())
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if id, err := strconv.Atoi(c.GetHeader("X-Test-Key-ID")); err == nil {
+			c.Set(ContextAPIClientKey, &model.APIKey{ID: id})
+		}
+		c.Next()
+	})
+	router.Use(RateLimit(opts, logger.Get()))
+	router.GET("/limited", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func serveRateLimited(router *gin.Engine, keyID int, remoteIP string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+	req.RemoteAddr = remoteIP + ":12345"
+	if keyID > 0 {
+		req.Header.Set("X-Test-Key-ID", strconv.Itoa(keyID))
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
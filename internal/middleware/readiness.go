@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"cruder/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadinessGate answers 503 with Retry-After: 1 until ready is set, so a load
+// balancer that honours Retry-After re-queues requests that arrive while the
+// database is still out of reach. Routes registered before it, such as the
+// health checks, keep answering.
+func ReadinessGate(ready *atomic.Bool, log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ready.Load() {
+			c.Next()
+			return
+		}
+		log.DebugContext(c.Request.Context(), "request rejected: not ready", loggerRequestAttrs(c)...)
+		c.Header("Retry-After", "1")
+		abortWithError(c, http.StatusServiceUnavailable, "service is starting")
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"cruder/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadinessGate(t *testing.T) {
+	// Given: a health route outside the gate and a user route behind it
+	gin.SetMode(gin.TestMode)
+	_, _ = logger.Configure(logger.DefaultOptions())
+	var ready atomic.Bool
+	router := gin.New()
+	router.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.Use(ReadinessGate(&ready, logger.Get()))
+	router.GET("/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+	serve := func(path string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		return resp
+	}
+
+	// When: not ready yet
+	// Then: only the health route answers
+	require.Equal(t, http.StatusOK, serve("/healthz").Code)
+	resp := serve("/users")
+	require.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	require.Equal(t, "1", resp.Header().Get("Retry-After"))
+	require.JSONEq(t, `{"error":"service is starting"}`, resp.Body.String())
+
+	// When: the database has answered
+	ready.Store(true)
+
+	// Then: the gate opens
+	require.Equal(t, http.StatusOK, serve("/users").Code)
+}
@@ -1,23 +1,50 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
 	"runtime/debug"
 
 	"log/slog"
 
+	"cruder/internal/controller/response"
 	"cruder/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
+// RecoveryOptions configures RecoveryWithOptions.
+type RecoveryOptions struct {
+	// ExposePanic adds the panic value to the 500 body. It only takes effect
+	// while gin runs in debug mode, so it can't leak internals in release.
+	ExposePanic bool
+}
+
 func Recovery(log *logger.Logger) gin.HandlerFunc {
+	return RecoveryWithOptions(log, RecoveryOptions{})
+}
+
+// RecoveryWithOptions turns a panic into a logged stack trace and a JSON 500
+// carrying the request ID, if the client sent one.
+func RecoveryWithOptions(log *logger.Logger, opts RecoveryOptions) gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered any) {
 		reqLogger := LoggerFromContext(c, log).With(
 			slog.Any("panic", recovered),
 			slog.String("stacktrace", string(debug.Stack())),
 		)
 		reqLogger.Error("panic recovered")
-		c.AbortWithStatus(http.StatusInternalServerError)
+
+		if c.Writer.Written() {
+			// part of the response is already on the wire; appending an error
+			// body would only corrupt it
+			c.Abort()
+			return
+		}
+		body := response.Error{Error: "internal server error", RequestID: c.GetHeader(HeaderRequestID)}
+		if opts.ExposePanic && gin.IsDebugging() {
+			body.Error = fmt.Sprintf("internal server error: %v", recovered)
+		}
+		c.Abort()
+		WriteError(c, http.StatusInternalServerError, body)
 	})
 }
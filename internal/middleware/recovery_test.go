@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cruder/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecovery_WritesJSONError(t *testing.T) {
+	router := setupRecoveryRouter(RecoveryOptions{ExposePanic: true}, func(c *gin.Context) {
+		panic("boom")
+	})
+
+	resp := serveRecovery(router, "req-123")
+
+	// ExposePanic is ignored outside debug mode
+	require.Equal(t, http.StatusInternalServerError, resp.Code)
+	require.JSONEq(t, `{"error":"internal server error","request_id":"req-123"}`, resp.Body.String())
+}
+
+func TestRecovery_ExposesPanicInDebugMode(t *testing.T) {
+	router := setupRecoveryRouter(RecoveryOptions{ExposePanic: true}, func(c *gin.Context) {
+		panic("boom")
+	})
+	gin.SetMode(gin.DebugMode)
+	t.Cleanup(func() { gin.SetMode(gin.TestMode) })
+
+	resp := serveRecovery(router, "")
+
+	require.Equal(t, http.StatusInternalServerError, resp.Code)
+	require.JSONEq(t, `{"error":"internal server error: boom"}`, resp.Body.String())
+}
+
+func TestRecovery_LeavesStartedResponseAlone(t *testing.T) {
+	router := setupRecoveryRouter(RecoveryOptions{}, func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+
+	resp := serveRecovery(router, "")
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, "partial", resp.Body.String())
+}
+
+func setupRecoveryRouter(opts RecoveryOptions, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	_, _ = logger.Configure(logger.DefaultOptions())
+	router := gin.New()
+	router.Use(RecoveryWithOptions(logger.Get(), opts))
+	router.GET("/panic", handler)
+	return router
+}
+
+func serveRecovery(router *gin.Engine, requestID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	if requestID != "" {
+		req.Header.Set(HeaderRequestID, requestID)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
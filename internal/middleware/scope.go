@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"cruder/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScope rejects requests whose API key lacks scope with 403. It runs
+// after APIKeyAuth; a request that reached it without a key is rejected too.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := apiClient(c); key == nil || !key.HasScope(scope) {
+			log := LoggerFromContext(c, logger.Get())
+			log.WarnContext(c.Request.Context(), "api key lacks required scope", append(loggerRequestAttrs(c), slog.String("api_key.required_scope", scope))...)
+			abortWithError(c, http.StatusForbidden, "api key lacks scope "+scope)
+			return
+		}
+		c.Next()
+	}
+}
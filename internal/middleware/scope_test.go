@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cruder/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		key    *model.APIKey
+		status int
+	}{
+		{"granted", &model.APIKey{ID: 1, Scopes: []string{model.ScopeUsersRead}}, http.StatusOK},
+		{"wildcard", &model.APIKey{ID: 1, Scopes: []string{model.ScopeAll}}, http.StatusOK},
+		{"other scope only", &model.APIKey{ID: 1, Scopes: []string{model.ScopeUsersWrite}}, http.StatusForbidden},
+		{"no scopes", &model.APIKey{ID: 1}, http.StatusForbidden},
+		{"unauthenticated", nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: a route requiring users:read behind a stand-in for APIKeyAuth
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if tt.key != nil {
+					c.Set(ContextAPIClientKey, tt.key)
+				}
+			})
+			router.GET("/users", RequireScope(model.ScopeUsersRead), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			// When: the route is called
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+			// Then: only keys holding the scope get through
+			require.Equal(t, tt.status, resp.Code)
+			if tt.status == http.StatusForbidden {
+				require.JSONEq(t, `{"error":"api key lacks scope users:read"}`, resp.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"cruder/internal/controller/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const errRequestTimeout = "request timeout"
+
+// Timeout bounds handler execution to d. The request context is replaced with a
+// context that is cancelled at the deadline; if the handler has not finished by
+// then, the client receives 503 and anything the handler writes afterwards is
+// discarded. The middleware still waits for the handler to return before
+// releasing the gin.Context, so the context is never reused while in use.
+//
+// Routes whose pattern is listed in streaming are passed through unbuffered
+// and unbounded: holding their output would defeat streaming, and a long
+// export is expected to outlive d.
+func Timeout(d time.Duration, streaming ...string) gin.HandlerFunc {
+	skip := make(map[string]struct{}, len(streaming))
+	for _, route := range streaming {
+		skip[route] = struct{}{}
+	}
+	return func(c *gin.Context) {
+		if _, ok := skip[c.FullPath()]; ok {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		original := c.Writer
+		// read before the handler goroutine starts, which may replace them
+		req, route := c.Request, c.FullPath()
+		tw := &timeoutWriter{ResponseWriter: original, header: make(http.Header)}
+		c.Writer = tw
+
+		done := make(chan struct{})
+		var panicked any
+		go func() {
+			defer close(done)
+			defer func() {
+				panicked = recover()
+			}()
+			c.Next()
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			tw.timeout(original, req, route)
+			<-done
+		}
+
+		c.Writer = original
+		if panicked != nil {
+			// re-raise on the middleware goroutine so Recovery can handle it
+			panic(panicked)
+		}
+		tw.flush(original)
+	}
+}
+
+// timeoutWriter buffers the handler's response so it can be dropped in favour
+// of a timeout response without racing the handler goroutine.
+type timeoutWriter struct {
+	gin.ResponseWriter
+
+	mu          sync.Mutex
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.wroteHeader {
+		return
+	}
+	w.status = code
+	w.wroteHeader = true
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.wroteHeader {
+		return
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.wroteHeader = true
+}
+
+func (w *timeoutWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !w.wroteHeader {
+		w.status = http.StatusOK
+		w.wroteHeader = true
+	}
+	return w.body.Write(p)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.wroteHeader {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.wroteHeader
+}
+
+// Flush is a no-op: output is buffered until the handler completes.
+func (w *timeoutWriter) Flush() {}
+
+func (w *timeoutWriter) timeout(dst gin.ResponseWriter, req *http.Request, route string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timedOut = true
+
+	writeError(dst, req, route, http.StatusServiceUnavailable, response.Error{Error: errRequestTimeout})
+	dst.Flush()
+}
+
+func (w *timeoutWriter) flush(dst gin.ResponseWriter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+
+	header := dst.Header()
+	for key, values := range w.header {
+		header[key] = values
+	}
+	if w.wroteHeader {
+		dst.WriteHeader(w.status)
+	}
+	if w.body.Len() > 0 {
+		_, _ = dst.Write(w.body.Bytes())
+	}
+}
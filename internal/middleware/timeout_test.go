@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout_FastHandlerPassesThrough(t *testing.T) {
+	router := setupTimeoutRouter(50*time.Millisecond, func(c *gin.Context) {
+		c.Header("X-Handler", "done")
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+
+	resp := serveTimeout(router)
+
+	require.Equal(t, http.StatusCreated, resp.Code)
+	require.Equal(t, "done", resp.Header().Get("X-Handler"))
+	require.JSONEq(t, `{"ok":true}`, resp.Body.String())
+}
+
+func TestTimeout_SlowHandlerReturns503(t *testing.T) {
+	router := setupTimeoutRouter(20*time.Millisecond, func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		c.JSON(http.StatusOK, gin.H{"late": true})
+	})
+
+	resp := serveTimeout(router)
+
+	require.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	require.JSONEq(t, `{"error":"request timeout"}`, resp.Body.String())
+}
+
+func TestTimeout_WriteAfterTimeoutIsDiscarded(t *testing.T) {
+	router := setupTimeoutRouter(10*time.Millisecond, func(c *gin.Context) {
+		time.Sleep(40 * time.Millisecond)
+		c.Header("X-Late", "true")
+		c.JSON(http.StatusOK, gin.H{"late": true})
+		c.Status(http.StatusAccepted)
+	})
+
+	resp := serveTimeout(router)
+
+	require.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	require.Empty(t, resp.Header().Get("X-Late"))
+	require.NotContains(t, resp.Body.String(), "late")
+}
+
+func TestTimeout_PanicReachesRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+		c.AbortWithStatus(http.StatusTeapot)
+	}))
+	router.Use(Timeout(50 * time.Millisecond))
+	router.GET("/slow", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	resp := serveTimeout(router)
+
+	require.Equal(t, http.StatusTeapot, resp.Code)
+}
+
+func TestTimeout_StreamingRouteIsUnbuffered(t *testing.T) {
+	// Given: /slow registered as a streaming route
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Timeout(10*time.Millisecond, "/slow"))
+	router.GET("/slow", func(c *gin.Context) {
+		_, _ = c.Writer.WriteString("line\n")
+		c.Writer.Flush()
+		time.Sleep(30 * time.Millisecond)
+		_, _ = c.Writer.WriteString("line\n")
+	})
+
+	// When: the handler outlives the timeout
+	resp := serveTimeout(router)
+
+	// Then: it is neither cut off nor buffered
+	require.True(t, resp.Flushed)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, "line\nline\n", resp.Body.String())
+}
+
+func setupTimeoutRouter(d time.Duration, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Timeout(d))
+	router.GET("/slow", handler)
+	return router
+}
+
+func serveTimeout(router *gin.Engine) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/slow", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
@@ -1,11 +1,39 @@
 package model
 
-import "time"
+import (
+	"slices"
+	"time"
+)
+
+// API key scopes. ScopeAll grants every scope.
+const (
+	ScopeAll        = "*"
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+)
+
+// Scopes lists every scope a key can be granted.
+var Scopes = []string{ScopeAll, ScopeUsersRead, ScopeUsersWrite}
 
 type APIKey struct {
-	ID         int       `json:"id"`
-	KeyHash    string    `json:"-"`
-	ClientName string    `json:"client_name"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID         int    `json:"id"`
+	KeyHash    string `json:"-"`
+	ClientName string `json:"client_name"`
+	// Label tells apart the keys of one client, e.g. "prod" and "staging".
+	Label     string     `json:"label"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Expired reports whether the key has an expiry at or before now.
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// HasScope reports whether the key was granted scope, directly or through
+// ScopeAll.
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, ScopeAll) || slices.Contains(k.Scopes, scope)
 }
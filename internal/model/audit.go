@@ -0,0 +1,67 @@
+package model
+
+import "time"
+
+// AuditEntry is one row of the append-only audit log: who changed which user,
+// how, and the user's editable fields before and after the change. Before is
+// nil for creates and After is nil for deletes.
+type AuditEntry struct {
+	ID        int64
+	Actor     string
+	Action    string
+	UserUUID  string
+	Before    *AuditFields
+	After     *AuditFields
+	CreatedAt time.Time
+}
+
+// AuditFields are the user fields captured in an audit entry.
+type AuditFields struct {
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	FullName      string `json:"full_name"`
+	AvatarURL     string `json:"avatar_url,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+	Status        string `json:"status,omitempty"`
+}
+
+// NewAuditFields captures the editable fields of u, or returns nil for a nil
+// user.
+func NewAuditFields(u *User) *AuditFields {
+	if u == nil {
+		return nil
+	}
+	return &AuditFields{Username: u.Username, Email: u.Email, FullName: u.FullName, AvatarURL: u.AvatarURL, EmailVerified: u.EmailVerified, Status: u.Status}
+}
+
+// Changed lists the JSON names of the fields that differ between Before and
+// After, treating a missing side as empty. A create lists every field it set.
+func (e AuditEntry) Changed() []string {
+	var before, after AuditFields
+	if e.Before != nil {
+		before = *e.Before
+	}
+	if e.After != nil {
+		after = *e.After
+	}
+	var changed []string
+	if before.Username != after.Username {
+		changed = append(changed, "username")
+	}
+	if before.Email != after.Email {
+		changed = append(changed, "email")
+	}
+	if before.FullName != after.FullName {
+		changed = append(changed, "full_name")
+	}
+	if before.AvatarURL != after.AvatarURL {
+		changed = append(changed, "avatar_url")
+	}
+	if before.EmailVerified != after.EmailVerified {
+		changed = append(changed, "email_verified")
+	}
+	if before.Status != after.Status {
+		changed = append(changed, "status")
+	}
+	return changed
+}
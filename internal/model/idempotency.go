@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// IdempotentResponse is the stored outcome of a request made with an
+// Idempotency-Key, replayed when the same client repeats the key.
+type IdempotentResponse struct {
+	APIKeyID    int
+	Key         string
+	RequestHash string
+	// StatusCode is zero while the request holding the key is still
+	// running; see Pending.
+	StatusCode int
+	// Location is the original response's Location header, if it set one.
+	Location  string
+	Body      []byte
+	ExpiresAt time.Time
+}
+
+// Pending reports whether the key is reserved by a request that hasn't
+// finished, so there is no response to replay yet.
+func (r *IdempotentResponse) Pending() bool {
+	return r.StatusCode == 0
+}
@@ -1,9 +1,61 @@
 package model
 
+import (
+	"slices"
+	"time"
+)
+
+// User statuses. A suspended user is kept, and still returned by reads, with
+// its status visible.
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+)
+
+// UserStatuses lists every status a user can have.
+var UserStatuses = []string{UserStatusActive, UserStatusSuspended}
+
+// ValidUserStatus reports whether status is one of UserStatuses.
+func ValidUserStatus(status string) bool {
+	return slices.Contains(UserStatuses, status)
+}
+
 type User struct {
 	ID       int    `json:"id"`
 	UUID     string `json:"uuid"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	FullName string `json:"full_name"`
+	// AvatarURL is an http(s) URL of the user's profile image, or "" for none.
+	AvatarURL string `json:"avatar_url,omitempty"`
+	// EmailVerified is set once the user redeems a verification token issued
+	// for their current email. Changing the email clears it.
+	EmailVerified bool `json:"email_verified"`
+	// Status is one of UserStatuses; new users are active.
+	Status string `json:"status"`
+	// Version starts at 1 and goes up by one with every update. Updates must
+	// name the version they were based on.
+	Version int `json:"version"`
+}
+
+// UserStats are headline counts of the users table. CreatedToday counts
+// users created since midnight in the database's time zone.
+type UserStats struct {
+	Total        int64
+	CreatedToday int64
+	Suspended    int64
+}
+
+// EmailVerificationToken is a pending verification. Only the token's hash is
+// stored; Email is the address it was issued for.
+type EmailVerificationToken struct {
+	TokenHash string
+	UserID    int
+	Email     string
+	ExpiresAt time.Time
+}
+
+// Expired reports whether the token expires at or before now.
+func (t *EmailVerificationToken) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
 }
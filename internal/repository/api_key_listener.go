@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"cruder/pkg/logger"
+
+	"github.com/lib/pq"
+)
+
+// APIKeyChangesChannel is the NOTIFY channel a trigger on api_keys signals
+// with the key_hash of every updated or deleted row.
+const APIKeyChangesChannel = "api_keys_changed"
+
+// APIKeyInvalidator drops cached API keys when the database reports a change.
+type APIKeyInvalidator interface {
+	InvalidateHash(hash string)
+	// InvalidateAll is called after the listener reconnects, since changes
+	// made while it was down were never delivered.
+	InvalidateAll()
+}
+
+// APIKeyListener holds a dedicated connection LISTENing on
+// APIKeyChangesChannel, so a key revoked on any instance, or directly in SQL,
+// leaves every cache as soon as the change commits.
+type APIKeyListener struct {
+	listener *pq.Listener
+	target   APIKeyInvalidator
+	log      *logger.Logger
+	done     chan struct{}
+}
+
+// ListenAPIKeyChanges starts an APIKeyListener that invalidates target's
+// cached keys, logging to log. A nil log means the global logger.
+func ListenAPIKeyChanges(dsn string, target APIKeyInvalidator, log *logger.Logger) (*APIKeyListener, error) {
+	log = logger.OrGlobal(log).With(slog.String("component", "repository.api_key_listener"))
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Warn("api key listener connection event", slog.Int("event", int(event)), slog.String("error", err.Error()))
+		}
+	})
+	if err := listener.Listen(APIKeyChangesChannel); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("listen on %s: %w", APIKeyChangesChannel, err)
+	}
+	l := &APIKeyListener{
+		listener: listener,
+		target:   target,
+		log:      log,
+		done:     make(chan struct{}),
+	}
+	go l.run(listener.Notify)
+	return l, nil
+}
+
+func (l *APIKeyListener) run(notifications <-chan *pq.Notification) {
+	defer close(l.done)
+	for n := range notifications {
+		l.handle(n)
+	}
+}
+
+func (l *APIKeyListener) handle(n *pq.Notification) {
+	// pq sends nil after re-establishing a lost connection
+	if n == nil {
+		l.log.Warn("api key listener reconnected, flushing cached keys")
+		l.target.InvalidateAll()
+		return
+	}
+	l.target.InvalidateHash(n.Extra)
+}
+
+// Close stops listening and waits for pending notifications to be handled.
+func (l *APIKeyListener) Close() error {
+	err := l.listener.Close()
+	<-l.done
+	return err
+}
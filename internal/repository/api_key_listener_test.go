@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"testing"
+
+	"cruder/pkg/logger"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingInvalidator struct {
+	hashes []string
+	all    int
+}
+
+func (r *recordingInvalidator) InvalidateHash(hash string) { r.hashes = append(r.hashes, hash) }
+func (r *recordingInvalidator) InvalidateAll()             { r.all++ }
+
+func TestAPIKeyListener_DispatchesNotifications(t *testing.T) {
+	// Given: a listener fed from a channel instead of a database connection
+	target := &recordingInvalidator{}
+	l := &APIKeyListener{target: target, log: logger.Get(), done: make(chan struct{})}
+	notifications := make(chan *pq.Notification, 3)
+
+	// When: a change, a reconnect, and another change arrive
+	notifications <- &pq.Notification{Channel: APIKeyChangesChannel, Extra: "hash-1"}
+	notifications <- nil
+	notifications <- &pq.Notification{Channel: APIKeyChangesChannel, Extra: "hash-2"}
+	close(notifications)
+	l.run(notifications)
+
+	// Then: changed keys are invalidated and the reconnect flushes everything
+	require.Equal(t, []string{"hash-1", "hash-2"}, target.hashes)
+	require.Equal(t, 1, target.all)
+}
@@ -3,29 +3,54 @@ package repository
 import (
 	"context"
 	"cruder/internal/model"
+	"cruder/pkg/logger"
 	"database/sql"
 	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 type APIKeyRepository interface {
 	GetByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	// Create fails with ErrUniqueViolation when clientName already has a key
+	// with this label.
+	Create(ctx context.Context, hash, clientName, label string, expiresAt *time.Time, scopes []string) (*model.APIKey, error)
+	// List returns keys ordered by id whose client_name contains client,
+	// case-insensitively; an empty client matches every key. A limit <= 0
+	// returns every match.
+	List(ctx context.Context, client string, limit, offset int) ([]model.APIKey, error)
+	DeleteByID(ctx context.Context, id int64) (bool, error)
 }
 
 type apiKeyRepository struct {
-	db *sql.DB
+	db  *sql.DB
+	log *logger.Logger
 }
 
 func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
-	return &apiKeyRepository{db: db}
+	return newAPIKeyRepository(db, logger.Get())
+}
+
+func newAPIKeyRepository(db *sql.DB, log *logger.Logger) APIKeyRepository {
+	repoLogger := log.With(slog.String("component", "repository.api_key"))
+	return &apiKeyRepository{
+		db:  db,
+		log: repoLogger,
+	}
 }
 
 func (r *apiKeyRepository) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	_, done := startOperation(ctx, r.log, "APIKeyRepository.GetByHash")
+	defer done()
 	var key model.APIKey
 	err := r.db.QueryRowContext(
 		ctx,
-		`SELECT id, key_hash, client_name, created_at, updated_at FROM api_keys WHERE key_hash = $1`,
+		`SELECT id, key_hash, client_name, label, scopes, expires_at, created_at, updated_at FROM api_keys WHERE key_hash = $1`,
 		hash,
-	).Scan(&key.ID, &key.KeyHash, &key.ClientName, &key.CreatedAt, &key.UpdatedAt)
+	).Scan(&key.ID, &key.KeyHash, &key.ClientName, &key.Label, (*pq.StringArray)(&key.Scopes), &key.ExpiresAt, &key.CreatedAt, &key.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -34,3 +59,69 @@ func (r *apiKeyRepository) GetByHash(ctx context.Context, hash string) (*model.A
 	}
 	return &key, nil
 }
+
+func (r *apiKeyRepository) Create(ctx context.Context, hash, clientName, label string, expiresAt *time.Time, scopes []string) (*model.APIKey, error) {
+	_, done := startOperation(ctx, r.log, "APIKeyRepository.Create")
+	defer done()
+	var key model.APIKey
+	err := r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO api_keys (key_hash, client_name, label, expires_at, scopes) VALUES ($1, $2, $3, $4, $5) RETURNING id, key_hash, client_name, label, scopes, expires_at, created_at, updated_at`,
+		hash,
+		clientName,
+		label,
+		expiresAt,
+		pq.Array(scopes),
+	).Scan(&key.ID, &key.KeyHash, &key.ClientName, &key.Label, (*pq.StringArray)(&key.Scopes), &key.ExpiresAt, &key.CreatedAt, &key.UpdatedAt)
+	if err != nil {
+		return nil, mapPQError(err)
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) List(ctx context.Context, client string, limit, offset int) ([]model.APIKey, error) {
+	_, done := startOperation(ctx, r.log, "APIKeyRepository.List")
+	defer done()
+
+	query := newSelectQuery(`SELECT id, key_hash, client_name, label, scopes, expires_at, created_at, updated_at FROM api_keys`)
+	if client != "" {
+		query.Where("client_name ILIKE '%' || " + query.arg(likeEscaper.Replace(client)) + " || '%'")
+	}
+	query.OrderBy("id").Limit(limit).Offset(offset)
+
+	rows, err := r.db.QueryContext(ctx, query.String(), query.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []model.APIKey
+	for rows.Next() {
+		var key model.APIKey
+		if err := rows.Scan(&key.ID, &key.KeyHash, &key.ClientName, &key.Label, (*pq.StringArray)(&key.Scopes), &key.ExpiresAt, &key.CreatedAt, &key.UpdatedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// likeEscaper makes LIKE treat wildcards in a search term literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *apiKeyRepository) DeleteByID(ctx context.Context, id int64) (bool, error) {
+	_, done := startOperation(ctx, r.log, "APIKeyRepository.DeleteByID")
+	defer done()
+	res, err := r.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyRepository_List_FiltersAndPages(t *testing.T) {
+	// Given: a client filter containing LIKE wildcards and a page window
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM api_keys WHERE client_name ILIKE '%' || $1 || '%' ORDER BY id LIMIT $2 OFFSET $3`)).
+		WithArgs(`50\%\_off`, 10, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "key_hash", "client_name", "label", "scopes", "expires_at", "created_at", "updated_at"}).
+			AddRow(21, "hash", "50%_off partner", "", "{*}", nil, now, now))
+
+	// When: listing
+	keys, err := NewAPIKeyRepository(db).List(context.Background(), "50%_off", 10, 20)
+
+	// Then: the wildcards are matched literally
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.Equal(t, "50%_off partner", keys[0].ClientName)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyRepository_List_NoFilter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM api_keys ORDER BY id`) + `$`).
+		WithoutArgs().
+		WillReturnRows(sqlmock.NewRows([]string{"id", "key_hash", "client_name", "label", "scopes", "expires_at", "created_at", "updated_at"}))
+
+	keys, err := NewAPIKeyRepository(db).List(context.Background(), "", 0, 0)
+
+	require.NoError(t, err)
+	require.Empty(t, keys)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
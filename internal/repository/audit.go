@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/pkg/logger"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+)
+
+type AuditRepository interface {
+	// Record appends entry to the audit log and fills in its ID and
+	// CreatedAt.
+	Record(ctx context.Context, entry *model.AuditEntry) error
+	// ListForUser returns up to limit entries for the user with userUUID,
+	// oldest first, starting after the entry with id afterID.
+	ListForUser(ctx context.Context, userUUID uuid.UUID, afterID int64, limit int) ([]model.AuditEntry, error)
+}
+
+type auditRepository struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return newAuditRepository(db, logger.Get())
+}
+
+func newAuditRepository(db *sql.DB, log *logger.Logger) AuditRepository {
+	repoLogger := log.With(slog.String("component", "repository.audit"))
+	return &auditRepository{
+		db:  db,
+		log: repoLogger,
+	}
+}
+
+func (r *auditRepository) Record(ctx context.Context, entry *model.AuditEntry) error {
+	_, done := startOperation(ctx, r.log, "AuditRepository.Record")
+	defer done()
+	before, err := marshalAuditFields(entry.Before)
+	if err != nil {
+		return err
+	}
+	after, err := marshalAuditFields(entry.After)
+	if err != nil {
+		return err
+	}
+	return r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO audit_log (actor, action, user_uuid, before, after) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		entry.Actor,
+		entry.Action,
+		entry.UserUUID,
+		before,
+		after,
+	).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+func (r *auditRepository) ListForUser(ctx context.Context, userUUID uuid.UUID, afterID int64, limit int) ([]model.AuditEntry, error) {
+	log, done := startOperation(ctx, r.log, "AuditRepository.ListForUser")
+	defer done()
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, actor, action, user_uuid, before, after, created_at FROM audit_log WHERE user_uuid = $1 AND id > $2 ORDER BY id LIMIT $3`,
+		userUUID.String(), afterID, limit)
+	if err != nil {
+		log.Error("list audit entries failed", slog.String("user.uuid", userUUID.String()), slog.String("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []model.AuditEntry
+	for rows.Next() {
+		var e model.AuditEntry
+		var before, after []byte
+		if err := rows.Scan(&e.ID, &e.Actor, &e.Action, &e.UserUUID, &before, &after, &e.CreatedAt); err != nil {
+			log.Error("scan audit entry failed", slog.String("error", err.Error()))
+			return nil, err
+		}
+		if e.Before, err = unmarshalAuditFields(before); err != nil {
+			return nil, err
+		}
+		if e.After, err = unmarshalAuditFields(after); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("iterate audit entries failed", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return entries, nil
+}
+
+// marshalAuditFields encodes fields for a JSONB column, mapping nil to NULL.
+func marshalAuditFields(fields *model.AuditFields) ([]byte, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("encode audit fields: %w", err)
+	}
+	return data, nil
+}
+
+// unmarshalAuditFields decodes a JSONB column, mapping NULL to nil.
+func unmarshalAuditFields(data []byte) (*model.AuditFields, error) {
+	if data == nil {
+		return nil, nil
+	}
+	var fields model.AuditFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("decode audit fields: %w", err)
+	}
+	return &fields, nil
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"cruder/internal/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditRepository_ListForUser(t *testing.T) {
+	// Given: a create and a delete recorded for the user
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	id := uuid.New()
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM audit_log WHERE user_uuid = $1 AND id > $2 ORDER BY id LIMIT $3`)).
+		WithArgs(id.String(), int64(4), 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "actor", "action", "user_uuid", "before", "after", "created_at"}).
+			AddRow(5, "billing", "user.create", id.String(), nil, []byte(`{"username":"jdoe","email":"jdoe@example.com","full_name":"","email_verified":false}`), now).
+			AddRow(8, "support", "user.delete", id.String(), []byte(`{"username":"jdoe","email":"jdoe@example.com","full_name":"","email_verified":true}`), nil, now))
+
+	// When: listing the page after entry 4
+	entries, err := NewAuditRepository(db).ListForUser(context.Background(), id, 4, 2)
+
+	// Then: both sides decode, with NULL as nil
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Nil(t, entries[0].Before)
+	require.Equal(t, &model.AuditFields{Username: "jdoe", Email: "jdoe@example.com"}, entries[0].After)
+	require.True(t, entries[1].Before.EmailVerified)
+	require.Nil(t, entries[1].After)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"cruder/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var userCacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "cruder",
+	Subsystem: "user_cache",
+	Name:      "lookups_total",
+	Help:      "GetByUUID lookups against the user cache, by result (hit or miss).",
+}, []string{"result"})
+
+var userCacheEntries = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: "cruder",
+	Subsystem: "user_cache",
+	Name:      "entries",
+	Help:      "Users currently held in the user cache.",
+})
+
+var userCacheEvictions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "cruder",
+	Subsystem: "user_cache",
+	Name:      "evictions_total",
+	Help:      "Users dropped from the user cache, by reason (capacity, expired, or write).",
+}, []string{"reason"})
+
+// Reasons a user leaves the cache, as counted by userCacheEvictions.
+const (
+	evictCapacity = "capacity"
+	evictExpired  = "expired"
+	evictWrite    = "write"
+)
+
+// CacheOptions configures the GetByUUID cache. The cache is disabled unless
+// both fields are positive.
+type CacheOptions struct {
+	// TTL bounds how long a user is served from memory.
+	TTL time.Duration
+	// MaxEntries caps the cache; the least recently used user is evicted
+	// first.
+	MaxEntries int
+}
+
+func (o CacheOptions) enabled() bool {
+	return o.TTL > 0 && o.MaxEntries > 0
+}
+
+// cachingUserRepository serves GetByUUID from an LRU cache. Only found users
+// are cached, so a user created after a 404 is visible straight away. Every
+// write evicts the users it touched.
+type cachingUserRepository struct {
+	UserRepository
+	opts CacheOptions
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]*list.Element
+	lru     *list.List
+	// generation counts evictions, so a read that raced a write can tell
+	// its row may be stale and skip caching it.
+	generation uint64
+}
+
+type userCacheEntry struct {
+	id      uuid.UUID
+	user    model.User
+	expires time.Time
+}
+
+func newCachingUserRepository(repo UserRepository, opts CacheOptions) *cachingUserRepository {
+	return &cachingUserRepository{
+		UserRepository: repo,
+		opts:           opts,
+		now:            time.Now,
+		entries:        make(map[uuid.UUID]*list.Element),
+		lru:            list.New(),
+	}
+}
+
+func (r *cachingUserRepository) GetByUUID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	r.mu.Lock()
+	if elem, ok := r.entries[id]; ok {
+		entry := elem.Value.(*userCacheEntry)
+		if r.now().Before(entry.expires) {
+			r.lru.MoveToFront(elem)
+			user := entry.user
+			r.mu.Unlock()
+			userCacheLookups.WithLabelValues("hit").Inc()
+			return &user, nil
+		}
+		r.removeLocked(elem, evictExpired)
+	}
+	generation := r.generation
+	r.mu.Unlock()
+	userCacheLookups.WithLabelValues("miss").Inc()
+
+	user, err := r.UserRepository.GetByUUID(ctx, id)
+	if err != nil || user == nil {
+		return user, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.generation == generation {
+		r.storeLocked(id, *user)
+	}
+	return user, nil
+}
+
+func (r *cachingUserRepository) UpdateByUUID(ctx context.Context, id uuid.UUID, username, email, fullName, avatarURL string, version int) (*model.User, error) {
+	defer r.evict(id)
+	return r.UserRepository.UpdateByUUID(ctx, id, username, email, fullName, avatarURL, version)
+}
+
+func (r *cachingUserRepository) UpdateByID(ctx context.Context, id int64, username, email, fullName, avatarURL string, version int) (*model.User, error) {
+	user, err := r.UserRepository.UpdateByID(ctx, id, username, email, fullName, avatarURL, version)
+	r.evictUsers(user)
+	return user, err
+}
+
+func (r *cachingUserRepository) DeleteByUUID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	defer r.evict(id)
+	return r.UserRepository.DeleteByUUID(ctx, id)
+}
+
+func (r *cachingUserRepository) DeleteByUsername(ctx context.Context, username string) (*model.User, error) {
+	user, err := r.UserRepository.DeleteByUsername(ctx, username)
+	r.evictUsers(user)
+	return user, err
+}
+
+func (r *cachingUserRepository) DeleteByID(ctx context.Context, id int64) (*model.User, error) {
+	user, err := r.UserRepository.DeleteByID(ctx, id)
+	r.evictUsers(user)
+	return user, err
+}
+
+func (r *cachingUserRepository) MarkEmailVerified(ctx context.Context, id int64, email string) (*model.User, error) {
+	user, err := r.UserRepository.MarkEmailVerified(ctx, id, email)
+	r.evictUsers(user)
+	return user, err
+}
+
+func (r *cachingUserRepository) SetStatus(ctx context.Context, uuid uuid.UUID, status string) (*model.User, error) {
+	defer r.evict(uuid)
+	return r.UserRepository.SetStatus(ctx, uuid, status)
+}
+
+func (r *cachingUserRepository) DeleteManyByUUID(ctx context.Context, uuids []uuid.UUID) ([]model.User, error) {
+	defer r.evict(uuids...)
+	return r.UserRepository.DeleteManyByUUID(ctx, uuids)
+}
+
+func (r *cachingUserRepository) UpdateManyByUUID(ctx context.Context, uuids []uuid.UUID, field, value string) ([]UserChange, error) {
+	defer r.evict(uuids...)
+	return r.UserRepository.UpdateManyByUUID(ctx, uuids, field, value)
+}
+
+// evictUsers evicts the users a write returned. Writes keyed by something
+// other than uuid only learn which cache entry they touched from the row.
+func (r *cachingUserRepository) evictUsers(users ...*model.User) {
+	var ids []uuid.UUID
+	for _, u := range users {
+		if u == nil {
+			continue
+		}
+		if id, err := uuid.Parse(u.UUID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	r.evict(ids...)
+}
+
+func (r *cachingUserRepository) evict(ids ...uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// bumped even when nothing is cached: a concurrent miss may be about to
+	// store the pre-write row
+	r.generation++
+	for _, id := range ids {
+		if elem, ok := r.entries[id]; ok {
+			r.removeLocked(elem, evictWrite)
+		}
+	}
+}
+
+func (r *cachingUserRepository) storeLocked(id uuid.UUID, user model.User) {
+	entry := &userCacheEntry{id: id, user: user, expires: r.now().Add(r.opts.TTL)}
+	if elem, ok := r.entries[id]; ok {
+		elem.Value = entry
+		r.lru.MoveToFront(elem)
+		return
+	}
+	r.entries[id] = r.lru.PushFront(entry)
+	userCacheEntries.Inc()
+	for r.lru.Len() > r.opts.MaxEntries {
+		r.removeLocked(r.lru.Back(), evictCapacity)
+	}
+}
+
+func (r *cachingUserRepository) removeLocked(elem *list.Element, reason string) {
+	r.lru.Remove(elem)
+	delete(r.entries, elem.Value.(*userCacheEntry).id)
+	userCacheEntries.Dec()
+	userCacheEvictions.WithLabelValues(reason).Inc()
+}
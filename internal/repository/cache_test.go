@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cruder/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUserStore is the repository behind the cache in these tests. Methods
+// the tests don't override panic through the nil embedded interface.
+type fakeUserStore struct {
+	UserRepository
+	users map[uuid.UUID]model.User
+	reads int
+}
+
+func newFakeUserStore(users ...model.User) *fakeUserStore {
+	store := &fakeUserStore{users: make(map[uuid.UUID]model.User)}
+	for _, u := range users {
+		store.users[uuid.MustParse(u.UUID)] = u
+	}
+	return store
+}
+
+func (s *fakeUserStore) GetByUUID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	s.reads++
+	u, ok := s.users[id]
+	if !ok {
+		return nil, nil
+	}
+	return &u, nil
+}
+
+func (s *fakeUserStore) UpdateByUUID(ctx context.Context, id uuid.UUID, username, email, fullName, avatarURL string, version int) (*model.User, error) {
+	u := s.users[id]
+	u.Username, u.Email, u.FullName, u.AvatarURL = username, email, fullName, avatarURL
+	s.users[id] = u
+	return &u, nil
+}
+
+func (s *fakeUserStore) DeleteByID(ctx context.Context, id int64) (*model.User, error) {
+	for key, u := range s.users {
+		if int64(u.ID) == id {
+			delete(s.users, key)
+			return &u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeUserStore) Create(ctx context.Context, username, email, fullName, avatarURL string) (*model.User, error) {
+	u := model.User{ID: len(s.users) + 1, UUID: uuid.NewString(), Username: username, Email: email, FullName: fullName, AvatarURL: avatarURL}
+	s.users[uuid.MustParse(u.UUID)] = u
+	return &u, nil
+}
+
+func TestCachingUserRepository_ServesHits(t *testing.T) {
+	// Given: a cached user
+	user := model.User{ID: 1, UUID: uuid.NewString(), Username: "jdoe"}
+	store := newFakeUserStore(user)
+	cache := newCachingUserRepository(store, CacheOptions{TTL: time.Minute, MaxEntries: 10})
+	id := uuid.MustParse(user.UUID)
+
+	// When: reading it twice and mutating the first result
+	first, err := cache.GetByUUID(context.Background(), id)
+	require.NoError(t, err)
+	first.Username = "mutated"
+	second, err := cache.GetByUUID(context.Background(), id)
+	require.NoError(t, err)
+
+	// Then: only the first read reached the store, and callers get copies
+	require.Equal(t, 1, store.reads)
+	require.Equal(t, "jdoe", second.Username)
+}
+
+func TestCachingUserRepository_DoesNotCacheMisses(t *testing.T) {
+	store := newFakeUserStore()
+	cache := newCachingUserRepository(store, CacheOptions{TTL: time.Minute, MaxEntries: 10})
+
+	created, err := cache.Create(context.Background(), "jdoe", "jdoe@example.com", "John Doe", "")
+	require.NoError(t, err)
+	missing := uuid.New()
+	for range 2 {
+		user, err := cache.GetByUUID(context.Background(), missing)
+		require.NoError(t, err)
+		require.Nil(t, user)
+	}
+	user, err := cache.GetByUUID(context.Background(), uuid.MustParse(created.UUID))
+	require.NoError(t, err)
+
+	require.Equal(t, 3, store.reads)
+	require.Equal(t, "jdoe", user.Username)
+}
+
+func TestCachingUserRepository_WritesEvict(t *testing.T) {
+	// Given: two cached users
+	first := model.User{ID: 1, UUID: uuid.NewString(), Username: "first"}
+	second := model.User{ID: 2, UUID: uuid.NewString(), Username: "second"}
+	store := newFakeUserStore(first, second)
+	cache := newCachingUserRepository(store, CacheOptions{TTL: time.Minute, MaxEntries: 10})
+	firstID, secondID := uuid.MustParse(first.UUID), uuid.MustParse(second.UUID)
+	_, _ = cache.GetByUUID(context.Background(), firstID)
+	_, _ = cache.GetByUUID(context.Background(), secondID)
+
+	// When: one is updated by uuid and the other deleted by id
+	_, err := cache.UpdateByUUID(context.Background(), firstID, "renamed", "", "", "", 1)
+	require.NoError(t, err)
+	_, err = cache.DeleteByID(context.Background(), 2)
+	require.NoError(t, err)
+
+	// Then: reads see the new state instead of the cached one
+	updated, err := cache.GetByUUID(context.Background(), firstID)
+	require.NoError(t, err)
+	require.Equal(t, "renamed", updated.Username)
+	deleted, err := cache.GetByUUID(context.Background(), secondID)
+	require.NoError(t, err)
+	require.Nil(t, deleted)
+	require.Equal(t, 4, store.reads)
+}
+
+func TestCachingUserRepository_EvictsLeastRecentlyUsed(t *testing.T) {
+	// Given: room for two users
+	users := []model.User{
+		{ID: 1, UUID: uuid.NewString()},
+		{ID: 2, UUID: uuid.NewString()},
+		{ID: 3, UUID: uuid.NewString()},
+	}
+	store := newFakeUserStore(users...)
+	cache := newCachingUserRepository(store, CacheOptions{TTL: time.Minute, MaxEntries: 2})
+	ids := make([]uuid.UUID, len(users))
+	for i, u := range users {
+		ids[i] = uuid.MustParse(u.UUID)
+	}
+
+	// When: 1 and 2 are cached, 1 is touched again, then 3 arrives
+	_, _ = cache.GetByUUID(context.Background(), ids[0])
+	_, _ = cache.GetByUUID(context.Background(), ids[1])
+	_, _ = cache.GetByUUID(context.Background(), ids[0])
+	_, _ = cache.GetByUUID(context.Background(), ids[2])
+	reads := store.reads
+
+	// Then: 2 was the one evicted
+	_, _ = cache.GetByUUID(context.Background(), ids[0])
+	require.Equal(t, reads, store.reads)
+	_, _ = cache.GetByUUID(context.Background(), ids[1])
+	require.Equal(t, reads+1, store.reads)
+}
+
+func TestCachingUserRepository_Expires(t *testing.T) {
+	user := model.User{ID: 1, UUID: uuid.NewString()}
+	store := newFakeUserStore(user)
+	cache := newCachingUserRepository(store, CacheOptions{TTL: time.Minute, MaxEntries: 10})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	id := uuid.MustParse(user.UUID)
+
+	_, _ = cache.GetByUUID(context.Background(), id)
+	now = now.Add(time.Minute)
+	_, _ = cache.GetByUUID(context.Background(), id)
+
+	require.Equal(t, 2, store.reads)
+}
+
+func TestCachingUserRepository_SkipsRowReadBeforeWrite(t *testing.T) {
+	// Given: a write lands while a miss is reading the old row
+	user := model.User{ID: 1, UUID: uuid.NewString(), Username: "old"}
+	store := newFakeUserStore(user)
+	cache := newCachingUserRepository(store, CacheOptions{TTL: time.Minute, MaxEntries: 10})
+	id := uuid.MustParse(user.UUID)
+	racing := &racingUserStore{fakeUserStore: store, during: func() {
+		_, _ = cache.UpdateByUUID(context.Background(), id, "new", "", "", "", 1)
+	}}
+	cache.UserRepository = racing
+
+	// When: the miss completes after the write
+	stale, err := cache.GetByUUID(context.Background(), id)
+	require.NoError(t, err)
+
+	// Then: its row is returned but not cached
+	require.Equal(t, "old", stale.Username)
+	fresh, err := cache.GetByUUID(context.Background(), id)
+	require.NoError(t, err)
+	require.Equal(t, "new", fresh.Username)
+}
+
+// racingUserStore runs during once, after reading a user but before
+// returning it, to interleave a write with a cache miss.
+type racingUserStore struct {
+	*fakeUserStore
+	during func()
+}
+
+func (s *racingUserStore) GetByUUID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	user, err := s.fakeUserStore.GetByUUID(ctx, id)
+	if s.during != nil {
+		during := s.during
+		s.during = nil
+		during()
+	}
+	return user, err
+}
+
+func TestCachingUserRepository_Metrics(t *testing.T) {
+	// Given: room for one user, and the counters as they stand
+	users := []model.User{{ID: 1, UUID: uuid.NewString()}, {ID: 2, UUID: uuid.NewString()}}
+	store := newFakeUserStore(users...)
+	cache := newCachingUserRepository(store, CacheOptions{TTL: time.Minute, MaxEntries: 1})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	first, second := uuid.MustParse(users[0].UUID), uuid.MustParse(users[1].UUID)
+	value := func(c prometheus.Collector) float64 { return testutil.ToFloat64(c) }
+	hits, misses := value(userCacheLookups.WithLabelValues("hit")), value(userCacheLookups.WithLabelValues("miss"))
+	entries := value(userCacheEntries)
+	capacity := value(userCacheEvictions.WithLabelValues(evictCapacity))
+	expired := value(userCacheEvictions.WithLabelValues(evictExpired))
+	written := value(userCacheEvictions.WithLabelValues(evictWrite))
+
+	// When: first is cached and hit, second pushes it out, second expires
+	// and is cached again, then a write evicts it
+	_, _ = cache.GetByUUID(context.Background(), first)
+	_, _ = cache.GetByUUID(context.Background(), first)
+	_, _ = cache.GetByUUID(context.Background(), second)
+	now = now.Add(time.Minute)
+	_, _ = cache.GetByUUID(context.Background(), second)
+	require.Equal(t, entries+1, value(userCacheEntries))
+	_, err := cache.UpdateByUUID(context.Background(), second, "renamed", "", "", "", 1)
+	require.NoError(t, err)
+
+	// Then: every lookup, entry, and eviction was counted
+	require.Equal(t, hits+1, value(userCacheLookups.WithLabelValues("hit")))
+	require.Equal(t, misses+3, value(userCacheLookups.WithLabelValues("miss")))
+	require.Equal(t, entries, value(userCacheEntries))
+	require.Equal(t, capacity+1, value(userCacheEvictions.WithLabelValues(evictCapacity)))
+	require.Equal(t, expired+1, value(userCacheEvictions.WithLabelValues(evictExpired)))
+	require.Equal(t, written+1, value(userCacheEvictions.WithLabelValues(evictWrite)))
+}
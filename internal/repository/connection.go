@@ -1,8 +1,11 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sync"
 
 	_ "github.com/lib/pq"
 )
@@ -15,21 +18,88 @@ type PostgresConnection struct {
 	db *sql.DB
 }
 
+// ConnectionOptions tunes the connection pool.
+type ConnectionOptions struct {
+	// MinIdleConns pre-opens this many connections at startup so the first
+	// requests don't pay connection-establishment cost. Zero disables warmup.
+	MinIdleConns int
+}
+
 func (p *PostgresConnection) DB() *sql.DB {
 	return p.db
 }
 
-func NewPostgresConnection(dsn string) (*PostgresConnection, error) {
-	db, err := sql.Open("postgres", dsn)
+func NewPostgresConnection(dsn string, opts ConnectionOptions) (*PostgresConnection, error) {
+	conn, err := OpenPostgresConnection(dsn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	if err := db.Ping(); err != nil {
+	if err := conn.db.Ping(); err != nil {
+		_ = conn.db.Close()
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	if err := conn.Warmup(context.Background(), opts.MinIdleConns); err != nil {
+		_ = conn.db.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// OpenPostgresConnection prepares a pool for dsn without connecting, so it
+// succeeds while the database is still unreachable. The caller pings and
+// warms the pool once the database answers.
+func OpenPostgresConnection(dsn string) (*PostgresConnection, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
 	return &PostgresConnection{
 		db: db,
 	}, nil
 }
+
+// Warmup pre-opens n idle connections, as ConnectionOptions.MinIdleConns
+// does for NewPostgresConnection. Zero does nothing.
+func (p *PostgresConnection) Warmup(ctx context.Context, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if err := warmup(ctx, p.db, n); err != nil {
+		return fmt.Errorf("failed to warm up connection pool: %w", err)
+	}
+	return nil
+}
+
+// warmup opens n connections concurrently, pings each, and releases them back
+// to the pool as idle connections.
+func warmup(ctx context.Context, db *sql.DB, n int) error {
+	db.SetMaxIdleConns(n)
+
+	conns := make([]*sql.Conn, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := db.Conn(ctx)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			conns[i] = conn
+			errs[i] = conn.PingContext(ctx)
+		}()
+	}
+	wg.Wait()
+
+	for _, conn := range conns {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/pkg/logger"
+	"database/sql"
+	"errors"
+	"log/slog"
+)
+
+type EmailVerificationRepository interface {
+	// CreateToken stores a token hash for userID, replacing any token the
+	// user was issued before.
+	CreateToken(ctx context.Context, token *model.EmailVerificationToken) error
+	// TakeToken deletes the token with hash and returns it, or nil when
+	// there is none. A token can be taken once, whether or not it expired.
+	TakeToken(ctx context.Context, hash string) (*model.EmailVerificationToken, error)
+}
+
+type emailVerificationRepository struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+func NewEmailVerificationRepository(db *sql.DB) EmailVerificationRepository {
+	return newEmailVerificationRepository(db, logger.Get())
+}
+
+func newEmailVerificationRepository(db *sql.DB, log *logger.Logger) EmailVerificationRepository {
+	repoLogger := log.With(slog.String("component", "repository.email_verification"))
+	return &emailVerificationRepository{
+		db:  db,
+		log: repoLogger,
+	}
+}
+
+func (r *emailVerificationRepository) CreateToken(ctx context.Context, token *model.EmailVerificationToken) error {
+	log, done := startOperation(ctx, r.log, "EmailVerificationRepository.CreateToken")
+	defer done()
+	_, err := r.db.ExecContext(
+		ctx,
+		`WITH superseded AS (DELETE FROM email_verification_tokens WHERE user_id = $2)
+		 INSERT INTO email_verification_tokens (token_hash, user_id, email, expires_at) VALUES ($1, $2, $3, $4)`,
+		token.TokenHash,
+		token.UserID,
+		token.Email,
+		token.ExpiresAt,
+	)
+	if err != nil {
+		log.Error("create verification token failed", slog.Int("user.id", token.UserID), slog.String("error", err.Error()))
+		return mapPQError(err)
+	}
+	return nil
+}
+
+func (r *emailVerificationRepository) TakeToken(ctx context.Context, hash string) (*model.EmailVerificationToken, error) {
+	log, done := startOperation(ctx, r.log, "EmailVerificationRepository.TakeToken")
+	defer done()
+	token := model.EmailVerificationToken{TokenHash: hash}
+	err := r.db.QueryRowContext(
+		ctx,
+		`DELETE FROM email_verification_tokens WHERE token_hash = $1 RETURNING user_id, email, expires_at`,
+		hash,
+	).Scan(&token.UserID, &token.Email, &token.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		log.Error("take verification token failed", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return &token, nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"cruder/internal/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailVerificationRepository_CreateToken_ReplacesEarlierTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	expiresAt := time.Now().Add(time.Hour)
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM email_verification_tokens WHERE user_id = $2`)).
+		WithArgs("hash", 7, "jdoe@example.com", expiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = NewEmailVerificationRepository(db).CreateToken(context.Background(), &model.EmailVerificationToken{
+		TokenHash: "hash",
+		UserID:    7,
+		Email:     "jdoe@example.com",
+		ExpiresAt: expiresAt,
+	})
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEmailVerificationRepository_TakeToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	expiresAt := time.Now().Add(time.Hour)
+	query := regexp.QuoteMeta(`DELETE FROM email_verification_tokens WHERE token_hash = $1 RETURNING user_id, email, expires_at`)
+	mock.ExpectQuery(query).WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "email", "expires_at"}).AddRow(7, "jdoe@example.com", expiresAt))
+	mock.ExpectQuery(query).WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "email", "expires_at"}))
+	repo := NewEmailVerificationRepository(db)
+
+	// When: taking the same token twice
+	first, err := repo.TakeToken(context.Background(), "hash")
+	require.NoError(t, err)
+	second, err := repo.TakeToken(context.Background(), "hash")
+	require.NoError(t, err)
+
+	// Then: only the first take finds it
+	require.Equal(t, &model.EmailVerificationToken{TokenHash: "hash", UserID: 7, Email: "jdoe@example.com", ExpiresAt: expiresAt}, first)
+	require.Nil(t, second)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_MarkEmailVerified_RequiresSameEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE users SET email_verified = TRUE, version = version + 1 WHERE id = $1 AND email = $2`)).
+		WithArgs(int64(7), "old@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "avatar_url", "email_verified", "status", "version"}))
+
+	user, err := NewUserRepository(db).MarkEmailVerified(context.Background(), 7, "old@example.com")
+
+	require.NoError(t, err)
+	require.Nil(t, user)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
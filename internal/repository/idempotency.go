@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/pkg/logger"
+	"database/sql"
+	"errors"
+	"log/slog"
+)
+
+type IdempotencyRepository interface {
+	// Get returns the unexpired row stored for key, or nil. It may be a
+	// reservation still pending.
+	Get(ctx context.Context, apiKeyID int, key string) (*model.IdempotentResponse, error)
+	// Reserve inserts resp as a pending row for its key, expiring at
+	// resp.ExpiresAt, and reports whether it did. False means an unexpired
+	// row already holds the key.
+	Reserve(ctx context.Context, resp *model.IdempotentResponse) (bool, error)
+	// Save fills in the response on the pending row Reserve made for resp's
+	// key and request hash.
+	Save(ctx context.Context, resp *model.IdempotentResponse) error
+	// Release deletes the pending row for key and requestHash, so the key
+	// can be used again. A stored response is left alone.
+	Release(ctx context.Context, apiKeyID int, key, requestHash string) error
+}
+
+type idempotencyRepository struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+func NewIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return newIdempotencyRepository(db, logger.Get())
+}
+
+func newIdempotencyRepository(db *sql.DB, log *logger.Logger) IdempotencyRepository {
+	repoLogger := log.With(slog.String("component", "repository.idempotency"))
+	return &idempotencyRepository{
+		db:  db,
+		log: repoLogger,
+	}
+}
+
+func (r *idempotencyRepository) Get(ctx context.Context, apiKeyID int, key string) (*model.IdempotentResponse, error) {
+	_, done := startOperation(ctx, r.log, "IdempotencyRepository.Get")
+	defer done()
+	resp := model.IdempotentResponse{APIKeyID: apiKeyID, Key: key}
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT request_hash, status_code, location, response_body, expires_at FROM idempotency_keys
+		 WHERE api_key_id = $1 AND idempotency_key = $2 AND expires_at > NOW()`,
+		apiKeyID,
+		key,
+	).Scan(&resp.RequestHash, &resp.StatusCode, &resp.Location, &resp.Body, &resp.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (r *idempotencyRepository) Reserve(ctx context.Context, resp *model.IdempotentResponse) (bool, error) {
+	_, done := startOperation(ctx, r.log, "IdempotencyRepository.Reserve")
+	defer done()
+	// an expired row, stored or pending, is overwritten so the key can be
+	// reused after its TTL or after a holder that never finished
+	result, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO idempotency_keys (api_key_id, idempotency_key, request_hash, status_code, location, response_body, expires_at)
+		 VALUES ($1, $2, $3, 0, '', '', $4)
+		 ON CONFLICT (api_key_id, idempotency_key) DO UPDATE SET
+		     request_hash = EXCLUDED.request_hash,
+		     status_code = 0,
+		     location = '',
+		     response_body = '',
+		     created_at = NOW(),
+		     expires_at = EXCLUDED.expires_at
+		 WHERE idempotency_keys.expires_at <= NOW()`,
+		resp.APIKeyID,
+		resp.Key,
+		resp.RequestHash,
+		resp.ExpiresAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	reserved, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return reserved == 1, nil
+}
+
+func (r *idempotencyRepository) Save(ctx context.Context, resp *model.IdempotentResponse) error {
+	_, done := startOperation(ctx, r.log, "IdempotencyRepository.Save")
+	defer done()
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE idempotency_keys
+		 SET status_code = $4, location = $5, response_body = $6, created_at = NOW(), expires_at = $7
+		 WHERE api_key_id = $1 AND idempotency_key = $2 AND request_hash = $3 AND status_code = 0`,
+		resp.APIKeyID,
+		resp.Key,
+		resp.RequestHash,
+		resp.StatusCode,
+		resp.Location,
+		resp.Body,
+		resp.ExpiresAt,
+	)
+	return err
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, apiKeyID int, key, requestHash string) error {
+	_, done := startOperation(ctx, r.log, "IdempotencyRepository.Release")
+	defer done()
+	_, err := r.db.ExecContext(
+		ctx,
+		`DELETE FROM idempotency_keys
+		 WHERE api_key_id = $1 AND idempotency_key = $2 AND request_hash = $3 AND status_code = 0`,
+		apiKeyID,
+		key,
+		requestHash,
+	)
+	return err
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "cruder",
+	Subsystem: "db",
+	Name:      "query_duration_seconds",
+	Help:      "Repository operation latency in seconds, including row scanning.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"operation"})
+
+var dbSharedReads = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "cruder",
+	Subsystem: "db",
+	Name:      "shared_reads_total",
+	Help:      "Reads answered by joining an identical query already in flight instead of issuing their own.",
+}, []string{"operation"})
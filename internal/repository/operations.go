@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"cruder/pkg/logger"
+)
+
+// operations maps repository methods to the stable db.operation names used in
+// logs, so slow or failing queries can be correlated with code paths.
+var operations = map[string]string{
+	"UserRepository.GetAll":            "users.get_all",
+	"UserRepository.List":              "users.list",
+	"UserRepository.GetAllAfter":       "users.get_all_after",
+	"UserRepository.GetByUsername":     "users.get_by_username",
+	"UserRepository.GetByID":           "users.get_by_id",
+	"UserRepository.GetByUUID":         "users.get_by_uuid",
+	"UserRepository.GetByUUIDs":        "users.get_by_uuids",
+	"UserRepository.ExistsByUUID":      "users.exists_by_uuid",
+	"UserRepository.ExistsByID":        "users.exists_by_id",
+	"UserRepository.TakenFields":       "users.taken_fields",
+	"UserRepository.Stats":             "users.stats",
+	"UserRepository.Create":            "users.create",
+	"UserRepository.UpdateByUUID":      "users.update_by_uuid",
+	"UserRepository.DeleteByUUID":      "users.delete_by_uuid",
+	"UserRepository.DeleteByUsername":  "users.delete_by_username",
+	"UserRepository.DeleteManyByUUID":  "users.delete_many_by_uuid",
+	"UserRepository.UpdateManyByUUID":  "users.update_many_by_uuid",
+	"UserRepository.UpdateByID":        "users.update_by_id",
+	"UserRepository.DeleteByID":        "users.delete_by_id",
+	"UserRepository.MarkEmailVerified": "users.mark_email_verified",
+	"UserRepository.SetStatus":         "users.set_status",
+	"UserRepository.Snapshot":          "users.snapshot",
+
+	"APIKeyRepository.GetByHash":  "api_keys.get_by_hash",
+	"APIKeyRepository.Create":     "api_keys.create",
+	"APIKeyRepository.List":       "api_keys.list",
+	"APIKeyRepository.DeleteByID": "api_keys.delete_by_id",
+
+	"IdempotencyRepository.Get":     "idempotency_keys.get",
+	"IdempotencyRepository.Reserve": "idempotency_keys.reserve",
+	"IdempotencyRepository.Save":    "idempotency_keys.save",
+	"IdempotencyRepository.Release": "idempotency_keys.release",
+
+	"AuditRepository.Record":      "audit_log.record",
+	"AuditRepository.ListForUser": "audit_log.list_for_user",
+
+	"EmailVerificationRepository.CreateToken": "email_verification_tokens.create",
+	"EmailVerificationRepository.TakeToken":   "email_verification_tokens.take",
+}
+
+// slowQueryThreshold is the elapsed time, in nanoseconds, above which a
+// finished operation logs at Warn. Zero disables slow-query logging.
+var slowQueryThreshold atomic.Int64
+
+// SetSlowQueryThreshold sets the process-wide duration above which repository
+// operations are logged as slow. Zero or negative disables the warning.
+func SetSlowQueryThreshold(d time.Duration) {
+	slowQueryThreshold.Store(int64(max(d, 0)))
+}
+
+// queryCounterKey is the context key for the counter set by
+// ContextWithQueryCounter.
+type queryCounterKey struct{}
+
+// ContextWithQueryCounter returns ctx carrying a fresh counter of repository
+// operations, which QueryCount reads. Operations run with a context derived
+// from it are counted, even from several goroutines at once.
+func ContextWithQueryCounter(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queryCounterKey{}, new(atomic.Int64))
+}
+
+// QueryCount returns the number of repository operations run so far with the
+// counter in ctx, and false when ctx carries none. A retried read counts once
+// per attempt.
+func QueryCount(ctx context.Context) (int64, bool) {
+	counter, ok := ctx.Value(queryCounterKey{}).(*atomic.Int64)
+	if !ok {
+		return 0, false
+	}
+	return counter.Load(), true
+}
+
+// startOperation returns a logger annotated with the method's db.operation
+// name, counts the operation against ctx's query counter, if any, and records
+// the query start at debug level. The returned done func,
+// meant to be deferred, records the elapsed time in the query histogram and
+// logs it at Debug, or at Warn past the slow-query threshold.
+func startOperation(ctx context.Context, log *logger.Logger, method string) (*logger.Logger, func()) {
+	if counter, ok := ctx.Value(queryCounterKey{}).(*atomic.Int64); ok {
+		counter.Add(1)
+	}
+	name, ok := operations[method]
+	if !ok {
+		name = method
+	}
+	opLogger := log.With(slog.String("db.operation", name)).BindContext(ctx)
+	opLogger.Debug("executing query")
+	start := time.Now()
+	return opLogger, func() {
+		elapsed := time.Since(start)
+		dbQueryDuration.WithLabelValues(name).Observe(elapsed.Seconds())
+		if threshold := time.Duration(slowQueryThreshold.Load()); threshold > 0 && elapsed > threshold {
+			opLogger.Warn("slow query", slog.Duration("db.duration", elapsed), slog.Duration("db.slow_query_threshold", threshold))
+			return
+		}
+		opLogger.Debug("query finished", slog.Duration("db.duration", elapsed))
+	}
+}
+
+// LogReadError logs a failed read at Error, or at Debug once ctx is done: a
+// client hanging up mid-read is routine, not a database problem. The
+// service layer uses it too, so both layers grade the same failure alike.
+func LogReadError(ctx context.Context, log *logger.Logger, msg string, err error, attrs ...any) {
+	attrs = append(attrs, slog.String("error", err.Error()))
+	if ctx.Err() != nil {
+		log.DebugContext(ctx, msg+": context done", attrs...)
+		return
+	}
+	log.ErrorContext(ctx, msg, attrs...)
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cruder/pkg/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperations_CoverRepositoryInterfaces(t *testing.T) {
+	for _, iface := range []reflect.Type{
+		reflect.TypeOf((*UserRepository)(nil)).Elem(),
+		reflect.TypeOf((*APIKeyRepository)(nil)).Elem(),
+		reflect.TypeOf((*IdempotencyRepository)(nil)).Elem(),
+		reflect.TypeOf((*AuditRepository)(nil)).Elem(),
+		reflect.TypeOf((*EmailVerificationRepository)(nil)).Elem(),
+	} {
+		for i := 0; i < iface.NumMethod(); i++ {
+			method := iface.Name() + "." + iface.Method(i).Name
+			require.Contains(t, operations, method, "missing db.operation name for %s", method)
+		}
+	}
+}
+
+func TestUserRepository_LogsOperationName(t *testing.T) {
+	// Given: a debug logger writing to a file and a mocked database
+	logPath := filepath.Join(t.TempDir(), "repo.log")
+	_, err := logger.Configure(logger.Options{Output: logger.OutputFile, FilePath: logPath, Level: "debug"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = logger.Configure(logger.DefaultOptions()) })
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(`SELECT id, uuid, username, email, full_name, COALESCE\(avatar_url, ''\), email_verified, status, version FROM users WHERE id = \$1`).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "avatar_url", "email_verified", "status", "version"}).
+			AddRow(7, "0b5c5c1e-7f4e-4a39-9d6c-6b1d2b0e9a10", "jdoe", "jdoe@example.com", "John Doe", "", false, "active", 1))
+
+	// When: fetching a user by id
+	repo := NewUserRepository(db)
+	_, err = repo.GetByID(context.Background(), 7)
+	require.NoError(t, err)
+
+	// Then: the debug log carries the stable operation name
+	contents, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Contains(t, string(contents), `"db.operation":"users.get_by_id"`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_RecordsQueryDuration(t *testing.T) {
+	// Given: a slow-query threshold below the mocked query delay
+	logPath := filepath.Join(t.TempDir(), "repo.log")
+	_, err := logger.Configure(logger.Options{Output: logger.OutputFile, FilePath: logPath, Level: "info"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = logger.Configure(logger.DefaultOptions()) })
+	SetSlowQueryThreshold(5 * time.Millisecond)
+	t.Cleanup(func() { SetSlowQueryThreshold(0) })
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(`DELETE FROM users WHERE id = \$1`).
+		WithArgs(int64(7)).
+		WillDelayFor(20 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "avatar_url", "email_verified", "status", "version"}).
+			AddRow(7, "0b5c5c1e-7f4e-4a39-9d6c-6b1d2b0e9a10", "jdoe", "jdoe@example.com", "John Doe", "", false, "active", 1))
+	before := queryCount(t, "users.delete_by_id")
+
+	// When: running the operation
+	_, err = NewUserRepository(db).DeleteByID(context.Background(), 7)
+	require.NoError(t, err)
+
+	// Then: the histogram has a series for it and the slow query is logged at Warn
+	require.Equal(t, before+1, queryCount(t, "users.delete_by_id"))
+	contents, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Contains(t, string(contents), `"message":"slow query"`)
+	require.Contains(t, string(contents), `"db.operation":"users.delete_by_id"`)
+}
+
+func TestQueryCounter_CountsOperations(t *testing.T) {
+	// Given: a context carrying a query counter
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	ctx := ContextWithQueryCounter(context.Background())
+
+	// When: running two operations with it
+	repo := NewUserRepository(db)
+	_, err = repo.ExistsByID(ctx, 1)
+	require.NoError(t, err)
+	_, err = repo.ExistsByID(ctx, 2)
+	require.NoError(t, err)
+
+	// Then: both are counted, and a context without a counter reports none
+	count, ok := QueryCount(ctx)
+	require.True(t, ok)
+	require.EqualValues(t, 2, count)
+	_, ok = QueryCount(context.Background())
+	require.False(t, ok)
+}
+
+func queryCount(t *testing.T, operation string) uint64 {
+	t.Helper()
+	var m dto.Metric
+	require.NoError(t, dbQueryDuration.WithLabelValues(operation).(prometheus.Histogram).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestLogReadError_DowngradesWhenContextDone(t *testing.T) {
+	var out bytes.Buffer
+	log := logger.FromSlog(slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	ctx, cancel := context.WithCancel(context.Background())
+	failure := errors.New("boom")
+
+	LogReadError(ctx, log, "read failed", failure, slog.Int("page.after", 3))
+	cancel()
+	LogReadError(ctx, log, "read failed", failure)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	require.Contains(t, lines[0], `"level":"ERROR","msg":"read failed","page.after":3,"error":"boom"`)
+	require.Contains(t, lines[1], `"level":"DEBUG","msg":"read failed: context done","error":"boom"`)
+}
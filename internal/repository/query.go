@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+)
+
+// selectQuery composes a SELECT from optional clauses. Values are bound
+// through arg, which hands back the next $n placeholder, so callers never
+// splice a value into the SQL text.
+type selectQuery struct {
+	from    string
+	where   []string
+	orderBy string
+	limit   string
+	offset  string
+	args    []any
+}
+
+func newSelectQuery(from string) *selectQuery {
+	return &selectQuery{from: from}
+}
+
+// arg binds value and returns its placeholder.
+func (q *selectQuery) arg(value any) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+// Where adds a condition; conditions are ANDed together.
+func (q *selectQuery) Where(cond string) *selectQuery {
+	q.where = append(q.where, cond)
+	return q
+}
+
+func (q *selectQuery) OrderBy(expr string) *selectQuery {
+	q.orderBy = expr
+	return q
+}
+
+// Limit caps the result; n <= 0 leaves it unbounded.
+func (q *selectQuery) Limit(n int) *selectQuery {
+	if n > 0 {
+		q.limit = q.arg(n)
+	}
+	return q
+}
+
+// Offset skips rows; n <= 0 skips none.
+func (q *selectQuery) Offset(n int) *selectQuery {
+	if n > 0 {
+		q.offset = q.arg(n)
+	}
+	return q
+}
+
+func (q *selectQuery) String() string {
+	var b strings.Builder
+	b.WriteString(q.from)
+	if len(q.where) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(q.where, " AND "))
+	}
+	if q.orderBy != "" {
+		b.WriteString(" ORDER BY ")
+		b.WriteString(q.orderBy)
+	}
+	if q.limit != "" {
+		b.WriteString(" LIMIT ")
+		b.WriteString(q.limit)
+	}
+	if q.offset != "" {
+		b.WriteString(" OFFSET ")
+		b.WriteString(q.offset)
+	}
+	return b.String()
+}
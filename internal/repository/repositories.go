@@ -1,15 +1,70 @@
 package repository
 
-import "database/sql"
+import (
+	"database/sql"
+
+	"cruder/pkg/logger"
+)
 
 type Repository struct {
-	Users   UserRepository
-	APIKeys APIKeyRepository
+	Users UserRepository
+	// UsersPrimary reads from the primary even when Users reads from a
+	// replica, and never from the user cache, so a write can be based on
+	// what is actually stored.
+	UsersPrimary UserRepository
+	APIKeys      APIKeyRepository
+	Idempotency  IdempotencyRepository
+	Audit        AuditRepository
+	// EmailVerifications holds pending email verification tokens.
+	EmailVerifications EmailVerificationRepository
+}
+
+// Options configures NewRepositoryWithOptions.
+type Options struct {
+	// ReadRetry retries user and API key reads after transient errors.
+	ReadRetry RetryOptions
+	// UserCache caches GetByUUID results in memory.
+	UserCache CacheOptions
+	// ReadReplica, when set, serves user reads while writes stay on the
+	// primary. API key, idempotency, audit, and verification token queries
+	// always use the primary, since a lagging read there would reject a key
+	// or replay that was just written.
+	ReadReplica *sql.DB
+	// Logger receives the repositories' logs. Nil means the global logger.
+	Logger *logger.Logger
 }
 
 func NewRepository(db *sql.DB) *Repository {
-	return &Repository{
-		Users:   NewUserRepository(db),
-		APIKeys: NewAPIKeyRepository(db),
+	return NewRepositoryWithOptions(db, Options{})
+}
+
+func NewRepositoryWithOptions(db *sql.DB, opts Options) *Repository {
+	log := logger.OrGlobal(opts.Logger)
+	replica := opts.ReadReplica
+	if replica == nil {
+		replica = db
+	}
+	repos := &Repository{
+		Users:              newUserRepository(db, replica, log),
+		APIKeys:            newAPIKeyRepository(db, log),
+		Idempotency:        newIdempotencyRepository(db, log),
+		Audit:              newAuditRepository(db, log),
+		EmailVerifications: newEmailVerificationRepository(db, log),
+	}
+	if opts.ReadRetry.Retries > 0 {
+		repos.Users = newRetryingUserRepository(repos.Users, opts.ReadRetry, log)
+		repos.APIKeys = newRetryingAPIKeyRepository(repos.APIKeys, opts.ReadRetry, log)
+	}
+	// concurrent lookups of one user or key share a query
+	repos.Users = newSharedUserRepository(repos.Users)
+	repos.APIKeys = newSharedAPIKeyRepository(repos.APIKeys)
+	repos.UsersPrimary = newUserRepository(db, db, log)
+	if opts.ReadRetry.Retries > 0 {
+		repos.UsersPrimary = newRetryingUserRepository(repos.UsersPrimary, opts.ReadRetry, log)
+	}
+	if opts.UserCache.enabled() {
+		// outermost, so a hit skips the retries too
+		repos.Users = newCachingUserRepository(repos.Users, opts.UserCache)
 	}
+	return repos
 }
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"cruder/internal/model"
+	"cruder/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// RetryOptions configures how read operations are retried after transient
+// database errors. Writes are never retried, since a write that failed on a
+// dropped connection may still have been applied.
+type RetryOptions struct {
+	// Retries is how many times a failed read is retried. Zero disables
+	// retries.
+	Retries int
+	// BaseDelay is the wait before the first retry; each later retry doubles
+	// it.
+	BaseDelay time.Duration
+}
+
+// retryRead runs read, retrying it with exponential backoff while it fails
+// with a retryable error and retries remain. It stops early once ctx is done
+// and returns the last error.
+func retryRead[T any](ctx context.Context, log *logger.Logger, opts RetryOptions, method string, read func() (T, error)) (T, error) {
+	delay := opts.BaseDelay
+	for attempt := 1; ; attempt++ {
+		result, err := read()
+		if err == nil || attempt > opts.Retries || !IsTransient(err) {
+			return result, err
+		}
+		log.Warn("retrying read after transient error",
+			slog.String("db.operation", operations[method]),
+			slog.Int("db.attempt", attempt),
+			slog.Duration("db.retry_delay", delay),
+			slog.String("error", err.Error()),
+		)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// retryingUserRepository retries the read methods of a UserRepository.
+// Writes and Snapshot pass straight through; reads inside a snapshot are not
+// retried because a transaction does not survive a lost connection.
+type retryingUserRepository struct {
+	UserRepository
+	opts RetryOptions
+	log  *logger.Logger
+}
+
+func newRetryingUserRepository(repo UserRepository, opts RetryOptions, log *logger.Logger) UserRepository {
+	return &retryingUserRepository{
+		UserRepository: repo,
+		opts:           opts,
+		log:            log.With(slog.String("component", "repository.user")),
+	}
+}
+
+func (r *retryingUserRepository) GetAll(ctx context.Context) ([]model.User, error) {
+	return retryRead(ctx, r.log, r.opts, "UserRepository.GetAll", func() ([]model.User, error) {
+		return r.UserRepository.GetAll(ctx)
+	})
+}
+
+func (r *retryingUserRepository) List(ctx context.Context, q ListUsersQuery) ([]model.User, error) {
+	return retryRead(ctx, r.log, r.opts, "UserRepository.List", func() ([]model.User, error) {
+		return r.UserRepository.List(ctx, q)
+	})
+}
+
+func (r *retryingUserRepository) GetAllAfter(ctx context.Context, cursorID int64, limit int) ([]model.User, error) {
+	return retryRead(ctx, r.log, r.opts, "UserRepository.GetAllAfter", func() ([]model.User, error) {
+		return r.UserRepository.GetAllAfter(ctx, cursorID, limit)
+	})
+}
+
+func (r *retryingUserRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	return retryRead(ctx, r.log, r.opts, "UserRepository.GetByUsername", func() (*model.User, error) {
+		return r.UserRepository.GetByUsername(ctx, username)
+	})
+}
+
+func (r *retryingUserRepository) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	return retryRead(ctx, r.log, r.opts, "UserRepository.GetByID", func() (*model.User, error) {
+		return r.UserRepository.GetByID(ctx, id)
+	})
+}
+
+func (r *retryingUserRepository) GetByUUID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	return retryRead(ctx, r.log, r.opts, "UserRepository.GetByUUID", func() (*model.User, error) {
+		return r.UserRepository.GetByUUID(ctx, id)
+	})
+}
+
+func (r *retryingUserRepository) GetByUUIDs(ctx context.Context, uuids []uuid.UUID) ([]model.User, error) {
+	return retryRead(ctx, r.log, r.opts, "UserRepository.GetByUUIDs", func() ([]model.User, error) {
+		return r.UserRepository.GetByUUIDs(ctx, uuids)
+	})
+}
+
+func (r *retryingUserRepository) ExistsByUUID(ctx context.Context, id uuid.UUID) (bool, error) {
+	return retryRead(ctx, r.log, r.opts, "UserRepository.ExistsByUUID", func() (bool, error) {
+		return r.UserRepository.ExistsByUUID(ctx, id)
+	})
+}
+
+func (r *retryingUserRepository) ExistsByID(ctx context.Context, id int64) (bool, error) {
+	return retryRead(ctx, r.log, r.opts, "UserRepository.ExistsByID", func() (bool, error) {
+		return r.UserRepository.ExistsByID(ctx, id)
+	})
+}
+
+func (r *retryingUserRepository) TakenFields(ctx context.Context, username, email, fullName string) ([]string, error) {
+	return retryRead(ctx, r.log, r.opts, "UserRepository.TakenFields", func() ([]string, error) {
+		return r.UserRepository.TakenFields(ctx, username, email, fullName)
+	})
+}
+
+func (r *retryingUserRepository) Stats(ctx context.Context) (*model.UserStats, error) {
+	return retryRead(ctx, r.log, r.opts, "UserRepository.Stats", func() (*model.UserStats, error) {
+		return r.UserRepository.Stats(ctx)
+	})
+}
+
+// retryingAPIKeyRepository retries the read methods of an APIKeyRepository.
+type retryingAPIKeyRepository struct {
+	APIKeyRepository
+	opts RetryOptions
+	log  *logger.Logger
+}
+
+func newRetryingAPIKeyRepository(repo APIKeyRepository, opts RetryOptions, log *logger.Logger) APIKeyRepository {
+	return &retryingAPIKeyRepository{
+		APIKeyRepository: repo,
+		opts:             opts,
+		log:              log.With(slog.String("component", "repository.api_key")),
+	}
+}
+
+func (r *retryingAPIKeyRepository) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	return retryRead(ctx, r.log, r.opts, "APIKeyRepository.GetByHash", func() (*model.APIKey, error) {
+		return r.APIKeyRepository.GetByHash(ctx, hash)
+	})
+}
+
+func (r *retryingAPIKeyRepository) List(ctx context.Context, client string, limit, offset int) ([]model.APIKey, error) {
+	return retryRead(ctx, r.log, r.opts, "APIKeyRepository.List", func() ([]model.APIKey, error) {
+		return r.APIKeyRepository.List(ctx, client, limit, offset)
+	})
+}
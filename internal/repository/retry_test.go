@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"cruder/pkg/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+var userColumns = []string{"id", "uuid", "username", "email", "full_name", "avatar_url", "email_verified", "status", "version"}
+
+func TestRetryingUserRepository_RetriesTransientReads(t *testing.T) {
+	// Given: the first lookup hits a server shutting down
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(`SELECT .* FROM users WHERE id = \$1`).
+		WithArgs(int64(7)).
+		WillReturnError(&pq.Error{Code: "57P01"})
+	mock.ExpectQuery(`SELECT .* FROM users WHERE id = \$1`).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(7, "0b5c5c1e-7f4e-4a39-9d6c-6b1d2b0e9a10", "jdoe", "jdoe@example.com", "John Doe", "", false, "active", 1))
+	repo := NewRepositoryWithOptions(db, Options{ReadRetry: RetryOptions{Retries: 2, BaseDelay: time.Millisecond}}).Users
+
+	// When: fetching the user
+	user, err := repo.GetByID(context.Background(), 7)
+
+	// Then: the retry succeeds
+	require.NoError(t, err)
+	require.Equal(t, "jdoe", user.Username)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRetryingUserRepository_GivesUp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	for range 2 {
+		mock.ExpectQuery(`SELECT .* FROM users`).WillReturnError(&pq.Error{Code: "08006"})
+	}
+	repo := NewRepositoryWithOptions(db, Options{ReadRetry: RetryOptions{Retries: 1, BaseDelay: time.Millisecond}}).Users
+
+	_, err = repo.GetAll(context.Background())
+
+	var pqErr *pq.Error
+	require.ErrorAs(t, err, &pqErr)
+	require.Equal(t, pq.ErrorCode("08006"), pqErr.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRetryingUserRepository_NeverRetriesWrites(t *testing.T) {
+	// Given: an insert that fails on a dropped connection
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(`INSERT INTO users`).WillReturnError(&pq.Error{Code: "08006"})
+	repo := NewRepositoryWithOptions(db, Options{ReadRetry: RetryOptions{Retries: 3, BaseDelay: time.Millisecond}}).Users
+
+	// When: creating a user
+	_, err = repo.Create(context.Background(), "jdoe", "jdoe@example.com", "John Doe", "")
+
+	// Then: the insert ran once, since it may already have been applied
+	require.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRetryingUserRepository_SkipsPermanentErrors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(`SELECT .* FROM users WHERE username = \$1`).
+		WithArgs("jdoe").
+		WillReturnError(&pq.Error{Code: "42P01"})
+	repo := NewRepositoryWithOptions(db, Options{ReadRetry: RetryOptions{Retries: 3, BaseDelay: time.Millisecond}}).Users
+
+	_, err = repo.GetByUsername(context.Background(), "jdoe")
+
+	require.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNewRepositoryWithOptions_LogsToOptionsLogger(t *testing.T) {
+	// Given: repositories built with their own logger and read retries
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	for range 2 {
+		mock.ExpectQuery(`SELECT .* FROM users`).WillReturnError(&pq.Error{Code: "08006"})
+	}
+	var out bytes.Buffer
+	log := logger.FromSlog(slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	repo := NewRepositoryWithOptions(db, Options{ReadRetry: RetryOptions{Retries: 1, BaseDelay: time.Millisecond}, Logger: log}).Users
+
+	// When: a read fails
+	_, err = repo.GetByID(context.Background(), 7)
+
+	// Then: the query and retry logs land there, tagged with the component
+	require.Error(t, err)
+	require.Contains(t, out.String(), `"component":"repository.user"`)
+	require.Contains(t, out.String(), `"db.operation":"users.get_by_id"`)
+}
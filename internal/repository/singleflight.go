@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+
+	"cruder/internal/model"
+
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+)
+
+// sharedUserRepository collapses concurrent GetByUUID calls for the same
+// uuid into one query; every caller gets its own copy of the result.
+type sharedUserRepository struct {
+	UserRepository
+	group singleflight.Group
+}
+
+func newSharedUserRepository(repo UserRepository) *sharedUserRepository {
+	return &sharedUserRepository{UserRepository: repo}
+}
+
+// GetByUUID, like sharedAPIKeyRepository.GetByHash, runs the shared query
+// without the first caller's cancellation. The query is counted against the
+// first caller's query counter only, since the others issue none.
+func (r *sharedUserRepository) GetByUUID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	ch := r.group.DoChan(id.String(), func() (any, error) {
+		return r.UserRepository.GetByUUID(context.WithoutCancel(ctx), id)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Shared {
+			dbSharedReads.WithLabelValues(operations["UserRepository.GetByUUID"]).Inc()
+		}
+		user := res.Val.(*model.User)
+		if res.Err != nil || user == nil {
+			return nil, res.Err
+		}
+		u := *user
+		return &u, nil
+	}
+}
+
+// sharedAPIKeyRepository collapses concurrent GetByHash calls for the same
+// hash into one query.
+type sharedAPIKeyRepository struct {
+	APIKeyRepository
+	group singleflight.Group
+}
+
+func newSharedAPIKeyRepository(repo APIKeyRepository) *sharedAPIKeyRepository {
+	return &sharedAPIKeyRepository{APIKeyRepository: repo}
+}
+
+// GetByHash runs the shared query without the first caller's cancellation,
+// so one client hanging up doesn't fail the others; each caller still stops
+// waiting when its own ctx is done.
+func (r *sharedAPIKeyRepository) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	ch := r.group.DoChan(hash, func() (any, error) {
+		return r.APIKeyRepository.GetByHash(context.WithoutCancel(ctx), hash)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Shared {
+			dbSharedReads.WithLabelValues(operations["APIKeyRepository.GetByHash"]).Inc()
+		}
+		key := res.Val.(*model.APIKey)
+		if res.Err != nil || key == nil {
+			return nil, res.Err
+		}
+		k := *key
+		return &k, nil
+	}
+}
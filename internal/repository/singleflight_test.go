@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cruder/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingUserStore holds every GetByUUID until release is closed.
+type blockingUserStore struct {
+	UserRepository
+	user    model.User
+	release chan struct{}
+	reads   atomic.Int32
+}
+
+func (s *blockingUserStore) GetByUUID(context.Context, uuid.UUID) (*model.User, error) {
+	s.reads.Add(1)
+	<-s.release
+	u := s.user
+	return &u, nil
+}
+
+func TestSharedUserRepository_CollapsesConcurrentLookups(t *testing.T) {
+	// Given: a lookup already in flight
+	id := uuid.New()
+	store := &blockingUserStore{user: model.User{ID: 1, UUID: id.String()}, release: make(chan struct{})}
+	repo := newSharedUserRepository(store)
+	results := make([]*model.User, 5)
+	errs := make([]error, len(results))
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = repo.GetByUUID(context.Background(), id)
+		}()
+	}
+	require.Eventually(t, func() bool { return store.reads.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond) // let the other callers join
+
+	// When: the query returns
+	close(store.release)
+	wg.Wait()
+
+	// Then: one query answered everyone, each with their own copy
+	require.EqualValues(t, 1, store.reads.Load())
+	require.NoError(t, errors.Join(errs...))
+	for _, user := range results[1:] {
+		require.Equal(t, *results[0], *user)
+		require.NotSame(t, results[0], user)
+	}
+}
+
+// blockingKeyStore holds every GetByHash until release is closed, ignoring
+// cancellation the way a query already sent to the server would.
+type blockingKeyStore struct {
+	APIKeyRepository
+	release chan struct{}
+}
+
+func (s *blockingKeyStore) GetByHash(_ context.Context, hash string) (*model.APIKey, error) {
+	<-s.release
+	return &model.APIKey{KeyHash: hash}, nil
+}
+
+func TestSharedAPIKeyRepository_WaiterCancellation(t *testing.T) {
+	store := &blockingKeyStore{release: make(chan struct{})}
+	repo := newSharedAPIKeyRepository(store)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// a caller that gave up stops waiting without failing the shared query
+	_, err := repo.GetByHash(ctx, "hash")
+	require.ErrorIs(t, err, context.Canceled)
+
+	close(store.release)
+	key, err := repo.GetByHash(context.Background(), "hash")
+	require.NoError(t, err)
+	require.Equal(t, "hash", key.KeyHash)
+}
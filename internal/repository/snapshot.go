@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// dbtx is the query surface shared by *sql.DB and *sql.Tx, so a repository
+// can run against the pool or inside a transaction.
+type dbtx interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// snapshotTxOptions start a read-only REPEATABLE READ transaction. Postgres
+// takes the snapshot at the first statement and every later statement in the
+// transaction reads from it.
+var snapshotTxOptions = &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+
+// inSnapshot runs fn inside a snapshot transaction on db. The transaction is
+// committed when fn succeeds and rolled back otherwise; since it is read-only
+// either way only releases the snapshot.
+func inSnapshot(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, snapshotTxOptions)
+	if err != nil {
+		return fmt.Errorf("begin snapshot: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("end snapshot: %w", err)
+	}
+	return nil
+}
+
+// inTx runs fn inside a read-write transaction on db, committing when fn
+// succeeds and rolling back otherwise. A db that is already a transaction
+// runs fn in it.
+func inTx(ctx context.Context, db dbtx, fn func(tx dbtx) error) error {
+	pool, ok := db.(*sql.DB)
+	if !ok {
+		return fn(db)
+	}
+	tx, err := pool.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
@@ -5,204 +5,809 @@ import (
 	"cruder/internal/model"
 	"cruder/pkg/logger"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"syscall"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
+	"golang.org/x/sync/errgroup"
 )
 
-var ErrUniqueViolation = errors.New("unique constraint violation")
+var (
+	ErrUniqueViolation = errors.New("unique constraint violation")
+	// ErrConstraintViolation covers NOT NULL and CHECK failures: the row was
+	// rejected because of a value the caller supplied.
+	ErrConstraintViolation = errors.New("constraint violation")
+)
 
 type UserRepository interface {
-	GetAll() ([]model.User, error)
-	GetByUsername(username string) (*model.User, error)
-	GetByID(id int64) (*model.User, error)
-	GetByUUID(uuid uuid.UUID) (*model.User, error)
-	Create(username, email, fullName string) (*model.User, error)
-	UpdateByUUID(uuid uuid.UUID, username, email, fullName string) (*model.User, error)
-	DeleteByUUID(uuid uuid.UUID) (bool, error)
-	UpdateByID(id int64, username, email, fullName string) (*model.User, error)
-	DeleteByID(id int64) (bool, error)
+	GetAll(ctx context.Context) ([]model.User, error)
+	List(ctx context.Context, q ListUsersQuery) ([]model.User, error)
+	GetAllAfter(ctx context.Context, cursorID int64, limit int) ([]model.User, error)
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	GetByID(ctx context.Context, id int64) (*model.User, error)
+	GetByUUID(ctx context.Context, uuid uuid.UUID) (*model.User, error)
+	// GetByUUIDs returns the users with the given uuids in no particular
+	// order, skipping uuids that match no user.
+	GetByUUIDs(ctx context.Context, uuids []uuid.UUID) ([]model.User, error)
+	ExistsByUUID(ctx context.Context, uuid uuid.UUID) (bool, error)
+	ExistsByID(ctx context.Context, id int64) (bool, error)
+	// TakenFields returns which of "username", "email" and "full_name"
+	// already belong to a user, comparing email case-insensitively like its
+	// unique index does. An empty fullName is not looked up.
+	TakenFields(ctx context.Context, username, email, fullName string) ([]string, error)
+	// Stats counts all users, those created today, and those suspended.
+	Stats(ctx context.Context) (*model.UserStats, error)
+	Create(ctx context.Context, username, email, fullName, avatarURL string) (*model.User, error)
+	// UpdateByUUID and UpdateByID write the fields and bump the version, but
+	// only while the stored version is still version. They return nil when no
+	// row matches, whether the user is gone or was changed in the meantime.
+	UpdateByUUID(ctx context.Context, uuid uuid.UUID, username, email, fullName, avatarURL string, version int) (*model.User, error)
+	DeleteByUUID(ctx context.Context, uuid uuid.UUID) (*model.User, error)
+	DeleteByUsername(ctx context.Context, username string) (*model.User, error)
+	// DeleteManyByUUID deletes every listed user in one statement and returns
+	// the rows it removed. UUIDs that match no user are skipped.
+	DeleteManyByUUID(ctx context.Context, uuids []uuid.UUID) ([]model.User, error)
+	// UpdateManyByUUID sets one field, status or avatar_url, to value on
+	// every listed user in a single transaction. It returns a change for
+	// each user found; a user whose field already held value is returned
+	// unchanged and keeps its version.
+	UpdateManyByUUID(ctx context.Context, uuids []uuid.UUID, field, value string) ([]UserChange, error)
+	UpdateByID(ctx context.Context, id int64, username, email, fullName, avatarURL string, version int) (*model.User, error)
+	DeleteByID(ctx context.Context, id int64) (*model.User, error)
+	// MarkEmailVerified sets email_verified on the user with id, provided
+	// their email is still email. It returns nil when no user matches, which
+	// covers an email changed since the token was issued.
+	MarkEmailVerified(ctx context.Context, id int64, email string) (*model.User, error)
+	// SetStatus sets the status of the user with uuid and bumps the version,
+	// unless the user already has status. It returns nil when no row changed,
+	// whether the user is gone or already had the status.
+	SetStatus(ctx context.Context, uuid uuid.UUID, status string) (*model.User, error)
+	// Snapshot calls fn with a repository whose reads all see the database
+	// as of a single point in time. Writes through it fail. Cancelling ctx
+	// rolls the snapshot back.
+	Snapshot(ctx context.Context, fn func(UserRepository) error) error
+}
+
+// UserChange is a user as it was before a write and as the write left it.
+type UserChange struct {
+	Before model.User
+	After  model.User
+}
+
+// bulkSetter is how UpdateManyByUUID writes one field: the column and the
+// SQL expression that turns $1 into its value.
+type bulkSetter struct {
+	column string
+	value  string
+}
+
+// bulkSetters are the fields UpdateManyByUUID can set. Only these names ever
+// reach the SQL text; values are always bound.
+var bulkSetters = map[string]bulkSetter{
+	"status":     {column: "status", value: "$1::user_status"},
+	"avatar_url": {column: "avatar_url", value: "NULLIF($1, '')"},
 }
 
 type userRepository struct {
-	db  *sql.DB
-	log *logger.Logger
+	db dbtx
+	// reader serves the read methods. It is a replica when one is configured
+	// and db otherwise.
+	reader dbtx
+	log    *logger.Logger
+
+	// pool is the connection pool snapshots are started from. It is nil on a
+	// repository that is already bound to a snapshot.
+	pool *sql.DB
 }
 
 func NewUserRepository(db *sql.DB) UserRepository {
-	repoLogger := logger.Get().With(slog.String("component", "repository.user"))
+	return NewUserRepositoryWithReplica(db, db)
+}
+
+// NewUserRepositoryWithReplica sends writes to primary and reads, snapshots
+// included, to replica. Replicas lag, so a read straight after a write may
+// not see it.
+func NewUserRepositoryWithReplica(primary, replica *sql.DB) UserRepository {
+	return newUserRepository(primary, replica, logger.Get())
+}
+
+func newUserRepository(primary, replica *sql.DB, log *logger.Logger) UserRepository {
+	repoLogger := log.With(slog.String("component", "repository.user"))
 	return &userRepository{
-		db:  db,
-		log: repoLogger,
+		db:     primary,
+		reader: replica,
+		log:    repoLogger,
+		pool:   replica,
+	}
+}
+
+func (r *userRepository) Snapshot(ctx context.Context, fn func(UserRepository) error) error {
+	if r.pool == nil {
+		// already inside a snapshot; nesting would only see the same data
+		return fn(r)
 	}
+	_, done := startOperation(ctx, r.log, "UserRepository.Snapshot")
+	defer done()
+	return inSnapshot(ctx, r.pool, func(tx *sql.Tx) error {
+		return fn(&userRepository{db: tx, reader: tx, log: r.log})
+	})
 }
 
-func (r *userRepository) GetAll() ([]model.User, error) {
-	rows, err := r.db.QueryContext(context.Background(), `SELECT id, uuid, username, email, full_name FROM users`)
+// GetAll stops scanning as soon as ctx is done, so a client that hangs up
+// mid-scan doesn't keep the query running.
+func (r *userRepository) GetAll(ctx context.Context) ([]model.User, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.GetAll")
+	defer done()
+	rows, err := r.reader.QueryContext(ctx, `SELECT id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version FROM users`)
 	if err != nil {
-		r.log.Error("get all users query failed", slog.String("error", err.Error()))
+		LogReadError(ctx, log, "get all users query failed", err)
 		return nil, err
 	}
 	defer rows.Close()
 
 	var users []model.User
 	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			log.Debug("get all users cancelled", slog.Int("users.scanned", len(users)))
+			return nil, err
+		}
 		var u model.User
-		if err := rows.Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName); err != nil {
+		if err := rows.Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.AvatarURL, &u.EmailVerified, &u.Status, &u.Version); err != nil {
 			return nil, err
 		}
 		users = append(users, u)
 	}
 
 	if err := rows.Err(); err != nil {
-		r.log.Error("get all users rows iteration failed", slog.String("error", err.Error()))
+		LogReadError(ctx, log, "get all users rows iteration failed", err)
 		return nil, err
 	}
 
 	return users, nil
 }
 
-func (r *userRepository) GetByUsername(username string) (*model.User, error) {
+// ListUsersQuery selects which users List returns and in what order.
+type ListUsersQuery struct {
+	// Limit caps the result size; zero returns every matching row.
+	Limit  int
+	Offset int
+	// AfterID keeps only users with a larger id, for keyset pagination.
+	AfterID int64
+	// Pinned users come first, in the given order, ahead of the default id
+	// ordering. UUIDs that match no user are ignored.
+	Pinned []uuid.UUID
+	// CreatedAfter and CreatedBefore bound created_at to the half-open range
+	// [CreatedAfter, CreatedBefore). A zero time leaves that side open.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// createdAtBound converts the instant bound to param into the wall time
+// created_at is written in. created_at has no time zone and defaults to
+// CURRENT_TIMESTAMP in the session's zone; binding the bound as a plain
+// timestamp would silently drop its offset.
+func createdAtBound(param string) string {
+	return "(" + param + "::timestamptz AT TIME ZONE current_setting('TimeZone'))"
+}
+
+// List returns users ordered by id, after any pinned users, so consecutive
+// pages don't overlap.
+func (r *userRepository) List(ctx context.Context, q ListUsersQuery) ([]model.User, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.List")
+	defer done()
+
+	query := newSelectQuery(`SELECT id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version FROM users`)
+	if q.AfterID > 0 {
+		query.Where("id > " + query.arg(q.AfterID))
+	}
+	if !q.CreatedAfter.IsZero() {
+		query.Where("created_at >= " + createdAtBound(query.arg(q.CreatedAfter)))
+	}
+	if !q.CreatedBefore.IsZero() {
+		query.Where("created_at < " + createdAtBound(query.arg(q.CreatedBefore)))
+	}
+
+	orderBy := "id"
+	if len(q.Pinned) > 0 {
+		pinned := make([]string, len(q.Pinned))
+		for i, id := range q.Pinned {
+			pinned[i] = id.String()
+		}
+		orderBy = "array_position(" + query.arg(pq.Array(pinned)) + "::uuid[], uuid) NULLS LAST, id"
+	}
+	query.OrderBy(orderBy).Limit(q.Limit).Offset(q.Offset)
+
+	rows, err := r.reader.QueryContext(ctx, query.String(), query.args...)
+	if err != nil {
+		log.Error("list users query failed", slog.String("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []model.User
+	for rows.Next() {
+		var u model.User
+		if err := rows.Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.AvatarURL, &u.EmailVerified, &u.Status, &u.Version); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error("list users rows iteration failed", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// GetAllAfter returns up to limit users with an id above cursorID. Unlike
+// offsets, the cursor stays stable when rows are inserted mid-scan.
+func (r *userRepository) GetAllAfter(ctx context.Context, cursorID int64, limit int) ([]model.User, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.GetAllAfter")
+	defer done()
+	rows, err := r.reader.QueryContext(ctx,
+		`SELECT id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version FROM users WHERE id > $1 ORDER BY id LIMIT $2`,
+		cursorID, limit,
+	)
+	if err != nil {
+		LogReadError(ctx, log, "get users after cursor query failed", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []model.User
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			log.Debug("get users after cursor cancelled", slog.Int("users.scanned", len(users)))
+			return nil, err
+		}
+		var u model.User
+		if err := rows.Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.AvatarURL, &u.EmailVerified, &u.Status, &u.Version); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		LogReadError(ctx, log, "get users after cursor rows iteration failed", err)
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.GetByUsername")
+	defer done()
 	var u model.User
-	if err := r.db.QueryRowContext(context.Background(), `SELECT id, uuid, username, email, full_name FROM users WHERE username = $1`, username).
-		Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName); err != nil {
+	if err := r.reader.QueryRowContext(ctx, `SELECT id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version FROM users WHERE username = $1`, username).
+		Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.AvatarURL, &u.EmailVerified, &u.Status, &u.Version); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
-		r.log.Error("get by username failed", slog.String("user.username", username), slog.String("error", err.Error()))
+		log.Error("get by username failed", slog.String("user.username", username), slog.String("error", err.Error()))
 		return nil, err
 	}
 	return &u, nil
 }
 
-func (r *userRepository) GetByID(id int64) (*model.User, error) {
+func (r *userRepository) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.GetByID")
+	defer done()
 	var u model.User
-	if err := r.db.QueryRowContext(context.Background(), `SELECT id, uuid, username, email, full_name FROM users WHERE id = $1`, id).
-		Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName); err != nil {
+	if err := r.reader.QueryRowContext(ctx, `SELECT id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version FROM users WHERE id = $1`, id).
+		Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.AvatarURL, &u.EmailVerified, &u.Status, &u.Version); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
-		r.log.Error("get by id failed", slog.Int64("user.id", id), slog.String("error", err.Error()))
+		log.Error("get by id failed", slog.Int64("user.id", id), slog.String("error", err.Error()))
 		return nil, err
 	}
 	return &u, nil
 }
 
-func (r *userRepository) GetByUUID(uuid uuid.UUID) (*model.User, error) {
+func (r *userRepository) GetByUUID(ctx context.Context, uuid uuid.UUID) (*model.User, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.GetByUUID")
+	defer done()
 	var u model.User
-	if err := r.db.QueryRowContext(context.Background(), `SELECT id, uuid, username, email, full_name FROM users WHERE uuid = $1`, uuid.String()).
-		Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName); err != nil {
+	if err := r.reader.QueryRowContext(ctx, `SELECT id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version FROM users WHERE uuid = $1`, uuid.String()).
+		Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.AvatarURL, &u.EmailVerified, &u.Status, &u.Version); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
-		r.log.Error("get by uuid failed", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
+		log.Error("get by uuid failed", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
 		return nil, err
 	}
 	return &u, nil
 }
 
-func (r *userRepository) Create(username, email, fullName string) (*model.User, error) {
+func (r *userRepository) GetByUUIDs(ctx context.Context, uuids []uuid.UUID) ([]model.User, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.GetByUUIDs")
+	defer done()
+	ids := make([]string, len(uuids))
+	for i, id := range uuids {
+		ids[i] = id.String()
+	}
+	rows, err := r.reader.QueryContext(ctx,
+		`SELECT id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version FROM users WHERE uuid = ANY($1::uuid[])`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		log.Error("get by uuids query failed", slog.Int("users.requested", len(uuids)), slog.String("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []model.User
+	for rows.Next() {
+		var u model.User
+		if err := rows.Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.AvatarURL, &u.EmailVerified, &u.Status, &u.Version); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("get by uuids rows iteration failed", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *userRepository) ExistsByUUID(ctx context.Context, uuid uuid.UUID) (bool, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.ExistsByUUID")
+	defer done()
+	var exists bool
+	if err := r.reader.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE uuid = $1)`, uuid.String()).
+		Scan(&exists); err != nil {
+		log.Error("exists by uuid failed", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
+		return false, err
+	}
+	return exists, nil
+}
+
+func (r *userRepository) TakenFields(ctx context.Context, username, email, fullName string) ([]string, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.TakenFields")
+	defer done()
+	var usernameTaken, emailTaken, fullNameTaken bool
+	if err := r.reader.QueryRowContext(ctx,
+		`SELECT COALESCE(bool_or(username = $1), FALSE), COALESCE(bool_or(lower(email) = lower($2)), FALSE), COALESCE(bool_or($3 <> '' AND full_name = $3), FALSE) `+
+			`FROM users WHERE username = $1 OR lower(email) = lower($2) OR ($3 <> '' AND full_name = $3)`,
+		username, email, fullName).
+		Scan(&usernameTaken, &emailTaken, &fullNameTaken); err != nil {
+		log.Error("taken fields failed", slog.String("error", err.Error()))
+		return nil, err
+	}
+	var taken []string
+	if usernameTaken {
+		taken = append(taken, "username")
+	}
+	if emailTaken {
+		taken = append(taken, "email")
+	}
+	if fullNameTaken {
+		taken = append(taken, "full_name")
+	}
+	return taken, nil
+}
+
+// statsQueries are the independent counts behind Stats. created_at has no
+// time zone, so "today" starts at midnight in the session's time zone.
+var statsQueries = []struct {
+	name  string
+	query string
+	args  []any
+}{
+	{name: "total", query: `SELECT count(*) FROM users`},
+	{name: "created_today", query: `SELECT count(*) FROM users WHERE created_at >= CURRENT_DATE`},
+	{name: "suspended", query: `SELECT count(*) FROM users WHERE status = $1`, args: []any{model.UserStatusSuspended}},
+}
+
+func (r *userRepository) Stats(ctx context.Context) (*model.UserStats, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.Stats")
+	defer done()
+	counts := make([]int64, len(statsQueries))
+	g, gctx := errgroup.WithContext(ctx)
+	if r.pool == nil {
+		// a snapshot is one transaction, which can't run queries side by side
+		g.SetLimit(1)
+	}
+	for i, q := range statsQueries {
+		g.Go(func() error {
+			if err := r.reader.QueryRowContext(gctx, q.query, q.args...).Scan(&counts[i]); err != nil {
+				return fmt.Errorf("count %s: %w", q.name, err)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		log.Error("user stats failed", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return &model.UserStats{Total: counts[0], CreatedToday: counts[1], Suspended: counts[2]}, nil
+}
+
+func (r *userRepository) ExistsByID(ctx context.Context, id int64) (bool, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.ExistsByID")
+	defer done()
+	var exists bool
+	if err := r.reader.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`, id).
+		Scan(&exists); err != nil {
+		log.Error("exists by id failed", slog.Int64("user.id", id), slog.String("error", err.Error()))
+		return false, err
+	}
+	return exists, nil
+}
+
+func (r *userRepository) Create(ctx context.Context, username, email, fullName, avatarURL string) (*model.User, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.Create")
+	defer done()
 	var u model.User
 	if err := r.db.QueryRowContext(
-		context.Background(),
-		`INSERT INTO users (username, email, full_name) VALUES ($1, $2, $3) RETURNING id, uuid, username, email, full_name`,
+		ctx,
+		`INSERT INTO users (username, email, full_name, avatar_url) VALUES ($1, $2, $3, NULLIF($4, '')) RETURNING id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version`,
 		username,
 		email,
 		fullName,
-	).Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName); err != nil {
+		avatarURL,
+	).Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.AvatarURL, &u.EmailVerified, &u.Status, &u.Version); err != nil {
 		err := mapPQError(err)
 		if errors.Is(err, ErrUniqueViolation) {
-			r.log.Warn("create failed: user already exists", slog.String("user.username", username))
+			log.Warn("create failed: user already exists", slog.String("user.username", username))
+		} else if errors.Is(err, ErrConstraintViolation) {
+			log.Warn("create failed: constraint violation", slog.String("error", err.Error()))
 		} else {
-			r.log.Error("create failed", slog.String("error", err.Error()))
+			log.Error("create failed", slog.String("error", err.Error()))
 		}
 		return nil, err
 	}
 	return &u, nil
 }
 
-func (r *userRepository) UpdateByUUID(uuid uuid.UUID, username, email, fullName string) (*model.User, error) {
+func (r *userRepository) UpdateByUUID(ctx context.Context, uuid uuid.UUID, username, email, fullName, avatarURL string, version int) (*model.User, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.UpdateByUUID")
+	defer done()
 	var u model.User
 	if err := r.db.QueryRowContext(
-		context.Background(),
-		`UPDATE users SET username = $1, email = $2, full_name = $3 WHERE uuid = $4 RETURNING id, uuid, username, email, full_name`,
+		ctx,
+		`UPDATE users SET username = $1, email = $2, full_name = $3, avatar_url = NULLIF($4, ''), email_verified = email_verified AND email = $2, version = version + 1 WHERE uuid = $5 AND version = $6 RETURNING id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version`,
 		username,
 		email,
 		fullName,
+		avatarURL,
 		uuid,
-	).Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName); err != nil {
+		version,
+	).Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.AvatarURL, &u.EmailVerified, &u.Status, &u.Version); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		mapped := mapPQError(err)
 		if errors.Is(mapped, ErrUniqueViolation) {
-			r.log.Warn("update by uuid failed: user already exists", slog.String("user.uuid", uuid.String()))
+			log.Warn("update by uuid failed: user already exists", slog.String("user.uuid", uuid.String()))
+		} else if errors.Is(mapped, ErrConstraintViolation) {
+			log.Warn("update by uuid failed: constraint violation", slog.String("user.uuid", uuid.String()), slog.String("error", mapped.Error()))
 		} else {
-			r.log.Error("update by uuid failed", slog.String("user.uuid", uuid.String()), slog.String("error", mapped.Error()))
+			log.Error("update by uuid failed", slog.String("user.uuid", uuid.String()), slog.String("error", mapped.Error()))
 		}
 		return nil, mapped
 	}
 	return &u, nil
 }
 
-func (r *userRepository) DeleteByUUID(uuid uuid.UUID) (bool, error) {
-	res, err := r.db.ExecContext(context.Background(), `DELETE FROM users WHERE uuid = $1`, uuid)
+// DeleteByUUID deletes the user and returns it as it was, or nil when no user
+// has that uuid.
+func (r *userRepository) DeleteByUUID(ctx context.Context, uuid uuid.UUID) (*model.User, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.DeleteByUUID")
+	defer done()
+	var u model.User
+	if err := r.db.QueryRowContext(ctx,
+		`DELETE FROM users WHERE uuid = $1 RETURNING id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version`, uuid).
+		Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.AvatarURL, &u.EmailVerified, &u.Status, &u.Version); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		log.Error("delete by uuid failed", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
+		return nil, err
+	}
+	return &u, nil
+}
+
+// DeleteByUsername deletes the user and returns it as it was, or nil when no
+// user has that username.
+func (r *userRepository) DeleteByUsername(ctx context.Context, username string) (*model.User, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.DeleteByUsername")
+	defer done()
+	var u model.User
+	if err := r.db.QueryRowContext(ctx,
+		`DELETE FROM users WHERE username = $1 RETURNING id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version`, username).
+		Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.AvatarURL, &u.EmailVerified, &u.Status, &u.Version); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		log.Error("delete by username failed", slog.String("user.username", username), slog.String("error", err.Error()))
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *userRepository) DeleteManyByUUID(ctx context.Context, uuids []uuid.UUID) ([]model.User, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.DeleteManyByUUID")
+	defer done()
+	ids := make([]string, len(uuids))
+	for i, id := range uuids {
+		ids[i] = id.String()
+	}
+	rows, err := r.db.QueryContext(ctx,
+		`DELETE FROM users WHERE uuid = ANY($1::uuid[]) RETURNING id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version`,
+		pq.Array(ids),
+	)
 	if err != nil {
-		r.log.Error("delete by uuid failed", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
-		return false, err
+		log.Error("delete many by uuid failed", slog.Int("users.requested", len(uuids)), slog.String("error", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deleted []model.User
+	for rows.Next() {
+		var u model.User
+		if err := rows.Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.AvatarURL, &u.EmailVerified, &u.Status, &u.Version); err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, u)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("delete many by uuid rows iteration failed", slog.String("error", err.Error()))
+		return nil, err
+	}
+	return deleted, nil
+}
+
+func (r *userRepository) UpdateManyByUUID(ctx context.Context, uuids []uuid.UUID, field, value string) ([]UserChange, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.UpdateManyByUUID")
+	defer done()
+	setter, ok := bulkSetters[field]
+	if !ok {
+		return nil, fmt.Errorf("bulk update of %q is not supported", field)
 	}
-	affected, err := res.RowsAffected()
+	ids := make([]string, len(uuids))
+	for i, id := range uuids {
+		ids[i] = id.String()
+	}
+
+	var changes []UserChange
+	err := inTx(ctx, r.db, func(tx dbtx) error {
+		// locks the rows in id order, so overlapping bulk updates queue
+		// instead of deadlocking
+		before, err := queryUsers(ctx, tx,
+			`SELECT id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version FROM users WHERE uuid = ANY($1::uuid[]) ORDER BY id FOR UPDATE`,
+			pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		after, err := queryUsers(ctx, tx,
+			`UPDATE users SET `+setter.column+` = `+setter.value+`, version = version + 1 WHERE uuid = ANY($2::uuid[]) AND `+setter.column+` IS DISTINCT FROM `+setter.value+
+				` RETURNING id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version`,
+			value, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		updated := make(map[int]model.User, len(after))
+		for _, u := range after {
+			updated[u.ID] = u
+		}
+		changes = make([]UserChange, len(before))
+		for i, u := range before {
+			changes[i] = UserChange{Before: u, After: u}
+			if next, ok := updated[u.ID]; ok {
+				changes[i].After = next
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		r.log.Error("delete by uuid rows affected failed", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
-		return false, err
+		err = mapPQError(err)
+		log.Error("update many by uuid failed", slog.Int("users.requested", len(uuids)), slog.String("field", field), slog.String("error", err.Error()))
+		return nil, err
+	}
+	return changes, nil
+}
+
+// queryUsers runs a query returning full user rows and scans them all.
+func queryUsers(ctx context.Context, db dbtx, query string, args ...any) ([]model.User, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var users []model.User
+	for rows.Next() {
+		var u model.User
+		if err := rows.Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.AvatarURL, &u.EmailVerified, &u.Status, &u.Version); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
 	}
-	return affected > 0, nil
+	return users, rows.Err()
 }
 
-func (r *userRepository) UpdateByID(id int64, username, email, fullName string) (*model.User, error) {
+func (r *userRepository) UpdateByID(ctx context.Context, id int64, username, email, fullName, avatarURL string, version int) (*model.User, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.UpdateByID")
+	defer done()
 	var u model.User
 	if err := r.db.QueryRowContext(
-		context.Background(),
-		`UPDATE users SET username = $1, email = $2, full_name = $3 WHERE id = $4 RETURNING id, uuid, username, email, full_name`,
+		ctx,
+		`UPDATE users SET username = $1, email = $2, full_name = $3, avatar_url = NULLIF($4, ''), email_verified = email_verified AND email = $2, version = version + 1 WHERE id = $5 AND version = $6 RETURNING id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version`,
 		username,
 		email,
 		fullName,
+		avatarURL,
 		id,
-	).Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName); err != nil {
+		version,
+	).Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.AvatarURL, &u.EmailVerified, &u.Status, &u.Version); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		mapped := mapPQError(err)
 		if errors.Is(mapped, ErrUniqueViolation) {
-			r.log.Warn("update by id failed: user already exists", slog.Int64("user.id", id))
+			log.Warn("update by id failed: user already exists", slog.Int64("user.id", id))
+		} else if errors.Is(mapped, ErrConstraintViolation) {
+			log.Warn("update by id failed: constraint violation", slog.Int64("user.id", id), slog.String("error", mapped.Error()))
 		} else {
-			r.log.Error("update by id failed", slog.Int64("user.id", id), slog.String("error", mapped.Error()))
+			log.Error("update by id failed", slog.Int64("user.id", id), slog.String("error", mapped.Error()))
 		}
 		return nil, mapped
 	}
 	return &u, nil
 }
 
-func (r *userRepository) DeleteByID(id int64) (bool, error) {
-	res, err := r.db.ExecContext(context.Background(), `DELETE FROM users WHERE id = $1`, id)
-	if err != nil {
-		r.log.Error("delete by id failed", slog.Int64("user.id", id), slog.String("error", err.Error()))
-		return false, err
+// DeleteByID deletes the user and returns it as it was, or nil when no user
+// has that id.
+func (r *userRepository) DeleteByID(ctx context.Context, id int64) (*model.User, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.DeleteByID")
+	defer done()
+	var u model.User
+	if err := r.db.QueryRowContext(ctx,
+		`DELETE FROM users WHERE id = $1 RETURNING id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version`, id).
+		Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.AvatarURL, &u.EmailVerified, &u.Status, &u.Version); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		log.Error("delete by id failed", slog.Int64("user.id", id), slog.String("error", err.Error()))
+		return nil, err
 	}
-	affected, err := res.RowsAffected()
-	if err != nil {
-		r.log.Error("delete by id rows affected failed", slog.Int64("user.id", id), slog.String("error", err.Error()))
-		return false, err
+	return &u, nil
+}
+
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id int64, email string) (*model.User, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.MarkEmailVerified")
+	defer done()
+	var u model.User
+	if err := r.db.QueryRowContext(ctx,
+		`UPDATE users SET email_verified = TRUE, version = version + 1 WHERE id = $1 AND email = $2 RETURNING id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version`,
+		id, email).
+		Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.AvatarURL, &u.EmailVerified, &u.Status, &u.Version); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		log.Error("mark email verified failed", slog.Int64("user.id", id), slog.String("error", err.Error()))
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *userRepository) SetStatus(ctx context.Context, uuid uuid.UUID, status string) (*model.User, error) {
+	log, done := startOperation(ctx, r.log, "UserRepository.SetStatus")
+	defer done()
+	var u model.User
+	if err := r.db.QueryRowContext(ctx,
+		`UPDATE users SET status = $1, version = version + 1 WHERE uuid = $2 AND status <> $1 RETURNING id, uuid, username, email, full_name, COALESCE(avatar_url, ''), email_verified, status, version`,
+		status, uuid.String()).
+		Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.AvatarURL, &u.EmailVerified, &u.Status, &u.Version); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		log.Error("set status failed", slog.String("user.uuid", uuid.String()), slog.String("status", status), slog.String("error", err.Error()))
+		return nil, mapPQError(err)
 	}
-	return affected > 0, nil
+	return &u, nil
 }
 
 func mapPQError(err error) error {
 	var pqErr *pq.Error
-	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
-		return ErrUniqueViolation
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch pqErr.Code {
+	case "23505": // unique_violation
+		return &ConstraintError{Kind: ErrUniqueViolation, Constraint: pqErr.Constraint, Err: pqErr}
+	case "23502", "23514": // not_null_violation, check_violation
+		return &ConstraintError{Kind: ErrConstraintViolation, Constraint: pqErr.Constraint, Err: pqErr}
 	}
 	return err
 }
+
+// ConstraintError is a write the database rejected. It matches its Kind,
+// ErrUniqueViolation or ErrConstraintViolation, and the underlying *pq.Error
+// with errors.Is and errors.As.
+type ConstraintError struct {
+	Kind error
+	// Constraint is the violated constraint or index, when Postgres names
+	// one (NOT NULL failures have none).
+	Constraint string
+	Err        *pq.Error
+}
+
+func (e *ConstraintError) Error() string {
+	if e.Kind == ErrUniqueViolation {
+		if e.Constraint == "" {
+			return e.Kind.Error()
+		}
+		return fmt.Sprintf("%s: %s", e.Kind, e.Constraint)
+	}
+	if e.Err == nil {
+		return e.Kind.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Kind, e.Err.Message)
+}
+
+func (e *ConstraintError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// constraintColumns maps the unique constraints and indexes on users to the
+// column each guards.
+var constraintColumns = map[string]string{
+	"users_username_key":    "username",
+	"users_email_key":       "email",
+	"users_email_lower_key": "email",
+	"users_uuid_key":        "uuid",
+	FullNameIndex:           "full_name",
+}
+
+// FullNameIndex names the optional unique index on users.full_name. It is
+// not created by a migration, since most deployments allow shared names;
+// CreateFullNameIndexSQL builds it where display names must be unique.
+const FullNameIndex = "users_full_name_key"
+
+// CreateFullNameIndexSQL creates FullNameIndex. Blank names are exempt so
+// users without one don't collide. It fails if names are already shared.
+const CreateFullNameIndexSQL = `CREATE UNIQUE INDEX IF NOT EXISTS ` + FullNameIndex + ` ON users (full_name) WHERE full_name <> ''`
+
+// Column returns the users column the violated constraint guards, or "" when
+// the constraint isn't one of them.
+func (e *ConstraintError) Column() string {
+	return constraintColumns[e.Constraint]
+}
+
+// IsTransient reports whether err is a transient failure, such as a dropped
+// connection or a server restart, after which a read may succeed. Reads are
+// retried on these, and callers may treat them as the database being
+// unavailable rather than the query being wrong.
+func IsTransient(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if pqErr.Code.Class() == "08" { // connection_exception
+			return true
+		}
+		switch pqErr.Code {
+		case "57P01", "57P02", "57P03": // admin_shutdown, crash_shutdown, cannot_connect_now
+			return true
+		case "53300": // too_many_connections
+			return true
+		}
+		return false
+	}
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
@@ -0,0 +1,404 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"syscall"
+	"testing"
+	"time"
+
+	"cruder/internal/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRepository_Create_CaseInsensitiveEmailConflict(t *testing.T) {
+	// Given: the lower(email) index rejects the insert
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(`INSERT INTO users`).
+		WithArgs("jdoe2", "JDoe@example.com", "John Doe", "").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "users_email_lower_key"})
+
+	// When: creating the user
+	_, err = NewUserRepository(db).Create(context.Background(), "jdoe2", "JDoe@example.com", "John Doe", "")
+
+	// Then: the violation maps to the same error as the plain unique key
+	require.ErrorIs(t, err, ErrUniqueViolation)
+	var cerr *ConstraintError
+	require.ErrorAs(t, err, &cerr)
+	require.Equal(t, "email", cerr.Column())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_Create_AvatarURLStoredAsNull(t *testing.T) {
+	// Given: an insert that leaves the avatar out
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta(`VALUES ($1, $2, $3, NULLIF($4, '')) RETURNING id, uuid, username, email, full_name, COALESCE(avatar_url, '')`)).
+		WithArgs("jdoe", "jdoe@example.com", "John Doe", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "avatar_url", "email_verified", "status", "version"}).
+			AddRow(7, uuid.NewString(), "jdoe", "jdoe@example.com", "John Doe", "", false, "active", 1))
+
+	// When: creating the user
+	user, err := NewUserRepository(db).Create(context.Background(), "jdoe", "jdoe@example.com", "John Doe", "")
+
+	// Then: "" is written as NULL and read back as ""
+	require.NoError(t, err)
+	require.Empty(t, user.AvatarURL)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_UpdateByID_StaleVersion(t *testing.T) {
+	// Given: a stored row whose version has moved past 3
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(`UPDATE users SET .*version = version \+ 1 WHERE id = \$5 AND version = \$6`).
+		WithArgs("jdoe", "jdoe@example.com", "John Doe", "", int64(7), 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "avatar_url", "email_verified", "status", "version"}))
+
+	// When: updating from version 3
+	user, err := NewUserRepository(db).UpdateByID(context.Background(), 7, "jdoe", "jdoe@example.com", "John Doe", "", 3)
+
+	// Then: nothing matches
+	require.NoError(t, err)
+	require.Nil(t, user)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_DeleteManyByUUID_OneStatement(t *testing.T) {
+	// Given: two uuids, only one of which exists
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	present, missing := uuid.New(), uuid.New()
+	mock.ExpectQuery(`DELETE FROM users WHERE uuid = ANY\(\$1::uuid\[\]\)`).
+		WithArgs(pq.Array([]string{present.String(), missing.String()})).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "avatar_url", "email_verified", "status", "version"}).
+			AddRow(1, present.String(), "jdoe", "jdoe@example.com", "John Doe", "", false, "active", 1))
+
+	// When: deleting both
+	deleted, err := NewUserRepository(db).DeleteManyByUUID(context.Background(), []uuid.UUID{present, missing})
+
+	// Then: a single query returns only the removed row
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	require.Equal(t, present.String(), deleted[0].UUID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_UpdateManyByUUID_OneTransaction(t *testing.T) {
+	// Given: three uuids: one active user, one already suspended, one missing
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	active, suspended, missing := uuid.New(), uuid.New(), uuid.New()
+	ids := pq.Array([]string{active.String(), suspended.String(), missing.String()})
+	columns := []string{"id", "uuid", "username", "email", "full_name", "avatar_url", "email_verified", "status", "version"}
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = ANY($1::uuid[]) ORDER BY id FOR UPDATE`)).
+		WithArgs(ids).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, active.String(), "jdoe", "jdoe@example.com", "John Doe", "", false, "active", 3).
+			AddRow(2, suspended.String(), "jroe", "jroe@example.com", "Jane Roe", "", false, "suspended", 5))
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE users SET status = $1::user_status, version = version + 1 WHERE uuid = ANY($2::uuid[]) AND status IS DISTINCT FROM $1::user_status RETURNING`)).
+		WithArgs("suspended", ids).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, active.String(), "jdoe", "jdoe@example.com", "John Doe", "", false, "suspended", 4))
+	mock.ExpectCommit()
+
+	// When: suspending all three
+	changes, err := NewUserRepository(db).UpdateManyByUUID(context.Background(), []uuid.UUID{active, suspended, missing}, "status", "suspended")
+
+	// Then: both found users come back, and only the active one changed
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	require.Equal(t, "active", changes[0].Before.Status)
+	require.Equal(t, "suspended", changes[0].After.Status)
+	require.Equal(t, 4, changes[0].After.Version)
+	require.Equal(t, changes[1].Before, changes[1].After)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_UpdateManyByUUID_RollsBackOnError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FOR UPDATE`).WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "avatar_url", "email_verified", "status", "version"}))
+	mock.ExpectQuery(`UPDATE users SET avatar_url = NULLIF\(\$1, ''\)`).WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	_, err = NewUserRepository(db).UpdateManyByUUID(context.Background(), []uuid.UUID{uuid.New()}, "avatar_url", "")
+
+	require.EqualError(t, err, "connection reset")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_UpdateManyByUUID_UnknownField(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = NewUserRepository(db).UpdateManyByUUID(context.Background(), []uuid.UUID{uuid.New()}, "email = 'x', username", "x")
+
+	require.EqualError(t, err, `bulk update of "email = 'x', username" is not supported`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_GetByUUIDs_OneQuery(t *testing.T) {
+	// Given: two uuids, only one of which exists
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	present, missing := uuid.New(), uuid.New()
+	mock.ExpectQuery(`^SELECT id, uuid, username, email, full_name, COALESCE\(avatar_url, ''\), email_verified, status, version FROM users WHERE uuid = ANY\(\$1::uuid\[\]\)$`).
+		WithArgs(pq.Array([]string{present.String(), missing.String()})).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "avatar_url", "email_verified", "status", "version"}).
+			AddRow(1, present.String(), "jdoe", "jdoe@example.com", "John Doe", "", false, "active", 1))
+
+	// When: loading both
+	users, err := NewUserRepository(db).GetByUUIDs(context.Background(), []uuid.UUID{present, missing})
+
+	// Then: a single query returns only the stored row
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, present.String(), users[0].UUID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_List_ComposesFilters(t *testing.T) {
+	// Given: a created_at window combined with a cursor and a page size
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	after := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := after.AddDate(0, 1, 0)
+	mock.ExpectQuery(`^SELECT id, uuid, username, email, full_name, COALESCE\(avatar_url, ''\), email_verified, status, version FROM users WHERE id > \$1 AND created_at >= \(\$2::timestamptz AT TIME ZONE current_setting\('TimeZone'\)\) AND created_at < \(\$3::timestamptz AT TIME ZONE current_setting\('TimeZone'\)\) ORDER BY id LIMIT \$4$`).
+		WithArgs(int64(10), after, before, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "avatar_url", "email_verified", "status", "version"}))
+
+	// When: listing
+	_, err = NewUserRepository(db).List(context.Background(), ListUsersQuery{AfterID: 10, Limit: 5, CreatedAfter: after, CreatedBefore: before})
+
+	// Then: each filter binds its own placeholder, in order
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_List_CreatedRangeKeepsOffset(t *testing.T) {
+	// Given: a bound written with a +02:00 offset
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	after := time.Date(2024, 1, 1, 10, 0, 0, 0, time.FixedZone("", 2*60*60))
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE created_at >= ($1::timestamptz AT TIME ZONE current_setting('TimeZone')) ORDER BY id`)).
+		WithArgs(after).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "avatar_url", "email_verified", "status", "version"}))
+
+	// When: listing from it
+	_, err = NewUserRepository(db).List(context.Background(), ListUsersQuery{CreatedAfter: after})
+
+	// Then: the bound is sent as an instant and converted to created_at's zone in SQL
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_ReadsFromReplica(t *testing.T) {
+	// Given: separate primary and replica connections
+	primary, primaryMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer primary.Close()
+	replica, replicaMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer replica.Close()
+	id := uuid.New()
+	columns := []string{"id", "uuid", "username", "email", "full_name", "avatar_url", "email_verified", "status", "version"}
+	replicaMock.ExpectQuery(`SELECT id, uuid, username, email, full_name, COALESCE\(avatar_url, ''\), email_verified, status, version FROM users WHERE uuid = \$1`).
+		WithArgs(id.String()).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, id.String(), "jdoe", "jdoe@example.com", "John Doe", "", false, "active", 1))
+	replicaMock.ExpectQuery(`SELECT EXISTS`).WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	primaryMock.ExpectQuery(`UPDATE users`).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, id.String(), "jdoe", "jdoe@example.com", "Jane Doe", "", false, "active", 1))
+	repo := NewUserRepositoryWithReplica(primary, replica)
+
+	// When: reading and then writing
+	_, err = repo.GetByUUID(context.Background(), id)
+	require.NoError(t, err)
+	_, err = repo.ExistsByID(context.Background(), 1)
+	require.NoError(t, err)
+	_, err = repo.UpdateByUUID(context.Background(), id, "jdoe", "jdoe@example.com", "Jane Doe", "", 1)
+	require.NoError(t, err)
+
+	// Then: reads went to the replica and the write to the primary
+	require.NoError(t, replicaMock.ExpectationsWereMet())
+	require.NoError(t, primaryMock.ExpectationsWereMet())
+}
+
+func TestNewRepositoryWithOptions_UsersPrimary(t *testing.T) {
+	primary, primaryMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer primary.Close()
+	replica, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer replica.Close()
+	id := uuid.New()
+	primaryMock.ExpectQuery(`SELECT id, uuid, username, email, full_name, COALESCE\(avatar_url, ''\), email_verified, status, version FROM users WHERE uuid = \$1`).
+		WithArgs(id.String()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "avatar_url", "email_verified", "status", "version"}))
+
+	repos := NewRepositoryWithOptions(primary, Options{ReadReplica: replica})
+	_, err = repos.UsersPrimary.GetByUUID(context.Background(), id)
+
+	require.NoError(t, err)
+	require.NoError(t, primaryMock.ExpectationsWereMet())
+
+}
+
+func TestNewRepositoryWithOptions_UsersPrimaryBypassesCache(t *testing.T) {
+	// Given: a user cache and no replica
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	id := uuid.New()
+	query := `SELECT id, uuid, username, email, full_name, COALESCE\(avatar_url, ''\), email_verified, status, version FROM users WHERE uuid = \$1`
+	columns := []string{"id", "uuid", "username", "email", "full_name", "avatar_url", "email_verified", "status", "version"}
+	for _, status := range []string{"active", "suspended"} {
+		mock.ExpectQuery(query).
+			WithArgs(id.String()).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, id.String(), "jdoe", "jdoe@example.com", "John Doe", "", false, status, 1))
+	}
+	repos := NewRepositoryWithOptions(db, Options{UserCache: CacheOptions{MaxEntries: 10, TTL: time.Minute}})
+
+	// When: the user is cached, then changed by another instance
+	cached, err := repos.Users.GetByUUID(context.Background(), id)
+	require.NoError(t, err)
+	fresh, err := repos.UsersPrimary.GetByUUID(context.Background(), id)
+
+	// Then: the primary read goes to the database and sees the change
+	require.NoError(t, err)
+	require.Equal(t, "active", cached.Status)
+	require.Equal(t, "suspended", fresh.Status)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMapPQError(t *testing.T) {
+	for _, tc := range []struct {
+		code string
+		want error
+	}{
+		{"23505", ErrUniqueViolation},
+		{"23502", ErrConstraintViolation},
+		{"23514", ErrConstraintViolation},
+	} {
+		err := mapPQError(&pq.Error{Code: pq.ErrorCode(tc.code), Message: "rejected"})
+		require.ErrorIs(t, err, tc.want, tc.code)
+	}
+
+	// the constraint and the driver error survive the mapping
+	err := mapPQError(&pq.Error{Code: "23505", Constraint: "users_username_key"})
+	require.EqualError(t, err, "unique constraint violation: users_username_key")
+	var cerr *ConstraintError
+	require.ErrorAs(t, err, &cerr)
+	require.Equal(t, "username", cerr.Column())
+	var pqErr *pq.Error
+	require.ErrorAs(t, err, &pqErr)
+
+	other := &pq.Error{Code: "40001"}
+	require.Same(t, other, mapPQError(other))
+	plain := errors.New("boom")
+	require.Same(t, plain, mapPQError(plain))
+}
+
+func TestIsTransient(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{&pq.Error{Code: "08006"}, true},
+		{&pq.Error{Code: "57P01"}, true},
+		{&pq.Error{Code: "57P03"}, true},
+		{&pq.Error{Code: "53300"}, true},
+		{&pq.Error{Code: "23505"}, false},
+		{&pq.Error{Code: "42P01"}, false},
+		{driver.ErrBadConn, true},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{io.ErrUnexpectedEOF, true},
+		{errors.New("boom"), false},
+	} {
+		require.Equal(t, tc.want, IsTransient(tc.err), "%v", tc.err)
+	}
+}
+
+func TestUserRepository_SetStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	id := uuid.MustParse("0b5c5c1e-7f4e-4a39-9d6c-6b1d2b0e9a10")
+	query := regexp.QuoteMeta(`UPDATE users SET status = $1, version = version + 1 WHERE uuid = $2 AND status <> $1`)
+	mock.ExpectQuery(query).
+		WithArgs("suspended", id.String()).
+		WillReturnRows(sqlmock.NewRows(userColumns).
+			AddRow(7, id.String(), "jdoe", "jdoe@example.com", "John Doe", "", false, "suspended", 2))
+	mock.ExpectQuery(query).
+		WithArgs("active", id.String()).
+		WillReturnRows(sqlmock.NewRows(userColumns))
+	repo := NewUserRepository(db)
+
+	user, err := repo.SetStatus(context.Background(), id, "suspended")
+	require.NoError(t, err)
+	require.Equal(t, "suspended", user.Status)
+	require.Equal(t, 2, user.Version)
+
+	missing, err := repo.SetStatus(context.Background(), id, "active")
+	require.NoError(t, err)
+	require.Nil(t, missing)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_TakenFields(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE username = $1 OR lower(email) = lower($2) OR ($3 <> '' AND full_name = $3)`)).
+		WithArgs("jdoe", "JDoe@example.com", "John Doe").
+		WillReturnRows(sqlmock.NewRows([]string{"username_taken", "email_taken", "full_name_taken"}).AddRow(false, true, true))
+
+	taken, err := NewUserRepository(db).TakenFields(context.Background(), "jdoe", "JDoe@example.com", "John Doe")
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"email", "full_name"}, taken)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_Stats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	// the counts run concurrently, so they may arrive in any order
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM users`) + `$`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE created_at >= CURRENT_DATE`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE status = $1`)).
+		WithArgs(model.UserStatusSuspended).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	stats, err := NewUserRepository(db).Stats(context.Background())
+
+	require.NoError(t, err)
+	require.Equal(t, &model.UserStats{Total: 12, CreatedToday: 3, Suspended: 1}, stats)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
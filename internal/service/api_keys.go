@@ -5,27 +5,76 @@ import (
 	"cruder/internal/model"
 	"cruder/internal/repository"
 	"cruder/pkg/logger"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var (
 	ErrAPIKeyMissing = errors.New("api key missing")
 	ErrAPIKeyInvalid = errors.New("api key invalid")
+	ErrAPIKeyExpired = errors.New("api key expired")
+
+	ErrAPIKeyNotFound     = errors.New("api key not found")
+	ErrInvalidAPIKeyInput = errors.New("invalid api key input")
+	ErrAPIKeyLabelTaken   = errors.New("client already has an api key with this label")
 )
 
+const apiKeyRandomBytes = 32
+
 type APIKeyService interface {
+	// Validate returns the stored key apiKey matched. A client may hold
+	// several keys, so callers that care which one was used read its Label.
 	Validate(ctx context.Context, apiKey string) (*model.APIKey, error)
+	// Create grants scopes, or model.ScopeAll when none are given. Labels are
+	// unique per client; reusing one fails with ErrAPIKeyLabelTaken.
+	Create(ctx context.Context, clientName, label string, expiresAt *time.Time, scopes []string) (*model.APIKey, string, error)
+	// List pages through keys whose client name contains client; a limit <= 0
+	// returns every match.
+	List(ctx context.Context, client string, limit, offset int) ([]model.APIKey, error)
+	Revoke(ctx context.Context, id int64) error
+	// Invalidate drops apiKey from the cache, so the next request re-reads
+	// it. InvalidateHash does the same given the stored hash, and
+	// InvalidateAll empties the cache.
+	Invalidate(apiKey string)
+	InvalidateHash(hash string)
+	InvalidateAll()
+	// Close stops background cache maintenance.
+	Close() error
+}
+
+type APIKeyServiceOptions struct {
+	// CacheTTL bounds how long a validated key is served from memory.
+	CacheTTL time.Duration
+	// RefreshInterval, when positive, re-reads every cached key from the
+	// repository on this period so row changes land before the entry expires.
+	// Entries used since the previous pass also get a fresh TTL, keeping hot
+	// keys cached without a request ever paying for the lookup.
+	RefreshInterval time.Duration
+	// SweepInterval is how often expired entries are deleted from the cache.
+	// Lookups skip them anyway, but without a sweep keys that are never seen
+	// again would stay in memory. Zero means the cache TTL.
+	SweepInterval time.Duration
+	// StaleOnError is how long past its TTL a cached key is still accepted
+	// when the repository lookup fails with a transient error, so clients
+	// seen recently keep working through a database blip. Keys not in the
+	// cache still fail. Zero always fails closed.
+	StaleOnError time.Duration
 }
 
 type cacheEntry struct {
 	key     *model.APIKey
 	expires time.Time
+	// used is shared by copies of the entry and set on every cache hit.
+	used *atomic.Bool
 }
 
 type apiKeyService struct {
@@ -35,55 +84,306 @@ type apiKeyService struct {
 	mu    sync.RWMutex
 	cache map[string]cacheEntry
 	ttl   time.Duration
+	stale time.Duration
+	now   func() time.Time
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 func NewAPIKeyService(repo repository.APIKeyRepository, ttl time.Duration) APIKeyService {
+	return NewAPIKeyServiceWithOptions(repo, APIKeyServiceOptions{CacheTTL: ttl})
+}
+
+func NewAPIKeyServiceWithOptions(repo repository.APIKeyRepository, opts APIKeyServiceOptions) APIKeyService {
+	return NewAPIKeyServiceWithLogger(repo, opts, logger.Get())
+}
+
+// NewAPIKeyServiceWithLogger is NewAPIKeyServiceWithOptions logging to log
+// instead of the global logger. A nil log falls back to the global logger.
+func NewAPIKeyServiceWithLogger(repo repository.APIKeyRepository, opts APIKeyServiceOptions, log *logger.Logger) APIKeyService {
+	ttl := opts.CacheTTL
 	if ttl <= 0 {
 		ttl = 5 * time.Minute
 	}
-	serviceLogger := logger.Get().With(slog.String("component", "service.api_key"))
-	return &apiKeyService{
+	serviceLogger := logger.OrGlobal(log).With(slog.String("component", "service.api_key"))
+	s := &apiKeyService{
 		repo:  repo,
 		log:   serviceLogger,
 		cache: make(map[string]cacheEntry),
 		ttl:   ttl,
+		stale: max(opts.StaleOnError, 0),
+		now:   time.Now,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
 	}
+	sweep := opts.SweepInterval
+	if sweep <= 0 {
+		sweep = ttl
+	}
+	go s.maintain(opts.RefreshInterval, sweep)
+	return s
 }
 
 func (s *apiKeyService) Validate(ctx context.Context, apiKey string) (*model.APIKey, error) {
 	apiKey = strings.TrimSpace(apiKey)
 	if apiKey == "" {
-		s.log.Warn("missing api key")
+		s.log.WarnContext(ctx, "missing api key")
 		return nil, ErrAPIKeyMissing
 	}
 
 	hash := hashAPIKey(apiKey)
 
 	if entry, ok := s.getCached(hash); ok {
+		entry.used.Store(true)
 		return entry.key, nil
 	}
 
 	key, err := s.repo.GetByHash(ctx, hash)
 	if err != nil {
-		s.log.Error("failed to fetch api key", slog.String("error", err.Error()))
+		if repository.IsTransient(err) {
+			if entry, ok := s.getStale(hash); ok {
+				s.log.WarnContext(ctx, "serving stale api key: lookup failed",
+					slog.String("client_name", entry.key.ClientName),
+					slog.Time("api_key.cache_expired_at", entry.expires),
+					slog.String("error", err.Error()))
+				return entry.key, nil
+			}
+		}
+		s.log.ErrorContext(ctx, "failed to fetch api key", slog.String("error", err.Error()))
 		return nil, err
 	}
 
 	if key == nil {
-		s.log.Warn("invalid api key provided")
+		s.log.WarnContext(ctx, "invalid api key provided")
 		return nil, ErrAPIKeyInvalid
 	}
 
-	entry := cacheEntry{
-		key:     key,
-		expires: time.Now().Add(s.ttl),
+	now := s.now()
+	if key.Expired(now) {
+		s.log.WarnContext(ctx, "expired api key provided", slog.String("client_name", key.ClientName))
+		return nil, ErrAPIKeyExpired
 	}
-	s.setCache(hash, entry)
 
-	s.log.Debug("api key validated", slog.String("client_name", key.ClientName))
+	s.setCache(hash, s.newCacheEntry(key, now))
+
+	s.log.DebugContext(ctx, "api key validated", slog.String("client_name", key.ClientName))
 	return key, nil
 }
 
+// Create generates a new random key for clientName and stores only its hash.
+// The plaintext key is returned to the caller once and is never persisted.
+func (s *apiKeyService) Create(ctx context.Context, clientName, label string, expiresAt *time.Time, scopes []string) (*model.APIKey, string, error) {
+	clientName = strings.TrimSpace(clientName)
+	label = strings.TrimSpace(label)
+	if clientName == "" {
+		s.log.WarnContext(ctx, "create api key invalid input: missing client name")
+		return nil, "", ErrInvalidAPIKeyInput
+	}
+	if expiresAt != nil && !expiresAt.After(s.now()) {
+		s.log.WarnContext(ctx, "create api key invalid input: expiry in the past", slog.String("client_name", clientName))
+		return nil, "", ErrInvalidAPIKeyInput
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		s.log.WarnContext(ctx, "create api key invalid input: unknown scope", slog.String("client_name", clientName), slog.String("error", err.Error()))
+		return nil, "", err
+	}
+
+	plain, err := generateAPIKey()
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to generate api key", slog.String("error", err.Error()))
+		return nil, "", err
+	}
+
+	key, err := s.repo.Create(ctx, hashAPIKey(plain), clientName, label, expiresAt, scopes)
+	if err != nil {
+		if errors.Is(err, repository.ErrUniqueViolation) {
+			s.log.WarnContext(ctx, "create api key rejected: label in use", slog.String("client_name", clientName), slog.String("label", label))
+			return nil, "", ErrAPIKeyLabelTaken
+		}
+		s.log.ErrorContext(ctx, "failed to store api key", slog.String("client_name", clientName), slog.String("error", err.Error()))
+		return nil, "", err
+	}
+
+	s.log.InfoContext(ctx, "api key created", slog.Int("api_key.id", key.ID), slog.String("client_name", key.ClientName), slog.String("label", key.Label))
+	return key, plain, nil
+}
+
+func (s *apiKeyService) List(ctx context.Context, client string, limit, offset int) ([]model.APIKey, error) {
+	keys, err := s.repo.List(ctx, strings.TrimSpace(client), limit, offset)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to list api keys", slog.String("error", err.Error()))
+		return nil, err
+	}
+	if keys == nil {
+		return []model.APIKey{}, nil
+	}
+	return keys, nil
+}
+
+func (s *apiKeyService) Revoke(ctx context.Context, id int64) error {
+	if id <= 0 {
+		s.log.WarnContext(ctx, "revoke api key invalid id", slog.Int64("api_key.id", id))
+		return ErrInvalidAPIKeyInput
+	}
+
+	ok, err := s.repo.DeleteByID(ctx, id)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to revoke api key", slog.Int64("api_key.id", id), slog.String("error", err.Error()))
+		return err
+	}
+	if !ok {
+		s.log.WarnContext(ctx, "revoke api key target not found", slog.Int64("api_key.id", id))
+		return ErrAPIKeyNotFound
+	}
+
+	s.evictByID(id)
+	s.log.InfoContext(ctx, "api key revoked", slog.Int64("api_key.id", id))
+	return nil
+}
+
+func (s *apiKeyService) Invalidate(apiKey string) {
+	s.InvalidateHash(hashAPIKey(strings.TrimSpace(apiKey)))
+}
+
+func (s *apiKeyService) InvalidateHash(hash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.cache[hash]; ok {
+		delete(s.cache, hash)
+		s.log.Debug("cached api key invalidated")
+	}
+}
+
+func (s *apiKeyService) InvalidateAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.cache)
+}
+
+func (s *apiKeyService) Close() error {
+	s.closeOnce.Do(func() { close(s.stop) })
+	<-s.done
+	return nil
+}
+
+func (s *apiKeyService) newCacheEntry(key *model.APIKey, now time.Time) cacheEntry {
+	// never cache a key beyond its own expiry
+	expires := now.Add(s.ttl)
+	if key.ExpiresAt != nil && key.ExpiresAt.Before(expires) {
+		expires = *key.ExpiresAt
+	}
+	return cacheEntry{
+		key:     key,
+		expires: expires,
+		used:    &atomic.Bool{},
+	}
+}
+
+// maintain runs the cache sweep and, when refresh is positive, the
+// refresh-ahead pass until Close.
+func (s *apiKeyService) maintain(refresh, sweep time.Duration) {
+	defer close(s.done)
+	sweeper := time.NewTicker(sweep)
+	defer sweeper.Stop()
+	var refreshC <-chan time.Time
+	if refresh > 0 {
+		refresher := time.NewTicker(refresh)
+		defer refresher.Stop()
+		refreshC = refresher.C
+	}
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-sweeper.C:
+			s.sweepExpired()
+		case <-refreshC:
+			s.refreshCached(refresh)
+		}
+	}
+}
+
+// sweepExpired deletes every cache entry past its expiry and any
+// StaleOnError window.
+func (s *apiKeyService) sweepExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	removed := 0
+	for hash, entry := range s.cache {
+		if !now.Before(entry.expires.Add(s.stale)) {
+			delete(s.cache, hash)
+			removed++
+		}
+	}
+	if removed > 0 {
+		s.log.Debug("expired api keys swept from cache", slog.Int("count", removed), slog.Int("remaining", len(s.cache)))
+	}
+}
+
+// refreshCached re-reads every live cache entry from the repository. Keys
+// that disappeared or expired are evicted; the rest are replaced, with the
+// previous TTL kept unless the entry was used since the last pass.
+func (s *apiKeyService) refreshCached(timeout time.Duration) {
+	s.mu.RLock()
+	snapshot := make(map[string]cacheEntry, len(s.cache))
+	for hash, entry := range s.cache {
+		snapshot[hash] = entry
+	}
+	s.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for hash, old := range snapshot {
+		now := s.now()
+		if !now.Before(old.expires) {
+			// past its TTL the entry is only a StaleOnError fallback,
+			// which the sweep drops once that window ends
+			if !now.Before(old.expires.Add(s.stale)) {
+				s.evictIfCurrent(hash, old)
+			}
+			continue
+		}
+		key, err := s.repo.GetByHash(ctx, hash)
+		if err != nil {
+			// keep serving the cached entry until it expires
+			s.log.WarnContext(ctx, "failed to refresh cached api key", slog.String("error", err.Error()))
+			continue
+		}
+		if key == nil || key.Expired(now) {
+			s.evictIfCurrent(hash, old)
+			continue
+		}
+		entry := s.newCacheEntry(key, now)
+		if !old.used.Load() && old.expires.Before(entry.expires) {
+			entry.expires = old.expires
+		}
+		s.replaceIfCurrent(hash, old, entry)
+	}
+}
+
+// replaceIfCurrent swaps in a refreshed entry unless Validate or Revoke
+// changed the slot while the repository call was in flight.
+func (s *apiKeyService) replaceIfCurrent(hash string, old, entry cacheEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := s.cache[hash]; ok && current.used == old.used {
+		s.cache[hash] = entry
+	}
+}
+
+func (s *apiKeyService) evictIfCurrent(hash string, old cacheEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := s.cache[hash]; ok && current.used == old.used {
+		delete(s.cache, hash)
+	}
+}
+
 func (s *apiKeyService) getCached(hash string) (cacheEntry, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -92,8 +392,25 @@ func (s *apiKeyService) getCached(hash string) (cacheEntry, bool) {
 	if !ok {
 		return cacheEntry{}, false
 	}
-	if time.Now().After(entry.expires) {
-		// stale entry, drop on write path
+	if !s.now().Before(entry.expires) {
+		// stale entry, left for the sweep or the next setCache
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// getStale returns an entry past its TTL but inside the StaleOnError
+// window, provided the key itself has not expired.
+func (s *apiKeyService) getStale(hash string) (cacheEntry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.cache[hash]
+	if !ok || s.stale == 0 {
+		return cacheEntry{}, false
+	}
+	now := s.now()
+	if !now.Before(entry.expires.Add(s.stale)) || entry.key.Expired(now) {
 		return cacheEntry{}, false
 	}
 	return entry, true
@@ -102,13 +419,52 @@ func (s *apiKeyService) getCached(hash string) (cacheEntry, bool) {
 func (s *apiKeyService) setCache(hash string, entry cacheEntry) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if time.Now().After(entry.expires) {
+	if !s.now().Before(entry.expires) {
 		delete(s.cache, hash)
 		return
 	}
 	s.cache[hash] = entry
 }
 
+// evictByID drops cached entries for a revoked key so it stops working
+// immediately instead of after the cache TTL.
+func (s *apiKeyService) evictByID(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, entry := range s.cache {
+		if entry.key != nil && int64(entry.key.ID) == id {
+			delete(s.cache, hash)
+		}
+	}
+}
+
+// normalizeScopes trims and dedupes scopes, rejecting unknown ones. An empty
+// list grants model.ScopeAll, matching keys created before scopes existed.
+func normalizeScopes(scopes []string) ([]string, error) {
+	var out []string
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !slices.Contains(model.Scopes, scope) {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIKeyInput, scope)
+		}
+		if !slices.Contains(out, scope) {
+			out = append(out, scope)
+		}
+	}
+	if len(out) == 0 {
+		return []string{model.ScopeAll}, nil
+	}
+	return out, nil
+}
+
+func generateAPIKey() (string, error) {
+	buf := make([]byte, apiKeyRandomBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
 func hashAPIKey(value string) string {
 	sum := sha256.Sum256([]byte(value))
 	return hex.EncodeToString(sum[:])
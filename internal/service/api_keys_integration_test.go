@@ -0,0 +1,185 @@
+//go:build integration
+
+package service_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"cruder/internal/middleware"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/require"
+)
+
+const apiKeysBasePath = "/api/v1/apikeys"
+
+type createdAPIKeyResponse struct {
+	ID         int    `json:"id"`
+	ClientName string `json:"client_name"`
+	Label      string `json:"label"`
+	Key        string `json:"key"`
+}
+
+func TestFunctionalAPIKeyLifecycle(t *testing.T) {
+	// Given: an admin creates a new key
+	var created createdAPIKeyResponse
+	resp, err := adminClient().R().
+		SetBody(map[string]string{"client_name": "lifecycle_client"}).
+		SetResult(&created).
+		Post(apiBaseURL + apiKeysBasePath + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode())
+	require.NotEmpty(t, created.Key)
+	require.NotContains(t, resp.String(), "key_hash")
+
+	// When: the new key is used to call the API
+	resp, err = httpClient.R().
+		SetHeader(middleware.HeaderAPIKey, created.Key).
+		Get(apiBaseURL + usersBasePath + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// Then: the key is listed by client name
+	var listed []createdAPIKeyResponse
+	resp, err = adminClient().R().
+		SetResult(&listed).
+		Get(apiBaseURL + apiKeysBasePath + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	names := make([]string, 0, len(listed))
+	for _, key := range listed {
+		require.Empty(t, key.Key)
+		names = append(names, key.ClientName)
+	}
+	require.Contains(t, names, "lifecycle_client")
+
+	// And: revoking it stops it from working
+	resp, err = adminClient().R().
+		Delete(fmt.Sprintf("%s%s/%d", apiBaseURL, apiKeysBasePath, created.ID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode())
+
+	resp, err = httpClient.R().
+		SetHeader(middleware.HeaderAPIKey, created.Key).
+		Get(apiBaseURL + usersBasePath + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode())
+}
+
+func TestFunctionalAPIKeyScopes(t *testing.T) {
+	// Given: a key that may only read users
+	var created createdAPIKeyResponse
+	resp, err := adminClient().R().
+		SetBody(map[string]any{"client_name": "read_only_client", "scopes": []string{"users:read"}}).
+		SetResult(&created).
+		Post(apiBaseURL + apiKeysBasePath + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode())
+	t.Cleanup(func() {
+		_, _ = adminClient().R().Delete(fmt.Sprintf("%s%s/%d", apiBaseURL, apiKeysBasePath, created.ID))
+	})
+	client := resty.New().SetHeader(middleware.HeaderAPIKey, created.Key)
+
+	// When / Then: reads succeed and writes are forbidden
+	resp, err = client.R().Get(apiBaseURL + usersBasePath + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	resp, err = client.R().
+		SetBody(map[string]string{"username": "scoped_user", "email": "scoped@example.com", "full_name": "Scoped User"}).
+		Post(apiBaseURL + usersBasePath + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode())
+	require.Contains(t, resp.String(), "api key lacks scope users:write")
+}
+
+func TestFunctionalAPIKeys_RotateUnderNewLabel(t *testing.T) {
+	// Given: a client holding its current key
+	create := func(label string) (createdAPIKeyResponse, *resty.Response) {
+		var created createdAPIKeyResponse
+		resp, err := adminClient().R().
+			SetBody(map[string]string{"client_name": "rotating_client", "label": label}).
+			SetResult(&created).
+			Post(apiBaseURL + apiKeysBasePath + "/")
+		require.NoError(t, err)
+		if resp.StatusCode() == http.StatusCreated {
+			t.Cleanup(func() {
+				_, _ = adminClient().R().Delete(fmt.Sprintf("%s%s/%d", apiBaseURL, apiKeysBasePath, created.ID))
+			})
+		}
+		return created, resp
+	}
+	old, resp := create("2026-q3")
+	require.Equal(t, http.StatusCreated, resp.StatusCode())
+
+	// When: it gets a second key under a new label, then the old one is revoked
+	current, resp := create("2026-q4")
+	require.Equal(t, http.StatusCreated, resp.StatusCode())
+	require.Equal(t, "2026-q4", current.Label)
+	_, resp = create("2026-q4")
+	require.Equal(t, http.StatusConflict, resp.StatusCode())
+
+	resp, err := adminClient().R().Delete(fmt.Sprintf("%s%s/%d", apiBaseURL, apiKeysBasePath, old.ID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode())
+
+	// Then: only the new key keeps working
+	resp, err = resty.New().SetHeader(middleware.HeaderAPIKey, current.Key).R().Get(apiBaseURL + usersBasePath + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	resp, err = resty.New().SetHeader(middleware.HeaderAPIKey, old.Key).R().Get(apiBaseURL + usersBasePath + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode())
+}
+
+func TestFunctionalAPIKeys_ListFiltersByClient(t *testing.T) {
+	// Given: three partner keys and one unrelated key
+	for _, name := range []string{"partner-alpha", "Partner-Beta", "partner-gamma", "billing"} {
+		var created createdAPIKeyResponse
+		resp, err := adminClient().R().
+			SetBody(map[string]string{"client_name": name}).
+			SetResult(&created).
+			Post(apiBaseURL + apiKeysBasePath + "/")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, resp.StatusCode())
+		t.Cleanup(func() {
+			_, _ = adminClient().R().Delete(fmt.Sprintf("%s%s/%d", apiBaseURL, apiKeysBasePath, created.ID))
+		})
+	}
+
+	// When: listing the second page of partners, two per page
+	var listed []createdAPIKeyResponse
+	resp, err := adminClient().R().
+		SetResult(&listed).
+		Get(apiBaseURL + apiKeysBasePath + "/?client=PARTNER&page=2&per_page=2")
+
+	// Then: only the third partner is returned, without its hash
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Len(t, listed, 1)
+	require.Equal(t, "partner-gamma", listed[0].ClientName)
+	require.NotContains(t, resp.String(), "key_hash")
+}
+
+func TestFunctionalAPIKeys_RejectUnknownScope(t *testing.T) {
+	resp, err := adminClient().R().
+		SetBody(map[string]any{"client_name": "typo_client", "scopes": []string{"users:admin"}}).
+		Post(apiBaseURL + apiKeysBasePath + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+}
+
+func TestFunctionalAPIKeys_RequireAdminKey(t *testing.T) {
+	resp, err := restyClient().R().
+		Get(apiBaseURL + apiKeysBasePath + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode())
+}
+
+func adminClient() *resty.Client {
+	return resty.New().
+		SetHeader(middleware.HeaderAPIKey, testAPIKey).
+		SetHeader(middleware.HeaderAdminKey, testAdminKey)
+}
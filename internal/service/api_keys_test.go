@@ -1,8 +1,15 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"cruder/internal/model"
+	"cruder/internal/repository"
+	"cruder/pkg/logger"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"sync"
 	"testing"
 	"time"
 
@@ -37,9 +44,327 @@ func TestAPIKeyServiceValidate(t *testing.T) {
 	require.Equal(t, 1, repo.callCount(hashAPIKey("valid-key")))
 }
 
+func TestNewAPIKeyServiceWithLogger_LogsToInjectedLogger(t *testing.T) {
+	var out bytes.Buffer
+	svc := NewAPIKeyServiceWithLogger(newMockAPIKeyRepository(), APIKeyServiceOptions{},
+		logger.FromSlog(slog.New(slog.NewJSONHandler(&out, nil))))
+	t.Cleanup(func() { require.NoError(t, svc.Close()) })
+
+	_, err := svc.Validate(context.Background(), "")
+
+	require.ErrorIs(t, err, ErrAPIKeyMissing)
+	require.Contains(t, out.String(), `"msg":"missing api key","component":"service.api_key"`)
+}
+
+func TestAPIKeyServiceCreate(t *testing.T) {
+	repo := newMockAPIKeyRepository()
+	svc := NewAPIKeyService(repo, time.Minute)
+	ctx := context.Background()
+
+	_, _, err := svc.Create(ctx, "   ", "", nil, nil)
+	require.ErrorIs(t, err, ErrInvalidAPIKeyInput)
+
+	past := time.Now().Add(-time.Hour)
+	_, _, err = svc.Create(ctx, "Expired Client", "", &past, nil)
+	require.ErrorIs(t, err, ErrInvalidAPIKeyInput)
+
+	key, plain, err := svc.Create(ctx, "  New Client ", "", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "New Client", key.ClientName)
+	require.Len(t, plain, apiKeyRandomBytes*2)
+	require.Equal(t, hashAPIKey(plain), key.KeyHash, "only the hash is stored")
+
+	validated, err := svc.Validate(ctx, plain)
+	require.NoError(t, err)
+	require.Equal(t, key.ID, validated.ID)
+}
+
+func TestAPIKeyServiceCreate_LabelsPerClient(t *testing.T) {
+	// Given: a client with a prod key
+	repo := newMockAPIKeyRepository()
+	svc := NewAPIKeyService(repo, time.Minute)
+	ctx := context.Background()
+	prod, prodPlain, err := svc.Create(ctx, "Partner", " prod ", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "prod", prod.Label)
+
+	// When: it adds a staging key and tries to reuse the prod label
+	staging, stagingPlain, err := svc.Create(ctx, "Partner", "staging", nil, nil)
+	require.NoError(t, err)
+	_, _, err = svc.Create(ctx, "Partner", "prod", nil, nil)
+
+	// Then: both keys work, each identifying itself, and the reuse is refused
+	require.ErrorIs(t, err, ErrAPIKeyLabelTaken)
+	validated, err := svc.Validate(ctx, prodPlain)
+	require.NoError(t, err)
+	require.Equal(t, prod.ID, validated.ID)
+	validated, err = svc.Validate(ctx, stagingPlain)
+	require.NoError(t, err)
+	require.Equal(t, staging.ID, validated.ID)
+	require.Equal(t, "staging", validated.Label)
+}
+
+func TestAPIKeyServiceCreate_Scopes(t *testing.T) {
+	repo := newMockAPIKeyRepository()
+	svc := NewAPIKeyService(repo, time.Minute)
+	ctx := context.Background()
+
+	key, _, err := svc.Create(ctx, "Full", "", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{model.ScopeAll}, key.Scopes)
+
+	key, _, err = svc.Create(ctx, "Reader", "", nil, []string{" users:read", "users:read"})
+	require.NoError(t, err)
+	require.Equal(t, []string{model.ScopeUsersRead}, key.Scopes)
+
+	_, _, err = svc.Create(ctx, "Typo", "", nil, []string{"user:read"})
+	require.ErrorIs(t, err, ErrInvalidAPIKeyInput)
+	require.ErrorContains(t, err, `unknown scope "user:read"`)
+}
+
+func TestAPIKeyServiceList_TrimsClientFilter(t *testing.T) {
+	repo := newMockAPIKeyRepository()
+	svc := NewAPIKeyService(repo, time.Minute)
+
+	keys, err := svc.List(context.Background(), "  test ", 10, 20)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.Equal(t, listCall{client: "test", limit: 10, offset: 20}, repo.lastList)
+}
+
+func TestAPIKeyServiceRevoke(t *testing.T) {
+	repo := newMockAPIKeyRepository()
+	svc := NewAPIKeyService(repo, time.Minute)
+	ctx := context.Background()
+
+	require.ErrorIs(t, svc.Revoke(ctx, 0), ErrInvalidAPIKeyInput)
+	require.ErrorIs(t, svc.Revoke(ctx, 99), ErrAPIKeyNotFound)
+
+	// warm the cache, then revoke: the cached entry must not outlive the row
+	_, err := svc.Validate(ctx, "valid-key")
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Revoke(ctx, 1))
+
+	_, err = svc.Validate(ctx, "valid-key")
+	require.ErrorIs(t, err, ErrAPIKeyInvalid)
+}
+
+func TestAPIKeyServiceValidate_Expired(t *testing.T) {
+	repo := newMockAPIKeyRepository()
+	expiresAt := time.Now().Add(-time.Second)
+	repo.data[hashAPIKey("valid-key")].ExpiresAt = &expiresAt
+	svc := NewAPIKeyService(repo, time.Minute)
+
+	_, err := svc.Validate(context.Background(), "valid-key")
+	require.ErrorIs(t, err, ErrAPIKeyExpired)
+}
+
+func TestAPIKeyServiceValidate_ExpiresMidCacheWindow(t *testing.T) {
+	// Given: a key that expires 30s from now and a one-minute cache TTL
+	start := time.Now()
+	clock := start
+	repo := newMockAPIKeyRepository()
+	expiresAt := start.Add(30 * time.Second)
+	repo.data[hashAPIKey("valid-key")].ExpiresAt = &expiresAt
+	svc := NewAPIKeyService(repo, time.Minute).(*apiKeyService)
+	svc.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	_, err := svc.Validate(ctx, "valid-key")
+	require.NoError(t, err)
+
+	// When: still inside both windows, the cached entry is served
+	clock = start.Add(20 * time.Second)
+	_, err = svc.Validate(ctx, "valid-key")
+	require.NoError(t, err)
+	require.Equal(t, 1, repo.callCount(hashAPIKey("valid-key")))
+
+	// Then: once the key expires the cache no longer serves it
+	clock = start.Add(31 * time.Second)
+	_, err = svc.Validate(ctx, "valid-key")
+	require.ErrorIs(t, err, ErrAPIKeyExpired)
+	require.Equal(t, 2, repo.callCount(hashAPIKey("valid-key")))
+}
+
+func TestAPIKeyServiceValidate_StaleOnError(t *testing.T) {
+	// Given: a key cached for a minute and accepted for two more on errors
+	start := time.Now()
+	clock := start
+	repo := newMockAPIKeyRepository()
+	svc := NewAPIKeyServiceWithOptions(repo, APIKeyServiceOptions{CacheTTL: time.Minute, StaleOnError: 2 * time.Minute}).(*apiKeyService)
+	t.Cleanup(func() { require.NoError(t, svc.Close()) })
+	svc.now = func() time.Time { return clock }
+	ctx := context.Background()
+	_, err := svc.Validate(ctx, "valid-key")
+	require.NoError(t, err)
+
+	// When: the database drops connections after the TTL has passed
+	repo.mu.Lock()
+	repo.getErr = driver.ErrBadConn
+	repo.mu.Unlock()
+	clock = start.Add(90 * time.Second)
+
+	// Then: the cached key is still accepted, but an unknown one is not
+	key, err := svc.Validate(ctx, "valid-key")
+	require.NoError(t, err)
+	require.Equal(t, "Test Client", key.ClientName)
+	_, err = svc.Validate(ctx, "unknown-key")
+	require.ErrorIs(t, err, driver.ErrBadConn)
+
+	// And: past the stale window the key fails too
+	clock = start.Add(3 * time.Minute)
+	_, err = svc.Validate(ctx, "valid-key")
+	require.ErrorIs(t, err, driver.ErrBadConn)
+}
+
+func TestAPIKeyServiceValidate_StaleOnErrorNeedsTransientError(t *testing.T) {
+	start := time.Now()
+	clock := start
+	repo := newMockAPIKeyRepository()
+	svc := NewAPIKeyServiceWithOptions(repo, APIKeyServiceOptions{CacheTTL: time.Minute, StaleOnError: time.Hour}).(*apiKeyService)
+	t.Cleanup(func() { require.NoError(t, svc.Close()) })
+	svc.now = func() time.Time { return clock }
+	ctx := context.Background()
+	_, err := svc.Validate(ctx, "valid-key")
+	require.NoError(t, err)
+
+	// a query error, unlike a lost connection, is not papered over
+	repo.mu.Lock()
+	repo.getErr = errors.New("relation \"api_keys\" does not exist")
+	repo.mu.Unlock()
+	clock = start.Add(2 * time.Minute)
+	_, err = svc.Validate(ctx, "valid-key")
+	require.Error(t, err)
+}
+
+func TestAPIKeyServiceValidate_FailsClosedByDefault(t *testing.T) {
+	start := time.Now()
+	clock := start
+	repo := newMockAPIKeyRepository()
+	svc := NewAPIKeyService(repo, time.Minute).(*apiKeyService)
+	t.Cleanup(func() { require.NoError(t, svc.Close()) })
+	svc.now = func() time.Time { return clock }
+	ctx := context.Background()
+	_, err := svc.Validate(ctx, "valid-key")
+	require.NoError(t, err)
+
+	repo.mu.Lock()
+	repo.getErr = driver.ErrBadConn
+	repo.mu.Unlock()
+	clock = start.Add(61 * time.Second)
+	_, err = svc.Validate(ctx, "valid-key")
+	require.ErrorIs(t, err, driver.ErrBadConn)
+}
+
+func TestAPIKeyServiceRefreshAhead_PicksUpRowChanges(t *testing.T) {
+	// Given: a long cache TTL with a short refresh interval
+	repo := newMockAPIKeyRepository()
+	svc := NewAPIKeyServiceWithOptions(repo, APIKeyServiceOptions{
+		CacheTTL:        time.Hour,
+		RefreshInterval: 20 * time.Millisecond,
+	})
+	t.Cleanup(func() { require.NoError(t, svc.Close()) })
+	ctx := context.Background()
+
+	key, err := svc.Validate(ctx, "valid-key")
+	require.NoError(t, err)
+	require.Equal(t, "Test Client", key.ClientName)
+
+	// When: the backing row changes
+	expiresAt := time.Now().Add(48 * time.Hour).UTC()
+	repo.replace(hashAPIKey("valid-key"), func(key *model.APIKey) {
+		key.ClientName = "Renamed Client"
+		key.ExpiresAt = &expiresAt
+	})
+
+	// Then: the cached entry reflects the change within a few refresh intervals
+	require.Eventually(t, func() bool {
+		key, err := svc.Validate(ctx, "valid-key")
+		return err == nil && key.ClientName == "Renamed Client" && key.ExpiresAt != nil
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestAPIKeyServiceRefreshAhead_EvictsDeletedRows(t *testing.T) {
+	repo := newMockAPIKeyRepository()
+	svc := NewAPIKeyServiceWithOptions(repo, APIKeyServiceOptions{
+		CacheTTL:        time.Hour,
+		RefreshInterval: 20 * time.Millisecond,
+	}).(*apiKeyService)
+	t.Cleanup(func() { require.NoError(t, svc.Close()) })
+	ctx := context.Background()
+
+	_, err := svc.Validate(ctx, "valid-key")
+	require.NoError(t, err)
+
+	// deleted behind the service's back, e.g. by another instance
+	_, err = repo.DeleteByID(ctx, 1)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		_, ok := svc.getCached(hashAPIKey("valid-key"))
+		return !ok
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestAPIKeyServiceRefreshAhead_UnusedEntriesKeepTTL(t *testing.T) {
+	repo := newMockAPIKeyRepository()
+	svc := NewAPIKeyService(repo, time.Minute).(*apiKeyService)
+	ctx := context.Background()
+
+	_, err := svc.Validate(ctx, "valid-key")
+	require.NoError(t, err)
+	before, ok := svc.getCached(hashAPIKey("valid-key"))
+	require.True(t, ok)
+
+	// a refresh pass without an intervening hit keeps the original expiry
+	svc.refreshCached(time.Second)
+	after, ok := svc.getCached(hashAPIKey("valid-key"))
+	require.True(t, ok)
+	require.Equal(t, before.expires, after.expires)
+
+	// after a hit, the next pass extends it
+	time.Sleep(time.Millisecond)
+	_, err = svc.Validate(ctx, "valid-key")
+	require.NoError(t, err)
+	svc.refreshCached(time.Second)
+	extended, ok := svc.getCached(hashAPIKey("valid-key"))
+	require.True(t, ok)
+	require.True(t, extended.expires.After(before.expires))
+}
+
+func TestAPIKeyServiceSweep_DropsExpiredEntries(t *testing.T) {
+	// Given: a key cached with a short TTL and a frequent sweep
+	repo := newMockAPIKeyRepository()
+	svc := NewAPIKeyServiceWithOptions(repo, APIKeyServiceOptions{
+		CacheTTL:      30 * time.Millisecond,
+		SweepInterval: 10 * time.Millisecond,
+	}).(*apiKeyService)
+	t.Cleanup(func() { require.NoError(t, svc.Close()) })
+
+	_, err := svc.Validate(context.Background(), "valid-key")
+	require.NoError(t, err)
+
+	// Then: the entry leaves the map without the key being presented again
+	require.Eventually(t, func() bool {
+		svc.mu.RLock()
+		defer svc.mu.RUnlock()
+		return len(svc.cache) == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
 type mockAPIKeyRepository struct {
-	data  map[string]*model.APIKey
-	calls map[string]int
+	mu       sync.Mutex
+	data     map[string]*model.APIKey
+	calls    map[string]int
+	lastList listCall
+	// getErr, when set, fails every GetByHash.
+	getErr error
+}
+
+type listCall struct {
+	client        string
+	limit, offset int
 }
 
 func newMockAPIKeyRepository() *mockAPIKeyRepository {
@@ -59,7 +384,12 @@ func newMockAPIKeyRepository() *mockAPIKeyRepository {
 }
 
 func (m *mockAPIKeyRepository) GetByHash(_ context.Context, hash string) (*model.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.calls[hash]++
+	if m.getErr != nil {
+		return nil, m.getErr
+	}
 	key, ok := m.data[hash]
 	if !ok {
 		return nil, nil
@@ -67,6 +397,106 @@ func (m *mockAPIKeyRepository) GetByHash(_ context.Context, hash string) (*model
 	return key, nil
 }
 
+func (m *mockAPIKeyRepository) Create(_ context.Context, hash, clientName, label string, expiresAt *time.Time, scopes []string) (*model.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.data {
+		if existing.ClientName == clientName && existing.Label == label {
+			return nil, repository.ErrUniqueViolation
+		}
+	}
+	key := &model.APIKey{
+		ID:         len(m.data) + 1,
+		KeyHash:    hash,
+		ClientName: clientName,
+		Label:      label,
+		Scopes:     scopes,
+		ExpiresAt:  expiresAt,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	m.data[hash] = key
+	return key, nil
+}
+
+func (m *mockAPIKeyRepository) List(_ context.Context, client string, limit, offset int) ([]model.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastList = listCall{client: client, limit: limit, offset: offset}
+	keys := make([]model.APIKey, 0, len(m.data))
+	for _, key := range m.data {
+		keys = append(keys, *key)
+	}
+	return keys, nil
+}
+
+func (m *mockAPIKeyRepository) DeleteByID(_ context.Context, id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for hash, key := range m.data {
+		if int64(key.ID) == id {
+			delete(m.data, hash)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (m *mockAPIKeyRepository) callCount(hash string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	return m.calls[hash]
 }
+
+// replace swaps in a copy of the stored row, as a real database read would return.
+func (m *mockAPIKeyRepository) replace(hash string, update func(key *model.APIKey)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := *m.data[hash]
+	update(&key)
+	m.data[hash] = &key
+}
+
+func TestAPIKeyServiceInvalidate(t *testing.T) {
+	// Given: a cached key that is then deleted behind the service's back
+	repo := newMockAPIKeyRepository()
+	svc := NewAPIKeyServiceWithOptions(repo, APIKeyServiceOptions{CacheTTL: time.Hour})
+	t.Cleanup(func() { require.NoError(t, svc.Close()) })
+	ctx := context.Background()
+	_, err := svc.Validate(ctx, "valid-key")
+	require.NoError(t, err)
+	_, err = repo.DeleteByID(ctx, 1)
+	require.NoError(t, err)
+	_, err = svc.Validate(ctx, "valid-key")
+	require.NoError(t, err, "still served from the cache")
+
+	// When: the key is invalidated
+	svc.Invalidate(" valid-key ")
+
+	// Then: the next request re-reads it and is rejected
+	_, err = svc.Validate(ctx, "valid-key")
+	require.ErrorIs(t, err, ErrAPIKeyInvalid)
+}
+
+func TestAPIKeyServiceInvalidateHashAndAll(t *testing.T) {
+	repo := newMockAPIKeyRepository()
+	svc := NewAPIKeyServiceWithOptions(repo, APIKeyServiceOptions{CacheTTL: time.Hour}).(*apiKeyService)
+	t.Cleanup(func() { require.NoError(t, svc.Close()) })
+	_, err := svc.Validate(context.Background(), "valid-key")
+	require.NoError(t, err)
+	hash := hashAPIKey("valid-key")
+
+	svc.InvalidateHash("unrelated")
+	_, ok := svc.getCached(hash)
+	require.True(t, ok)
+
+	svc.InvalidateHash(hash)
+	_, ok = svc.getCached(hash)
+	require.False(t, ok)
+
+	_, err = svc.Validate(context.Background(), "valid-key")
+	require.NoError(t, err)
+	svc.InvalidateAll()
+	_, ok = svc.getCached(hash)
+	require.False(t, ok)
+}
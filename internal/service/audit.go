@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"cruder/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// Audit actions recorded for user mutations.
+const (
+	AuditActionCreate = "user.create"
+	AuditActionUpdate = "user.update"
+	AuditActionDelete = "user.delete"
+	// AuditActionVerifyEmail records a redeemed email verification token.
+	AuditActionVerifyEmail = "user.verify_email"
+	// AuditActionSetStatus records a user being suspended or reactivated.
+	AuditActionSetStatus = "user.set_status"
+)
+
+// ErrAuditUnavailable is returned by History when the service was built
+// without UserServiceOptions.Audit.
+var ErrAuditUnavailable = errors.New("audit log is not configured")
+
+// auditTimeout bounds the audit insert, which no longer follows the request's
+// deadline.
+const auditTimeout = 5 * time.Second
+
+// unknownActor is recorded for mutations whose context names no actor.
+const unknownActor = "unknown"
+
+type actorKey struct{}
+
+// ContextWithActor returns a copy of ctx naming actor as the caller that
+// mutations made with it are attributed to in the audit log.
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by ContextWithActor, or "" when none
+// was set.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// recordAudit appends a committed mutation to the audit log. It never fails
+// the mutation: the change is already saved, so an audit error is logged and
+// the caller still gets its response. The insert is detached from ctx, so a
+// client that disconnects or a request that times out right after the commit
+// doesn't cancel it and leave the change unaudited.
+func (s *userService) recordAudit(ctx context.Context, action string, before, after *model.User) {
+	if s.audit == nil {
+		return
+	}
+	actor := ActorFromContext(ctx)
+	if actor == "" {
+		actor = unknownActor
+	}
+	subject := after
+	if subject == nil {
+		subject = before
+	}
+	entry := &model.AuditEntry{
+		Actor:    actor,
+		Action:   action,
+		UserUUID: subject.UUID,
+		Before:   model.NewAuditFields(before),
+		After:    model.NewAuditFields(after),
+	}
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditTimeout)
+	defer cancel()
+	if err := s.audit.Record(recordCtx, entry); err != nil {
+		s.log.ErrorContext(ctx, "failed to record audit entry",
+			slog.String("audit.action", action),
+			slog.String("audit.actor", actor),
+			slog.String("user.uuid", subject.UUID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// History returns up to limit audit entries for the user with uuid, oldest
+// first, after the entry with id afterID. History outlives the user, so a
+// deleted user's entries are still returned; ErrUserNotFound means the user
+// does not exist and never had any.
+func (s *userService) History(ctx context.Context, uuid uuid.UUID, afterID int64, limit int) ([]model.AuditEntry, error) {
+	if s.audit == nil {
+		s.log.ErrorContext(ctx, "history failed: no audit repository configured")
+		return nil, ErrAuditUnavailable
+	}
+	if afterID < 0 || limit <= 0 {
+		s.log.WarnContext(ctx, "history invalid page", slog.Int64("page.after", afterID), slog.Int("page.limit", limit))
+		return nil, ErrInvalidUserInput
+	}
+
+	entries, err := s.audit.ListForUser(ctx, uuid, afterID, limit)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to list audit entries", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
+		return nil, err
+	}
+	if len(entries) == 0 && afterID == 0 {
+		// users created before the audit log existed have no entries yet
+		exists, err := s.repo.ExistsByUUID(ctx, uuid)
+		if err != nil {
+			s.log.ErrorContext(ctx, "failed to check user for history", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
+			return nil, err
+		}
+		if !exists {
+			s.log.WarnContext(ctx, "history target not found", slog.String("user.uuid", uuid.String()))
+			recordUserOutcome(outcomeNotFound)
+			return nil, ErrUserNotFound
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,120 @@
+//go:build integration
+
+package service_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"cruder/internal/model"
+	"cruder/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunctionalAuditLog(t *testing.T) {
+	resetUsersTable(t)
+
+	// Given: a user created, renamed, and deleted through the API
+	created := createUser(t, "audited", "audited@example.com", "Audited User")
+	resp, err := restyClient().R().
+		SetBody(map[string]any{"full_name": "Renamed User", "version": created.Version}).
+		Patch(fmt.Sprintf("%s%s/uuid/%s", apiBaseURL, usersBasePath, created.UUID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	resp, err = restyClient().R().
+		Delete(fmt.Sprintf("%s%s/uuid/%s", apiBaseURL, usersBasePath, created.UUID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode())
+
+	// When: reading the audit log for that user
+	rows, err := testDB.Query(`SELECT actor, action, before, after FROM audit_log WHERE user_uuid = $1 ORDER BY id`, created.UUID)
+	require.NoError(t, err)
+	defer rows.Close()
+	var actions []string
+	var entries []struct{ before, after *model.AuditFields }
+	for rows.Next() {
+		var actor, action string
+		var before, after []byte
+		require.NoError(t, rows.Scan(&actor, &action, &before, &after))
+		require.Equal(t, "integration-test-client", actor)
+		actions = append(actions, action)
+		entries = append(entries, struct{ before, after *model.AuditFields }{decodeAuditFields(t, before), decodeAuditFields(t, after)})
+	}
+	require.NoError(t, rows.Err())
+
+	// Then: each mutation was recorded by the acting client, in order
+	require.Equal(t, []string{service.AuditActionCreate, service.AuditActionUpdate, service.AuditActionDelete}, actions)
+	require.Nil(t, entries[0].before)
+	require.Equal(t, "Audited User", entries[1].before.FullName)
+	require.Equal(t, "Renamed User", entries[1].after.FullName)
+	require.Equal(t, "Renamed User", entries[2].before.FullName)
+	require.Nil(t, entries[2].after)
+
+	// And: the log cannot be rewritten
+	_, err = testDB.Exec(`DELETE FROM audit_log WHERE user_uuid = $1`, created.UUID)
+	require.ErrorContains(t, err, "append-only")
+}
+
+func TestFunctionalUserHistory(t *testing.T) {
+	resetUsersTable(t)
+	created := createUser(t, "history", "history@example.com", "History User")
+	userURL := fmt.Sprintf("%s%s/uuid/%s", apiBaseURL, usersBasePath, created.UUID)
+	resp, err := restyClient().R().
+		SetBody(map[string]any{"email": "moved@example.com", "version": created.Version}).
+		Patch(userURL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	resp, err = restyClient().R().Delete(userURL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode())
+
+	// When: an admin pages through the deleted user's history
+	type page struct {
+		Entries []struct {
+			Action  string   `json:"action"`
+			Actor   string   `json:"actor"`
+			Changed []string `json:"changed"`
+		} `json:"entries"`
+		NextCursor *int64 `json:"next_cursor"`
+	}
+	var first, rest page
+	resp, err = adminClient().R().SetResult(&first).Get(userURL + "/history?limit=2")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.NotNil(t, first.NextCursor)
+	resp, err = adminClient().R().SetResult(&rest).Get(fmt.Sprintf("%s/history?after=%d&limit=2", userURL, *first.NextCursor))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// Then: every change is listed in order, with what it touched
+	require.Len(t, first.Entries, 2)
+	require.Equal(t, service.AuditActionCreate, first.Entries[0].Action)
+	require.Equal(t, service.AuditActionUpdate, first.Entries[1].Action)
+	require.Equal(t, []string{"email"}, first.Entries[1].Changed)
+	require.Equal(t, "integration-test-client", first.Entries[1].Actor)
+	require.Len(t, rest.Entries, 1)
+	require.Equal(t, service.AuditActionDelete, rest.Entries[0].Action)
+	require.Nil(t, rest.NextCursor)
+
+	// And: a user that never existed is a 404, and the API key alone is not enough
+	resp, err = adminClient().R().Get(fmt.Sprintf("%s%s/uuid/%s/history", apiBaseURL, usersBasePath, uuid.New()))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode())
+	resp, err = restyClient().R().Get(userURL + "/history")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode())
+}
+
+func decodeAuditFields(t *testing.T, data []byte) *model.AuditFields {
+	t.Helper()
+	if data == nil {
+		return nil
+	}
+	var fields model.AuditFields
+	require.NoError(t, json.Unmarshal(data, &fields))
+	return &fields
+}
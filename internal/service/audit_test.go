@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"cruder/internal/model"
+	"cruder/internal/service/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingAuditRepository struct {
+	entries []model.AuditEntry
+	err     error
+}
+
+func (r *recordingAuditRepository) Record(ctx context.Context, entry *model.AuditEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.entries = append(r.entries, *entry)
+	return r.err
+}
+
+// ListForUser numbers entries from 1 in the order they were recorded, like
+// the id column does.
+func (r *recordingAuditRepository) ListForUser(_ context.Context, userUUID uuid.UUID, afterID int64, limit int) ([]model.AuditEntry, error) {
+	var entries []model.AuditEntry
+	for i, e := range r.entries {
+		e.ID = int64(i + 1)
+		if e.UserUUID == userUUID.String() && e.ID > afterID && len(entries) < limit {
+			entries = append(entries, e)
+		}
+	}
+	return entries, r.err
+}
+
+func TestUserService_AuditsUpdateWithBeforeAndAfter(t *testing.T) {
+	// Given: a service with an audit log and an existing user
+	repo := mocks.NewUserRepositoryMock(t)
+	audit := &recordingAuditRepository{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Audit: audit})
+	id := uuid.New()
+	existing := &model.User{ID: 1, UUID: id.String(), Username: "jdoe", Email: "jdoe@example.com", FullName: "John Doe"}
+	updated := &model.User{ID: 1, UUID: id.String(), Username: "jdoe", Email: "jdoe@example.com", FullName: "Jane Doe"}
+	repo.On("GetByUUID", mock.Anything, id).Return(existing, nil).Once()
+	repo.On("UpdateByUUID", mock.Anything, id, "jdoe", "jdoe@example.com", "Jane Doe", "", 0).Return(updated, nil).Once()
+
+	// When: an authenticated client updates the full name
+	fullName := "Jane Doe"
+	ctx := ContextWithActor(context.Background(), "billing")
+	_, err := service.UpdateByUUID(ctx, id, UpdateUserInput{Version: intPtr(0), FullName: &fullName})
+
+	// Then: the audit entry names the client and both versions of the fields
+	require.NoError(t, err)
+	require.Equal(t, []model.AuditEntry{{
+		Actor:    "billing",
+		Action:   AuditActionUpdate,
+		UserUUID: id.String(),
+		Before:   &model.AuditFields{Username: "jdoe", Email: "jdoe@example.com", FullName: "John Doe"},
+		After:    &model.AuditFields{Username: "jdoe", Email: "jdoe@example.com", FullName: "Jane Doe"},
+	}}, audit.entries)
+}
+
+func TestUserService_AuditsDeleteByID(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	audit := &recordingAuditRepository{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Audit: audit})
+	deleted := &model.User{ID: 7, UUID: uuid.NewString(), Username: "jdoe"}
+	repo.On("DeleteByID", mock.Anything, int64(7)).Return(deleted, nil).Once()
+
+	require.NoError(t, service.DeleteByID(context.Background(), 7))
+
+	require.Len(t, audit.entries, 1)
+	entry := audit.entries[0]
+	require.Equal(t, AuditActionDelete, entry.Action)
+	require.Equal(t, deleted.UUID, entry.UserUUID)
+	require.Equal(t, unknownActor, entry.Actor)
+	require.Equal(t, "jdoe", entry.Before.Username)
+	require.Nil(t, entry.After)
+}
+
+func TestUserService_AuditFailureDoesNotFailMutation(t *testing.T) {
+	// Given: an audit log that rejects every insert
+	repo := mocks.NewUserRepositoryMock(t)
+	audit := &recordingAuditRepository{err: errUnexpected}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Audit: audit})
+	repo.On("Create", mock.Anything, "jdoe", "jdoe@example.com", "John Doe", "").
+		Return(&model.User{ID: 1, UUID: uuid.NewString(), Username: "jdoe"}, nil).Once()
+
+	// When: creating a user
+	user, err := service.Create(context.Background(), "jdoe", "jdoe@example.com", "John Doe", "")
+
+	// Then: the user is still returned
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	require.Len(t, audit.entries, 1)
+}
+
+func TestUserService_AuditOutlivesCancelledRequest(t *testing.T) {
+	// Given: a request whose context is cancelled once the insert commits
+	repo := mocks.NewUserRepositoryMock(t)
+	audit := &recordingAuditRepository{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Audit: audit})
+	ctx, cancel := context.WithCancel(context.Background())
+	repo.On("Create", mock.Anything, "jdoe", "jdoe@example.com", "John Doe", "").
+		Run(func(mock.Arguments) { cancel() }).
+		Return(&model.User{ID: 1, UUID: uuid.NewString(), Username: "jdoe"}, nil).Once()
+
+	// When: creating a user
+	_, err := service.Create(ctx, "jdoe", "jdoe@example.com", "John Doe", "")
+
+	// Then: the committed create is still audited
+	require.NoError(t, err)
+	require.Len(t, audit.entries, 1)
+	require.Equal(t, AuditActionCreate, audit.entries[0].Action)
+}
+
+func TestUserService_FailedMutationIsNotAudited(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	audit := &recordingAuditRepository{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Audit: audit})
+	repo.On("DeleteByUUID", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(nil, nil).Once()
+
+	err := service.DeleteByUUID(context.Background(), uuid.New())
+
+	require.ErrorIs(t, err, ErrUserNotFound)
+	require.Empty(t, audit.entries)
+}
+
+func TestUserService_History(t *testing.T) {
+	// Given: a user who was created and then renamed
+	repo := mocks.NewUserRepositoryMock(t)
+	audit := &recordingAuditRepository{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Audit: audit})
+	id := uuid.New()
+	before := &model.User{ID: 1, UUID: id.String(), Username: "jdoe", Email: "jdoe@example.com"}
+	after := &model.User{ID: 1, UUID: id.String(), Username: "jdoe2", Email: "jdoe@example.com"}
+	service.(*userService).recordAudit(context.Background(), AuditActionCreate, nil, before)
+	service.(*userService).recordAudit(context.Background(), AuditActionCreate, nil, &model.User{UUID: uuid.NewString()})
+	service.(*userService).recordAudit(context.Background(), AuditActionUpdate, before, after)
+
+	// When: paging through their history one entry at a time
+	first, err := service.History(context.Background(), id, 0, 1)
+	require.NoError(t, err)
+	second, err := service.History(context.Background(), id, first[0].ID, 1)
+	require.NoError(t, err)
+	rest, err := service.History(context.Background(), id, second[0].ID, 1)
+	require.NoError(t, err)
+
+	// Then: the entries come oldest first and only for that user
+	require.Equal(t, AuditActionCreate, first[0].Action)
+	require.Equal(t, AuditActionUpdate, second[0].Action)
+	require.Equal(t, []string{"username"}, second[0].Changed())
+	require.Empty(t, rest)
+}
+
+func TestUserService_History_UnknownUser(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Audit: &recordingAuditRepository{}})
+	unknown, existing := uuid.New(), uuid.New()
+	repo.On("ExistsByUUID", mock.Anything, unknown).Return(false, nil).Once()
+	repo.On("ExistsByUUID", mock.Anything, existing).Return(true, nil).Once()
+
+	_, err := service.History(context.Background(), unknown, 0, 20)
+	require.ErrorIs(t, err, ErrUserNotFound)
+
+	// a user from before the audit log has an empty history, not a 404
+	entries, err := service.History(context.Background(), existing, 0, 20)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
@@ -0,0 +1,23 @@
+//go:build integration
+
+package service_test
+
+import (
+	"testing"
+
+	"cruder/internal/repository"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresConnection_Warmup(t *testing.T) {
+	// Given: a connection configured to pre-open idle connections
+	conn, err := repository.NewPostgresConnection(dsn, repository.ConnectionOptions{MinIdleConns: 4})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.DB().Close() })
+
+	// Then: the pool reports the warmed connections as idle
+	stats := conn.DB().Stats()
+	require.Equal(t, 4, stats.Idle)
+	require.Equal(t, 4, stats.OpenConnections)
+}
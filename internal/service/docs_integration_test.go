@@ -0,0 +1,45 @@
+//go:build integration
+
+package service_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"cruder/internal/middleware"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunctionalOpenAPISpec(t *testing.T) {
+	client := resty.New().SetBaseURL(apiBaseURL)
+
+	// When: fetching the spec without an API key
+	resp, err := client.R().Get("/openapi.json")
+
+	// Then: it describes the API key scheme clients must send
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	var spec struct {
+		Paths               map[string]any `json:"paths"`
+		SecurityDefinitions map[string]struct {
+			Type string `json:"type"`
+			Name string `json:"name"`
+			In   string `json:"in"`
+		} `json:"securityDefinitions"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body(), &spec))
+	require.Contains(t, spec.Paths, usersBasePath+"/")
+	scheme := spec.SecurityDefinitions["APIKeyAuth"]
+	require.Equal(t, "apiKey", scheme.Type)
+	require.Equal(t, middleware.HeaderAPIKey, scheme.Name)
+	require.Equal(t, "header", scheme.In)
+
+	// And: the Swagger UI is served alongside it
+	resp, err = client.R().Get("/docs/index.html")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Contains(t, resp.String(), "swagger-ui")
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"time"
+
+	"cruder/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// DefaultVerificationTTL is how long a verification token stays valid when
+// UserServiceOptions.VerificationTTL is unset.
+const DefaultVerificationTTL = 24 * time.Hour
+
+const verificationTokenBytes = 32
+
+var (
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+	// ErrVerificationTokenInvalid covers tokens that were never issued, were
+	// already used, or were issued for an email the user has since changed.
+	ErrVerificationTokenInvalid = errors.New("verification token invalid")
+	ErrVerificationTokenExpired = errors.New("verification token expired")
+	// ErrVerificationUnavailable is returned when the service was built
+	// without UserServiceOptions.Verifications.
+	ErrVerificationUnavailable = errors.New("email verification is not configured")
+)
+
+// EmailVerification is a freshly issued token. Token is the only copy of the
+// plaintext; the repository keeps its hash.
+type EmailVerification struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+func (s *userService) IssueEmailVerification(ctx context.Context, uuid uuid.UUID) (*EmailVerification, error) {
+	if s.readOnly.Enabled() {
+		s.log.WarnContext(ctx, "issue email verification rejected: read-only mode", slog.String("user.uuid", uuid.String()))
+		return nil, ErrReadOnly
+	}
+	if s.verifications == nil {
+		s.log.ErrorContext(ctx, "issue email verification failed: no token repository configured")
+		return nil, ErrVerificationUnavailable
+	}
+
+	user, err := s.primary.GetByUUID(ctx, uuid)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to fetch user for email verification", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
+		return nil, err
+	}
+	if user == nil {
+		s.log.WarnContext(ctx, "issue email verification target not found", slog.String("user.uuid", uuid.String()))
+		recordUserOutcome(outcomeNotFound)
+		return nil, ErrUserNotFound
+	}
+	if user.EmailVerified {
+		s.log.WarnContext(ctx, "issue email verification rejected: already verified", slog.String("user.uuid", uuid.String()))
+		return nil, ErrEmailAlreadyVerified
+	}
+
+	token, err := generateVerificationToken()
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to generate verification token", slog.String("error", err.Error()))
+		return nil, err
+	}
+	issued := &model.EmailVerificationToken{
+		TokenHash: hashVerificationToken(token),
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(s.verificationTTL()).UTC(),
+	}
+	if err := s.verifications.CreateToken(ctx, issued); err != nil {
+		s.log.ErrorContext(ctx, "failed to store verification token", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
+		return nil, err
+	}
+	s.log.InfoContext(ctx, "email verification issued", slog.String("user.uuid", user.UUID), slog.Time("verification.expires_at", issued.ExpiresAt))
+	return &EmailVerification{Token: token, ExpiresAt: issued.ExpiresAt}, nil
+}
+
+// VerifyEmail redeems token and marks its user's email as verified. The
+// token is consumed even when verification fails, so every token is good for
+// one attempt.
+func (s *userService) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	if s.readOnly.Enabled() {
+		s.log.WarnContext(ctx, "verify email rejected: read-only mode")
+		return nil, ErrReadOnly
+	}
+	if s.verifications == nil {
+		s.log.ErrorContext(ctx, "verify email failed: no token repository configured")
+		return nil, ErrVerificationUnavailable
+	}
+	if token == "" {
+		s.log.WarnContext(ctx, "verify email rejected: empty token")
+		return nil, ErrVerificationTokenInvalid
+	}
+
+	issued, err := s.verifications.TakeToken(ctx, hashVerificationToken(token))
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to take verification token", slog.String("error", err.Error()))
+		return nil, err
+	}
+	if issued == nil {
+		s.log.WarnContext(ctx, "verify email rejected: unknown token")
+		return nil, ErrVerificationTokenInvalid
+	}
+	if issued.Expired(time.Now()) {
+		s.log.WarnContext(ctx, "verify email rejected: token expired", slog.Int("user.id", issued.UserID), slog.Time("verification.expires_at", issued.ExpiresAt))
+		return nil, ErrVerificationTokenExpired
+	}
+
+	id := int64(issued.UserID)
+	existing, err := s.primary.GetByID(ctx, id)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to fetch user for verify email", slog.Int64("user.id", id), slog.String("error", err.Error()))
+		return nil, err
+	}
+	if existing == nil {
+		// deleting the user cascades to its tokens, so this only loses a race
+		s.log.WarnContext(ctx, "verify email target not found", slog.Int64("user.id", id))
+		return nil, ErrVerificationTokenInvalid
+	}
+	if existing.EmailVerified {
+		s.log.WarnContext(ctx, "verify email rejected: already verified", slog.String("user.uuid", existing.UUID))
+		return nil, ErrEmailAlreadyVerified
+	}
+
+	verified, err := s.repo.MarkEmailVerified(ctx, id, issued.Email)
+	if err != nil {
+		s.log.ErrorContext(ctx, "verify email repository error", slog.Int64("user.id", id), slog.String("error", err.Error()))
+		return nil, err
+	}
+	if verified == nil {
+		s.log.WarnContext(ctx, "verify email rejected: email changed since the token was issued", slog.String("user.uuid", existing.UUID))
+		return nil, ErrVerificationTokenInvalid
+	}
+	s.log.InfoContext(ctx, "user email verified", slog.String("user.uuid", verified.UUID))
+	s.committed(ctx, AuditActionVerifyEmail, existing, verified)
+	return verified, nil
+}
+
+func (s *userService) verificationTTL() time.Duration {
+	if s.opts.VerificationTTL > 0 {
+		return s.opts.VerificationTTL
+	}
+	return DefaultVerificationTTL
+}
+
+func generateVerificationToken() (string, error) {
+	buf := make([]byte, verificationTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func hashVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
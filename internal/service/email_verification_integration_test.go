@@ -0,0 +1,88 @@
+//go:build integration
+
+package service_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type emailVerificationResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func TestFunctionalEmailVerification(t *testing.T) {
+	resetUsersTable(t)
+	created := createUser(t, "verify_me", "verify@example.com", "Verify Me")
+	require.False(t, created.EmailVerified)
+	issueURL := fmt.Sprintf("%s%s/uuid/%s/verification", apiBaseURL, usersBasePath, created.UUID)
+	verifyURL := apiBaseURL + usersBasePath + "/verify"
+
+	// Given: an issued token
+	var issued emailVerificationResponse
+	resp, err := restyClient().R().SetResult(&issued).Post(issueURL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode())
+	require.NotEmpty(t, issued.Token)
+	require.True(t, issued.ExpiresAt.After(time.Now()))
+
+	// Then: only its hash is stored
+	var stored int
+	require.NoError(t, testDB.QueryRow(`SELECT COUNT(*) FROM email_verification_tokens WHERE token_hash = $1`, issued.Token).Scan(&stored))
+	require.Zero(t, stored)
+
+	// When: redeeming it
+	var verified userResponse
+	resp, err = restyClient().R().
+		SetBody(map[string]string{"token": issued.Token}).
+		SetResult(&verified).
+		Post(verifyURL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.True(t, verified.EmailVerified)
+
+	// Then: the token is spent and a verified user gets no new one
+	resp, err = restyClient().R().SetBody(map[string]string{"token": issued.Token}).Post(verifyURL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+	resp, err = restyClient().R().Post(issueURL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusConflict, resp.StatusCode())
+
+	// And: changing the email clears the flag
+	var updated userResponse
+	resp, err = restyClient().R().
+		SetBody(map[string]any{"email": "moved@example.com", "version": verified.Version}).
+		SetResult(&updated).
+		Patch(fmt.Sprintf("%s%s/uuid/%s", apiBaseURL, usersBasePath, created.UUID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.False(t, updated.EmailVerified)
+}
+
+func TestFunctionalEmailVerification_EmailChangedAfterIssue(t *testing.T) {
+	resetUsersTable(t)
+	created := createUser(t, "verify_moved", "before@example.com", "Verify Moved")
+	var issued emailVerificationResponse
+	resp, err := restyClient().R().SetResult(&issued).
+		Post(fmt.Sprintf("%s%s/uuid/%s/verification", apiBaseURL, usersBasePath, created.UUID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode())
+	resp, err = restyClient().R().
+		SetBody(map[string]any{"email": "after@example.com", "version": created.Version}).
+		Patch(fmt.Sprintf("%s%s/uuid/%s", apiBaseURL, usersBasePath, created.UUID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	resp, err = restyClient().R().
+		SetBody(map[string]string{"token": issued.Token}).
+		Post(apiBaseURL + usersBasePath + "/verify")
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+}
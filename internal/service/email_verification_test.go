@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cruder/internal/model"
+	"cruder/internal/service/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryVerifications keeps tokens by hash, like the real table.
+type memoryVerifications struct {
+	tokens map[string]model.EmailVerificationToken
+}
+
+func newMemoryVerifications() *memoryVerifications {
+	return &memoryVerifications{tokens: make(map[string]model.EmailVerificationToken)}
+}
+
+func (m *memoryVerifications) CreateToken(_ context.Context, token *model.EmailVerificationToken) error {
+	for hash, t := range m.tokens {
+		if t.UserID == token.UserID {
+			delete(m.tokens, hash)
+		}
+	}
+	m.tokens[token.TokenHash] = *token
+	return nil
+}
+
+func (m *memoryVerifications) TakeToken(_ context.Context, hash string) (*model.EmailVerificationToken, error) {
+	token, ok := m.tokens[hash]
+	if !ok {
+		return nil, nil
+	}
+	delete(m.tokens, hash)
+	return &token, nil
+}
+
+func TestUserService_IssueEmailVerification_StoresOnlyHash(t *testing.T) {
+	// Given: an unverified user
+	repo := mocks.NewUserRepositoryMock(t)
+	tokens := newMemoryVerifications()
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Verifications: tokens, VerificationTTL: time.Hour})
+	id := uuid.New()
+	repo.On("GetByUUID", mock.Anything, id).Return(&model.User{ID: 7, UUID: id.String(), Email: "jdoe@example.com"}, nil).Once()
+
+	// When: issuing a token
+	issued, err := service.IssueEmailVerification(context.Background(), id)
+
+	// Then: the caller gets the plaintext and the store only its hash
+	require.NoError(t, err)
+	require.Len(t, issued.Token, verificationTokenBytes*2)
+	require.WithinDuration(t, time.Now().Add(time.Hour), issued.ExpiresAt, time.Minute)
+	require.Len(t, tokens.tokens, 1)
+	stored, ok := tokens.tokens[hashVerificationToken(issued.Token)]
+	require.True(t, ok)
+	require.NotEqual(t, issued.Token, stored.TokenHash)
+	require.Equal(t, 7, stored.UserID)
+	require.Equal(t, "jdoe@example.com", stored.Email)
+}
+
+func TestUserService_IssueEmailVerification_AlreadyVerified(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Verifications: newMemoryVerifications()})
+	id := uuid.New()
+	repo.On("GetByUUID", mock.Anything, id).Return(&model.User{ID: 7, UUID: id.String(), EmailVerified: true}, nil).Once()
+
+	_, err := service.IssueEmailVerification(context.Background(), id)
+
+	require.ErrorIs(t, err, ErrEmailAlreadyVerified)
+}
+
+func TestUserService_VerifyEmail_Success(t *testing.T) {
+	// Given: an issued token
+	repo := mocks.NewUserRepositoryMock(t)
+	tokens := newMemoryVerifications()
+	audit := &recordingAuditRepository{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Verifications: tokens, Audit: audit})
+	id := uuid.New()
+	user := &model.User{ID: 7, UUID: id.String(), Username: "jdoe", Email: "jdoe@example.com"}
+	repo.On("GetByUUID", mock.Anything, id).Return(user, nil).Once()
+	issued, err := service.IssueEmailVerification(context.Background(), id)
+	require.NoError(t, err)
+	verified := *user
+	verified.EmailVerified = true
+	repo.On("GetByID", mock.Anything, int64(7)).Return(user, nil).Once()
+	repo.On("MarkEmailVerified", mock.Anything, int64(7), "jdoe@example.com").Return(&verified, nil).Once()
+
+	// When: redeeming it twice
+	got, err := service.VerifyEmail(context.Background(), issued.Token)
+	require.NoError(t, err)
+	_, again := service.VerifyEmail(context.Background(), issued.Token)
+
+	// Then: the first call verifies and is audited, the second finds no token
+	require.True(t, got.EmailVerified)
+	require.ErrorIs(t, again, ErrVerificationTokenInvalid)
+	require.Len(t, audit.entries, 1)
+	require.Equal(t, AuditActionVerifyEmail, audit.entries[0].Action)
+	require.False(t, audit.entries[0].Before.EmailVerified)
+	require.True(t, audit.entries[0].After.EmailVerified)
+}
+
+func TestUserService_VerifyEmail_Rejections(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   model.EmailVerificationToken
+		stored  *model.User
+		wantErr error
+	}{
+		{
+			name:    "expired",
+			token:   model.EmailVerificationToken{UserID: 7, Email: "jdoe@example.com", ExpiresAt: time.Now().Add(-time.Second)},
+			wantErr: ErrVerificationTokenExpired,
+		},
+		{
+			name:    "already verified",
+			token:   model.EmailVerificationToken{UserID: 7, Email: "jdoe@example.com", ExpiresAt: time.Now().Add(time.Hour)},
+			stored:  &model.User{ID: 7, Email: "jdoe@example.com", EmailVerified: true},
+			wantErr: ErrEmailAlreadyVerified,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewUserRepositoryMock(t)
+			tokens := newMemoryVerifications()
+			tt.token.TokenHash = hashVerificationToken("plaintext")
+			tokens.tokens[tt.token.TokenHash] = tt.token
+			if tt.stored != nil {
+				repo.On("GetByID", mock.Anything, int64(7)).Return(tt.stored, nil).Once()
+			}
+			service := NewUserServiceWithOptions(repo, UserServiceOptions{Verifications: tokens})
+
+			_, err := service.VerifyEmail(context.Background(), "plaintext")
+
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Empty(t, tokens.tokens, "a rejected token is still consumed")
+		})
+	}
+}
+
+func TestUserService_VerifyEmail_EmailChanged(t *testing.T) {
+	// Given: a token issued before the user changed their email
+	repo := mocks.NewUserRepositoryMock(t)
+	tokens := newMemoryVerifications()
+	hash := hashVerificationToken("plaintext")
+	tokens.tokens[hash] = model.EmailVerificationToken{TokenHash: hash, UserID: 7, Email: "old@example.com", ExpiresAt: time.Now().Add(time.Hour)}
+	repo.On("GetByID", mock.Anything, int64(7)).Return(&model.User{ID: 7, Email: "new@example.com"}, nil).Once()
+	repo.On("MarkEmailVerified", mock.Anything, int64(7), "old@example.com").Return(nil, nil).Once()
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Verifications: tokens})
+
+	// When: redeeming it
+	_, err := service.VerifyEmail(context.Background(), "plaintext")
+
+	// Then: the new address stays unverified
+	require.ErrorIs(t, err, ErrVerificationTokenInvalid)
+}
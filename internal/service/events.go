@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"cruder/internal/model"
+)
+
+// ErrEventBufferFull is returned by ChannelPublisher.Publish when no
+// subscriber has drained the buffer in time.
+var ErrEventBufferFull = errors.New("event buffer is full")
+
+// UserEvent describes a committed user mutation.
+type UserEvent struct {
+	// Action is one of the AuditAction constants.
+	Action     string    `json:"action"`
+	UserUUID   string    `json:"user_uuid"`
+	OccurredAt time.Time `json:"occurred_at"`
+	// Actor is the client named by ContextWithActor, or "unknown".
+	Actor string `json:"actor"`
+	// RequestID is the X-Request-ID of the request that made the change, if
+	// it sent one.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying the id of the HTTP
+// request it serves, for events published while handling it.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the id set by ContextWithRequestID, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// EventPublisher is notified after every successful user mutation. Publish
+// errors are logged and never fail the mutation.
+type EventPublisher interface {
+	Publish(ctx context.Context, event UserEvent) error
+}
+
+// NoopPublisher discards every event; it is the default publisher.
+type NoopPublisher struct{}
+
+func (NoopPublisher) Publish(context.Context, UserEvent) error { return nil }
+
+// ChannelPublisher hands events to in-process subscribers through a buffered
+// channel. Publish never blocks the mutation: once the buffer is full, events
+// are dropped with ErrEventBufferFull.
+type ChannelPublisher struct {
+	mu     sync.RWMutex
+	events chan UserEvent
+	closed bool
+}
+
+func NewChannelPublisher(buffer int) *ChannelPublisher {
+	return &ChannelPublisher{events: make(chan UserEvent, buffer)}
+}
+
+// Events returns the channel subscribers read from. It is closed by Close.
+func (p *ChannelPublisher) Events() <-chan UserEvent {
+	return p.events
+}
+
+func (p *ChannelPublisher) Publish(_ context.Context, event UserEvent) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return nil
+	}
+	select {
+	case p.events <- event:
+		return nil
+	default:
+		return ErrEventBufferFull
+	}
+}
+
+// Close closes the Events channel; later events are discarded.
+func (p *ChannelPublisher) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		p.closed = true
+		close(p.events)
+	}
+}
+
+// committed runs the side effects of a saved mutation: the audit entry and
+// the lifecycle event.
+func (s *userService) committed(ctx context.Context, action string, before, after *model.User) {
+	s.recordAudit(ctx, action, before, after)
+	s.publishEvent(ctx, action, before, after)
+}
+
+func (s *userService) publishEvent(ctx context.Context, action string, before, after *model.User) {
+	subject := after
+	if subject == nil {
+		subject = before
+	}
+	actor := ActorFromContext(ctx)
+	if actor == "" {
+		actor = unknownActor
+	}
+	event := UserEvent{
+		Action:     action,
+		UserUUID:   subject.UUID,
+		OccurredAt: time.Now().UTC(),
+		Actor:      actor,
+		RequestID:  RequestIDFromContext(ctx),
+	}
+	if err := s.events.Publish(ctx, event); err != nil {
+		s.log.ErrorContext(ctx, "failed to publish user event",
+			slog.String("event.action", action),
+			slog.String("user.uuid", subject.UUID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
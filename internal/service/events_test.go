@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cruder/internal/model"
+	"cruder/internal/service/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// stubPublisher records published events and fails with err when set.
+type stubPublisher struct {
+	events []UserEvent
+	err    error
+}
+
+func (p *stubPublisher) Publish(_ context.Context, event UserEvent) error {
+	p.events = append(p.events, event)
+	return p.err
+}
+
+func TestUserService_PublishesCreateEvent(t *testing.T) {
+	// Given: a service with an event publisher
+	repo := mocks.NewUserRepositoryMock(t)
+	events := &stubPublisher{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Events: events})
+	created := &model.User{ID: 1, UUID: uuid.NewString(), Username: "jdoe"}
+	repo.On("Create", mock.Anything, "jdoe", "jdoe@example.com", "John Doe", "").Return(created, nil).Once()
+
+	// When: an authenticated client creates a user
+	before := time.Now().UTC()
+	ctx := ContextWithActor(context.Background(), "billing")
+	_, err := service.Create(ctx, "jdoe", "jdoe@example.com", "John Doe", "")
+
+	// Then: one event names the action, user and client
+	require.NoError(t, err)
+	require.Len(t, events.events, 1)
+	event := events.events[0]
+	require.Equal(t, AuditActionCreate, event.Action)
+	require.Equal(t, created.UUID, event.UserUUID)
+	require.Equal(t, "billing", event.Actor)
+	require.False(t, event.OccurredAt.Before(before))
+}
+
+func TestUserService_PublishesDeleteEventPerBulkDeletedUser(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	events := &stubPublisher{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Events: events})
+	first, second := uuid.New(), uuid.New()
+	repo.On("DeleteManyByUUID", mock.Anything, []uuid.UUID{first, second}).
+		Return([]model.User{{ID: 1, UUID: first.String()}, {ID: 2, UUID: second.String()}}, nil).Once()
+
+	_, err := service.DeleteManyByUUID(context.Background(), []uuid.UUID{first, second})
+
+	require.NoError(t, err)
+	require.Len(t, events.events, 2)
+	for i, id := range []uuid.UUID{first, second} {
+		require.Equal(t, AuditActionDelete, events.events[i].Action)
+		require.Equal(t, id.String(), events.events[i].UserUUID)
+		require.Equal(t, unknownActor, events.events[i].Actor)
+	}
+}
+
+func TestUserService_PublishFailureDoesNotFailMutation(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	events := &stubPublisher{err: errUnexpected}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Events: events})
+	repo.On("DeleteByID", mock.Anything, int64(7)).Return(&model.User{ID: 7, UUID: uuid.NewString()}, nil).Once()
+
+	require.NoError(t, service.DeleteByID(context.Background(), 7))
+	require.Len(t, events.events, 1)
+}
+
+func TestUserService_FailedMutationPublishesNothing(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	events := &stubPublisher{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Events: events})
+	repo.On("DeleteByID", mock.Anything, int64(7)).Return(nil, nil).Once()
+
+	require.ErrorIs(t, service.DeleteByID(context.Background(), 7), ErrUserNotFound)
+	require.Empty(t, events.events)
+}
+
+func TestChannelPublisher_DropsWhenFull(t *testing.T) {
+	// Given: a publisher with room for one event
+	publisher := NewChannelPublisher(1)
+	first := UserEvent{Action: AuditActionCreate, UserUUID: uuid.NewString()}
+
+	// When: publishing two without a subscriber draining them
+	require.NoError(t, publisher.Publish(context.Background(), first))
+	err := publisher.Publish(context.Background(), UserEvent{Action: AuditActionDelete})
+
+	// Then: the second is dropped rather than blocking, and the first is delivered
+	require.ErrorIs(t, err, ErrEventBufferFull)
+	require.Equal(t, first, <-publisher.Events())
+}
+
+func TestChannelPublisher_Close(t *testing.T) {
+	publisher := NewChannelPublisher(1)
+	publisher.Close()
+	publisher.Close()
+
+	require.NoError(t, publisher.Publish(context.Background(), UserEvent{}))
+	_, open := <-publisher.Events()
+	require.False(t, open)
+}
+
+func TestUserService_EventCarriesRequestID(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	events := &stubPublisher{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Events: events})
+	repo.On("DeleteByID", mock.Anything, int64(7)).Return(&model.User{ID: 7, UUID: uuid.NewString()}, nil).Once()
+
+	ctx := ContextWithRequestID(context.Background(), "req-42")
+	require.NoError(t, service.DeleteByID(ctx, 7))
+
+	require.Len(t, events.events, 1)
+	require.Equal(t, "req-42", events.events[0].RequestID)
+}
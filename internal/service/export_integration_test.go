@@ -0,0 +1,78 @@
+//go:build integration
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"cruder/internal/model"
+	"cruder/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunctionalExport_Snapshot(t *testing.T) {
+	tests := []struct {
+		name        string
+		snapshot    bool
+		seesNewUser bool
+	}{
+		{name: "snapshot excludes users created mid-export", snapshot: true, seesNewUser: false},
+		{name: "plain export picks them up", snapshot: false, seesNewUser: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: the seeded users and a count of them before the export
+			resetUsersTable(t)
+			var before int
+			require.NoError(t, testDB.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&before))
+			require.Greater(t, before, 1)
+
+			// When: a user is inserted after the first batch has been read
+			var exported []model.User
+			err := testApp.Service.Users.Export(context.Background(), service.ExportOptions{BatchSize: 1, Snapshot: tt.snapshot}, func(u model.User) error {
+				if len(exported) == 0 {
+					_, err := testDB.Exec(`INSERT INTO users (username, email, full_name) VALUES ('midexport', 'midexport@example.com', 'Mid Export')`)
+					require.NoError(t, err)
+				}
+				exported = append(exported, u)
+				return nil
+			})
+
+			// Then: only a plain export sees the new row
+			require.NoError(t, err)
+			want := before
+			if tt.seesNewUser {
+				want++
+			}
+			require.Len(t, exported, want)
+			for i := 1; i < len(exported); i++ {
+				require.Greater(t, exported[i].ID, exported[i-1].ID)
+			}
+		})
+	}
+}
+
+func TestFunctionalExportEndpoint(t *testing.T) {
+	resetUsersTable(t)
+
+	// When: exporting over HTTP
+	resp, err := restyClient().R().Get(apiBaseURL + usersBasePath + "/export")
+	require.NoError(t, err)
+
+	// Then: every seeded user arrives as one JSON line
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Equal(t, "application/x-ndjson", resp.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSuffix(resp.String(), "\n"), "\n")
+	require.Len(t, lines, 3)
+	for _, line := range lines {
+		var user userResponse
+		require.NoError(t, json.Unmarshal([]byte(line), &user))
+		require.NotEmpty(t, user.UUID)
+	}
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultHealthCheckTimeout bounds each check when CheckHealth is given no
+// timeout.
+const DefaultHealthCheckTimeout = 2 * time.Second
+
+// HealthCheck probes one dependency. A failing Critical check makes the
+// service unhealthy; other failures are reported but tolerated.
+type HealthCheck struct {
+	Name     string
+	Critical bool
+	Check    func(ctx context.Context) error
+}
+
+// HealthResult is the outcome of one HealthCheck.
+type HealthResult struct {
+	Critical bool
+	Latency  time.Duration
+	Err      error
+}
+
+// CheckHealth runs every check concurrently, each bounded by timeout, and
+// reports whether all critical checks passed.
+func CheckHealth(ctx context.Context, checks []HealthCheck, timeout time.Duration) (map[string]HealthResult, bool) {
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+	results := make(map[string]HealthResult, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			start := time.Now()
+			err := check.Check(checkCtx)
+			result := HealthResult{Critical: check.Critical, Latency: time.Since(start), Err: err}
+			mu.Lock()
+			results[check.Name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	healthy := true
+	for _, result := range results {
+		if result.Critical && result.Err != nil {
+			healthy = false
+		}
+	}
+	return results, healthy
+}
@@ -0,0 +1,36 @@
+//go:build integration
+
+package service_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"cruder/internal/controller/response"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunctionalHealthDetail(t *testing.T) {
+	client := resty.New().SetBaseURL(apiBaseURL)
+
+	// When: probing liveness without an API key
+	resp, err := client.R().Get("/healthz")
+
+	// Then: it answers without touching dependencies
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// When: asking for the dependency report
+	resp, err = client.R().Get("/health/detail")
+
+	// Then: the database check passes
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	var checks map[string]response.HealthCheck
+	require.NoError(t, json.Unmarshal(resp.Body(), &checks))
+	require.Equal(t, response.HealthStatusOK, checks["db"].Status)
+	require.True(t, checks["db"].Critical)
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckHealth(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("connection refused") }
+	hanging := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	t.Run("non-critical failure stays healthy", func(t *testing.T) {
+		results, healthy := CheckHealth(context.Background(), []HealthCheck{
+			{Name: "db", Critical: true, Check: ok},
+			{Name: "cache", Check: failing},
+		}, time.Second)
+
+		require.True(t, healthy)
+		require.NoError(t, results["db"].Err)
+		require.EqualError(t, results["cache"].Err, "connection refused")
+	})
+
+	t.Run("critical failure is unhealthy", func(t *testing.T) {
+		_, healthy := CheckHealth(context.Background(), []HealthCheck{{Name: "db", Critical: true, Check: failing}}, time.Second)
+		require.False(t, healthy)
+	})
+
+	t.Run("slow checks time out", func(t *testing.T) {
+		start := time.Now()
+		results, healthy := CheckHealth(context.Background(), []HealthCheck{
+			{Name: "db", Critical: true, Check: hanging},
+			{Name: "replica", Critical: true, Check: hanging},
+		}, 20*time.Millisecond)
+
+		require.False(t, healthy)
+		require.ErrorIs(t, results["db"].Err, context.DeadlineExceeded)
+		require.Less(t, time.Since(start), time.Second, "checks run concurrently")
+	})
+}
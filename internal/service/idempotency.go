@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"cruder/pkg/logger"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	ErrIdempotencyKeyReused  = errors.New("idempotency key reused with a different request")
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+const (
+	defaultIdempotencyTTL        = 24 * time.Hour
+	defaultIdempotencyPendingTTL = time.Minute
+	defaultIdempotencyMaxCached  = 10000
+)
+
+type IdempotencyService interface {
+	// Reserve claims the client's key for a new request and returns nil, or
+	// returns the response stored for the key. A different request body
+	// yields ErrIdempotencyKeyReused, and a key held by a request that
+	// hasn't finished yields ErrIdempotencyInProgress.
+	Reserve(ctx context.Context, apiKeyID int, key, requestHash string) (*model.IdempotentResponse, error)
+	// Store records the response for a reserved key so repeats of the key
+	// replay it until the TTL passes.
+	Store(ctx context.Context, apiKeyID int, key, requestHash string, statusCode int, location string, body []byte) error
+	// Release gives up a reservation whose request failed, so the key can
+	// be retried.
+	Release(ctx context.Context, apiKeyID int, key, requestHash string) error
+}
+
+type IdempotencyServiceOptions struct {
+	// TTL is how long a stored response is replayed. Defaults to 24h.
+	TTL time.Duration
+	// PendingTTL is how long a reservation holds its key when the request
+	// never finishes, e.g. because the process died. Defaults to 1m.
+	PendingTTL time.Duration
+	// MaxCached bounds the in-memory copy of stored responses.
+	MaxCached int
+	// Logger receives the service's logs. Nil means the global logger.
+	Logger *logger.Logger
+}
+
+type idempotencyCacheKey struct {
+	apiKeyID int
+	key      string
+}
+
+type idempotencyService struct {
+	repo repository.IdempotencyRepository
+	log  *logger.Logger
+
+	ttl        time.Duration
+	pendingTTL time.Duration
+	maxCached  int
+	now        func() time.Time
+
+	mu    sync.RWMutex
+	cache map[idempotencyCacheKey]*model.IdempotentResponse
+}
+
+func NewIdempotencyService(repo repository.IdempotencyRepository, opts IdempotencyServiceOptions) IdempotencyService {
+	if opts.TTL <= 0 {
+		opts.TTL = defaultIdempotencyTTL
+	}
+	if opts.PendingTTL <= 0 {
+		opts.PendingTTL = defaultIdempotencyPendingTTL
+	}
+	if opts.MaxCached <= 0 {
+		opts.MaxCached = defaultIdempotencyMaxCached
+	}
+	return &idempotencyService{
+		repo:       repo,
+		log:        logger.OrGlobal(opts.Logger).With(slog.String("component", "service.idempotency")),
+		ttl:        opts.TTL,
+		pendingTTL: opts.PendingTTL,
+		maxCached:  opts.MaxCached,
+		now:        time.Now,
+		cache:      make(map[idempotencyCacheKey]*model.IdempotentResponse),
+	}
+}
+
+func (s *idempotencyService) Reserve(ctx context.Context, apiKeyID int, key, requestHash string) (*model.IdempotentResponse, error) {
+	cacheKey := idempotencyCacheKey{apiKeyID: apiKeyID, key: key}
+	resp, ok := s.getCached(cacheKey)
+	if !ok {
+		// the insert is the check, so two requests racing with a new key
+		// can't both be let through
+		reserved, err := s.repo.Reserve(ctx, &model.IdempotentResponse{
+			APIKeyID:    apiKeyID,
+			Key:         key,
+			RequestHash: requestHash,
+			ExpiresAt:   s.now().Add(s.pendingTTL),
+		})
+		if err != nil {
+			s.log.ErrorContext(ctx, "failed to reserve idempotency key", slog.Int("api_key.id", apiKeyID), slog.String("error", err.Error()))
+			return nil, err
+		}
+		if reserved {
+			return nil, nil
+		}
+		resp, err = s.repo.Get(ctx, apiKeyID, key)
+		if err != nil {
+			s.log.ErrorContext(ctx, "failed to fetch idempotent response", slog.Int("api_key.id", apiKeyID), slog.String("error", err.Error()))
+			return nil, err
+		}
+		if resp == nil {
+			// the holder released the key after our insert lost
+			return nil, ErrIdempotencyInProgress
+		}
+		if !resp.Pending() {
+			s.setCache(cacheKey, resp)
+		}
+	}
+
+	if resp.RequestHash != requestHash {
+		s.log.WarnContext(ctx, "idempotency key reused with a different request", slog.Int("api_key.id", apiKeyID))
+		return nil, ErrIdempotencyKeyReused
+	}
+	if resp.Pending() {
+		return nil, ErrIdempotencyInProgress
+	}
+	return resp, nil
+}
+
+func (s *idempotencyService) Store(ctx context.Context, apiKeyID int, key, requestHash string, statusCode int, location string, body []byte) error {
+	resp := &model.IdempotentResponse{
+		APIKeyID:    apiKeyID,
+		Key:         key,
+		RequestHash: requestHash,
+		StatusCode:  statusCode,
+		Location:    location,
+		Body:        body,
+		ExpiresAt:   s.now().Add(s.ttl),
+	}
+	if err := s.repo.Save(ctx, resp); err != nil {
+		s.log.ErrorContext(ctx, "failed to store idempotent response", slog.Int("api_key.id", apiKeyID), slog.String("error", err.Error()))
+		return err
+	}
+	// not cached here: Reserve caches whichever response the database kept
+	return nil
+}
+
+func (s *idempotencyService) Release(ctx context.Context, apiKeyID int, key, requestHash string) error {
+	if err := s.repo.Release(ctx, apiKeyID, key, requestHash); err != nil {
+		s.log.ErrorContext(ctx, "failed to release idempotency key", slog.Int("api_key.id", apiKeyID), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *idempotencyService) getCached(key idempotencyCacheKey) (*model.IdempotentResponse, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	resp, ok := s.cache[key]
+	if !ok || !s.now().Before(resp.ExpiresAt) {
+		return nil, false
+	}
+	return resp, true
+}
+
+// setCache keeps stored responses until they expire. When full it drops
+// expired entries and, failing that, skips caching rather than evicting
+// live ones; the repository remains the source of truth.
+func (s *idempotencyService) setCache(key idempotencyCacheKey, resp *model.IdempotentResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if len(s.cache) >= s.maxCached {
+		for k, cached := range s.cache {
+			if !now.Before(cached.ExpiresAt) {
+				delete(s.cache, k)
+			}
+		}
+		if len(s.cache) >= s.maxCached {
+			return
+		}
+	}
+	s.cache[key] = resp
+}
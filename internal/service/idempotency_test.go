@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyService_ReserveStoreAndReplay(t *testing.T) {
+	repo := newMockIdempotencyRepository()
+	svc := NewIdempotencyService(repo, IdempotencyServiceOptions{})
+	ctx := context.Background()
+
+	resp, err := svc.Reserve(ctx, 1, "retry-1", "hash-a")
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	require.NoError(t, svc.Store(ctx, 1, "retry-1", "hash-a", 201, "/api/v1/users/uuid/abc", []byte(`{"id":7}`)))
+
+	resp, err = svc.Reserve(ctx, 1, "retry-1", "hash-a")
+	require.NoError(t, err)
+	require.Equal(t, 201, resp.StatusCode)
+	require.Equal(t, "/api/v1/users/uuid/abc", resp.Location)
+	require.JSONEq(t, `{"id":7}`, string(resp.Body))
+	require.Equal(t, 1, repo.gets)
+
+	// cached after the first hit
+	_, err = svc.Reserve(ctx, 1, "retry-1", "hash-a")
+	require.NoError(t, err)
+	require.Equal(t, 1, repo.gets)
+}
+
+func TestIdempotencyService_ScopedPerClient(t *testing.T) {
+	repo := newMockIdempotencyRepository()
+	svc := NewIdempotencyService(repo, IdempotencyServiceOptions{})
+	ctx := context.Background()
+	_, err := svc.Reserve(ctx, 1, "shared", "hash-a")
+	require.NoError(t, err)
+	require.NoError(t, svc.Store(ctx, 1, "shared", "hash-a", 201, "", []byte(`{}`)))
+
+	resp, err := svc.Reserve(ctx, 2, "shared", "hash-b")
+
+	require.NoError(t, err)
+	require.Nil(t, resp)
+}
+
+func TestIdempotencyService_ReusedWithDifferentRequest(t *testing.T) {
+	repo := newMockIdempotencyRepository()
+	svc := NewIdempotencyService(repo, IdempotencyServiceOptions{})
+	ctx := context.Background()
+	_, err := svc.Reserve(ctx, 1, "retry-1", "hash-a")
+	require.NoError(t, err)
+	require.NoError(t, svc.Store(ctx, 1, "retry-1", "hash-a", 201, "", []byte(`{}`)))
+
+	_, err = svc.Reserve(ctx, 1, "retry-1", "hash-b")
+
+	require.ErrorIs(t, err, ErrIdempotencyKeyReused)
+}
+
+func TestIdempotencyService_PendingKeyIsInProgress(t *testing.T) {
+	// Given: a key reserved by a request that hasn't finished
+	repo := newMockIdempotencyRepository()
+	svc := NewIdempotencyService(repo, IdempotencyServiceOptions{})
+	ctx := context.Background()
+	_, err := svc.Reserve(ctx, 1, "retry-1", "hash-a")
+	require.NoError(t, err)
+
+	// When: a retry arrives meanwhile
+	_, err = svc.Reserve(ctx, 1, "retry-1", "hash-a")
+
+	// Then: it is told to wait, and another body is still refused
+	require.ErrorIs(t, err, ErrIdempotencyInProgress)
+	_, err = svc.Reserve(ctx, 1, "retry-1", "hash-b")
+	require.ErrorIs(t, err, ErrIdempotencyKeyReused)
+
+	// When: the first request fails and releases the key
+	require.NoError(t, svc.Release(ctx, 1, "retry-1", "hash-a"))
+
+	// Then: the retry can reserve it
+	resp, err := svc.Reserve(ctx, 1, "retry-1", "hash-a")
+	require.NoError(t, err)
+	require.Nil(t, resp)
+}
+
+func TestIdempotencyService_AbandonedReservationExpires(t *testing.T) {
+	repo := newMockIdempotencyRepository()
+	svc := NewIdempotencyService(repo, IdempotencyServiceOptions{PendingTTL: time.Minute}).(*idempotencyService)
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	repo.now = func() time.Time { return now }
+	ctx := context.Background()
+	_, err := svc.Reserve(ctx, 1, "retry-1", "hash-a")
+	require.NoError(t, err)
+
+	// the holder died without settling the key
+	now = now.Add(2 * time.Minute)
+	resp, err := svc.Reserve(ctx, 1, "retry-1", "hash-a")
+
+	require.NoError(t, err)
+	require.Nil(t, resp)
+}
+
+func TestIdempotencyService_ExpiredEntryIsNotServedFromCache(t *testing.T) {
+	repo := newMockIdempotencyRepository()
+	svc := NewIdempotencyService(repo, IdempotencyServiceOptions{TTL: time.Hour}).(*idempotencyService)
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+	_, err := svc.Reserve(ctx, 1, "retry-1", "hash-a")
+	require.NoError(t, err)
+	require.NoError(t, svc.Store(ctx, 1, "retry-1", "hash-a", 201, "", []byte(`{}`)))
+	_, err = svc.Reserve(ctx, 1, "retry-1", "hash-a")
+	require.NoError(t, err)
+
+	// the repository stops returning the row once it expires
+	now = now.Add(2 * time.Hour)
+	repo.now = func() time.Time { return now }
+	resp, err := svc.Reserve(ctx, 1, "retry-1", "hash-a")
+
+	require.NoError(t, err)
+	require.Nil(t, resp)
+}
+
+type mockIdempotencyRepository struct {
+	mu   sync.Mutex
+	rows map[idempotencyCacheKey]model.IdempotentResponse
+	gets int
+	now  func() time.Time
+}
+
+func newMockIdempotencyRepository() *mockIdempotencyRepository {
+	return &mockIdempotencyRepository{
+		rows: make(map[idempotencyCacheKey]model.IdempotentResponse),
+		now:  time.Now,
+	}
+}
+
+func (m *mockIdempotencyRepository) Get(_ context.Context, apiKeyID int, key string) (*model.IdempotentResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gets++
+	row, ok := m.rows[idempotencyCacheKey{apiKeyID: apiKeyID, key: key}]
+	if !ok || !m.now().Before(row.ExpiresAt) {
+		return nil, nil
+	}
+	return &row, nil
+}
+
+func (m *mockIdempotencyRepository) Reserve(_ context.Context, resp *model.IdempotentResponse) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := idempotencyCacheKey{apiKeyID: resp.APIKeyID, key: resp.Key}
+	if row, ok := m.rows[k]; ok && m.now().Before(row.ExpiresAt) {
+		return false, nil
+	}
+	m.rows[k] = *resp
+	return true, nil
+}
+
+func (m *mockIdempotencyRepository) Save(_ context.Context, resp *model.IdempotentResponse) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := idempotencyCacheKey{apiKeyID: resp.APIKeyID, key: resp.Key}
+	if row, ok := m.rows[k]; ok && row.Pending() && row.RequestHash == resp.RequestHash {
+		m.rows[k] = *resp
+	}
+	return nil
+}
+
+func (m *mockIdempotencyRepository) Release(_ context.Context, apiKeyID int, key, requestHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := idempotencyCacheKey{apiKeyID: apiKeyID, key: key}
+	if row, ok := m.rows[k]; ok && row.Pending() && row.RequestHash == requestHash {
+		delete(m.rows, k)
+	}
+	return nil
+}
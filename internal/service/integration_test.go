@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"cruder/internal/app"
+	"cruder/internal/config"
 	"cruder/pkg/logger"
 
 	"github.com/gin-gonic/gin"
@@ -34,7 +35,10 @@ var (
 	dsn        string
 )
 
-const testAPIKey = "integration-test-api-key"
+const (
+	testAPIKey   = "integration-test-api-key"
+	testAdminKey = "integration-test-admin-key"
+)
 
 func TestMain(m *testing.M) {
 	pool, err := dockertest.NewPool("")
@@ -86,11 +90,27 @@ func TestMain(m *testing.M) {
 	if _, err := logger.Configure(logger.Options{Output: logger.OutputStdout, Level: "info"}); err != nil {
 		log.Printf("failed to configure test logger: %v", err)
 	}
-	testApp, err = app.New(dsn)
+	if err := os.Setenv("ADMIN_API_KEY", testAdminKey); err != nil {
+		log.Fatalf("failed to set admin key: %v", err)
+	}
+	if err := os.Setenv("POSTGRES_DSN", dsn); err != nil {
+		log.Fatalf("failed to set dsn: %v", err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	testApp, err = app.New(cfg)
 	if err != nil {
 		log.Fatalf("failed to initialize application: %v", err)
 	}
 
+	for deadline := time.Now().Add(10 * time.Second); !testApp.Ready(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			log.Fatalf("application did not become ready")
+		}
+	}
+
 	testServer = httptest.NewServer(testApp.Engine)
 	apiBaseURL = testServer.URL
 
@@ -109,7 +129,7 @@ func TestMain(m *testing.M) {
 
 func resetUsersTable(t *testing.T) {
 	t.Helper()
-	if _, err := testDB.Exec("TRUNCATE users RESTART IDENTITY CASCADE"); err != nil {
+	if _, err := testDB.Exec("TRUNCATE users, idempotency_keys, audit_log RESTART IDENTITY CASCADE"); err != nil {
 		t.Fatalf("failed to truncate users: %v", err)
 	}
 	if err := seedUsers(); err != nil {
@@ -142,7 +162,7 @@ func seedAPIKey(db *sql.DB, plainKey, clientName string) error {
 	hash := sha256.Sum256([]byte(plainKey))
 	hashHex := hex.EncodeToString(hash[:])
 	_, err := db.Exec(
-		`INSERT INTO api_keys (key_hash, client_name) VALUES ($1, $2) ON CONFLICT (key_hash) DO NOTHING`,
+		`INSERT INTO api_keys (key_hash, client_name) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
 		hashHex, clientName,
 	)
 	return err
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	outcomeCreated  = "created"
+	outcomeConflict = "conflict"
+	outcomeNotFound = "not_found"
+)
+
+var userOutcomesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "cruder",
+	Subsystem: "user_service",
+	Name:      "outcomes_total",
+	Help:      "User service outcomes by type.",
+}, []string{"outcome"})
+
+func recordUserOutcome(outcome string) {
+	userOutcomesTotal.WithLabelValues(outcome).Inc()
+}
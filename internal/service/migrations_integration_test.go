@@ -0,0 +1,71 @@
+//go:build integration
+
+package service_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	expiresAtMigration  = 20261016100000
+	lowerEmailMigration = 20261016100100
+)
+
+// scratchDatabase creates an empty database next to the shared one so a test
+// can run migrations against data the shared schema would reject.
+func scratchDatabase(t *testing.T, name string) *sql.DB {
+	t.Helper()
+	_, err := testDB.Exec("CREATE DATABASE " + name)
+	require.NoError(t, err)
+	db, err := sql.Open("postgres", strings.Replace(dsn, "dbname=postgres", "dbname="+name, 1))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+		_, _ = testDB.Exec("DROP DATABASE IF EXISTS " + name + " WITH (FORCE)")
+	})
+	return db
+}
+
+func TestMigration_LowerEmailIndexRejectsCaseVariants(t *testing.T) {
+	root, err := projectRoot()
+	require.NoError(t, err)
+	migrationsDir := filepath.Join(root, "migrations")
+	db := scratchDatabase(t, "lower_email_migration")
+
+	// Given: a schema from before the index, holding accounts that differ
+	// only in the case of the local part or of the domain
+	require.NoError(t, goose.UpTo(db, migrationsDir, expiresAtMigration))
+	_, err = db.Exec(`INSERT INTO users (username, email, full_name) VALUES
+		('foo1', 'Foo@Example.com', 'Foo One'),
+		('foo2', 'foo@example.com', 'Foo Two'),
+		('bar1', 'bar@Example.com', 'Bar One'),
+		('bar2', 'bar@example.com', 'Bar Two')`)
+	require.NoError(t, err)
+
+	// When: applying the index migration
+	err = goose.UpTo(db, migrationsDir, lowerEmailMigration)
+
+	// Then: it refuses with the colliding ids instead of a unique violation
+	require.ErrorContains(t, err, "differs only in letter case: ids 4, 5; ids 6, 7")
+	version, err := goose.GetDBVersion(db)
+	require.NoError(t, err)
+	require.EqualValues(t, expiresAtMigration, version)
+
+	// When: the duplicates are resolved and the migration rerun
+	_, err = db.Exec(`DELETE FROM users WHERE username IN ('foo2', 'bar2')`)
+	require.NoError(t, err)
+	require.NoError(t, goose.UpTo(db, migrationsDir, lowerEmailMigration))
+
+	// Then: the domains are lowercased and the index is in place
+	var email string
+	require.NoError(t, db.QueryRow(`SELECT email FROM users WHERE username = 'foo1'`).Scan(&email))
+	require.Equal(t, "Foo@example.com", email)
+	_, err = db.Exec(`INSERT INTO users (username, email, full_name) VALUES ('foo3', 'FOO@EXAMPLE.COM', 'Foo Three')`)
+	require.ErrorContains(t, err, "users_email_lower_key")
+}
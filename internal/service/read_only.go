@@ -0,0 +1,17 @@
+package service
+
+import "sync/atomic"
+
+// ReadOnlyMode is a runtime switch that makes mutations fail with ErrReadOnly.
+// It is safe for concurrent use; a nil mode is never read-only.
+type ReadOnlyMode struct {
+	enabled atomic.Bool
+}
+
+func (m *ReadOnlyMode) Set(enabled bool) {
+	m.enabled.Store(enabled)
+}
+
+func (m *ReadOnlyMode) Enabled() bool {
+	return m != nil && m.enabled.Load()
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"cruder/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// SelfTest runs a create, read, update, and delete round-trip through users
+// with a throwaway account, so a broken migration or missing table
+// permission fails startup instead of the first real request. The account is
+// deleted even when a later step fails. A nil log means the global logger.
+func SelfTest(users UserService, log *logger.Logger) (err error) {
+	log = logger.OrGlobal(log).With(slog.String("component", "service.self_test"))
+	ctx := ContextWithActor(context.Background(), "self-test")
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Errorf("self-test: generate name: %w", err)
+	}
+	tag := hex.EncodeToString(suffix)
+	username := "selftest_" + tag
+
+	created, err := users.Create(ctx, username, "selftest+"+tag+"@example.invalid", "Self Test", "")
+	if err != nil {
+		return selfTestFailed(log, "create", err)
+	}
+	id, err := uuid.Parse(created.UUID)
+	if err != nil {
+		return selfTestFailed(log, "create", fmt.Errorf("invalid uuid %q: %w", created.UUID, err))
+	}
+
+	deleted := false
+	defer func() {
+		if deleted {
+			return
+		}
+		if cleanupErr := users.DeleteByUUID(ctx, id); cleanupErr != nil {
+			log.Error("self-test cleanup failed", slog.String("user.uuid", id.String()), slog.String("error", cleanupErr.Error()))
+			err = errors.Join(err, fmt.Errorf("self-test: cleanup: %w", cleanupErr))
+		}
+	}()
+
+	read, err := users.GetByUUID(ctx, id)
+	if err != nil {
+		return selfTestFailed(log, "read", err)
+	}
+	if read.Username != username {
+		return selfTestFailed(log, "read", fmt.Errorf("read back username %q, want %q", read.Username, username))
+	}
+
+	fullName := "Self Test Updated"
+	updated, err := users.UpdateByUUID(ctx, id, UpdateUserInput{FullName: &fullName, Version: &read.Version})
+	if err != nil {
+		return selfTestFailed(log, "update", err)
+	}
+	if updated.FullName != fullName {
+		return selfTestFailed(log, "update", fmt.Errorf("updated full name %q, want %q", updated.FullName, fullName))
+	}
+
+	if err := users.DeleteByUUID(ctx, id); err != nil {
+		return selfTestFailed(log, "delete", err)
+	}
+	deleted = true
+	if _, err := users.GetByUUID(ctx, id); !errors.Is(err, ErrUserNotFound) {
+		return selfTestFailed(log, "delete", fmt.Errorf("user still readable after delete: %v", err))
+	}
+
+	log.Info("self-test passed")
+	return nil
+}
+
+func selfTestFailed(log *logger.Logger, step string, err error) error {
+	log.Error("self-test failed", slog.String("self_test.step", step), slog.String("error", err.Error()))
+	return fmt.Errorf("self-test %s: %w", step, err)
+}
@@ -0,0 +1,26 @@
+//go:build integration
+
+package service_test
+
+import (
+	"testing"
+
+	"cruder/internal/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunctionalSelfTest(t *testing.T) {
+	resetUsersTable(t)
+	var before int
+	require.NoError(t, testDB.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&before))
+
+	// When: running the startup self-test against the real database
+	err := service.SelfTest(testApp.Service.Users, nil)
+
+	// Then: it passes and leaves no rows behind
+	require.NoError(t, err)
+	var after int
+	require.NoError(t, testDB.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&after))
+	require.Equal(t, before, after)
+}
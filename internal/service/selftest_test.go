@@ -0,0 +1,40 @@
+package service
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"cruder/internal/model"
+	"cruder/internal/service/mocks"
+	"cruder/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfTest_CleansUpAfterPartialFailure(t *testing.T) {
+	// Given: a repository where the update step fails
+	repo := mocks.NewUserRepositoryMock(t)
+	users := NewUserService(repo)
+	id := uuid.New()
+	created := &model.User{ID: 1, UUID: id.String(), Email: "selftest@example.invalid", FullName: "Self Test"}
+	repo.On("Create", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("string"), "Self Test", "").
+		Run(func(args mock.Arguments) { created.Username = args.String(1) }).
+		Return(created, nil).Once()
+	repo.On("GetByUUID", mock.Anything, id).Return(created, nil)
+	repo.On("UpdateByUUID", mock.Anything, id, mock.Anything, mock.Anything, "Self Test Updated", "", 0).Return(nil, errUnexpected).Once()
+	repo.On("DeleteByUUID", mock.Anything, id).Return(created, nil).Once()
+
+	var out bytes.Buffer
+
+	// When: running the self-test
+	err := SelfTest(users, logger.FromSlog(slog.New(slog.NewJSONHandler(&out, nil))))
+
+	// Then: the failure names the step and the temporary user was still deleted
+	require.ErrorIs(t, err, errUnexpected)
+	require.ErrorContains(t, err, "self-test update")
+	repo.AssertExpectations(t)
+	require.Contains(t, out.String(), `"msg":"self-test failed","component":"service.self_test"`)
+}
@@ -2,17 +2,44 @@ package service
 
 import (
 	"cruder/internal/repository"
-	"time"
+	"cruder/pkg/logger"
 )
 
 type Service struct {
-	Users   UserService
-	APIKeys APIKeyService
+	Users       UserService
+	APIKeys     APIKeyService
+	Idempotency IdempotencyService
+
+	// ReadOnly toggles read-only mode for user mutations at runtime.
+	ReadOnly *ReadOnlyMode
 }
 
-func NewService(repos *repository.Repository, apiKeyTTL time.Duration) *Service {
+// NewService builds every service over repos, logging to log. A nil log means
+// the global logger; passing one leaves the global logger and slog's default
+// alone.
+func NewService(repos *repository.Repository, apiKeyOpts APIKeyServiceOptions, userOpts UserServiceOptions, log *logger.Logger) *Service {
+	log = logger.OrGlobal(log)
+	if userOpts.ReadOnly == nil {
+		userOpts.ReadOnly = &ReadOnlyMode{}
+	}
+	if userOpts.Audit == nil {
+		userOpts.Audit = repos.Audit
+	}
+	if userOpts.Primary == nil {
+		userOpts.Primary = repos.UsersPrimary
+	}
+	if userOpts.Verifications == nil {
+		userOpts.Verifications = repos.EmailVerifications
+	}
 	return &Service{
-		Users:   NewUserService(repos.Users),
-		APIKeys: NewAPIKeyService(repos.APIKeys, apiKeyTTL),
+		Users:       NewUserServiceWithLogger(repos.Users, userOpts, log),
+		APIKeys:     NewAPIKeyServiceWithLogger(repos.APIKeys, apiKeyOpts, log),
+		Idempotency: NewIdempotencyService(repos.Idempotency, IdempotencyServiceOptions{Logger: log}),
+		ReadOnly:    userOpts.ReadOnly,
 	}
 }
+
+// Close stops background work owned by the services.
+func (s *Service) Close() error {
+	return s.APIKeys.Close()
+}
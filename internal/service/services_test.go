@@ -0,0 +1,38 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"cruder/internal/repository"
+	"cruder/internal/service/mocks"
+	"cruder/pkg/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewService_LogsToInjectedLogger(t *testing.T) {
+	// Given: the service layer built with its own logger
+	var out bytes.Buffer
+	readOnly := &ReadOnlyMode{}
+	readOnly.Set(true)
+	repos := &repository.Repository{
+		Users:   mocks.NewUserRepositoryMock(t),
+		APIKeys: newMockAPIKeyRepository(),
+	}
+	services := NewService(repos, APIKeyServiceOptions{}, UserServiceOptions{ReadOnly: readOnly},
+		logger.FromSlog(slog.New(slog.NewJSONHandler(&out, nil))))
+	t.Cleanup(func() { require.NoError(t, services.Close()) })
+
+	// When: the user and API key services log
+	_, createErr := services.Users.Create(context.Background(), "jdoe", "jdoe@example.com", "", "")
+	_, validateErr := services.APIKeys.Validate(context.Background(), "")
+
+	// Then: both records land there
+	require.ErrorIs(t, createErr, ErrReadOnly)
+	require.ErrorIs(t, validateErr, ErrAPIKeyMissing)
+	require.Contains(t, out.String(), `"component":"service.user"`)
+	require.Contains(t, out.String(), `"component":"service.api_key"`)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"cruder/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// BulkUpdateResult reports the outcome of UpdateManyByUUID.
+type BulkUpdateResult struct {
+	// Updated counts the users whose field changed. Users that already had
+	// the value are neither counted nor audited.
+	Updated int
+	// NotFound lists the requested uuids that matched no user, in request
+	// order.
+	NotFound []uuid.UUID
+}
+
+// bulkUpdateField describes a field UpdateManyByUUID may set.
+type bulkUpdateField struct {
+	// action is the audit action recorded for each changed user.
+	action string
+	// normalize returns the value to store, or a field error message.
+	normalize func(value string) (string, string)
+}
+
+// bulkUpdateFields is the allow-list for UpdateManyByUUID, keyed by JSON
+// field name. Fields that must be unique per user, like username and email,
+// are deliberately absent.
+var bulkUpdateFields = map[string]bulkUpdateField{
+	"status": {
+		action: AuditActionSetStatus,
+		normalize: func(value string) (string, string) {
+			if !model.ValidUserStatus(value) {
+				return "", "must be one of " + strings.Join(model.UserStatuses, ", ")
+			}
+			return value, ""
+		},
+	},
+	"avatar_url": {
+		action: AuditActionUpdate,
+		normalize: func(value string) (string, string) {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				return "", ""
+			}
+			return value, checkAvatarURL(value)
+		},
+	},
+}
+
+// BulkUpdateFields lists, sorted, the fields UpdateManyByUUID accepts.
+func BulkUpdateFields() []string {
+	return slices.Sorted(maps.Keys(bulkUpdateFields))
+}
+
+// UpdateManyByUUID sets field to value on the listed users in one
+// transaction. Duplicates are ignored. The whole batch is rejected if the
+// field or value is invalid, or if the uuids are empty, over the cap, or
+// include a reserved uuid.
+func (s *userService) UpdateManyByUUID(ctx context.Context, uuids []uuid.UUID, field, value string) (*BulkUpdateResult, error) {
+	if s.readOnly.Enabled() {
+		s.log.WarnContext(ctx, "bulk update rejected: read-only mode", slog.Int("users.requested", len(uuids)))
+		return nil, ErrReadOnly
+	}
+
+	spec, ok := bulkUpdateFields[field]
+	if !ok {
+		s.log.WarnContext(ctx, "bulk update rejected: field not allowed", slog.String("field", field))
+		return nil, &ValidationError{Fields: map[string]string{field: "cannot be bulk updated; use one of " + strings.Join(BulkUpdateFields(), ", ")}}
+	}
+	value, msg := spec.normalize(value)
+	if msg != "" {
+		s.log.WarnContext(ctx, "bulk update invalid input", slog.String("field", field))
+		return nil, &ValidationError{Fields: map[string]string{field: msg}}
+	}
+
+	maxBatch := s.opts.MaxBulkUpdate
+	if maxBatch <= 0 {
+		maxBatch = DefaultMaxBulkUpdate
+	}
+	unique, err := bulkTargets(uuids, maxBatch)
+	if err != nil {
+		s.log.WarnContext(ctx, "bulk update rejected", slog.Int("users.requested", len(uuids)), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	changes, err := s.repo.UpdateManyByUUID(ctx, unique, field, value)
+	if err != nil {
+		s.log.ErrorContext(ctx, "bulk update repository error", slog.Int("users.requested", len(unique)), slog.String("field", field), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	found := make([]model.User, len(changes))
+	result := &BulkUpdateResult{}
+	for i := range changes {
+		found[i] = changes[i].After
+		if changes[i].After.Version == changes[i].Before.Version {
+			continue
+		}
+		result.Updated++
+		s.committed(ctx, spec.action, &changes[i].Before, &changes[i].After)
+	}
+	result.NotFound = missingUUIDs(unique, found)
+	s.log.InfoContext(ctx, "users bulk updated", slog.Int("users.requested", len(unique)), slog.String("field", field), slog.Int("users.updated", result.Updated))
+	return result, nil
+}
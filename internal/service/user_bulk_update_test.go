@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"cruder/internal/service/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserService_UpdateManyByUUID_Suspends(t *testing.T) {
+	// Given: an active user, an already suspended one, and an unknown uuid
+	repo := mocks.NewUserRepositoryMock(t)
+	audit := &recordingAuditRepository{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Audit: audit})
+	active, suspended, missing := uuid.New(), uuid.New(), uuid.New()
+	before := model.User{ID: 1, UUID: active.String(), Status: model.UserStatusActive, Version: 3}
+	after := before
+	after.Status, after.Version = model.UserStatusSuspended, 4
+	unchanged := model.User{ID: 2, UUID: suspended.String(), Status: model.UserStatusSuspended, Version: 5}
+	repo.On("UpdateManyByUUID", mock.Anything, []uuid.UUID{active, missing, suspended}, "status", model.UserStatusSuspended).
+		Return([]repository.UserChange{{Before: before, After: after}, {Before: unchanged, After: unchanged}}, nil).Once()
+
+	// When: suspending them all, with one uuid repeated
+	result, err := service.UpdateManyByUUID(context.Background(), []uuid.UUID{active, missing, active, suspended}, "status", model.UserStatusSuspended)
+
+	// Then: only the user that changed is counted and audited
+	require.NoError(t, err)
+	require.Equal(t, &BulkUpdateResult{Updated: 1, NotFound: []uuid.UUID{missing}}, result)
+	require.Len(t, audit.entries, 1)
+	require.Equal(t, AuditActionSetStatus, audit.entries[0].Action)
+	require.Equal(t, model.UserStatusSuspended, audit.entries[0].After.Status)
+}
+
+func TestUserService_UpdateManyByUUID_ClearsAvatar(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	id := uuid.New()
+	repo.On("UpdateManyByUUID", mock.Anything, []uuid.UUID{id}, "avatar_url", "").Return(nil, nil).Once()
+
+	result, err := service.UpdateManyByUUID(context.Background(), []uuid.UUID{id}, "avatar_url", "  ")
+
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{id}, result.NotFound)
+}
+
+func TestUserService_UpdateManyByUUID_RejectsInput(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	readOnly := &ReadOnlyMode{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{MaxBulkUpdate: 2, ReadOnly: readOnly})
+	ids := []uuid.UUID{uuid.New()}
+
+	for _, tc := range []struct {
+		field, value, want string
+	}{
+		{"email", "x@example.com", "cannot be bulk updated; use one of avatar_url, status"},
+		{"status", "deleted", "must be one of active, suspended"},
+		{"avatar_url", "ftp://example.com/a.png", fieldInvalidURL},
+	} {
+		_, err := service.UpdateManyByUUID(context.Background(), ids, tc.field, tc.value)
+		var verr *ValidationError
+		require.ErrorAs(t, err, &verr, tc.field)
+		require.Equal(t, map[string]string{tc.field: tc.want}, verr.Fields)
+	}
+
+	_, err := service.UpdateManyByUUID(context.Background(), nil, "status", model.UserStatusActive)
+	require.ErrorIs(t, err, ErrInvalidUserInput)
+
+	_, err = service.UpdateManyByUUID(context.Background(), []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}, "status", model.UserStatusActive)
+	require.ErrorContains(t, err, "at most 2 uuids")
+
+	_, err = service.UpdateManyByUUID(context.Background(), []uuid.UUID{uuid.Max}, "status", model.UserStatusActive)
+	require.ErrorIs(t, err, ErrReservedUUID)
+
+	readOnly.Set(true)
+	_, err = service.UpdateManyByUUID(context.Background(), ids, "status", model.UserStatusActive)
+	require.ErrorIs(t, err, ErrReadOnly)
+
+	repo.AssertNotCalled(t, "UpdateManyByUUID", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cruder/internal/model"
+	"cruder/internal/repository"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultStatsTTL is the StatsTTL the config uses when STATS_CACHE_TTL is
+// unset.
+const DefaultStatsTTL = 10 * time.Second
+
+// statsCache holds the last counts Stats computed. Its lock only guards
+// the cached values; a refresh runs outside it, shared through group, so a
+// burst of dashboard polls runs the queries once and each poll still gives
+// up at its own deadline.
+type statsCache struct {
+	mu      sync.Mutex
+	stats   model.UserStats
+	expires time.Time
+	group   singleflight.Group
+}
+
+// Stats returns headline user counts, reusing them for
+// UserServiceOptions.StatsTTL after they are computed.
+func (s *userService) Stats(ctx context.Context) (*model.UserStats, error) {
+	ttl := s.opts.StatsTTL
+	if ttl <= 0 {
+		return s.computeStats(ctx)
+	}
+	s.stats.mu.Lock()
+	if time.Now().Before(s.stats.expires) {
+		stats := s.stats.stats
+		s.stats.mu.Unlock()
+		return &stats, nil
+	}
+	s.stats.mu.Unlock()
+
+	// the refresh ignores the first caller's cancellation, so its hanging up
+	// doesn't fail, or restart, the refresh for everyone waiting on it
+	ch := s.stats.group.DoChan("stats", func() (any, error) {
+		stats, err := s.computeStats(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		s.stats.mu.Lock()
+		s.stats.stats, s.stats.expires = *stats, time.Now().Add(ttl)
+		s.stats.mu.Unlock()
+		return *stats, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		stats := res.Val.(model.UserStats)
+		return &stats, nil
+	}
+}
+
+func (s *userService) computeStats(ctx context.Context) (*model.UserStats, error) {
+	stats, err := s.repo.Stats(ctx)
+	if err != nil {
+		repository.LogReadError(ctx, s.log, "failed to count users", err)
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cruder/internal/model"
+	"cruder/internal/service/mocks"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserService_Stats_CachesForTTL(t *testing.T) {
+	// Given: a service that reuses its counts for a minute
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{StatsTTL: time.Minute})
+	repo.On("Stats", mock.Anything).Return(&model.UserStats{Total: 12, CreatedToday: 3, Suspended: 1}, nil).Once()
+
+	// When: polled twice
+	first, err := service.Stats(context.Background())
+	require.NoError(t, err)
+	second, err := service.Stats(context.Background())
+
+	// Then: the counts are computed once
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+	require.Equal(t, int64(12), second.Total)
+}
+
+func TestUserService_Stats_ErrorIsNotCached(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{StatsTTL: time.Minute})
+	repo.On("Stats", mock.Anything).Return((*model.UserStats)(nil), errors.New("db down")).Once()
+	repo.On("Stats", mock.Anything).Return(&model.UserStats{Total: 1}, nil).Once()
+
+	_, err := service.Stats(context.Background())
+	require.Error(t, err)
+
+	stats, err := service.Stats(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), stats.Total)
+}
+
+func TestUserService_Stats_ZeroTTLRecomputes(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{})
+	repo.On("Stats", mock.Anything).Return(&model.UserStats{Total: 1}, nil).Twice()
+
+	for range 2 {
+		_, err := service.Stats(context.Background())
+		require.NoError(t, err)
+	}
+}
+
+func TestUserService_Stats_WaitersKeepTheirOwnDeadline(t *testing.T) {
+	// Given: a slow refresh started by a caller who then hangs up
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{StatsTTL: time.Minute})
+	started, release := make(chan struct{}), make(chan struct{})
+	repo.On("Stats", mock.Anything).Run(func(args mock.Arguments) {
+		close(started)
+		<-release
+		require.NoError(t, args.Get(0).(context.Context).Err(), "refresh must not inherit cancellation")
+	}).Return(&model.UserStats{Total: 5}, nil).Once()
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := service.Stats(leaderCtx)
+		leaderErr <- err
+	}()
+	<-started
+
+	// When: the leader cancels, and another poll times out while waiting
+	cancel()
+	waiterCtx, cancelWaiter := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelWaiter()
+	_, waiterErr := service.Stats(waiterCtx)
+
+	// Then: both return promptly with their own error, and the refresh
+	// completes once for a later poll
+	require.ErrorIs(t, <-leaderErr, context.Canceled)
+	require.ErrorIs(t, waiterErr, context.DeadlineExceeded)
+	done := make(chan *model.UserStats)
+	go func() {
+		stats, err := service.Stats(context.Background())
+		require.NoError(t, err)
+		done <- stats
+	}()
+	close(release)
+	require.Equal(t, int64(5), (<-done).Total)
+}
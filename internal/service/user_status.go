@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"cruder/internal/model"
+
+	"github.com/google/uuid"
+)
+
+// SetStatus moves the user with uuid to status, which must be one of
+// model.UserStatuses. Setting the status a user already has is a no-op that
+// returns the user unchanged. The write only applies while the stored status
+// differs, so of two concurrent calls for the same status only one bumps the
+// version and is audited; the other finds nothing to change.
+func (s *userService) SetStatus(ctx context.Context, uuid uuid.UUID, status string) (*model.User, error) {
+	if s.readOnly.Enabled() {
+		s.log.WarnContext(ctx, "set status rejected: read-only mode", slog.String("user.uuid", uuid.String()))
+		return nil, ErrReadOnly
+	}
+	if isReservedUUID(uuid) {
+		s.log.WarnContext(ctx, "set status rejected: reserved uuid", slog.String("user.uuid", uuid.String()))
+		return nil, ErrReservedUUID
+	}
+	if !model.ValidUserStatus(status) {
+		verr := &ValidationError{Fields: map[string]string{"status": "must be one of " + strings.Join(model.UserStatuses, ", ")}}
+		s.log.WarnContext(ctx, "set status invalid input", slog.String("user.uuid", uuid.String()), slog.String("status", status))
+		return nil, verr
+	}
+
+	existing, err := s.primary.GetByUUID(ctx, uuid)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to fetch user for set status", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
+		return nil, err
+	}
+	if existing == nil {
+		s.log.WarnContext(ctx, "set status target not found", slog.String("user.uuid", uuid.String()))
+		recordUserOutcome(outcomeNotFound)
+		return nil, ErrUserNotFound
+	}
+	if existing.Status == status {
+		return existing, nil
+	}
+
+	updated, err := s.repo.SetStatus(ctx, uuid, status)
+	if err != nil {
+		s.log.ErrorContext(ctx, "set status repository error", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
+		return nil, err
+	}
+	if updated == nil {
+		// either deleted meanwhile, or a concurrent call set the status first
+		current, err := s.primary.GetByUUID(ctx, uuid)
+		if err != nil {
+			s.log.ErrorContext(ctx, "failed to re-read user after set status", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
+			return nil, err
+		}
+		if current == nil {
+			s.log.WarnContext(ctx, "set status target deleted concurrently", slog.String("user.uuid", uuid.String()))
+			recordUserOutcome(outcomeNotFound)
+			return nil, ErrUserNotFound
+		}
+		return current, nil
+	}
+	s.log.InfoContext(ctx, "user status changed", slog.String("user.uuid", updated.UUID), slog.String("user.status", updated.Status))
+	s.committed(ctx, AuditActionSetStatus, existing, updated)
+	return updated, nil
+}
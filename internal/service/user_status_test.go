@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cruder/internal/model"
+	"cruder/internal/service/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserService_SetStatus_Suspends(t *testing.T) {
+	// Given: an active user
+	repo := mocks.NewUserRepositoryMock(t)
+	audit := &recordingAuditRepository{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Audit: audit})
+	id := uuid.New()
+	user := &model.User{ID: 7, UUID: id.String(), Username: "jdoe", Status: model.UserStatusActive, Version: 1}
+	suspended := *user
+	suspended.Status = model.UserStatusSuspended
+	suspended.Version = 2
+	repo.On("GetByUUID", mock.Anything, id).Return(user, nil).Once()
+	repo.On("SetStatus", mock.Anything, id, model.UserStatusSuspended).Return(&suspended, nil).Once()
+
+	// When: suspending them
+	got, err := service.SetStatus(context.Background(), id, model.UserStatusSuspended)
+
+	// Then: the new status is stored and audited
+	require.NoError(t, err)
+	require.Equal(t, model.UserStatusSuspended, got.Status)
+	require.Len(t, audit.entries, 1)
+	require.Equal(t, AuditActionSetStatus, audit.entries[0].Action)
+	require.Equal(t, model.UserStatusActive, audit.entries[0].Before.Status)
+	require.Equal(t, model.UserStatusSuspended, audit.entries[0].After.Status)
+}
+
+func TestUserService_SetStatus_Unchanged(t *testing.T) {
+	// Given: a user who is already suspended
+	repo := mocks.NewUserRepositoryMock(t)
+	audit := &recordingAuditRepository{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Audit: audit})
+	id := uuid.New()
+	user := &model.User{ID: 7, UUID: id.String(), Status: model.UserStatusSuspended, Version: 3}
+	repo.On("GetByUUID", mock.Anything, id).Return(user, nil).Once()
+
+	// When: suspending them again
+	got, err := service.SetStatus(context.Background(), id, model.UserStatusSuspended)
+
+	// Then: nothing is written and the user comes back as is
+	require.NoError(t, err)
+	require.Equal(t, user, got)
+	require.Empty(t, audit.entries)
+}
+
+func TestUserService_SetStatus_LosesRace(t *testing.T) {
+	// Given: an active user whom a concurrent call suspends between the read
+	// and the write
+	repo := mocks.NewUserRepositoryMock(t)
+	audit := &recordingAuditRepository{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Audit: audit})
+	id := uuid.New()
+	active := &model.User{ID: 7, UUID: id.String(), Status: model.UserStatusActive, Version: 1}
+	suspended := &model.User{ID: 7, UUID: id.String(), Status: model.UserStatusSuspended, Version: 2}
+	repo.On("GetByUUID", mock.Anything, id).Return(active, nil).Once()
+	repo.On("SetStatus", mock.Anything, id, model.UserStatusSuspended).Return((*model.User)(nil), nil).Once()
+	repo.On("GetByUUID", mock.Anything, id).Return(suspended, nil).Once()
+
+	// When: this call's guarded write finds nothing to change
+	got, err := service.SetStatus(context.Background(), id, model.UserStatusSuspended)
+
+	// Then: it returns the user as the winner left them, without a second audit entry
+	require.NoError(t, err)
+	require.Equal(t, suspended, got)
+	require.Empty(t, audit.entries)
+}
+
+func TestUserService_SetStatus_Rejections(t *testing.T) {
+	id := uuid.New()
+	tests := []struct {
+		name    string
+		uuid    uuid.UUID
+		status  string
+		setup   func(repo *mocks.UserRepositoryMock)
+		wantErr error
+	}{
+		{
+			name:    "unknown status",
+			uuid:    id,
+			status:  "banned",
+			wantErr: ErrInvalidUserInput,
+		},
+		{
+			name:    "reserved uuid",
+			uuid:    uuid.Nil,
+			status:  model.UserStatusSuspended,
+			wantErr: ErrReservedUUID,
+		},
+		{
+			name:   "missing user",
+			uuid:   id,
+			status: model.UserStatusSuspended,
+			setup: func(repo *mocks.UserRepositoryMock) {
+				repo.On("GetByUUID", mock.Anything, id).Return(nil, nil).Once()
+			},
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name:   "deleted before the write",
+			uuid:   id,
+			status: model.UserStatusSuspended,
+			setup: func(repo *mocks.UserRepositoryMock) {
+				repo.On("GetByUUID", mock.Anything, id).Return(&model.User{UUID: id.String(), Status: model.UserStatusActive}, nil).Once()
+				repo.On("SetStatus", mock.Anything, id, model.UserStatusSuspended).Return(nil, nil).Once()
+				repo.On("GetByUUID", mock.Anything, id).Return(nil, nil).Once()
+			},
+			wantErr: ErrUserNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewUserRepositoryMock(t)
+			if tt.setup != nil {
+				tt.setup(repo)
+			}
+
+			_, err := NewUserService(repo).SetStatus(context.Background(), tt.uuid, tt.status)
+
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUserService_SetStatus_ReportsAllowedValues(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+
+	_, err := NewUserService(repo).SetStatus(context.Background(), uuid.New(), "banned")
+
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr))
+	require.Equal(t, map[string]string{"status": "must be one of active, suspended"}, verr.Fields)
+}
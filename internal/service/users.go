@@ -1,58 +1,313 @@
 package service
 
 import (
+	"context"
 	"cruder/internal/model"
 	"cruder/internal/repository"
 	"cruder/pkg/logger"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/mail"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// Field length limits, in characters, checked before any write. Each is at
+// most its users column size; change them together with a migration.
+const (
+	// MaxUsernameLength matches the users.username column size. The default
+	// UsernamePolicy is stricter.
+	MaxUsernameLength = 50
+	// MaxEmailLength is the longest address RFC 5321 allows.
+	MaxEmailLength    = 254
+	MaxFullNameLength = 100
+	// MaxAvatarURLLength matches the users.avatar_url column size.
+	MaxAvatarURLLength = 2048
+)
+
+// MaxPinnedUsers bounds the pin list accepted by List.
+const MaxPinnedUsers = 50
+
+// DefaultMaxBulkDelete caps DeleteManyByUUID when
+// UserServiceOptions.MaxBulkDelete is unset.
+const DefaultMaxBulkDelete = 1000
+
+// DefaultMaxBulkUpdate caps UpdateManyByUUID when
+// UserServiceOptions.MaxBulkUpdate is unset.
+const DefaultMaxBulkUpdate = 1000
+
+// MaxBatchGet caps the uuids accepted by GetByUUIDs.
+const MaxBatchGet = 100
+
+// DefaultExportBatchSize is how many users Export reads per query when
+// ExportOptions.BatchSize is unset.
+const DefaultExportBatchSize = 500
+
 var (
 	ErrUserNotFound      = errors.New("user not found")
 	ErrInvalidUserInput  = errors.New("invalid user input")
 	ErrUserAlreadyExists = errors.New("user already exists")
+	ErrReadOnly          = errors.New("service is read-only")
+	// ErrPreconditionFailed is returned when UpdateUserInput.Precondition
+	// rejects the stored user.
+	ErrPreconditionFailed = errors.New("user has changed")
+	// ErrVersionConflict is returned when UpdateUserInput.Version is not the
+	// stored version, meaning another update got in first.
+	ErrVersionConflict = errors.New("user version is out of date")
+
+	ErrImmutableField         = fmt.Errorf("%w: id and uuid cannot be changed", ErrInvalidUserInput)
+	ErrUsernameLooksLikeEmail = fmt.Errorf("%w: username looks like an email address", ErrInvalidUserInput)
+	ErrReservedUUID           = fmt.Errorf("%w: uuid is reserved", ErrInvalidUserInput)
+	ErrEmptyCreatedRange      = fmt.Errorf("%w: created_after must be before created_before", ErrInvalidUserInput)
 )
 
 type UserService interface {
-	GetAll() ([]model.User, error)
-	GetByUsername(username string) (*model.User, error)
-	GetByID(id int64) (*model.User, error)
-	GetByUUID(uuid uuid.UUID) (*model.User, error)
-	Create(username, email, fullName string) (*model.User, error)
-	UpdateByUUID(uuid uuid.UUID, input UpdateUserInput) (*model.User, error)
-	DeleteByUUID(uuid uuid.UUID) error
-	UpdateByID(id int64, input UpdateUserInput) (*model.User, error)
-	DeleteByID(id int64) error
+	// GetAll, GetAllAfter and Export stop reading once ctx is done.
+	GetAll(ctx context.Context) ([]model.User, error)
+	List(ctx context.Context, q ListUsersQuery) ([]model.User, error)
+	GetAllAfter(ctx context.Context, cursorID int64, limit int) ([]model.User, error)
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	GetByID(ctx context.Context, id int64) (*model.User, error)
+	GetByUUID(ctx context.Context, uuid uuid.UUID) (*model.User, error)
+	GetByUUIDs(ctx context.Context, uuids []uuid.UUID) (*BatchGetResult, error)
+	// ExistsByUUID and ExistsByID check for a user without loading it.
+	ExistsByUUID(ctx context.Context, uuid uuid.UUID) (bool, error)
+	ExistsByID(ctx context.Context, id int64) (bool, error)
+	// Mutations take a context so the acting client, set with
+	// ContextWithActor, can be recorded in the audit log.
+	Create(ctx context.Context, username, email, fullName, avatarURL string) (*model.User, error)
+	UpdateByUUID(ctx context.Context, uuid uuid.UUID, input UpdateUserInput) (*model.User, error)
+	DeleteByUUID(ctx context.Context, uuid uuid.UUID) error
+	DeleteByUsername(ctx context.Context, username string) error
+	DeleteManyByUUID(ctx context.Context, uuids []uuid.UUID) (*BulkDeleteResult, error)
+	// UpdateManyByUUID sets one field, named by its JSON name, to value on
+	// every listed user. Only the fields in BulkUpdateFields can be set.
+	UpdateManyByUUID(ctx context.Context, uuids []uuid.UUID, field, value string) (*BulkUpdateResult, error)
+	UpdateByID(ctx context.Context, id int64, input UpdateUserInput) (*model.User, error)
+	DeleteByID(ctx context.Context, id int64) error
+	Export(ctx context.Context, opts ExportOptions, emit func(model.User) error) error
+	// IssueEmailVerification creates a verification token for the user's
+	// current email, replacing any earlier one. VerifyEmail redeems it.
+	IssueEmailVerification(ctx context.Context, uuid uuid.UUID) (*EmailVerification, error)
+	VerifyEmail(ctx context.Context, token string) (*model.User, error)
+	// SetStatus suspends or reactivates a user. Suspended users are still
+	// returned by every read.
+	SetStatus(ctx context.Context, uuid uuid.UUID, status string) (*model.User, error)
+	// History lists the audit entries for a user, oldest first, after the
+	// entry with id afterID.
+	History(ctx context.Context, uuid uuid.UUID, afterID int64, limit int) ([]model.AuditEntry, error)
+	// Stats returns headline user counts, which may be up to
+	// UserServiceOptions.StatsTTL old.
+	Stats(ctx context.Context) (*model.UserStats, error)
+}
+
+// ExportOptions controls how Export walks the users table.
+type ExportOptions struct {
+	// BatchSize is the number of users read per query.
+	BatchSize int
+	// Snapshot reads every batch from one read-only REPEATABLE READ
+	// transaction, so users created or deleted while the export runs don't
+	// show up in it.
+	Snapshot bool
 }
 
 type userService struct {
 	repo repository.UserRepository
-	log  *logger.Logger
+	// primary serves the reads updates are merged onto, so a lagging read
+	// replica can't write stale fields back.
+	primary  repository.UserRepository
+	audit    repository.AuditRepository
+	events   EventPublisher
+	log      *logger.Logger
+	readOnly *ReadOnlyMode
+	opts     UserServiceOptions
+
+	usernamePolicy UsernamePolicy
+	// verifications stores email verification tokens; nil disables the
+	// verification flow.
+	verifications repository.EmailVerificationRepository
+	stats         statsCache
+}
+
+type UserServiceOptions struct {
+	// ReadOnly, when enabled, rejects all mutations with ErrReadOnly.
+	ReadOnly *ReadOnlyMode
+	// RejectEmailLikeUsernames rejects a username that parses as an email
+	// address or equals the email, which usually means the fields were swapped.
+	RejectEmailLikeUsernames bool
+	// UsernamePolicy overrides DefaultUsernamePolicy.
+	UsernamePolicy *UsernamePolicy
+	// Audit receives an entry for every successful mutation. Nil disables
+	// auditing.
+	Audit repository.AuditRepository
+	// MaxBulkDelete caps the uuids accepted by DeleteManyByUUID; zero means
+	// DefaultMaxBulkDelete.
+	MaxBulkDelete int
+	// MaxBulkUpdate caps the uuids accepted by UpdateManyByUUID; zero means
+	// DefaultMaxBulkUpdate.
+	MaxBulkUpdate int
+	// Events is notified after every successful mutation; nil means
+	// NoopPublisher.
+	Events EventPublisher
+	// Primary reads what writes are based on: from the primary database
+	// even when the repository reads from a replica, and past any cache.
+	// Nil uses the repository.
+	Primary repository.UserRepository
+	// Verifications stores email verification tokens. Nil makes the
+	// verification methods fail with ErrVerificationUnavailable.
+	Verifications repository.EmailVerificationRepository
+	// VerificationTTL is how long an issued token is valid; zero means
+	// DefaultVerificationTTL.
+	VerificationTTL time.Duration
+	// PrecheckUniqueness looks up the username and email before Create
+	// inserts, so a duplicate is reported with every taken field. The unique
+	// constraints still decide races between concurrent creates.
+	PrecheckUniqueness bool
+	// UniqueFullName treats full_name as a unique display name. The
+	// database enforces it through repository.FullNameIndex, which the app
+	// creates or requires at startup; here it only adds full_name to the
+	// uniqueness pre-check.
+	UniqueFullName bool
+	// StatsTTL is how long Stats reuses its counts. Zero recomputes them on
+	// every call.
+	StatsTTL time.Duration
+}
+
+// BulkDeleteResult reports the outcome of DeleteManyByUUID.
+type BulkDeleteResult struct {
+	Deleted int
+	// NotFound lists the requested uuids that matched no user, in request
+	// order.
+	NotFound []uuid.UUID
 }
 
+// BatchGetResult reports the outcome of GetByUUIDs.
+type BatchGetResult struct {
+	// Users are the users found, in no particular order.
+	Users []model.User
+	// Missing lists the requested uuids that matched no user, in request
+	// order.
+	Missing []uuid.UUID
+}
+
+// ListUsersQuery selects a window of users and optional pinned-first ordering.
+type ListUsersQuery = repository.ListUsersQuery
+
 type UpdateUserInput struct {
 	Username *string
 	Email    *string
 	FullName *string
+	// AvatarURL, when set, replaces the avatar; "" removes it.
+	AvatarURL *string
+	// Version is the version the update is based on, as last read by the
+	// caller. It is required.
+	Version *int
+
+	// Immutable lists identifier fields the caller attempted to set; any entry rejects the update.
+	Immutable []string
+
+	// Precondition, when set, is checked against the stored user before the
+	// update is applied; returning false fails it with ErrPreconditionFailed.
+	// The check runs on the row read ahead of the write, so it narrows but
+	// does not close the window for a concurrent update.
+	Precondition func(current model.User) bool
 }
 
 func NewUserService(repo repository.UserRepository) UserService {
-	serviceLogger := logger.Get().With(slog.String("component", "service.user"))
+	return NewUserServiceWithOptions(repo, UserServiceOptions{})
+}
+
+func NewUserServiceWithOptions(repo repository.UserRepository, opts UserServiceOptions) UserService {
+	return NewUserServiceWithLogger(repo, opts, logger.Get())
+}
+
+// NewUserServiceWithLogger is NewUserServiceWithOptions logging to log
+// instead of the global logger, so embedding programs needn't configure it.
+// A nil log falls back to the global logger.
+func NewUserServiceWithLogger(repo repository.UserRepository, opts UserServiceOptions, log *logger.Logger) UserService {
+	serviceLogger := logger.OrGlobal(log).With(slog.String("component", "service.user"))
+	policy := DefaultUsernamePolicy
+	if opts.UsernamePolicy != nil {
+		policy = *opts.UsernamePolicy
+	}
+	events := opts.Events
+	if events == nil {
+		events = NoopPublisher{}
+	}
+	primary := opts.Primary
+	if primary == nil {
+		primary = repo
+	}
 	return &userService{
-		repo: repo,
-		log:  serviceLogger,
+		repo:           repo,
+		primary:        primary,
+		audit:          opts.Audit,
+		events:         events,
+		verifications:  opts.Verifications,
+		log:            serviceLogger,
+		readOnly:       opts.ReadOnly,
+		opts:           opts,
+		usernamePolicy: policy,
 	}
 }
 
-func (s *userService) GetAll() ([]model.User, error) {
-	users, err := s.repo.GetAll()
+// checkUsernameNotEmail applies the optional swapped-fields heuristic.
+func (s *userService) checkUsernameNotEmail(username, email string) error {
+	if !s.opts.RejectEmailLikeUsernames {
+		return nil
+	}
+	if strings.EqualFold(username, email) {
+		return ErrUsernameLooksLikeEmail
+	}
+	if addr, err := mail.ParseAddress(username); err == nil && addr.Address == username {
+		return ErrUsernameLooksLikeEmail
+	}
+	return nil
+}
+
+func (s *userService) GetAll(ctx context.Context) ([]model.User, error) {
+	users, err := s.repo.GetAll(ctx)
+	if err != nil {
+		repository.LogReadError(ctx, s.log, "failed to fetch users", err)
+		return nil, err
+	}
+	if users == nil {
+		return []model.User{}, nil
+	}
+	return users, nil
+}
+
+func (s *userService) List(ctx context.Context, q ListUsersQuery) ([]model.User, error) {
+	if q.Limit < 0 || q.Offset < 0 || q.AfterID < 0 || len(q.Pinned) > MaxPinnedUsers {
+		s.log.WarnContext(ctx, "list users invalid query", slog.Int("page.limit", q.Limit), slog.Int("page.offset", q.Offset), slog.Int64("page.after", q.AfterID), slog.Int("pinned.count", len(q.Pinned)))
+		return nil, ErrInvalidUserInput
+	}
+	if !q.CreatedAfter.IsZero() && !q.CreatedBefore.IsZero() && !q.CreatedAfter.Before(q.CreatedBefore) {
+		s.log.WarnContext(ctx, "list users empty created_at range", slog.Time("created_after", q.CreatedAfter), slog.Time("created_before", q.CreatedBefore))
+		return nil, ErrEmptyCreatedRange
+	}
+	users, err := s.repo.List(ctx, q)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to list users", slog.Int("page.limit", q.Limit), slog.Int("page.offset", q.Offset), slog.String("error", err.Error()))
+		return nil, err
+	}
+	if users == nil {
+		return []model.User{}, nil
+	}
+	return users, nil
+}
+
+func (s *userService) GetAllAfter(ctx context.Context, cursorID int64, limit int) ([]model.User, error) {
+	if cursorID < 0 || limit <= 0 {
+		s.log.WarnContext(ctx, "get users after cursor invalid window", slog.Int64("page.after", cursorID), slog.Int("page.limit", limit))
+		return nil, ErrInvalidUserInput
+	}
+	users, err := s.repo.GetAllAfter(ctx, cursorID, limit)
 	if err != nil {
-		s.log.Error("failed to fetch users", slog.String("error", err.Error()))
+		repository.LogReadError(ctx, s.log, "failed to fetch users after cursor", err, slog.Int64("page.after", cursorID))
 		return nil, err
 	}
 	if users == nil {
@@ -61,237 +316,679 @@ func (s *userService) GetAll() ([]model.User, error) {
 	return users, nil
 }
 
-func (s *userService) GetByUsername(username string) (*model.User, error) {
-	user, err := s.repo.GetByUsername(username)
+// Export calls emit for every user in id order, reading in batches. An error
+// from emit stops the export and is returned as is, and so does ctx being
+// done, checked before each batch and each emit.
+func (s *userService) Export(ctx context.Context, opts ExportOptions, emit func(model.User) error) error {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultExportBatchSize
+	}
+
+	export := func(repo repository.UserRepository) error {
+		var after int64
+		for {
+			users, err := repo.GetAllAfter(ctx, after, batchSize)
+			if err != nil {
+				repository.LogReadError(ctx, s.log, "failed to export users", err, slog.Int64("page.after", after))
+				return err
+			}
+			for _, u := range users {
+				if err := ctx.Err(); err != nil {
+					s.log.DebugContext(ctx, "export cancelled", slog.Int64("page.after", after))
+					return err
+				}
+				if err := emit(u); err != nil {
+					return err
+				}
+			}
+			if len(users) < batchSize {
+				return nil
+			}
+			after = int64(users[len(users)-1].ID)
+		}
+	}
+
+	if !opts.Snapshot {
+		return export(s.repo)
+	}
+	return s.repo.Snapshot(ctx, export)
+}
+
+func (s *userService) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	user, err := s.repo.GetByUsername(ctx, username)
 	if err != nil {
-		s.log.Error("failed to fetch user by username", slog.String("user.username", username), slog.String("error", err.Error()))
+		s.log.ErrorContext(ctx, "failed to fetch user by username", slog.String("user.username", username), slog.String("error", err.Error()))
 		return nil, err
 	}
 	if user == nil {
-		s.log.Debug("user by username not found", slog.String("user.username", username))
+		s.log.DebugContext(ctx, "user by username not found", slog.String("user.username", username))
+		recordUserOutcome(outcomeNotFound)
 		return nil, ErrUserNotFound
 	}
 	return user, nil
 }
 
-func (s *userService) GetByID(id int64) (*model.User, error) {
-	user, err := s.repo.GetByID(id)
+func (s *userService) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	user, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		s.log.Error("failed to fetch user by id", slog.Int64("user.id", id), slog.String("error", err.Error()))
+		s.log.ErrorContext(ctx, "failed to fetch user by id", slog.Int64("user.id", id), slog.String("error", err.Error()))
 		return nil, err
 	}
 	if user == nil {
-		s.log.Debug("user by id not found", slog.Int64("user.id", id))
+		s.log.DebugContext(ctx, "user by id not found", slog.Int64("user.id", id))
+		recordUserOutcome(outcomeNotFound)
 		return nil, ErrUserNotFound
 	}
 	return user, nil
 }
 
-func (s *userService) GetByUUID(uuid uuid.UUID) (*model.User, error) {
-	user, err := s.repo.GetByUUID(uuid)
+func (s *userService) GetByUUID(ctx context.Context, uuid uuid.UUID) (*model.User, error) {
+	user, err := s.repo.GetByUUID(ctx, uuid)
 	if err != nil {
-		s.log.Error("failed to fetch user by uuid", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
+		s.log.ErrorContext(ctx, "failed to fetch user by uuid", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
 		return nil, err
 	}
 	if user == nil {
-		s.log.Debug("user by uuid not found", slog.String("user.uuid", uuid.String()))
+		s.log.DebugContext(ctx, "user by uuid not found", slog.String("user.uuid", uuid.String()))
+		recordUserOutcome(outcomeNotFound)
 		return nil, ErrUserNotFound
 	}
 	return user, nil
 }
 
-func (s *userService) Create(username, email, fullName string) (*model.User, error) {
+// GetByUUIDs loads the listed users in one query. Duplicates are ignored.
+// The whole batch is rejected if it is empty or over MaxBatchGet.
+func (s *userService) GetByUUIDs(ctx context.Context, uuids []uuid.UUID) (*BatchGetResult, error) {
+	var unique []uuid.UUID
+	seen := make(map[uuid.UUID]struct{}, len(uuids))
+	for _, id := range uuids {
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		unique = append(unique, id)
+	}
+	switch {
+	case len(unique) == 0:
+		s.log.WarnContext(ctx, "batch get invalid input: no uuids")
+		return nil, fmt.Errorf("%w: uuids must not be empty", ErrInvalidUserInput)
+	case len(unique) > MaxBatchGet:
+		s.log.WarnContext(ctx, "batch get invalid input: too many uuids", slog.Int("users.requested", len(unique)), slog.Int("users.max", MaxBatchGet))
+		return nil, fmt.Errorf("%w: at most %d uuids per request", ErrInvalidUserInput, MaxBatchGet)
+	}
+
+	users, err := s.repo.GetByUUIDs(ctx, unique)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to fetch users by uuids", slog.Int("users.requested", len(unique)), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	result := &BatchGetResult{Users: users}
+	if result.Users == nil {
+		result.Users = []model.User{}
+	}
+	for _, u := range users {
+		if id, err := uuid.Parse(u.UUID); err == nil {
+			delete(seen, id)
+		}
+	}
+	for _, id := range unique {
+		if _, missing := seen[id]; missing {
+			result.Missing = append(result.Missing, id)
+		}
+	}
+	return result, nil
+}
+
+func (s *userService) ExistsByUUID(ctx context.Context, uuid uuid.UUID) (bool, error) {
+	exists, err := s.repo.ExistsByUUID(ctx, uuid)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to check user by uuid", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
+		return false, err
+	}
+	return exists, nil
+}
+
+func (s *userService) ExistsByID(ctx context.Context, id int64) (bool, error) {
+	if id <= 0 {
+		s.log.WarnContext(ctx, "exists by id invalid id", slog.Int64("user.id", id))
+		return false, ErrInvalidUserInput
+	}
+	exists, err := s.repo.ExistsByID(ctx, id)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to check user by id", slog.Int64("user.id", id), slog.String("error", err.Error()))
+		return false, err
+	}
+	return exists, nil
+}
+
+func (s *userService) Create(ctx context.Context, username, email, fullName, avatarURL string) (*model.User, error) {
+	if s.readOnly.Enabled() {
+		s.log.WarnContext(ctx, "create user rejected: read-only mode")
+		return nil, ErrReadOnly
+	}
+
 	username = strings.TrimSpace(username)
 	email = strings.TrimSpace(email)
 	fullName = strings.TrimSpace(fullName)
+	avatarURL = strings.TrimSpace(avatarURL)
 
-	if username == "" || fullName == "" {
-		s.log.Warn("create user invalid input: missing username or full name")
-		return nil, ErrInvalidUserInput
+	fields := map[string]string{}
+	if msg := s.usernamePolicy.check(username); msg != "" {
+		fields["username"] = msg
+	}
+	// full_name is optional; a user who gives none is known by their username
+	if fullName == "" {
+		fullName = username
+	}
+	if msg := checkMaxLength(fullName, MaxFullNameLength); msg != "" {
+		fields["full_name"] = msg
+	}
+	if avatarURL != "" {
+		if msg := checkAvatarURL(avatarURL); msg != "" {
+			fields["avatar_url"] = msg
+		}
+	}
+	switch {
+	case email == "":
+		fields["email"] = fieldRequired
+	default:
+		if msg := checkMaxLength(email, MaxEmailLength); msg != "" {
+			fields["email"] = msg
+		} else if normalized, ok := normalizeEmail(email); ok {
+			email = normalized
+		} else {
+			fields["email"] = fieldInvalidEmail
+		}
+	}
+	// checked first so a swapped username isn't reported as a plain policy violation
+	if err := s.checkUsernameNotEmail(username, email); err != nil {
+		s.log.WarnContext(ctx, "create user rejected: username looks like an email")
+		return nil, err
+	}
+	if len(fields) > 0 {
+		verr := &ValidationError{Fields: fields}
+		s.log.WarnContext(ctx, "create user invalid input", slog.String("error", verr.Error()))
+		return nil, verr
 	}
 
-	_, err := mail.ParseAddress(email)
-	if err != nil {
-		s.log.Warn("create user invalid email format")
-		return nil, ErrInvalidUserInput
+	if s.opts.PrecheckUniqueness {
+		if conflict := s.precheckUniqueness(ctx, username, email, fullName); conflict != nil {
+			s.log.WarnContext(ctx, "create user duplicate", slog.String("user.username", username), slog.String("error", conflict.Error()))
+			recordUserOutcome(outcomeConflict)
+			return nil, conflict
+		}
 	}
 
-	user, err := s.repo.Create(username, email, fullName)
+	user, err := s.repo.Create(ctx, username, email, fullName, avatarURL)
 	if err != nil {
 		if errors.Is(err, repository.ErrUniqueViolation) {
-			s.log.Warn("create user duplicate", slog.String("user.username", username))
-			return nil, ErrUserAlreadyExists
+			conflict := conflictError(err)
+			s.log.WarnContext(ctx, "create user duplicate", slog.String("user.username", username), slog.String("error", conflict.Error()))
+			recordUserOutcome(outcomeConflict)
+			return nil, conflict
+		}
+		if errors.Is(err, repository.ErrConstraintViolation) {
+			s.log.WarnContext(ctx, "create user rejected by database constraint", slog.String("error", err.Error()))
+			return nil, ErrInvalidUserInput
 		}
-		s.log.Error("create user repository error", slog.String("error", err.Error()))
+		s.log.ErrorContext(ctx, "create user repository error", slog.String("error", err.Error()))
 		return nil, err
 	}
 
-	s.log.Info("user created", slog.String("user.uuid", user.UUID), slog.Int("user.id", user.ID))
+	s.log.InfoContext(ctx, "user created", slog.String("user.uuid", user.UUID), slog.Int("user.id", user.ID))
+	recordUserOutcome(outcomeCreated)
+	s.committed(ctx, AuditActionCreate, nil, user)
 	return user, nil
 }
 
-func (s *userService) UpdateByUUID(uuid uuid.UUID, input UpdateUserInput) (*model.User, error) {
-	if input.Username == nil && input.Email == nil && input.FullName == nil {
-		s.log.Warn("update by uuid invalid input: no fields provided", slog.String("user.uuid", uuid.String()))
+// precheckUniqueness returns a ConflictError naming every field of a new
+// user that is already taken, or nil. The check is advisory: when the lookup
+// fails, the insert goes ahead and the constraints report any duplicate.
+//
+// It deliberately runs outside the insert's transaction. Holding one across
+// both would not stop a concurrent insert of the same name either, short of
+// locking the table, so a user created between the check and the insert is
+// still caught by the unique indexes and reported through conflictError,
+// only without the other taken fields.
+func (s *userService) precheckUniqueness(ctx context.Context, username, email, fullName string) error {
+	if !s.opts.UniqueFullName {
+		fullName = ""
+	}
+	taken, err := s.primary.TakenFields(ctx, username, email, fullName)
+	if err != nil {
+		s.log.WarnContext(ctx, "uniqueness pre-check failed", slog.String("error", err.Error()))
+		return nil
+	}
+	if len(taken) == 0 {
+		return nil
+	}
+	fields := make(map[string]string, len(taken))
+	for _, field := range taken {
+		fields[field] = fieldTaken
+	}
+	return &ConflictError{Fields: fields}
+}
+
+// conflictError turns a unique violation from the repository into
+// ErrUserAlreadyExists, naming the field when the constraint guards one.
+func conflictError(err error) error {
+	var cerr *repository.ConstraintError
+	if errors.As(err, &cerr) {
+		switch field := cerr.Column(); field {
+		case "username", "email", "full_name":
+			return &ConflictError{Fields: map[string]string{field: fieldTaken}}
+		}
+	}
+	return ErrUserAlreadyExists
+}
+
+func (s *userService) UpdateByUUID(ctx context.Context, uuid uuid.UUID, input UpdateUserInput) (*model.User, error) {
+	if s.readOnly.Enabled() {
+		s.log.WarnContext(ctx, "update by uuid rejected: read-only mode", slog.String("user.uuid", uuid.String()))
+		return nil, ErrReadOnly
+	}
+
+	if isReservedUUID(uuid) {
+		s.log.WarnContext(ctx, "update by uuid rejected: reserved uuid", slog.String("user.uuid", uuid.String()))
+		return nil, ErrReservedUUID
+	}
+
+	if len(input.Immutable) > 0 {
+		s.log.WarnContext(ctx, "update by uuid rejected: immutable fields provided", slog.String("user.uuid", uuid.String()), slog.Any("fields", input.Immutable))
+		return nil, ErrImmutableField
+	}
+
+	if input.Username == nil && input.Email == nil && input.FullName == nil && input.AvatarURL == nil {
+		s.log.WarnContext(ctx, "update by uuid invalid input: no fields provided", slog.String("user.uuid", uuid.String()))
 		return nil, ErrInvalidUserInput
 	}
+	if input.Version == nil {
+		verr := &ValidationError{Fields: map[string]string{"version": fieldRequired}}
+		s.log.WarnContext(ctx, "update by uuid invalid input: no version provided", slog.String("user.uuid", uuid.String()))
+		return nil, verr
+	}
 
-	existing, err := s.repo.GetByUUID(uuid)
+	existing, err := s.primary.GetByUUID(ctx, uuid)
 	if err != nil {
-		s.log.Error("failed to fetch existing user by uuid", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
+		s.log.ErrorContext(ctx, "failed to fetch existing user by uuid", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
 		return nil, err
 	}
 	if existing == nil {
-		s.log.Warn("update by uuid target not found", slog.String("user.uuid", uuid.String()))
+		s.log.WarnContext(ctx, "update by uuid target not found", slog.String("user.uuid", uuid.String()))
+		recordUserOutcome(outcomeNotFound)
 		return nil, ErrUserNotFound
 	}
+	if input.Precondition != nil && !input.Precondition(*existing) {
+		s.log.WarnContext(ctx, "update by uuid rejected: precondition failed", slog.String("user.uuid", uuid.String()))
+		return nil, ErrPreconditionFailed
+	}
+	if existing.Version != *input.Version {
+		s.log.WarnContext(ctx, "update by uuid rejected: stale version", slog.String("user.uuid", uuid.String()), slog.Int("user.version", existing.Version), slog.Int("request.version", *input.Version))
+		recordUserOutcome(outcomeConflict)
+		return nil, ErrVersionConflict
+	}
 
 	username := existing.Username
 	email := existing.Email
 	fullName := existing.FullName
+	avatarURL := existing.AvatarURL
 
+	fields := map[string]string{}
 	if input.Username != nil {
 		trimmed := strings.TrimSpace(*input.Username)
-		if trimmed == "" {
-			s.log.Warn("update by uuid invalid username", slog.String("user.uuid", uuid.String()))
-			return nil, ErrInvalidUserInput
+		if msg := s.usernamePolicy.check(trimmed); msg != "" {
+			fields["username"] = msg
 		}
 		username = trimmed
 	}
 
 	if input.Email != nil {
 		trimmed := strings.TrimSpace(*input.Email)
+		email = trimmed
 		if trimmed == "" {
-			s.log.Warn("update by uuid empty email", slog.String("user.uuid", uuid.String()))
-			return nil, ErrInvalidUserInput
-		}
-		if _, err := mail.ParseAddress(trimmed); err != nil {
-			s.log.Warn("update by uuid invalid email", slog.String("user.uuid", uuid.String()))
-			return nil, ErrInvalidUserInput
+			fields["email"] = fieldRequired
+		} else if msg := checkMaxLength(trimmed, MaxEmailLength); msg != "" {
+			fields["email"] = msg
+		} else if normalized, ok := normalizeEmail(trimmed); ok {
+			email = normalized
+		} else {
+			fields["email"] = fieldInvalidEmail
 		}
-		email = trimmed
 	}
 
+	// unlike username and email, full_name may be cleared: "" (or blanks)
+	// stores an empty name, while a nil pointer leaves it untouched
 	if input.FullName != nil {
-		trimmed := strings.TrimSpace(*input.FullName)
-		fullName = trimmed
+		fullName = strings.TrimSpace(*input.FullName)
+		if msg := checkMaxLength(fullName, MaxFullNameLength); msg != "" {
+			fields["full_name"] = msg
+		}
+	}
+	if input.AvatarURL != nil {
+		avatarURL = strings.TrimSpace(*input.AvatarURL)
+		if avatarURL != "" {
+			if msg := checkAvatarURL(avatarURL); msg != "" {
+				fields["avatar_url"] = msg
+			}
+		}
 	}
 
-	updated, err := s.repo.UpdateByUUID(uuid, username, email, fullName)
+	if input.Username != nil || input.Email != nil {
+		if err := s.checkUsernameNotEmail(username, email); err != nil {
+			s.log.WarnContext(ctx, "update by uuid rejected: username looks like an email", slog.String("user.uuid", uuid.String()))
+			return nil, err
+		}
+	}
+	if len(fields) > 0 {
+		verr := &ValidationError{Fields: fields}
+		s.log.WarnContext(ctx, "update by uuid invalid input", slog.String("user.uuid", uuid.String()), slog.String("error", verr.Error()))
+		return nil, verr
+	}
+
+	updated, err := s.repo.UpdateByUUID(ctx, uuid, username, email, fullName, avatarURL, *input.Version)
 	if err != nil {
 		if errors.Is(err, repository.ErrUniqueViolation) {
-			s.log.Warn("update by uuid duplicate", slog.String("user.uuid", uuid.String()))
-			return nil, ErrUserAlreadyExists
+			conflict := conflictError(err)
+			s.log.WarnContext(ctx, "update by uuid duplicate", slog.String("user.uuid", uuid.String()), slog.String("error", conflict.Error()))
+			recordUserOutcome(outcomeConflict)
+			return nil, conflict
+		}
+		if errors.Is(err, repository.ErrConstraintViolation) {
+			s.log.WarnContext(ctx, "update by uuid rejected by database constraint", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
+			return nil, ErrInvalidUserInput
 		}
-		s.log.Error("update by uuid repository error", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
+		s.log.ErrorContext(ctx, "update by uuid repository error", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
 		return nil, err
 	}
 	if updated == nil {
-		s.log.Warn("update by uuid resulted in not found", slog.String("user.uuid", uuid.String()))
-		return nil, ErrUserNotFound
+		// the row was read above, so a concurrent update or delete won the race
+		s.log.WarnContext(ctx, "update by uuid lost a concurrent write", slog.String("user.uuid", uuid.String()))
+		recordUserOutcome(outcomeConflict)
+		return nil, ErrVersionConflict
 	}
-	s.log.Info("user updated by uuid", slog.String("user.uuid", updated.UUID), slog.Int("user.id", updated.ID))
+	s.log.InfoContext(ctx, "user updated by uuid", slog.String("user.uuid", updated.UUID), slog.Int("user.id", updated.ID))
+	s.committed(ctx, AuditActionUpdate, existing, updated)
 	return updated, nil
 }
 
-func (s *userService) DeleteByUUID(uuid uuid.UUID) error {
-	ok, err := s.repo.DeleteByUUID(uuid)
+func (s *userService) DeleteByUUID(ctx context.Context, uuid uuid.UUID) error {
+	if s.readOnly.Enabled() {
+		s.log.WarnContext(ctx, "delete by uuid rejected: read-only mode", slog.String("user.uuid", uuid.String()))
+		return ErrReadOnly
+	}
+
+	if isReservedUUID(uuid) {
+		s.log.WarnContext(ctx, "delete by uuid rejected: reserved uuid", slog.String("user.uuid", uuid.String()))
+		return ErrReservedUUID
+	}
+
+	deleted, err := s.repo.DeleteByUUID(ctx, uuid)
+	if err != nil {
+		s.log.ErrorContext(ctx, "delete by uuid repository error", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
+		return err
+	}
+	if deleted == nil {
+		s.log.WarnContext(ctx, "delete by uuid target not found", slog.String("user.uuid", uuid.String()))
+		recordUserOutcome(outcomeNotFound)
+		return ErrUserNotFound
+	}
+	s.log.InfoContext(ctx, "user deleted by uuid", slog.String("user.uuid", uuid.String()))
+	s.committed(ctx, AuditActionDelete, deleted, nil)
+	return nil
+}
+
+func (s *userService) DeleteByUsername(ctx context.Context, username string) error {
+	if s.readOnly.Enabled() {
+		s.log.WarnContext(ctx, "delete by username rejected: read-only mode", slog.String("user.username", username))
+		return ErrReadOnly
+	}
+
+	if username == "" {
+		s.log.WarnContext(ctx, "delete by username invalid input: empty username")
+		return ErrInvalidUserInput
+	}
+
+	deleted, err := s.repo.DeleteByUsername(ctx, username)
 	if err != nil {
-		s.log.Error("delete by uuid repository error", slog.String("user.uuid", uuid.String()), slog.String("error", err.Error()))
+		s.log.ErrorContext(ctx, "delete by username repository error", slog.String("user.username", username), slog.String("error", err.Error()))
 		return err
 	}
-	if !ok {
-		s.log.Warn("delete by uuid target not found", slog.String("user.uuid", uuid.String()))
+	if deleted == nil {
+		s.log.WarnContext(ctx, "delete by username target not found", slog.String("user.username", username))
+		recordUserOutcome(outcomeNotFound)
 		return ErrUserNotFound
 	}
-	s.log.Info("user deleted by uuid", slog.String("user.uuid", uuid.String()))
+	s.log.InfoContext(ctx, "user deleted by username", slog.String("user.username", username), slog.String("user.uuid", deleted.UUID))
+	s.committed(ctx, AuditActionDelete, deleted, nil)
 	return nil
 }
 
-func (s *userService) UpdateByID(id int64, input UpdateUserInput) (*model.User, error) {
+// DeleteManyByUUID deletes the listed users in one statement. Duplicates are
+// ignored. The whole batch is rejected if it is empty, over the cap, or
+// names a reserved uuid.
+func (s *userService) DeleteManyByUUID(ctx context.Context, uuids []uuid.UUID) (*BulkDeleteResult, error) {
+	if s.readOnly.Enabled() {
+		s.log.WarnContext(ctx, "bulk delete rejected: read-only mode", slog.Int("users.requested", len(uuids)))
+		return nil, ErrReadOnly
+	}
+
+	maxBatch := s.opts.MaxBulkDelete
+	if maxBatch <= 0 {
+		maxBatch = DefaultMaxBulkDelete
+	}
+	unique, err := bulkTargets(uuids, maxBatch)
+	if err != nil {
+		s.log.WarnContext(ctx, "bulk delete rejected", slog.Int("users.requested", len(uuids)), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	deleted, err := s.repo.DeleteManyByUUID(ctx, unique)
+	if err != nil {
+		s.log.ErrorContext(ctx, "bulk delete repository error", slog.Int("users.requested", len(unique)), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	result := &BulkDeleteResult{Deleted: len(deleted), NotFound: missingUUIDs(unique, deleted)}
+	for i := range deleted {
+		s.committed(ctx, AuditActionDelete, &deleted[i], nil)
+	}
+	s.log.InfoContext(ctx, "users bulk deleted", slog.Int("users.requested", len(unique)), slog.Int("users.deleted", result.Deleted))
+	return result, nil
+}
+
+// bulkTargets returns uuids without duplicates, in request order. The whole
+// batch is rejected if it is empty, holds more than max uuids, or names a
+// reserved uuid.
+func bulkTargets(uuids []uuid.UUID, max int) ([]uuid.UUID, error) {
+	var unique []uuid.UUID
+	seen := make(map[uuid.UUID]struct{}, len(uuids))
+	for _, id := range uuids {
+		if isReservedUUID(id) {
+			return nil, ErrReservedUUID
+		}
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		unique = append(unique, id)
+	}
+	switch {
+	case len(unique) == 0:
+		return nil, fmt.Errorf("%w: uuids must not be empty", ErrInvalidUserInput)
+	case len(unique) > max:
+		return nil, fmt.Errorf("%w: at most %d uuids per request", ErrInvalidUserInput, max)
+	}
+	return unique, nil
+}
+
+// missingUUIDs lists, in order, the requested uuids that no user in found has.
+func missingUUIDs(requested []uuid.UUID, found []model.User) []uuid.UUID {
+	present := make(map[string]struct{}, len(found))
+	for _, u := range found {
+		present[u.UUID] = struct{}{}
+	}
+	var missing []uuid.UUID
+	for _, id := range requested {
+		if _, ok := present[id.String()]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+func (s *userService) UpdateByID(ctx context.Context, id int64, input UpdateUserInput) (*model.User, error) {
+	if s.readOnly.Enabled() {
+		s.log.WarnContext(ctx, "update by id rejected: read-only mode", slog.Int64("user.id", id))
+		return nil, ErrReadOnly
+	}
+
 	if id <= 0 {
-		s.log.Warn("update by id invalid id", slog.Int64("user.id", id))
+		s.log.WarnContext(ctx, "update by id invalid id", slog.Int64("user.id", id))
 		return nil, ErrInvalidUserInput
 	}
 
-	if input.Username == nil && input.Email == nil && input.FullName == nil {
-		s.log.Warn("update by id invalid input: no fields provided", slog.Int64("user.id", id))
+	if len(input.Immutable) > 0 {
+		s.log.WarnContext(ctx, "update by id rejected: immutable fields provided", slog.Int64("user.id", id), slog.Any("fields", input.Immutable))
+		return nil, ErrImmutableField
+	}
+
+	if input.Username == nil && input.Email == nil && input.FullName == nil && input.AvatarURL == nil {
+		s.log.WarnContext(ctx, "update by id invalid input: no fields provided", slog.Int64("user.id", id))
 		return nil, ErrInvalidUserInput
 	}
+	if input.Version == nil {
+		verr := &ValidationError{Fields: map[string]string{"version": fieldRequired}}
+		s.log.WarnContext(ctx, "update by id invalid input: no version provided", slog.Int64("user.id", id))
+		return nil, verr
+	}
 
-	existing, err := s.repo.GetByID(id)
+	existing, err := s.primary.GetByID(ctx, id)
 	if err != nil {
-		s.log.Error("failed to fetch existing user by id", slog.Int64("user.id", id), slog.String("error", err.Error()))
+		s.log.ErrorContext(ctx, "failed to fetch existing user by id", slog.Int64("user.id", id), slog.String("error", err.Error()))
 		return nil, err
 	}
 	if existing == nil {
-		s.log.Warn("update by id target not found", slog.Int64("user.id", id))
+		s.log.WarnContext(ctx, "update by id target not found", slog.Int64("user.id", id))
+		recordUserOutcome(outcomeNotFound)
 		return nil, ErrUserNotFound
 	}
+	if input.Precondition != nil && !input.Precondition(*existing) {
+		s.log.WarnContext(ctx, "update by id rejected: precondition failed", slog.Int64("user.id", id))
+		return nil, ErrPreconditionFailed
+	}
+	if existing.Version != *input.Version {
+		s.log.WarnContext(ctx, "update by id rejected: stale version", slog.Int64("user.id", id), slog.Int("user.version", existing.Version), slog.Int("request.version", *input.Version))
+		recordUserOutcome(outcomeConflict)
+		return nil, ErrVersionConflict
+	}
 
 	username := existing.Username
 	email := existing.Email
 	fullName := existing.FullName
+	avatarURL := existing.AvatarURL
 
+	fields := map[string]string{}
 	if input.Username != nil {
 		trimmed := strings.TrimSpace(*input.Username)
-		if trimmed == "" {
-			s.log.Warn("update by id invalid username", slog.Int64("user.id", id))
-			return nil, ErrInvalidUserInput
+		if msg := s.usernamePolicy.check(trimmed); msg != "" {
+			fields["username"] = msg
 		}
 		username = trimmed
 	}
 
 	if input.Email != nil {
 		trimmed := strings.TrimSpace(*input.Email)
+		email = trimmed
 		if trimmed == "" {
-			s.log.Warn("update by id empty email", slog.Int64("user.id", id))
-			return nil, ErrInvalidUserInput
+			fields["email"] = fieldRequired
+		} else if msg := checkMaxLength(trimmed, MaxEmailLength); msg != "" {
+			fields["email"] = msg
+		} else if normalized, ok := normalizeEmail(trimmed); ok {
+			email = normalized
+		} else {
+			fields["email"] = fieldInvalidEmail
 		}
-		if _, err := mail.ParseAddress(trimmed); err != nil {
-			s.log.Warn("update by id invalid email", slog.Int64("user.id", id))
-			return nil, ErrInvalidUserInput
-		}
-		email = trimmed
 	}
 
+	// unlike username and email, full_name may be cleared: "" (or blanks)
+	// stores an empty name, while a nil pointer leaves it untouched
 	if input.FullName != nil {
-		trimmed := strings.TrimSpace(*input.FullName)
-		fullName = trimmed
+		fullName = strings.TrimSpace(*input.FullName)
+		if msg := checkMaxLength(fullName, MaxFullNameLength); msg != "" {
+			fields["full_name"] = msg
+		}
+	}
+	if input.AvatarURL != nil {
+		avatarURL = strings.TrimSpace(*input.AvatarURL)
+		if avatarURL != "" {
+			if msg := checkAvatarURL(avatarURL); msg != "" {
+				fields["avatar_url"] = msg
+			}
+		}
+	}
+
+	if input.Username != nil || input.Email != nil {
+		if err := s.checkUsernameNotEmail(username, email); err != nil {
+			s.log.WarnContext(ctx, "update by id rejected: username looks like an email", slog.Int64("user.id", id))
+			return nil, err
+		}
+	}
+	if len(fields) > 0 {
+		verr := &ValidationError{Fields: fields}
+		s.log.WarnContext(ctx, "update by id invalid input", slog.Int64("user.id", id), slog.String("error", verr.Error()))
+		return nil, verr
 	}
 
-	updated, err := s.repo.UpdateByID(id, username, email, fullName)
+	updated, err := s.repo.UpdateByID(ctx, id, username, email, fullName, avatarURL, *input.Version)
 	if err != nil {
 		if errors.Is(err, repository.ErrUniqueViolation) {
-			s.log.Warn("update by id duplicate", slog.Int64("user.id", id))
-			return nil, ErrUserAlreadyExists
+			conflict := conflictError(err)
+			s.log.WarnContext(ctx, "update by id duplicate", slog.Int64("user.id", id), slog.String("error", conflict.Error()))
+			recordUserOutcome(outcomeConflict)
+			return nil, conflict
+		}
+		if errors.Is(err, repository.ErrConstraintViolation) {
+			s.log.WarnContext(ctx, "update by id rejected by database constraint", slog.Int64("user.id", id), slog.String("error", err.Error()))
+			return nil, ErrInvalidUserInput
 		}
-		s.log.Error("update by id repository error", slog.Int64("user.id", id), slog.String("error", err.Error()))
+		s.log.ErrorContext(ctx, "update by id repository error", slog.Int64("user.id", id), slog.String("error", err.Error()))
 		return nil, err
 	}
 	if updated == nil {
-		s.log.Warn("update by id resulted in not found", slog.Int64("user.id", id))
-		return nil, ErrUserNotFound
+		// the row was read above, so a concurrent update or delete won the race
+		s.log.WarnContext(ctx, "update by id lost a concurrent write", slog.Int64("user.id", id))
+		recordUserOutcome(outcomeConflict)
+		return nil, ErrVersionConflict
 	}
-	s.log.Info("user updated by id", slog.Int("user.id", updated.ID), slog.String("user.uuid", updated.UUID))
+	s.log.InfoContext(ctx, "user updated by id", slog.Int("user.id", updated.ID), slog.String("user.uuid", updated.UUID))
+	s.committed(ctx, AuditActionUpdate, existing, updated)
 	return updated, nil
 }
 
-func (s *userService) DeleteByID(id int64) error {
+func (s *userService) DeleteByID(ctx context.Context, id int64) error {
+	if s.readOnly.Enabled() {
+		s.log.WarnContext(ctx, "delete by id rejected: read-only mode", slog.Int64("user.id", id))
+		return ErrReadOnly
+	}
+
 	if id <= 0 {
-		s.log.Warn("delete by id invalid id", slog.Int64("user.id", id))
+		s.log.WarnContext(ctx, "delete by id invalid id", slog.Int64("user.id", id))
 		return ErrInvalidUserInput
 	}
 
-	ok, err := s.repo.DeleteByID(id)
+	deleted, err := s.repo.DeleteByID(ctx, id)
 	if err != nil {
-		s.log.Error("delete by id repository error", slog.Int64("user.id", id), slog.String("error", err.Error()))
+		s.log.ErrorContext(ctx, "delete by id repository error", slog.Int64("user.id", id), slog.String("error", err.Error()))
 		return err
 	}
-	if !ok {
-		s.log.Warn("delete by id target not found", slog.Int64("user.id", id))
+	if deleted == nil {
+		s.log.WarnContext(ctx, "delete by id target not found", slog.Int64("user.id", id))
+		recordUserOutcome(outcomeNotFound)
 		return ErrUserNotFound
 	}
-	s.log.Info("user deleted by id", slog.Int64("user.id", id))
+	s.log.InfoContext(ctx, "user deleted by id", slog.Int64("user.id", id))
+	s.committed(ctx, AuditActionDelete, deleted, nil)
 	return nil
 }
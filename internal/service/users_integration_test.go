@@ -5,27 +5,38 @@ package service_test
 import (
 	"fmt"
 	"net/http"
+	"strings"
+	"sync"
 	"testing"
 
 	"cruder/internal/middleware"
+	"cruder/internal/repository"
 	"cruder/internal/service"
 
 	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
 const usersBasePath = "/api/v1/users"
 
 type userResponse struct {
-	ID       int    `json:"id"`
-	UUID     string `json:"uuid"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	FullName string `json:"full_name"`
+	ID            int     `json:"id"`
+	UUID          string  `json:"uuid"`
+	Username      string  `json:"username"`
+	Email         string  `json:"email"`
+	FullName      string  `json:"full_name"`
+	AvatarURL     *string `json:"avatar_url"`
+	EmailVerified bool    `json:"email_verified"`
+	Status        string  `json:"status"`
+	Version       int     `json:"version"`
 }
 
 type errorResponse struct {
-	Error string `json:"error"`
+	Error  string            `json:"error"`
+	Param  string            `json:"param"`
+	Rule   string            `json:"rule"`
+	Fields map[string]string `json:"fields"`
 }
 
 func TestFunctionalUserLifecycle(t *testing.T) {
@@ -87,14 +98,117 @@ func TestFunctionalUserLifecycle(t *testing.T) {
 	require.Equal(t, service.ErrUserNotFound.Error(), errResp.Error)
 }
 
+func TestFunctionalListUsers_Pagination(t *testing.T) {
+	resetUsersTable(t)
+
+	// page 2 of size 2 holds only the third seeded user
+	var page []userResponse
+	resp, err := restyClient().R().
+		SetResult(&page).
+		Get(apiBaseURL + usersBasePath + "/?page=2&per_page=2")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Len(t, page, 1)
+	require.Equal(t, "bjones", page[0].Username)
+
+	// the same window in offset style
+	resp, err = restyClient().R().
+		SetResult(&page).
+		Get(apiBaseURL + usersBasePath + "/?limit=2&offset=2")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Len(t, page, 1)
+
+	resp, err = restyClient().R().
+		Get(apiBaseURL + usersBasePath + "/?page=1&limit=2")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+}
+
+func TestFunctionalListUsers_Cursor(t *testing.T) {
+	resetUsersTable(t)
+
+	type userPage struct {
+		Users      []userResponse `json:"users"`
+		NextCursor *int64         `json:"next_cursor"`
+	}
+
+	// first page of two from the start
+	var page userPage
+	resp, err := restyClient().R().
+		SetResult(&page).
+		Get(apiBaseURL + usersBasePath + "/?after=0&limit=2")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Len(t, page.Users, 2)
+	require.NotNil(t, page.NextCursor)
+
+	// a user inserted mid-scan lands after the cursor instead of shifting the window
+	createUser(t, "late_user", "late@example.com", "Late User")
+
+	var next userPage
+	resp, err = restyClient().R().
+		SetResult(&next).
+		Get(fmt.Sprintf("%s%s/?after=%d&limit=2", apiBaseURL, usersBasePath, *page.NextCursor))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Len(t, next.Users, 2)
+	require.Equal(t, "bjones", next.Users[0].Username)
+	require.Equal(t, "late_user", next.Users[1].Username)
+}
+
+func TestFunctionalListUsers_Pinned(t *testing.T) {
+	resetUsersTable(t)
+
+	var all []userResponse
+	resp, err := restyClient().R().
+		SetResult(&all).
+		Get(apiBaseURL + usersBasePath + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	byName := make(map[string]string, len(all))
+	for _, u := range all {
+		byName[u.Username] = u.UUID
+	}
+
+	// Given: bjones then jdoe pinned, plus a UUID that matches nobody
+	pin := strings.Join([]string{byName["bjones"], uuid.NewString(), byName["jdoe"]}, ",")
+
+	// When: listing with the pin list
+	var users []userResponse
+	resp, err = restyClient().R().
+		SetResult(&users).
+		Get(apiBaseURL + usersBasePath + "/?pin=" + pin)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// Then: pinned users lead in the given order and the rest follow by id
+	usernames := make([]string, len(users))
+	for i, u := range users {
+		usernames[i] = u.Username
+	}
+	require.Equal(t, []string{"bjones", "jdoe", "asmith"}, usernames)
+
+	// and pinning composes with pagination
+	resp, err = restyClient().R().
+		SetResult(&users).
+		Get(apiBaseURL + usersBasePath + "/?limit=2&offset=1&pin=" + pin)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Len(t, users, 2)
+	require.Equal(t, "jdoe", users[0].Username)
+	require.Equal(t, "asmith", users[1].Username)
+}
+
 func TestFunctionalUpdateByUUID(t *testing.T) {
 	resetUsersTable(t)
 	user := createUser(t, "update_uuid", "update@example.com", "Update UUID")
 
-	updatePayload := map[string]string{
+	updatePayload := map[string]any{
 		"username":  "updated_name",
 		"email":     "updated@example.com",
 		"full_name": "Updated Full Name",
+		"version":   user.Version,
 	}
 
 	var updated userResponse
@@ -107,13 +221,46 @@ func TestFunctionalUpdateByUUID(t *testing.T) {
 	require.Equal(t, updatePayload["username"], updated.Username)
 	require.Equal(t, updatePayload["email"], updated.Email)
 	require.Equal(t, updatePayload["full_name"], updated.FullName)
+	require.Equal(t, user.Version+1, updated.Version)
+}
+
+func TestFunctionalUpdate_VersionConflict(t *testing.T) {
+	resetUsersTable(t)
+	user := createUser(t, "versioned", "versioned@example.com", "Versioned User")
+	url := fmt.Sprintf("%s%s/uuid/%s", apiBaseURL, usersBasePath, user.UUID)
+
+	// Given: one client updates from the version both clients read
+	resp, err := restyClient().R().
+		SetBody(map[string]any{"full_name": "First Writer", "version": user.Version}).
+		Patch(url)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// When: the other client updates from the same version
+	var errResp struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	resp, err = restyClient().R().
+		SetBody(map[string]any{"full_name": "Second Writer", "version": user.Version}).
+		SetError(&errResp).
+		Patch(url)
+
+	// Then: the second update is refused instead of overwriting the first
+	require.NoError(t, err)
+	require.Equal(t, http.StatusConflict, resp.StatusCode())
+	require.Equal(t, "version_conflict", errResp.Code)
+	var fetched userResponse
+	_, err = restyClient().R().SetResult(&fetched).Get(url)
+	require.NoError(t, err)
+	require.Equal(t, "First Writer", fetched.FullName)
 }
 
 func TestFunctionalUpdateByUUID_InvalidEmail(t *testing.T) {
 	resetUsersTable(t)
 	user := createUser(t, "invalid_email", "valid@example.com", "Invalid Email")
 
-	payload := map[string]string{"email": "not-an-email"}
+	payload := map[string]any{"email": "not-an-email", "version": user.Version}
 	var errResp errorResponse
 	resp, err := restyClient().R().
 		SetBody(payload).
@@ -122,6 +269,111 @@ func TestFunctionalUpdateByUUID_InvalidEmail(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
 	require.Equal(t, service.ErrInvalidUserInput.Error(), errResp.Error)
+	require.Equal(t, map[string]string{"email": "not a valid address"}, errResp.Fields)
+}
+
+func TestFunctionalUpdate_MergePatch(t *testing.T) {
+	resetUsersTable(t)
+	user := createUser(t, "merge_patch", "merge@example.com", "Merge Patch")
+	url := fmt.Sprintf("%s%s/uuid/%s", apiBaseURL, usersBasePath, user.UUID)
+
+	// When: full_name is sent as null and email is left out
+	var updated userResponse
+	resp, err := restyClient().R().
+		SetBody(map[string]any{"full_name": nil, "version": user.Version}).
+		SetResult(&updated).
+		Patch(url)
+
+	// Then: the name is cleared and the email is kept
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Empty(t, updated.FullName)
+	require.Equal(t, "merge@example.com", updated.Email)
+
+	// And: email cannot be cleared with null
+	var errResp errorResponse
+	resp, err = restyClient().R().
+		SetBody(map[string]any{"email": nil, "version": updated.Version}).
+		SetError(&errResp).
+		Patch(url)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+	require.Equal(t, map[string]string{"email": "required"}, errResp.Fields)
+}
+
+func TestFunctionalAvatarURL(t *testing.T) {
+	resetUsersTable(t)
+	user := createUser(t, "avatar_user", "avatar@example.com", "Avatar User")
+	require.Nil(t, user.AvatarURL)
+	url := fmt.Sprintf("%s%s/uuid/%s", apiBaseURL, usersBasePath, user.UUID)
+
+	// When: a script URL is sent
+	var errResp errorResponse
+	resp, err := restyClient().R().
+		SetBody(map[string]any{"avatar_url": "javascript:alert(1)", "version": user.Version}).
+		SetError(&errResp).
+		Patch(url)
+
+	// Then: it is refused by field
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+	require.Equal(t, map[string]string{"avatar_url": "must be an http or https URL"}, errResp.Fields)
+
+	// When: an https URL is set
+	var updated userResponse
+	resp, err = restyClient().R().
+		SetBody(map[string]any{"avatar_url": "https://cdn.example.com/avatar.png", "version": user.Version}).
+		SetResult(&updated).
+		Patch(url)
+
+	// Then: it is stored and returned
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.NotNil(t, updated.AvatarURL)
+	require.Equal(t, "https://cdn.example.com/avatar.png", *updated.AvatarURL)
+
+	// And: null removes it again
+	resp, err = restyClient().R().
+		SetBody(map[string]any{"avatar_url": nil, "version": updated.Version}).
+		SetResult(&updated).
+		Patch(url)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Nil(t, updated.AvatarURL)
+}
+
+func TestFunctionalUpdate_RejectsImmutableFields(t *testing.T) {
+	resetUsersTable(t)
+	user := createUser(t, "immutable_ids", "immutable@example.com", "Immutable IDs")
+
+	// When: the body tries to change the uuid on the uuid route
+	var errResp errorResponse
+	resp, err := restyClient().R().
+		SetBody(map[string]any{"uuid": "00000000-0000-0000-0000-000000000001", "full_name": "Changed"}).
+		SetError(&errResp).
+		Patch(fmt.Sprintf("%s%s/uuid/%s", apiBaseURL, usersBasePath, user.UUID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+	require.Equal(t, service.ErrImmutableField.Error(), errResp.Error)
+
+	// And: the body tries to change the id on the id route
+	resp, err = restyClient().R().
+		SetBody(map[string]any{"id": 999, "full_name": "Changed"}).
+		SetError(&errResp).
+		Patch(fmt.Sprintf("%s%s/id/%d", apiBaseURL, usersBasePath, user.ID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+	require.Equal(t, service.ErrImmutableField.Error(), errResp.Error)
+
+	// Then: the user is unchanged
+	var fetched userResponse
+	resp, err = restyClient().R().
+		SetResult(&fetched).
+		Get(fmt.Sprintf("%s%s/uuid/%s", apiBaseURL, usersBasePath, user.UUID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Equal(t, user.ID, fetched.ID)
+	require.Equal(t, "Immutable IDs", fetched.FullName)
 }
 
 func TestFunctionalGetUserByUUID(t *testing.T) {
@@ -145,13 +397,15 @@ func TestFunctionalGetUserByUUID(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
 	require.Equal(t, "invalid uuid", errResp.Error)
+	require.Equal(t, "uuid", errResp.Param)
+	require.Equal(t, "uuid", errResp.Rule)
 }
 
 func TestFunctionalUpdateByID(t *testing.T) {
 	resetUsersTable(t)
 	user := createUser(t, "update_id", "updateid@example.com", "Update ID")
 
-	newName := map[string]string{"full_name": "Updated Via ID"}
+	newName := map[string]any{"full_name": "Updated Via ID", "version": user.Version}
 	var updated userResponse
 	resp, err := restyClient().R().
 		SetBody(newName).
@@ -181,6 +435,112 @@ func TestFunctionalCreate_Duplicate(t *testing.T) {
 	require.Equal(t, service.ErrUserAlreadyExists.Error(), errResp.Error)
 }
 
+func TestFunctionalCreate_DuplicateEmailDifferentCase(t *testing.T) {
+	resetUsersTable(t)
+	created := createUser(t, "case_user", "Case.User@Example.com", "Case User")
+	require.Equal(t, "Case.User@example.com", created.Email)
+
+	payload := map[string]string{
+		"username":  "case_user2",
+		"email":     "case.user@EXAMPLE.com",
+		"full_name": "Case User Two",
+	}
+	var errResp errorResponse
+	resp, err := restyClient().R().
+		SetBody(payload).
+		SetError(&errResp).
+		Post(apiBaseURL + usersBasePath + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusConflict, resp.StatusCode())
+	require.Equal(t, service.ErrUserAlreadyExists.Error(), errResp.Error)
+}
+
+func TestFunctionalCreate_IdempotencyKeyReplays(t *testing.T) {
+	resetUsersTable(t)
+	payload := map[string]string{
+		"username":  "retry_user",
+		"email":     "retry@example.com",
+		"full_name": "Retry User",
+	}
+
+	// Given: a create that succeeded with an idempotency key
+	var first userResponse
+	resp, err := restyClient().R().
+		SetHeader(middleware.HeaderIdempotencyKey, "import-42").
+		SetBody(payload).
+		SetResult(&first).
+		Post(apiBaseURL + usersBasePath + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode())
+
+	// When: the importer retries with the same key
+	var replayed userResponse
+	resp, err = restyClient().R().
+		SetHeader(middleware.HeaderIdempotencyKey, "import-42").
+		SetBody(payload).
+		SetResult(&replayed).
+		Post(apiBaseURL + usersBasePath + "/")
+
+	// Then: the original 201 comes back instead of a 409
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode())
+	require.Equal(t, "true", resp.Header().Get(middleware.HeaderIdempotentReplayed))
+	require.Equal(t, first.UUID, replayed.UUID)
+	require.Equal(t, usersBasePath+"/uuid/"+first.UUID, resp.Header().Get("Location"))
+
+	// and the same key with another body is refused
+	payload["username"] = "other_user"
+	resp, err = restyClient().R().
+		SetHeader(middleware.HeaderIdempotencyKey, "import-42").
+		SetBody(payload).
+		Post(apiBaseURL + usersBasePath + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode())
+}
+
+func TestFunctionalCreate_ConcurrentIdempotentRetriesCreateOnce(t *testing.T) {
+	resetUsersTable(t)
+	payload := map[string]string{
+		"username":  "race_user",
+		"email":     "race@example.com",
+		"full_name": "Race User",
+	}
+
+	// When: an importer fires the same keyed create several times at once
+	const attempts = 8
+	codes := make([]int, attempts)
+	errs := make([]errorResponse, attempts)
+	var wg sync.WaitGroup
+	for i := range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := restyClient().R().
+				SetHeader(middleware.HeaderIdempotencyKey, "import-race").
+				SetBody(payload).
+				SetError(&errs[i]).
+				Post(apiBaseURL + usersBasePath + "/")
+			if err == nil {
+				codes[i] = resp.StatusCode()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Then: each attempt created the user, replayed it, or was told to
+	// retry; none got as far as a duplicate insert
+	for i, code := range codes {
+		if code != http.StatusCreated {
+			require.Equal(t, http.StatusConflict, code)
+			require.Equal(t, service.ErrIdempotencyInProgress.Error(), errs[i].Error)
+		}
+	}
+	// and exactly one user exists
+	var count int
+	require.NoError(t, testDB.QueryRow(`SELECT count(*) FROM users WHERE username = 'race_user'`).Scan(&count))
+	require.Equal(t, 1, count)
+}
+
 func TestFunctionalGetByUsername_NotFound(t *testing.T) {
 	resetUsersTable(t)
 	var errResp errorResponse
@@ -220,6 +580,222 @@ func TestFunctionalDeleteByID(t *testing.T) {
 	require.Equal(t, "invalid id", errResp.Error)
 }
 
+func TestFunctionalDeleteByUsername(t *testing.T) {
+	resetUsersTable(t)
+	created := createUser(t, "delete_name", "deletename@example.com", "Delete Name")
+
+	// When: deleting by username
+	resp, err := restyClient().R().
+		Delete(fmt.Sprintf("%s%s/username/%s", apiBaseURL, usersBasePath, created.Username))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode())
+
+	// Then: the user is gone and deleting again returns 404
+	resp, err = restyClient().R().
+		Get(fmt.Sprintf("%s%s/uuid/%s", apiBaseURL, usersBasePath, created.UUID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode())
+
+	var errResp errorResponse
+	resp, err = restyClient().R().
+		SetError(&errResp).
+		Delete(fmt.Sprintf("%s%s/username/%s", apiBaseURL, usersBasePath, created.Username))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode())
+	require.Equal(t, service.ErrUserNotFound.Error(), errResp.Error)
+}
+
+func TestFunctionalBatchGet(t *testing.T) {
+	resetUsersTable(t)
+	first := createUser(t, "batch_one", "batchone@example.com", "Batch One")
+	second := createUser(t, "batch_two", "batchtwo@example.com", "Batch Two")
+	missing := uuid.NewString()
+
+	// When: fetching both users plus an unknown uuid through the query string
+	var result struct {
+		Users   []userResponse `json:"users"`
+		Missing []string       `json:"missing"`
+	}
+	resp, err := restyClient().R().
+		SetQueryParam("uuids", strings.Join([]string{first.UUID, missing, second.UUID}, ",")).
+		SetResult(&result).
+		Post(apiBaseURL + usersBasePath + "/batch-get")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// Then: both users come back and the unknown uuid is reported
+	require.ElementsMatch(t, []userResponse{first, second}, result.Users)
+	require.Equal(t, []string{missing}, result.Missing)
+}
+
+func TestFunctionalBulkDelete(t *testing.T) {
+	resetUsersTable(t)
+	first := createUser(t, "bulk_one", "bulkone@example.com", "Bulk One")
+	second := createUser(t, "bulk_two", "bulktwo@example.com", "Bulk Two")
+	missing := uuid.NewString()
+
+	// When: bulk deleting both users plus an unknown uuid
+	var result struct {
+		Deleted  int      `json:"deleted"`
+		NotFound []string `json:"not_found"`
+	}
+	resp, err := restyClient().R().
+		SetBody(map[string][]string{"uuids": {first.UUID, missing, second.UUID}}).
+		SetResult(&result).
+		Post(apiBaseURL + usersBasePath + "/bulk-delete")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// Then: both are gone, each audited, and the unknown uuid is reported
+	require.Equal(t, 2, result.Deleted)
+	require.Equal(t, []string{missing}, result.NotFound)
+	for _, u := range []userResponse{first, second} {
+		resp, err = restyClient().R().
+			Get(fmt.Sprintf("%s%s/uuid/%s", apiBaseURL, usersBasePath, u.UUID))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, resp.StatusCode())
+	}
+	var audited int
+	require.NoError(t, testDB.QueryRow(`SELECT COUNT(*) FROM audit_log WHERE action = 'user.delete'`).Scan(&audited))
+	require.Equal(t, 2, audited)
+}
+
+func TestFunctionalBulkUpdate(t *testing.T) {
+	resetUsersTable(t)
+	first := createUser(t, "bulkupd_one", "bulkupdone@example.com", "Bulk One")
+	second := createUser(t, "bulkupd_two", "bulkupdtwo@example.com", "Bulk Two")
+	missing := uuid.NewString()
+
+	// When: suspending both users plus an unknown uuid
+	var result struct {
+		Updated  int      `json:"updated"`
+		NotFound []string `json:"not_found"`
+	}
+	resp, err := restyClient().R().
+		SetBody(map[string]any{"uuids": []string{first.UUID, missing, second.UUID}, "status": "suspended"}).
+		SetResult(&result).
+		Patch(apiBaseURL + usersBasePath + "/bulk")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// Then: both are suspended, each audited, and the unknown uuid is reported
+	require.Equal(t, 2, result.Updated)
+	require.Equal(t, []string{missing}, result.NotFound)
+	var suspended int
+	require.NoError(t, testDB.QueryRow(`SELECT COUNT(*) FROM users WHERE status = 'suspended' AND version = 2`).Scan(&suspended))
+	require.Equal(t, 2, suspended)
+	var audited int
+	require.NoError(t, testDB.QueryRow(`SELECT COUNT(*) FROM audit_log WHERE action = 'user.set_status'`).Scan(&audited))
+	require.Equal(t, 2, audited)
+
+	// When: repeating the request, then naming a field that can't be bulk set
+	resp, err = restyClient().R().
+		SetBody(map[string]any{"uuids": []string{first.UUID, second.UUID}, "status": "suspended"}).
+		SetResult(&result).
+		Patch(apiBaseURL + usersBasePath + "/bulk")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Equal(t, 0, result.Updated)
+	resp, err = restyClient().R().
+		SetBody(map[string]any{"uuids": []string{first.UUID}, "email": "taken@example.com"}).
+		Patch(apiBaseURL + usersBasePath + "/bulk")
+	require.NoError(t, err)
+
+	// Then: nothing changes the second time and email is refused
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+}
+
+func TestFunctionalHeadUser(t *testing.T) {
+	resetUsersTable(t)
+	created := createUser(t, "head_user", "headuser@example.com", "Head User")
+
+	// When: checking the user and an unknown uuid with HEAD
+	resp, err := restyClient().R().
+		Head(fmt.Sprintf("%s%s/uuid/%s", apiBaseURL, usersBasePath, created.UUID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Empty(t, resp.Body())
+
+	resp, err = restyClient().R().
+		Head(fmt.Sprintf("%s%s/uuid/%s", apiBaseURL, usersBasePath, uuid.NewString()))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode())
+
+	// Then: the id route agrees, and the check still needs an API key
+	resp, err = restyClient().R().
+		Head(fmt.Sprintf("%s%s/id/%d", apiBaseURL, usersBasePath, created.ID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	resp, err = resty.New().R().
+		Head(fmt.Sprintf("%s%s/uuid/%s", apiBaseURL, usersBasePath, created.UUID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode())
+}
+
+func TestFunctionalListCreatedRange(t *testing.T) {
+	resetUsersTable(t)
+	for _, row := range []struct{ name, createdAt string }{
+		{"range_early", "2025-12-31 23:59:59"},
+		{"range_start", "2026-01-01 00:00:00"},
+		{"range_late", "2026-01-31 12:00:00"},
+		{"range_end", "2026-02-01 00:00:00"},
+	} {
+		_, err := testDB.Exec(`INSERT INTO users (username, email, full_name, created_at) VALUES ($1, $1 || '@example.com', 'Range', $2)`, row.name, row.createdAt)
+		require.NoError(t, err)
+	}
+
+	// When: listing January, in full and one user per keyset page
+	var users []userResponse
+	resp, err := restyClient().R().
+		SetResult(&users).
+		Get(apiBaseURL + usersBasePath + "/?created_after=2026-01-01T00:00:00Z&created_before=2026-02-01T00:00:00Z")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	var page struct {
+		Users      []userResponse `json:"users"`
+		NextCursor *int64         `json:"next_cursor"`
+	}
+	resp, err = restyClient().R().
+		SetResult(&page).
+		Get(apiBaseURL + usersBasePath + "/?created_after=2026-01-01T00:00:00Z&created_before=2026-02-01T00:00:00Z&after=0&limit=1")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// Then: the range includes its start and excludes its end
+	require.Len(t, users, 2)
+	require.Equal(t, "range_start", users[0].Username)
+	require.Equal(t, "range_late", users[1].Username)
+	require.Len(t, page.Users, 1)
+	require.Equal(t, "range_start", page.Users[0].Username)
+	require.NotNil(t, page.NextCursor)
+}
+
+func TestFunctionalListCreatedRangeWithOffset(t *testing.T) {
+	// Given: a user created at 08:30 in the session time zone, UTC in the test database
+	resetUsersTable(t)
+	_, err := testDB.Exec(`INSERT INTO users (username, email, full_name, created_at) VALUES ('offset_user', 'offset@example.com', 'Offset', '2024-01-01 08:30:00')`)
+	require.NoError(t, err)
+
+	// When: listing from 10:00+02:00 (08:00 UTC) and from 10:45+02:00 (08:45 UTC)
+	list := func(after string) []userResponse {
+		var users []userResponse
+		resp, err := restyClient().R().
+			SetQueryParam("created_after", after).
+			SetResult(&users).
+			Get(apiBaseURL + usersBasePath + "/")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode())
+		return users
+	}
+	before, after := list("2024-01-01T10:00:00+02:00"), list("2024-01-01T10:45:00+02:00")
+
+	// Then: the offset is honoured rather than read as wall time
+	require.Len(t, before, 1)
+	require.Empty(t, after)
+}
+
 func createUser(t *testing.T, username, email, fullName string) userResponse {
 	t.Helper()
 	payload := map[string]string{
@@ -234,6 +810,7 @@ func createUser(t *testing.T, username, email, fullName string) userResponse {
 		Post(apiBaseURL + usersBasePath + "/")
 	require.NoError(t, err)
 	require.Equal(t, http.StatusCreated, resp.StatusCode())
+	require.Equal(t, usersBasePath+"/uuid/"+user.UUID, resp.Header().Get("Location"))
 
 	return user
 }
@@ -244,3 +821,97 @@ func restyClient() *resty.Client {
 	return httpClient.SetBaseURL(apiBaseURL).
 		SetHeader(middleware.HeaderAPIKey, testAPIKey)
 }
+
+func TestFunctionalSuspendAndActivate(t *testing.T) {
+	resetUsersTable(t)
+	created := createUser(t, "suspend_me", "suspend@example.com", "Suspend Me")
+	require.Equal(t, "active", created.Status)
+	userURL := fmt.Sprintf("%s%s/uuid/%s", apiBaseURL, usersBasePath, created.UUID)
+
+	// When: suspending the user
+	var suspended userResponse
+	resp, err := restyClient().R().SetResult(&suspended).Post(userURL + "/suspend")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Equal(t, "suspended", suspended.Status)
+	require.Equal(t, created.Version+1, suspended.Version)
+
+	// Then: they can still be fetched, showing the status
+	var fetched userResponse
+	resp, err = restyClient().R().SetResult(&fetched).Get(userURL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Equal(t, "suspended", fetched.Status)
+
+	// And: activating them restores the status
+	var activated userResponse
+	resp, err = restyClient().R().SetResult(&activated).Post(userURL + "/activate")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Equal(t, "active", activated.Status)
+
+	resp, err = restyClient().R().Post(fmt.Sprintf("%s%s/uuid/%s/suspend", apiBaseURL, usersBasePath, uuid.New()))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode())
+}
+
+func TestFunctionalUserStats(t *testing.T) {
+	resetUsersTable(t)
+	createUser(t, "stats_one", "stats1@example.com", "Stats One")
+	second := createUser(t, "stats_two", "stats2@example.com", "Stats Two")
+	resp, err := restyClient().R().Post(fmt.Sprintf("%s%s/uuid/%s/suspend", apiBaseURL, usersBasePath, second.UUID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	var stats struct {
+		Total        int64 `json:"total"`
+		CreatedToday int64 `json:"created_today"`
+		Suspended    int64 `json:"suspended"`
+	}
+	resp, err = restyClient().R().SetResult(&stats).Get(apiBaseURL + usersBasePath + "/stats")
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.EqualValues(t, 2, stats.Total)
+	require.EqualValues(t, 2, stats.CreatedToday)
+	require.EqualValues(t, 1, stats.Suspended)
+}
+
+func TestFunctionalCreateRejectsFormBody(t *testing.T) {
+	resetUsersTable(t)
+
+	// When: creating a user from a form-encoded body
+	resp, err := restyClient().R().
+		SetFormData(map[string]string{"username": "formuser", "email": "form@example.com"}).
+		Post(apiBaseURL + usersBasePath + "/")
+	require.NoError(t, err)
+
+	// Then: it is refused as the wrong media type and nothing is stored
+	require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode())
+	var count int
+	require.NoError(t, testDB.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count))
+	require.Zero(t, count)
+}
+
+func TestFunctionalUniqueFullName(t *testing.T) {
+	// Given: the optional full_name index, and a user called "Shared Name"
+	resetUsersTable(t)
+	_, err := testDB.Exec(repository.CreateFullNameIndexSQL)
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = testDB.Exec(`DROP INDEX IF EXISTS ` + repository.FullNameIndex) })
+	createUser(t, "shared_one", "sharedone@example.com", "Shared Name")
+
+	// When: another user takes the same name
+	var body struct {
+		Fields map[string]string `json:"fields"`
+	}
+	resp, err := restyClient().R().
+		SetBody(map[string]string{"username": "shared_two", "email": "sharedtwo@example.com", "full_name": "Shared Name"}).
+		SetError(&body).
+		Post(apiBaseURL + usersBasePath + "/")
+	require.NoError(t, err)
+
+	// Then: the conflict names full_name
+	require.Equal(t, http.StatusConflict, resp.StatusCode())
+	require.Equal(t, map[string]string{"full_name": "already taken"}, body.Fields)
+}
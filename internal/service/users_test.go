@@ -3,14 +3,22 @@ package service
 //go:generate sh -c "cd ../.. && mockery --config=mockery.yaml"
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
 	"testing"
+	"time"
 
 	"cruder/internal/model"
 	"cruder/internal/repository"
 	"cruder/internal/service/mocks"
+	"cruder/pkg/logger"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -21,7 +29,7 @@ func TestUserService_Create_Success(t *testing.T) {
 	// Given: a repository that accepts user creation
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
-	repo.On("Create", "new_user", "user@example.com", "Test User").
+	repo.On("Create", mock.Anything, "new_user", "user@example.com", "Test User", "").
 		Return(&model.User{
 			ID:       1,
 			UUID:     uuid.NewString(),
@@ -31,7 +39,7 @@ func TestUserService_Create_Success(t *testing.T) {
 		}, nil).Once()
 
 	// When: creating a user with padded fields
-	user, err := service.Create("  new_user  ", "user@example.com", "  Test User ")
+	user, err := service.Create(context.Background(), "  new_user  ", "user@example.com", "  Test User ", "")
 
 	// Then: the user is created and trimmed input was passed to the repository
 	require.NoError(t, err)
@@ -40,53 +48,648 @@ func TestUserService_Create_Success(t *testing.T) {
 	repo.AssertExpectations(t)
 }
 
+func TestUserService_RecordsOutcomeMetrics(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	created := userOutcomesTotal.WithLabelValues(outcomeCreated)
+	conflict := userOutcomesTotal.WithLabelValues(outcomeConflict)
+	notFound := userOutcomesTotal.WithLabelValues(outcomeNotFound)
+	createdBefore := testutil.ToFloat64(created)
+	conflictBefore := testutil.ToFloat64(conflict)
+	notFoundBefore := testutil.ToFloat64(notFound)
+
+	repo.On("Create", mock.Anything, "metrics_user", "metrics@example.com", "Metrics User", "").
+		Return(&model.User{ID: 1, Username: "metrics_user"}, nil).Once()
+	repo.On("Create", mock.Anything, "dup_user", "dup@example.com", "Dup User", "").
+		Return((*model.User)(nil), repository.ErrUniqueViolation).Once()
+	repo.On("GetByID", mock.Anything, int64(99)).Return((*model.User)(nil), nil).Once()
+
+	_, err := service.Create(context.Background(), "metrics_user", "metrics@example.com", "Metrics User", "")
+	require.NoError(t, err)
+	_, err = service.Create(context.Background(), "dup_user", "dup@example.com", "Dup User", "")
+	require.ErrorIs(t, err, ErrUserAlreadyExists)
+	_, err = service.GetByID(context.Background(), 99)
+	require.ErrorIs(t, err, ErrUserNotFound)
+
+	require.Equal(t, createdBefore+1, testutil.ToFloat64(created))
+	require.Equal(t, conflictBefore+1, testutil.ToFloat64(conflict))
+	require.Equal(t, notFoundBefore+1, testutil.ToFloat64(notFound))
+}
+
 func TestUserService_Create_InvalidEmail(t *testing.T) {
 	// Given: a user service with a mock repository
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
 
 	// When: creating a user with malformed email
-	_, err := service.Create("name", "invalid-email", "Full Name")
+	_, err := service.Create(context.Background(), "name", "invalid-email", "Full Name", "")
 
 	// Then: invalid user input error is returned
 	require.ErrorIs(t, err, ErrInvalidUserInput)
-	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_Create_LowercasesEmailDomain(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("Create", mock.Anything, "new_user", "Foo.Bar@example.com", "Test User", "").
+		Return(&model.User{ID: 1, Username: "new_user", Email: "Foo.Bar@example.com"}, nil).Once()
+
+	_, err := service.Create(context.Background(), "new_user", "Foo Bar <Foo.Bar@Example.COM>", "Test User", "")
+
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestUserService_Create_UsernameTooLong(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+
+	_, err := service.Create(context.Background(), strings.Repeat("a", MaxUsernameLength+1), "user@example.com", "Full Name", "")
+
+	require.ErrorIs(t, err, ErrInvalidUserInput)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestUserService_Create_Duplicate(t *testing.T) {
 	// Given: repository returns unique violation
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
-	repo.On("Create", "dup_user", "dup@example.com", "Dup User").
+	repo.On("Create", mock.Anything, "dup_user", "dup@example.com", "Dup User", "").
 		Return((*model.User)(nil), repository.ErrUniqueViolation).Once()
 
 	// When: creating a user with duplicate data
-	_, err := service.Create("dup_user", "dup@example.com", "Dup User")
+	_, err := service.Create(context.Background(), "dup_user", "dup@example.com", "Dup User", "")
 
 	// Then: duplicate error is translated to ErrUserAlreadyExists
 	require.ErrorIs(t, err, ErrUserAlreadyExists)
 	repo.AssertExpectations(t)
 }
 
+func TestUserService_Create_DuplicateNamesField(t *testing.T) {
+	// Given: the lower(email) index rejects the insert
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("Create", mock.Anything, "jdoe2", "jdoe@example.com", "John Doe", "").
+		Return((*model.User)(nil), &repository.ConstraintError{Kind: repository.ErrUniqueViolation, Constraint: "users_email_lower_key"}).Once()
+
+	// When: creating the user
+	_, err := service.Create(context.Background(), "jdoe2", "jdoe@example.com", "John Doe", "")
+
+	// Then: the conflict names the email field
+	require.ErrorIs(t, err, ErrUserAlreadyExists)
+	var cerr *ConflictError
+	require.ErrorAs(t, err, &cerr)
+	require.Equal(t, map[string]string{"email": "already taken"}, cerr.Fields)
+	require.EqualError(t, err, "user already exists: email: already taken")
+}
+
+func TestUserService_Create_PrecheckReportsEveryTakenField(t *testing.T) {
+	// Given: a username and an email that both already belong to users
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{PrecheckUniqueness: true})
+	repo.On("TakenFields", mock.Anything, "jdoe", "jdoe@example.com", "").Return([]string{"username", "email"}, nil).Once()
+
+	// When: creating the user
+	_, err := service.Create(context.Background(), "jdoe", "jdoe@example.com", "John Doe", "")
+
+	// Then: both fields are named and no insert is attempted
+	var cerr *ConflictError
+	require.ErrorAs(t, err, &cerr)
+	require.Equal(t, map[string]string{"username": "already taken", "email": "already taken"}, cerr.Fields)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_Create_PrecheckIsAdvisory(t *testing.T) {
+	tests := []struct {
+		name     string
+		taken    []string
+		checkErr error
+	}{
+		{name: "nothing taken"},
+		{name: "lookup failed", checkErr: errors.New("connection reset")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: a pre-check that finds nothing, and a concurrent create that wins the race
+			repo := mocks.NewUserRepositoryMock(t)
+			service := NewUserServiceWithOptions(repo, UserServiceOptions{PrecheckUniqueness: true})
+			repo.On("TakenFields", mock.Anything, "jdoe", "jdoe@example.com", "").Return(tt.taken, tt.checkErr).Once()
+			repo.On("Create", mock.Anything, "jdoe", "jdoe@example.com", "John Doe", "").
+				Return((*model.User)(nil), &repository.ConstraintError{Kind: repository.ErrUniqueViolation, Constraint: "users_username_key"}).Once()
+
+			// When: creating the user
+			_, err := service.Create(context.Background(), "jdoe", "jdoe@example.com", "John Doe", "")
+
+			// Then: the constraint still reports the duplicate
+			var cerr *ConflictError
+			require.ErrorAs(t, err, &cerr)
+			require.Equal(t, map[string]string{"username": "already taken"}, cerr.Fields)
+		})
+	}
+}
+
+func TestUserService_Create_UniqueFullName(t *testing.T) {
+	// Given: display names are unique, and "John Doe" is taken
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{PrecheckUniqueness: true, UniqueFullName: true})
+	repo.On("TakenFields", mock.Anything, "jdoe", "jdoe@example.com", "John Doe").Return([]string{"full_name"}, nil).Once()
+	repo.On("TakenFields", mock.Anything, "jdoe2", "jdoe2@example.com", "Jane Doe").Return(nil, nil).Once()
+	repo.On("Create", mock.Anything, "jdoe2", "jdoe2@example.com", "Jane Doe", "").
+		Return((*model.User)(nil), &repository.ConstraintError{Kind: repository.ErrUniqueViolation, Constraint: repository.FullNameIndex}).Once()
+
+	// When: the pre-check catches one duplicate and the index a racing one
+	_, precheckErr := service.Create(context.Background(), "jdoe", "jdoe@example.com", "John Doe", "")
+	_, constraintErr := service.Create(context.Background(), "jdoe2", "jdoe2@example.com", "Jane Doe", "")
+
+	// Then: both name full_name
+	for _, err := range []error{precheckErr, constraintErr} {
+		var cerr *ConflictError
+		require.ErrorAs(t, err, &cerr)
+		require.Equal(t, map[string]string{"full_name": "already taken"}, cerr.Fields)
+	}
+}
+
+func TestNewUserServiceWithLogger_LogsToInjectedLogger(t *testing.T) {
+	// Given: a service built with its own logger
+	var out bytes.Buffer
+	repo := mocks.NewUserRepositoryMock(t)
+	readOnly := &ReadOnlyMode{}
+	readOnly.Set(true)
+	service := NewUserServiceWithLogger(repo, UserServiceOptions{ReadOnly: readOnly},
+		logger.FromSlog(slog.New(slog.NewJSONHandler(&out, nil))))
+
+	// When: a call logs
+	_, err := service.Create(context.Background(), "jdoe", "jdoe@example.com", "", "")
+
+	// Then: the record lands there, tagged with the component
+	require.ErrorIs(t, err, ErrReadOnly)
+	require.Contains(t, out.String(), `"component":"service.user"`)
+}
+
+func TestUserService_ReadOnly_RejectsMutations(t *testing.T) {
+	// Given: a service whose read-only mode is switched on
+	repo := mocks.NewUserRepositoryMock(t)
+	readOnly := &ReadOnlyMode{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{ReadOnly: readOnly})
+	readOnly.Set(true)
+	id := uuid.New()
+	name := "renamed"
+
+	// When: calling every mutation
+	_, createErr := service.Create(context.Background(), "new_user", "user@example.com", "Test User", "")
+	_, updateUUIDErr := service.UpdateByUUID(context.Background(), id, UpdateUserInput{Version: intPtr(0), Username: &name})
+	_, updateIDErr := service.UpdateByID(context.Background(), 1, UpdateUserInput{Version: intPtr(0), Username: &name})
+	deleteUUIDErr := service.DeleteByUUID(context.Background(), id)
+	deleteIDErr := service.DeleteByID(context.Background(), 1)
+	_, issueErr := service.IssueEmailVerification(context.Background(), id)
+	_, verifyErr := service.VerifyEmail(context.Background(), "token")
+	_, statusErr := service.SetStatus(context.Background(), id, model.UserStatusSuspended)
+
+	// Then: each fails with ErrReadOnly without touching the repository
+	require.ErrorIs(t, createErr, ErrReadOnly)
+	require.ErrorIs(t, updateUUIDErr, ErrReadOnly)
+	require.ErrorIs(t, updateIDErr, ErrReadOnly)
+	require.ErrorIs(t, deleteUUIDErr, ErrReadOnly)
+	require.ErrorIs(t, deleteIDErr, ErrReadOnly)
+	require.ErrorIs(t, issueErr, ErrReadOnly)
+	require.ErrorIs(t, verifyErr, ErrReadOnly)
+	require.ErrorIs(t, statusErr, ErrReadOnly)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "DeleteByID", mock.Anything, mock.Anything)
+}
+
+func TestUserService_ReadOnly_AllowsReads(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	readOnly := &ReadOnlyMode{}
+	readOnly.Set(true)
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{ReadOnly: readOnly})
+	repo.On("GetAll", mock.Anything).Return([]model.User{{ID: 1}}, nil).Once()
+	repo.On("GetByID", mock.Anything, int64(1)).Return(&model.User{ID: 1}, nil).Once()
+
+	users, err := service.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	user, err := service.GetByID(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, user.ID)
+}
+
+func TestUserService_ReadOnly_Toggle(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	readOnly := &ReadOnlyMode{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{ReadOnly: readOnly})
+	repo.On("DeleteByID", mock.Anything, int64(7)).Return(&model.User{ID: 7}, nil).Once()
+
+	readOnly.Set(true)
+	require.ErrorIs(t, service.DeleteByID(context.Background(), 7), ErrReadOnly)
+
+	readOnly.Set(false)
+	require.NoError(t, service.DeleteByID(context.Background(), 7))
+}
+
+func TestUserService_Create_UsernameLooksLikeEmail(t *testing.T) {
+	// Given: the swapped-fields check is enabled
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{RejectEmailLikeUsernames: true})
+
+	// When: the username is an email address, or equals the email
+	_, swappedErr := service.Create(context.Background(), "user@example.com", "other@example.com", "Test User", "")
+	_, equalErr := service.Create(context.Background(), "JDoe@Example.com", "jdoe@example.com", "Test User", "")
+
+	// Then: creation is rejected with the dedicated error
+	require.ErrorIs(t, swappedErr, ErrUsernameLooksLikeEmail)
+	require.ErrorIs(t, swappedErr, ErrInvalidUserInput)
+	require.ErrorIs(t, equalErr, ErrUsernameLooksLikeEmail)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_Create_UsernameLooksLikeEmail_FlagOff(t *testing.T) {
+	// a policy without a character pattern, as some deployments configure
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{
+		UsernamePolicy: &UsernamePolicy{MinLength: 1, MaxLength: MaxUsernameLength},
+	})
+	repo.On("Create", mock.Anything, "user@example.com", "other@example.com", "Test User", "").
+		Return(&model.User{ID: 1, Username: "user@example.com"}, nil).Once()
+
+	user, err := service.Create(context.Background(), "user@example.com", "other@example.com", "Test User", "")
+
+	require.NoError(t, err)
+	require.Equal(t, "user@example.com", user.Username)
+}
+
+func TestUserService_UpdateByID_UsernameLooksLikeEmail(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{RejectEmailLikeUsernames: true})
+	repo.On("GetByID", mock.Anything, int64(1)).
+		Return(&model.User{ID: 1, Username: "jdoe", Email: "jdoe@example.com", FullName: "John Doe"}, nil).Once()
+	username := "jdoe@example.com"
+
+	_, err := service.UpdateByID(context.Background(), 1, UpdateUserInput{Version: intPtr(0), Username: &username})
+
+	require.ErrorIs(t, err, ErrUsernameLooksLikeEmail)
+	repo.AssertNotCalled(t, "UpdateByID", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_Create_ReportsInvalidFields(t *testing.T) {
+	// Given: a user service with a mock repository
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+
+	// When: several fields are invalid at once
+	_, err := service.Create(context.Background(), "   ", "not-an-email", "", "")
+
+	// Then: every failing field is reported and the sentinel still matches
+	require.ErrorIs(t, err, ErrInvalidUserInput)
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, map[string]string{
+		"username": "required",
+		"email":    "not a valid address",
+	}, verr.Fields)
+	require.Equal(t, "invalid user input: email: not a valid address; username: required", err.Error())
+}
+
+func TestUserService_Create_FullNameDefaultsToUsername(t *testing.T) {
+	// Given: a user service with a mock repository
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("Create", mock.Anything, "jdoe", "jdoe@example.com", "jdoe", "").
+		Return(&model.User{ID: 1, Username: "jdoe", Email: "jdoe@example.com", FullName: "jdoe"}, nil).Once()
+
+	// When: creating a user with a blank full name
+	user, err := service.Create(context.Background(), "jdoe", "jdoe@example.com", "   ", "")
+
+	// Then: the username stands in for it
+	require.NoError(t, err)
+	require.Equal(t, "jdoe", user.FullName)
+}
+
+func TestUserService_UpdateByUUID_ReportsInvalidFields(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	id := uuid.New()
+	repo.On("GetByUUID", mock.Anything, id).
+		Return(&model.User{ID: 1, UUID: id.String(), Username: "jdoe", Email: "jdoe@example.com", FullName: "John Doe"}, nil).Once()
+	username := strings.Repeat("a", MaxUsernameLength+1)
+	email := ""
+
+	_, err := service.UpdateByUUID(context.Background(), id, UpdateUserInput{Version: intPtr(0), Username: &username, Email: &email})
+
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, map[string]string{
+		"username": "must be between 3 and 32 characters",
+		"email":    "required",
+	}, verr.Fields)
+}
+
+func TestUserService_UpdateByUUID_ClearsFullName(t *testing.T) {
+	id := uuid.New()
+	existing := &model.User{ID: 1, UUID: id.String(), Username: "jdoe", Email: "jdoe@example.com", FullName: "John Doe"}
+
+	t.Run("empty string clears it", func(t *testing.T) {
+		for _, value := range []string{"", "   "} {
+			repo := mocks.NewUserRepositoryMock(t)
+			service := NewUserService(repo)
+			repo.On("GetByUUID", mock.Anything, id).Return(existing, nil).Once()
+			repo.On("UpdateByUUID", mock.Anything, id, "jdoe", "jdoe@example.com", "", "", 0).
+				Return(&model.User{ID: 1, UUID: id.String(), Username: "jdoe", Email: "jdoe@example.com", Version: 1}, nil).Once()
+
+			updated, err := service.UpdateByUUID(context.Background(), id, UpdateUserInput{Version: intPtr(0), FullName: &value})
+
+			require.NoError(t, err)
+			require.Empty(t, updated.FullName)
+		}
+	})
+
+	t.Run("omitted leaves it", func(t *testing.T) {
+		repo := mocks.NewUserRepositoryMock(t)
+		service := NewUserService(repo)
+		repo.On("GetByUUID", mock.Anything, id).Return(existing, nil).Once()
+		repo.On("UpdateByUUID", mock.Anything, id, "jdoe2", "jdoe@example.com", "John Doe", "", 0).
+			Return(&model.User{ID: 1, UUID: id.String(), Username: "jdoe2", Email: "jdoe@example.com", FullName: "John Doe", Version: 1}, nil).Once()
+		username := "jdoe2"
+
+		_, err := service.UpdateByUUID(context.Background(), id, UpdateUserInput{Version: intPtr(0), Username: &username})
+
+		require.NoError(t, err)
+	})
+
+	t.Run("username and email cannot be cleared", func(t *testing.T) {
+		repo := mocks.NewUserRepositoryMock(t)
+		service := NewUserService(repo)
+		repo.On("GetByUUID", mock.Anything, id).Return(existing, nil).Once()
+		empty := ""
+
+		_, err := service.UpdateByUUID(context.Background(), id, UpdateUserInput{Version: intPtr(0), Username: &empty, Email: &empty, FullName: &empty})
+
+		var verr *ValidationError
+		require.ErrorAs(t, err, &verr)
+		require.Equal(t, map[string]string{"username": "required", "email": "required"}, verr.Fields)
+	})
+}
+
+func TestUserService_RejectsOverlongFields(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	id := uuid.New()
+	repo.On("GetByUUID", mock.Anything, id).
+		Return(&model.User{ID: 1, UUID: id.String(), Username: "jdoe", Email: "jdoe@example.com", FullName: "John Doe"}, nil).Once()
+	email := strings.Repeat("a", MaxEmailLength-len("@example.com")+1) + "@example.com"
+	fullName := strings.Repeat("é", MaxFullNameLength+1)
+	want := map[string]string{
+		"email":     "must be at most 254 characters",
+		"full_name": "must be at most 100 characters",
+	}
+
+	_, err := service.Create(context.Background(), "jdoe", email, fullName, "")
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, want, verr.Fields)
+
+	_, err = service.UpdateByUUID(context.Background(), id, UpdateUserInput{Version: intPtr(0), Email: &email, FullName: &fullName})
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, want, verr.Fields)
+
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "UpdateByUUID", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_Create_AvatarURL(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+
+	rejected := map[string]string{
+		"javascript:alert(1)":          "must be an http or https URL",
+		"JavaScript://example.com/%0a": "must be an http or https URL",
+		"data:image/png;base64,AAAA":   "must be an http or https URL",
+		"ftp://example.com/me.png":     "must be an http or https URL",
+		"/avatars/me.png":              "must be an http or https URL",
+		"example.com/me.png":           "must be an http or https URL",
+		"https:///me.png":              "must be an http or https URL",
+		"https://example.com/" + strings.Repeat("a", MaxAvatarURLLength): "must be at most 2048 characters",
+	}
+	for avatarURL, msg := range rejected {
+		_, err := service.Create(context.Background(), "jdoe", "jdoe@example.com", "John Doe", avatarURL)
+		var verr *ValidationError
+		require.ErrorAs(t, err, &verr, avatarURL)
+		require.ErrorIs(t, err, ErrInvalidUserInput)
+		require.Equal(t, map[string]string{"avatar_url": msg}, verr.Fields, avatarURL)
+	}
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	repo.On("Create", mock.Anything, "jdoe", "jdoe@example.com", "John Doe", "HTTPS://cdn.example.com/u/1.png?s=64").
+		Return(&model.User{ID: 1, UUID: uuid.NewString(), AvatarURL: "HTTPS://cdn.example.com/u/1.png?s=64"}, nil).Once()
+	_, err := service.Create(context.Background(), "jdoe", "jdoe@example.com", "John Doe", " HTTPS://cdn.example.com/u/1.png?s=64 ")
+	require.NoError(t, err)
+}
+
+func TestUserService_UpdateByUUID_AvatarURL(t *testing.T) {
+	existing := &model.User{ID: 10, UUID: uuid.NewString(), Username: "current", Email: "current@example.com", FullName: "Current Name", AvatarURL: "https://cdn.example.com/old.png"}
+	id := uuid.MustParse(existing.UUID)
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("GetByUUID", mock.Anything, id).Return(existing, nil)
+
+	t.Run("invalid", func(t *testing.T) {
+		bad := "javascript:alert(document.cookie)"
+		_, err := service.UpdateByUUID(context.Background(), id, UpdateUserInput{Version: intPtr(0), AvatarURL: &bad})
+
+		var verr *ValidationError
+		require.ErrorAs(t, err, &verr)
+		require.Equal(t, map[string]string{"avatar_url": "must be an http or https URL"}, verr.Fields)
+	})
+
+	t.Run("omitted keeps it", func(t *testing.T) {
+		name := "New Name"
+		repo.On("UpdateByUUID", mock.Anything, id, "current", "current@example.com", "New Name", "https://cdn.example.com/old.png", 0).
+			Return(existing, nil).Once()
+
+		_, err := service.UpdateByUUID(context.Background(), id, UpdateUserInput{Version: intPtr(0), FullName: &name})
+		require.NoError(t, err)
+	})
+
+	t.Run("empty clears it", func(t *testing.T) {
+		empty := "  "
+		repo.On("UpdateByUUID", mock.Anything, id, "current", "current@example.com", "Current Name", "", 0).
+			Return(existing, nil).Once()
+
+		_, err := service.UpdateByUUID(context.Background(), id, UpdateUserInput{Version: intPtr(0), AvatarURL: &empty})
+		require.NoError(t, err)
+	})
+	repo.AssertExpectations(t)
+}
+
+func TestUserService_Create_UsernamePolicy(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+
+	cases := map[string]string{
+		"a b!@#":                DefaultUsernamePolicy.Description,
+		"1jdoe":                 DefaultUsernamePolicy.Description,
+		"_jdoe":                 DefaultUsernamePolicy.Description,
+		"jd":                    "must be between 3 and 32 characters",
+		strings.Repeat("a", 33): "must be between 3 and 32 characters",
+	}
+	for username, message := range cases {
+		_, err := service.Create(context.Background(), username, "user@example.com", "Test User", "")
+
+		var verr *ValidationError
+		require.ErrorAs(t, err, &verr, username)
+		require.Equal(t, message, verr.Fields["username"], username)
+	}
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_Create_UsernamePolicyAccepts(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("Create", mock.Anything, "j.doe-99_x", "user@example.com", "Test User", "").
+		Return(&model.User{ID: 1, Username: "j.doe-99_x"}, nil).Once()
+
+	_, err := service.Create(context.Background(), "j.doe-99_x", "user@example.com", "Test User", "")
+
+	require.NoError(t, err)
+}
+
+func TestUserService_UpdateByID_UsernamePolicy(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("GetByID", mock.Anything, int64(1)).
+		Return(&model.User{ID: 1, Username: "jdoe", Email: "jdoe@example.com", FullName: "John Doe"}, nil).Once()
+	username := "bad name"
+
+	_, err := service.UpdateByID(context.Background(), 1, UpdateUserInput{Version: intPtr(0), Username: &username})
+
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, DefaultUsernamePolicy.Description, verr.Fields["username"])
+}
+
 func TestUserService_GetAll_Success(t *testing.T) {
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
 	expected := []model.User{{ID: 1}, {ID: 2}}
-	repo.On("GetAll").Return(expected, nil).Once()
+	repo.On("GetAll", mock.Anything).Return(expected, nil).Once()
 
-	users, err := service.GetAll()
+	users, err := service.GetAll(context.Background())
 
 	require.NoError(t, err)
 	require.Equal(t, expected, users)
 	repo.AssertExpectations(t)
 }
 
+func TestUserService_List(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	query := ListUsersQuery{Limit: 10, Offset: 20, Pinned: []uuid.UUID{uuid.New()}}
+	repo.On("List", mock.Anything, query).Return(nil, nil).Once()
+
+	users, err := service.List(context.Background(), query)
+	require.NoError(t, err)
+	require.Equal(t, []model.User{}, users)
+
+	_, err = service.List(context.Background(), ListUsersQuery{Limit: -1})
+	require.ErrorIs(t, err, ErrInvalidUserInput)
+
+	_, err = service.List(context.Background(), ListUsersQuery{Pinned: make([]uuid.UUID, MaxPinnedUsers+1)})
+	require.ErrorIs(t, err, ErrInvalidUserInput)
+}
+
+func TestUserService_GetAllAfter(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("GetAllAfter", mock.Anything, int64(5), 10).Return(nil, nil).Once()
+
+	users, err := service.GetAllAfter(context.Background(), 5, 10)
+	require.NoError(t, err)
+	require.Equal(t, []model.User{}, users)
+
+	_, err = service.GetAllAfter(context.Background(), 5, 0)
+	require.ErrorIs(t, err, ErrInvalidUserInput)
+	_, err = service.GetAllAfter(context.Background(), -1, 10)
+	require.ErrorIs(t, err, ErrInvalidUserInput)
+}
+
+func TestUserService_Export_WalksBatches(t *testing.T) {
+	// Given: three users read two at a time
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("GetAllAfter", mock.Anything, int64(0), 2).Return([]model.User{{ID: 1}, {ID: 2}}, nil).Once()
+	repo.On("GetAllAfter", mock.Anything, int64(2), 2).Return([]model.User{{ID: 3}}, nil).Once()
+
+	// When: exporting without a snapshot
+	var ids []int
+	err := service.Export(context.Background(), ExportOptions{BatchSize: 2}, func(u model.User) error {
+		ids = append(ids, u.ID)
+		return nil
+	})
+
+	// Then: every user is emitted once, in order, straight from the pool
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3}, ids)
+	repo.AssertNotCalled(t, "Snapshot", mock.Anything, mock.Anything)
+}
+
+func TestUserService_Export_Snapshot(t *testing.T) {
+	// Given: a snapshot bound to its own repository
+	repo := mocks.NewUserRepositoryMock(t)
+	snapshot := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("Snapshot", mock.Anything, mock.Anything).
+		Return(func(_ context.Context, fn func(repository.UserRepository) error) error { return fn(snapshot) }).Once()
+	snapshot.On("GetAllAfter", mock.Anything, int64(0), DefaultExportBatchSize).Return([]model.User{{ID: 1}}, nil).Once()
+
+	// When: exporting inside a snapshot
+	var count int
+	err := service.Export(context.Background(), ExportOptions{Snapshot: true}, func(model.User) error {
+		count++
+		return nil
+	})
+
+	// Then: reads go through the snapshot repository
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+}
+
+func TestUserService_Export_EmitErrorStops(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("GetAllAfter", mock.Anything, int64(0), 2).Return([]model.User{{ID: 1}, {ID: 2}}, nil).Once()
+
+	err := service.Export(context.Background(), ExportOptions{BatchSize: 2}, func(model.User) error { return errUnexpected })
+
+	require.ErrorIs(t, err, errUnexpected)
+}
+
+func TestUserService_Export_StopsWhenCancelled(t *testing.T) {
+	// Given: a client that goes away after the first user
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("GetAllAfter", mock.Anything, int64(0), 2).Return([]model.User{{ID: 1}, {ID: 2}}, nil).Once()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// When: exporting
+	var emitted int
+	err := service.Export(ctx, ExportOptions{BatchSize: 2}, func(model.User) error {
+		emitted++
+		cancel()
+		return nil
+	})
+
+	// Then: the rest of the batch and the next batch are skipped
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 1, emitted)
+}
+
 func TestUserService_GetAll_Error(t *testing.T) {
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
-	repo.On("GetAll").Return(nil, errUnexpected).Once()
+	repo.On("GetAll", mock.Anything).Return(nil, errUnexpected).Once()
 
-	users, err := service.GetAll()
+	users, err := service.GetAll(context.Background())
 
 	require.Error(t, err)
 	require.Nil(t, users)
@@ -104,8 +707,8 @@ func TestUserService_UpdateByUUID_Success(t *testing.T) {
 
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
-	repo.On("GetByUUID", mock.AnythingOfType("uuid.UUID")).Return(existing, nil).Once()
-	repo.On("UpdateByUUID", mock.AnythingOfType("uuid.UUID"), "current", "current@example.com", "Updated Name").
+	repo.On("GetByUUID", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(existing, nil).Once()
+	repo.On("UpdateByUUID", mock.Anything, mock.AnythingOfType("uuid.UUID"), "current", "current@example.com", "Updated Name", "", 0).
 		Return(&model.User{
 			ID:       existing.ID,
 			UUID:     existing.UUID,
@@ -116,7 +719,8 @@ func TestUserService_UpdateByUUID_Success(t *testing.T) {
 	newName := "  Updated Name "
 
 	// When: updating only the full name
-	result, err := service.UpdateByUUID(uuid.MustParse(existing.UUID), UpdateUserInput{
+	result, err := service.UpdateByUUID(context.Background(), uuid.MustParse(existing.UUID), UpdateUserInput{
+		Version:  intPtr(0),
 		FullName: strPtr(newName),
 	})
 
@@ -126,6 +730,115 @@ func TestUserService_UpdateByUUID_Success(t *testing.T) {
 	repo.AssertExpectations(t)
 }
 
+func TestUserService_UpdateByUUID_MergesOntoPrimaryRead(t *testing.T) {
+	// Given: a replica still serving the old email and a primary with the new one
+	replica := mocks.NewUserRepositoryMock(t)
+	primary := mocks.NewUserRepositoryMock(t)
+	service := NewUserServiceWithOptions(replica, UserServiceOptions{Primary: primary})
+	id := uuid.New()
+	primary.On("GetByUUID", mock.Anything, id).
+		Return(&model.User{ID: 1, UUID: id.String(), Username: "jdoe", Email: "new@example.com", FullName: "John Doe"}, nil).Once()
+	replica.On("UpdateByUUID", mock.Anything, id, "jdoe", "new@example.com", "Jane Doe", "", 0).
+		Return(&model.User{ID: 1, UUID: id.String(), Username: "jdoe", Email: "new@example.com", FullName: "Jane Doe"}, nil).Once()
+
+	// When: only the full name is updated
+	_, err := service.UpdateByUUID(context.Background(), id, UpdateUserInput{Version: intPtr(0), FullName: strPtr("Jane Doe")})
+
+	// Then: the untouched email comes from the primary, not the replica
+	require.NoError(t, err)
+}
+
+func TestUserService_UpdateByUUID_PreconditionFailed(t *testing.T) {
+	// Given: a stored user the precondition no longer accepts
+	existing := &model.User{ID: 10, UUID: uuid.NewString(), Username: "current", Email: "current@example.com"}
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("GetByUUID", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(existing, nil).Once()
+	var seen model.User
+
+	// When: updating with that precondition
+	_, err := service.UpdateByUUID(context.Background(), uuid.MustParse(existing.UUID), UpdateUserInput{
+		Version:  intPtr(0),
+		FullName: strPtr("Updated Name"),
+		Precondition: func(current model.User) bool {
+			seen = current
+			return false
+		},
+	})
+
+	// Then: the precondition saw the stored row and nothing was written
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	require.Equal(t, *existing, seen)
+	repo.AssertNotCalled(t, "UpdateByUUID", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_UpdateByID_PreconditionHolds(t *testing.T) {
+	existing := &model.User{ID: 10, UUID: uuid.NewString(), Username: "current", Email: "current@example.com"}
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("GetByID", mock.Anything, int64(10)).Return(existing, nil).Once()
+	repo.On("UpdateByID", mock.Anything, int64(10), "current", "current@example.com", "Updated Name", "", 0).
+		Return(&model.User{ID: 10, UUID: existing.UUID, Username: "current", Email: "current@example.com", FullName: "Updated Name"}, nil).Once()
+
+	updated, err := service.UpdateByID(context.Background(), 10, UpdateUserInput{
+		Version:      intPtr(0),
+		FullName:     strPtr("Updated Name"),
+		Precondition: func(model.User) bool { return true },
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, "Updated Name", updated.FullName)
+}
+
+func TestUserService_UpdateByUUID_RequiresVersion(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+
+	_, err := service.UpdateByUUID(context.Background(), uuid.New(), UpdateUserInput{FullName: strPtr("Name")})
+
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, map[string]string{"version": "required"}, verr.Fields)
+	repo.AssertNotCalled(t, "GetByUUID", mock.Anything, mock.Anything)
+}
+
+func TestUserService_UpdateByUUID_StaleVersion(t *testing.T) {
+	// Given: a stored user at version 3
+	existing := &model.User{ID: 10, UUID: uuid.NewString(), Username: "current", Email: "current@example.com", Version: 3}
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("GetByUUID", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(existing, nil).Once()
+
+	// When: updating from version 2
+	_, err := service.UpdateByUUID(context.Background(), uuid.MustParse(existing.UUID), UpdateUserInput{
+		FullName: strPtr("Updated Name"),
+		Version:  intPtr(2),
+	})
+
+	// Then: the update is refused without a write
+	require.ErrorIs(t, err, ErrVersionConflict)
+	require.NotErrorIs(t, err, ErrUserAlreadyExists)
+	repo.AssertNotCalled(t, "UpdateByUUID", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_UpdateByID_LosesConcurrentWrite(t *testing.T) {
+	// Given: another update lands between the read and the write
+	existing := &model.User{ID: 10, UUID: uuid.NewString(), Username: "current", Email: "current@example.com", Version: 3}
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("GetByID", mock.Anything, int64(10)).Return(existing, nil).Once()
+	repo.On("UpdateByID", mock.Anything, int64(10), "current", "current@example.com", "Updated Name", "", 3).Return(nil, nil).Once()
+
+	// When: updating from the version that was read
+	_, err := service.UpdateByID(context.Background(), 10, UpdateUserInput{
+		FullName: strPtr("Updated Name"),
+		Version:  intPtr(3),
+	})
+
+	// Then: the write matched no row and is reported as a conflict
+	require.ErrorIs(t, err, ErrVersionConflict)
+}
+
 func TestUserService_UpdateByUUID_InvalidEmail(t *testing.T) {
 	// Given: an existing user in repository
 	existing := &model.User{
@@ -137,12 +850,13 @@ func TestUserService_UpdateByUUID_InvalidEmail(t *testing.T) {
 	}
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
-	repo.On("GetByUUID", mock.AnythingOfType("uuid.UUID")).Return(existing, nil).Once()
+	repo.On("GetByUUID", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(existing, nil).Once()
 	badEmail := "not-an-email"
 
 	// When: updating with an invalid email value
-	_, err := service.UpdateByUUID(uuid.MustParse(existing.UUID), UpdateUserInput{
-		Email: &badEmail,
+	_, err := service.UpdateByUUID(context.Background(), uuid.MustParse(existing.UUID), UpdateUserInput{
+		Version: intPtr(0),
+		Email:   &badEmail,
 	})
 
 	// Then: invalid user input error is returned
@@ -156,20 +870,52 @@ func TestUserService_UpdateByUUID_NoFieldsProvided(t *testing.T) {
 	service := NewUserService(repo)
 
 	// When: updating without providing any fields
-	_, err := service.UpdateByUUID(uuid.New(), UpdateUserInput{})
+	_, err := service.UpdateByUUID(context.Background(), uuid.New(), UpdateUserInput{})
 
 	// Then: invalid user input error is returned
 	require.ErrorIs(t, err, ErrInvalidUserInput)
-	repo.AssertNotCalled(t, "GetByUUID", mock.Anything)
+	repo.AssertNotCalled(t, "GetByUUID", mock.Anything, mock.Anything)
+}
+
+func TestUserService_UpdateByUUID_ImmutableFields(t *testing.T) {
+	// Given: user service with a mock repository
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+
+	// When: the payload attempts to change the uuid alongside a valid field
+	_, err := service.UpdateByUUID(context.Background(), uuid.New(), UpdateUserInput{
+		Version:   intPtr(0),
+		FullName:  strPtr("Name"),
+		Immutable: []string{"uuid"},
+	})
+
+	// Then: the update is rejected before touching the repository
+	require.ErrorIs(t, err, ErrImmutableField)
+	require.ErrorIs(t, err, ErrInvalidUserInput)
+	repo.AssertNotCalled(t, "GetByUUID", mock.Anything, mock.Anything)
+}
+
+func TestUserService_UpdateByID_ImmutableFields(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+
+	_, err := service.UpdateByID(context.Background(), 5, UpdateUserInput{
+		Version:   intPtr(0),
+		Username:  strPtr("name"),
+		Immutable: []string{"id"},
+	})
+
+	require.ErrorIs(t, err, ErrImmutableField)
+	repo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
 }
 
 func TestUserService_GetByUsername_Success(t *testing.T) {
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
 	existing := &model.User{Username: "tester"}
-	repo.On("GetByUsername", "tester").Return(existing, nil).Once()
+	repo.On("GetByUsername", mock.Anything, "tester").Return(existing, nil).Once()
 
-	user, err := service.GetByUsername("tester")
+	user, err := service.GetByUsername(context.Background(), "tester")
 
 	require.NoError(t, err)
 	require.Equal(t, existing, user)
@@ -179,9 +925,9 @@ func TestUserService_GetByUsername_Success(t *testing.T) {
 func TestUserService_GetByUsername_NotFound(t *testing.T) {
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
-	repo.On("GetByUsername", "missing").Return((*model.User)(nil), nil).Once()
+	repo.On("GetByUsername", mock.Anything, "missing").Return((*model.User)(nil), nil).Once()
 
-	user, err := service.GetByUsername("missing")
+	user, err := service.GetByUsername(context.Background(), "missing")
 
 	require.ErrorIs(t, err, ErrUserNotFound)
 	require.Nil(t, user)
@@ -190,9 +936,9 @@ func TestUserService_GetByUsername_NotFound(t *testing.T) {
 func TestUserService_GetByUsername_Error(t *testing.T) {
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
-	repo.On("GetByUsername", "err").Return((*model.User)(nil), errUnexpected).Once()
+	repo.On("GetByUsername", mock.Anything, "err").Return((*model.User)(nil), errUnexpected).Once()
 
-	user, err := service.GetByUsername("err")
+	user, err := service.GetByUsername(context.Background(), "err")
 
 	require.Error(t, err)
 	require.Nil(t, user)
@@ -202,9 +948,9 @@ func TestUserService_GetByID_Success(t *testing.T) {
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
 	existing := &model.User{ID: 10}
-	repo.On("GetByID", int64(10)).Return(existing, nil).Once()
+	repo.On("GetByID", mock.Anything, int64(10)).Return(existing, nil).Once()
 
-	user, err := service.GetByID(10)
+	user, err := service.GetByID(context.Background(), 10)
 
 	require.NoError(t, err)
 	require.Equal(t, existing, user)
@@ -214,9 +960,9 @@ func TestUserService_GetByID_Success(t *testing.T) {
 func TestUserService_GetByID_NotFound(t *testing.T) {
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
-	repo.On("GetByID", int64(11)).Return((*model.User)(nil), nil).Once()
+	repo.On("GetByID", mock.Anything, int64(11)).Return((*model.User)(nil), nil).Once()
 
-	user, err := service.GetByID(11)
+	user, err := service.GetByID(context.Background(), 11)
 
 	require.ErrorIs(t, err, ErrUserNotFound)
 	require.Nil(t, user)
@@ -225,9 +971,9 @@ func TestUserService_GetByID_NotFound(t *testing.T) {
 func TestUserService_GetByID_Error(t *testing.T) {
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
-	repo.On("GetByID", int64(12)).Return((*model.User)(nil), errUnexpected).Once()
+	repo.On("GetByID", mock.Anything, int64(12)).Return((*model.User)(nil), errUnexpected).Once()
 
-	user, err := service.GetByID(12)
+	user, err := service.GetByID(context.Background(), 12)
 
 	require.Error(t, err)
 	require.Nil(t, user)
@@ -238,9 +984,9 @@ func TestUserService_GetByUUID_Success(t *testing.T) {
 	service := NewUserService(repo)
 	u := uuid.New()
 	existing := &model.User{UUID: u.String()}
-	repo.On("GetByUUID", u).Return(existing, nil).Once()
+	repo.On("GetByUUID", mock.Anything, u).Return(existing, nil).Once()
 
-	user, err := service.GetByUUID(u)
+	user, err := service.GetByUUID(context.Background(), u)
 
 	require.NoError(t, err)
 	require.Equal(t, existing, user)
@@ -251,9 +997,9 @@ func TestUserService_GetByUUID_NotFound(t *testing.T) {
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
 	u := uuid.New()
-	repo.On("GetByUUID", u).Return((*model.User)(nil), nil).Once()
+	repo.On("GetByUUID", mock.Anything, u).Return((*model.User)(nil), nil).Once()
 
-	user, err := service.GetByUUID(u)
+	user, err := service.GetByUUID(context.Background(), u)
 
 	require.ErrorIs(t, err, ErrUserNotFound)
 	require.Nil(t, user)
@@ -263,9 +1009,9 @@ func TestUserService_GetByUUID_Error(t *testing.T) {
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
 	u := uuid.New()
-	repo.On("GetByUUID", u).Return((*model.User)(nil), errUnexpected).Once()
+	repo.On("GetByUUID", mock.Anything, u).Return((*model.User)(nil), errUnexpected).Once()
 
-	user, err := service.GetByUUID(u)
+	user, err := service.GetByUUID(context.Background(), u)
 
 	require.Error(t, err)
 	require.Nil(t, user)
@@ -282,14 +1028,15 @@ func TestUserService_UpdateByUUID_DuplicateEmail(t *testing.T) {
 	}
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
-	repo.On("GetByUUID", mock.AnythingOfType("uuid.UUID")).Return(existing, nil).Once()
-	repo.On("UpdateByUUID", mock.AnythingOfType("uuid.UUID"), "current", mock.Anything, mock.Anything).
+	repo.On("GetByUUID", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(existing, nil).Once()
+	repo.On("UpdateByUUID", mock.Anything, mock.AnythingOfType("uuid.UUID"), "current", mock.Anything, mock.Anything, "", 0).
 		Return((*model.User)(nil), repository.ErrUniqueViolation).Once()
 	newEmail := "duplicate@example.com"
 
 	// When: updating email that conflicts with existing user
-	_, err := service.UpdateByUUID(uuid.MustParse(existing.UUID), UpdateUserInput{
-		Email: &newEmail,
+	_, err := service.UpdateByUUID(context.Background(), uuid.MustParse(existing.UUID), UpdateUserInput{
+		Version: intPtr(0),
+		Email:   &newEmail,
 	})
 
 	// Then: ErrUserAlreadyExists is returned
@@ -297,14 +1044,69 @@ func TestUserService_UpdateByUUID_DuplicateEmail(t *testing.T) {
 	repo.AssertExpectations(t)
 }
 
+func TestUserService_UpdateByUUID_LowercasesEmailDomain(t *testing.T) {
+	existing := &model.User{ID: 10, UUID: uuid.NewString(), Username: "current", Email: "current@example.com", FullName: "Current Name"}
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("GetByUUID", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(existing, nil).Once()
+	repo.On("UpdateByUUID", mock.Anything, mock.AnythingOfType("uuid.UUID"), "current", "Current@example.org", "Current Name", "", 0).
+		Return(existing, nil).Once()
+	newEmail := " Current@EXAMPLE.org "
+
+	_, err := service.UpdateByUUID(context.Background(), uuid.MustParse(existing.UUID), UpdateUserInput{Version: intPtr(0), Email: &newEmail})
+
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestUserService_UpdateByID_ConstraintViolationIsInvalidInput(t *testing.T) {
+	// Given: the database rejects the row through a NOT NULL or CHECK constraint
+	existing := &model.User{ID: 10, Username: "current", Email: "current@example.com", FullName: "Current Name"}
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("GetByID", mock.Anything, int64(10)).Return(existing, nil).Once()
+	repo.On("UpdateByID", mock.Anything, int64(10), "current", "current@example.com", "", "", 0).
+		Return((*model.User)(nil), fmt.Errorf("%w: new row violates check constraint", repository.ErrConstraintViolation)).Once()
+	empty := ""
+
+	// When: clearing the full name
+	_, err := service.UpdateByID(context.Background(), 10, UpdateUserInput{Version: intPtr(0), FullName: &empty})
+
+	// Then: the client gets invalid input rather than an internal error
+	require.ErrorIs(t, err, ErrInvalidUserInput)
+	repo.AssertExpectations(t)
+}
+
+func TestUserService_UpdateByUUID_NilUUID(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	name := "New Name"
+
+	_, err := service.UpdateByUUID(context.Background(), uuid.Nil, UpdateUserInput{Version: intPtr(0), FullName: &name})
+
+	require.ErrorIs(t, err, ErrReservedUUID)
+	require.ErrorIs(t, err, ErrInvalidUserInput)
+	repo.AssertNotCalled(t, "GetByUUID", mock.Anything, mock.Anything)
+}
+
+func TestUserService_DeleteByUUID_NilUUID(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+
+	err := service.DeleteByUUID(context.Background(), uuid.Nil)
+
+	require.ErrorIs(t, err, ErrReservedUUID)
+	repo.AssertNotCalled(t, "DeleteByUUID", mock.Anything, mock.Anything)
+}
+
 func TestUserService_DeleteByUUID_Success(t *testing.T) {
 	// Given: repository successfully deletes a user
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
-	repo.On("DeleteByUUID", mock.AnythingOfType("uuid.UUID")).Return(true, nil).Once()
+	repo.On("DeleteByUUID", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(&model.User{}, nil).Once()
 
 	// When: deleting an existing user
-	err := service.DeleteByUUID(uuid.New())
+	err := service.DeleteByUUID(context.Background(), uuid.New())
 
 	// Then: no error is returned
 	require.NoError(t, err)
@@ -315,10 +1117,10 @@ func TestUserService_DeleteByUUID_NotFound(t *testing.T) {
 	// Given: repository reports user not found
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
-	repo.On("DeleteByUUID", mock.AnythingOfType("uuid.UUID")).Return(false, nil).Once()
+	repo.On("DeleteByUUID", mock.Anything, mock.AnythingOfType("uuid.UUID")).Return(nil, nil).Once()
 
 	// When: deleting a non-existent user
-	err := service.DeleteByUUID(uuid.New())
+	err := service.DeleteByUUID(context.Background(), uuid.New())
 
 	// Then: ErrUserNotFound is returned
 	require.ErrorIs(t, err, ErrUserNotFound)
@@ -331,31 +1133,32 @@ func TestUserService_UpdateByID_InvalidID(t *testing.T) {
 	service := NewUserService(repo)
 
 	// When: updating using an invalid (non-positive) ID
-	_, err := service.UpdateByID(0, UpdateUserInput{
+	_, err := service.UpdateByID(context.Background(), 0, UpdateUserInput{
+		Version:  intPtr(0),
 		FullName: strPtr("Name"),
 	})
 
 	// Then: invalid user input error is returned
 	require.ErrorIs(t, err, ErrInvalidUserInput)
-	repo.AssertNotCalled(t, "GetByID", mock.Anything)
+	repo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
 }
 
 func TestUserService_DeleteByID_InvalidID(t *testing.T) {
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
 
-	err := service.DeleteByID(0)
+	err := service.DeleteByID(context.Background(), 0)
 
 	require.ErrorIs(t, err, ErrInvalidUserInput)
-	repo.AssertNotCalled(t, "DeleteByID", mock.Anything)
+	repo.AssertNotCalled(t, "DeleteByID", mock.Anything, mock.Anything)
 }
 
 func TestUserService_DeleteByID_Success(t *testing.T) {
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
-	repo.On("DeleteByID", int64(15)).Return(true, nil).Once()
+	repo.On("DeleteByID", mock.Anything, int64(15)).Return(&model.User{ID: 15}, nil).Once()
 
-	err := service.DeleteByID(15)
+	err := service.DeleteByID(context.Background(), 15)
 
 	require.NoError(t, err)
 	repo.AssertExpectations(t)
@@ -364,14 +1167,130 @@ func TestUserService_DeleteByID_Success(t *testing.T) {
 func TestUserService_DeleteByID_NotFound(t *testing.T) {
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
-	repo.On("DeleteByID", int64(16)).Return(false, nil).Once()
+	repo.On("DeleteByID", mock.Anything, int64(16)).Return(nil, nil).Once()
 
-	err := service.DeleteByID(16)
+	err := service.DeleteByID(context.Background(), 16)
 
 	require.ErrorIs(t, err, ErrUserNotFound)
 	repo.AssertExpectations(t)
 }
 
+func TestUserService_DeleteByUsername(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	repo.On("DeleteByUsername", mock.Anything, "jdoe").Return(&model.User{ID: 3, Username: "jdoe"}, nil).Once()
+	repo.On("DeleteByUsername", mock.Anything, "ghost").Return(nil, nil).Once()
+
+	require.NoError(t, service.DeleteByUsername(context.Background(), "jdoe"))
+	require.ErrorIs(t, service.DeleteByUsername(context.Background(), "ghost"), ErrUserNotFound)
+	require.ErrorIs(t, service.DeleteByUsername(context.Background(), ""), ErrInvalidUserInput)
+}
+
+func TestUserService_DeleteManyByUUID(t *testing.T) {
+	// Given: three requested uuids, one repeated and one unknown
+	repo := mocks.NewUserRepositoryMock(t)
+	audit := &recordingAuditRepository{}
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{Audit: audit})
+	first, second, missing := uuid.New(), uuid.New(), uuid.New()
+	repo.On("DeleteManyByUUID", mock.Anything, []uuid.UUID{first, missing, second}).Return([]model.User{
+		{ID: 1, UUID: first.String()},
+		{ID: 2, UUID: second.String()},
+	}, nil).Once()
+
+	// When: bulk deleting them
+	result, err := service.DeleteManyByUUID(context.Background(), []uuid.UUID{first, missing, first, second})
+
+	// Then: the repository sees each uuid once and the unknown one is reported
+	require.NoError(t, err)
+	require.Equal(t, &BulkDeleteResult{Deleted: 2, NotFound: []uuid.UUID{missing}}, result)
+	require.Len(t, audit.entries, 2)
+}
+
+func TestUserService_DeleteManyByUUID_RejectsBatch(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserServiceWithOptions(repo, UserServiceOptions{MaxBulkDelete: 2})
+
+	_, err := service.DeleteManyByUUID(context.Background(), nil)
+	require.ErrorIs(t, err, ErrInvalidUserInput)
+
+	_, err = service.DeleteManyByUUID(context.Background(), []uuid.UUID{uuid.New(), uuid.New(), uuid.New()})
+	require.ErrorIs(t, err, ErrInvalidUserInput)
+	require.ErrorContains(t, err, "at most 2 uuids")
+
+	_, err = service.DeleteManyByUUID(context.Background(), []uuid.UUID{uuid.New(), uuid.Nil})
+	require.ErrorIs(t, err, ErrReservedUUID)
+
+	repo.AssertNotCalled(t, "DeleteManyByUUID", mock.Anything, mock.Anything)
+}
+
+func TestUserService_GetByUUIDs(t *testing.T) {
+	// Given: three requested uuids, one repeated and one unknown
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	first, second, missing := uuid.New(), uuid.New(), uuid.New()
+	repo.On("GetByUUIDs", mock.Anything, []uuid.UUID{first, missing, second}).Return([]model.User{
+		{ID: 2, UUID: second.String()},
+		{ID: 1, UUID: first.String()},
+	}, nil).Once()
+
+	// When: fetching them in one batch
+	result, err := service.GetByUUIDs(context.Background(), []uuid.UUID{first, missing, first, second})
+
+	// Then: the repository sees each uuid once and the unknown one is reported
+	require.NoError(t, err)
+	require.Len(t, result.Users, 2)
+	require.Equal(t, []uuid.UUID{missing}, result.Missing)
+}
+
+func TestUserService_GetByUUIDs_RejectsBatch(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+
+	_, err := service.GetByUUIDs(context.Background(), nil)
+	require.ErrorIs(t, err, ErrInvalidUserInput)
+
+	tooMany := make([]uuid.UUID, MaxBatchGet+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.New()
+	}
+	_, err = service.GetByUUIDs(context.Background(), tooMany)
+	require.ErrorIs(t, err, ErrInvalidUserInput)
+	require.ErrorContains(t, err, fmt.Sprintf("at most %d uuids", MaxBatchGet))
+
+	repo.AssertNotCalled(t, "GetByUUIDs", mock.Anything, mock.Anything)
+}
+
+func TestUserService_List_EmptyCreatedRange(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := service.List(context.Background(), ListUsersQuery{CreatedAfter: at, CreatedBefore: at})
+
+	require.ErrorIs(t, err, ErrEmptyCreatedRange)
+	require.ErrorIs(t, err, ErrInvalidUserInput)
+	repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+}
+
+func TestUserService_Exists(t *testing.T) {
+	repo := mocks.NewUserRepositoryMock(t)
+	service := NewUserService(repo)
+	id := uuid.New()
+	repo.On("ExistsByUUID", mock.Anything, id).Return(true, nil).Once()
+	repo.On("ExistsByID", mock.Anything, int64(4)).Return(false, nil).Once()
+
+	exists, err := service.ExistsByUUID(context.Background(), id)
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	exists, err = service.ExistsByID(context.Background(), 4)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	_, err = service.ExistsByID(context.Background(), 0)
+	require.ErrorIs(t, err, ErrInvalidUserInput)
+}
+
 func TestUserService_UpdateByID_EmailValidation(t *testing.T) {
 	// Given: repository contains an existing user
 	existing := &model.User{
@@ -384,8 +1303,8 @@ func TestUserService_UpdateByID_EmailValidation(t *testing.T) {
 	repo := mocks.NewUserRepositoryMock(t)
 	service := NewUserService(repo)
 	newEmail := "updated@example.com"
-	repo.On("GetByID", int64(existing.ID)).Return(existing, nil).Once()
-	repo.On("UpdateByID", int64(existing.ID), "current", newEmail, "Holder").
+	repo.On("GetByID", mock.Anything, int64(existing.ID)).Return(existing, nil).Once()
+	repo.On("UpdateByID", mock.Anything, int64(existing.ID), "current", newEmail, "Holder", "", 0).
 		Return(&model.User{
 			ID:       existing.ID,
 			UUID:     existing.UUID,
@@ -395,8 +1314,9 @@ func TestUserService_UpdateByID_EmailValidation(t *testing.T) {
 		}, nil).Once()
 
 	// When: updating email to a valid address
-	result, err := service.UpdateByID(int64(existing.ID), UpdateUserInput{
-		Email: &newEmail,
+	result, err := service.UpdateByID(context.Background(), int64(existing.ID), UpdateUserInput{
+		Version: intPtr(0),
+		Email:   &newEmail,
 	})
 
 	// Then: repository receives new email and returns updated user
@@ -409,3 +1329,7 @@ func TestUserService_UpdateByID_EmailValidation(t *testing.T) {
 func strPtr(s string) *string {
 	return &s
 }
+
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	fieldRequired     = "required"
+	fieldInvalidEmail = "not a valid address"
+	fieldTaken        = "already taken"
+	fieldInvalidURL   = "must be an http or https URL"
+)
+
+// normalizeEmail parses email and returns the bare address with its domain
+// lowercased. The local part keeps its case: RFC 5321 lets mail servers treat
+// it as case-sensitive, so uniqueness is enforced case-insensitively by the
+// users_email_lower_key index instead of by rewriting it.
+func normalizeEmail(email string) (string, bool) {
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return "", false
+	}
+	at := strings.LastIndex(addr.Address, "@")
+	if at < 0 {
+		return "", false
+	}
+	return addr.Address[:at] + strings.ToLower(addr.Address[at:]), true
+}
+
+// checkMaxLength returns the field message for a value longer than max
+// characters, or "" when it fits.
+func checkMaxLength(value string, max int) string {
+	if utf8.RuneCountInString(value) > max {
+		return fmt.Sprintf("must be at most %d characters", max)
+	}
+	return ""
+}
+
+// checkAvatarURL returns the field message for a trimmed, non-empty avatar
+// URL, or "" when it is an absolute http or https URL with a host. Every
+// other scheme, javascript: and data: included, is refused, since clients
+// put the value straight into an image source.
+func checkAvatarURL(value string) string {
+	if msg := checkMaxLength(value, MaxAvatarURLLength); msg != "" {
+		return msg
+	}
+	u, err := url.ParseRequestURI(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fieldInvalidURL
+	}
+	return ""
+}
+
+// reservedUUIDs never identify a real user. Today users.uuid is generated
+// by the database, so only lookups can carry one; a create path that accepts
+// client-supplied UUIDs must reject these as well.
+var reservedUUIDs = map[uuid.UUID]struct{}{
+	uuid.Nil: {},
+	uuid.Max: {},
+}
+
+func isReservedUUID(id uuid.UUID) bool {
+	_, reserved := reservedUUIDs[id]
+	return reserved
+}
+
+// ValidationError reports which user fields failed validation, keyed by JSON
+// field name. It wraps ErrInvalidUserInput so existing errors.Is checks hold.
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	return formatFields(ErrInvalidUserInput, e.Fields)
+}
+
+// formatFields renders err followed by the field reasons, sorted by field.
+func formatFields(err error, fields map[string]string) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+": "+fields[key])
+	}
+	return fmt.Sprintf("%s: %s", err, strings.Join(parts, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidUserInput
+}
+
+// ConflictError reports which user fields hold a value another user already
+// has, keyed by JSON field name like ValidationError. It wraps
+// ErrUserAlreadyExists so existing errors.Is checks hold.
+type ConflictError struct {
+	Fields map[string]string
+}
+
+func (e *ConflictError) Error() string {
+	return formatFields(ErrUserAlreadyExists, e.Fields)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrUserAlreadyExists
+}
+
+// usernamePattern is the default username character policy: a leading
+// letter followed by letters, digits, '_', '.', or '-'.
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.-]*$`)
+
+// UsernamePolicy constrains new and updated usernames. MaxLength is capped at
+// MaxUsernameLength so a policy can never admit values the column rejects.
+type UsernamePolicy struct {
+	MinLength int
+	MaxLength int
+	// Pattern must match the whole username; Description explains it to clients.
+	Pattern     *regexp.Regexp
+	Description string
+}
+
+// DefaultUsernamePolicy is applied when UserServiceOptions leaves the policy unset.
+var DefaultUsernamePolicy = UsernamePolicy{
+	MinLength:   3,
+	MaxLength:   32,
+	Pattern:     usernamePattern,
+	Description: "must start with a letter and contain only letters, digits, '_', '.', or '-'",
+}
+
+// check returns the field message for a trimmed username, or "" when valid.
+func (p UsernamePolicy) check(username string) string {
+	if username == "" {
+		return fieldRequired
+	}
+	maxLength := p.MaxLength
+	if maxLength <= 0 || maxLength > MaxUsernameLength {
+		maxLength = MaxUsernameLength
+	}
+	if n := utf8.RuneCountInString(username); n < p.MinLength || n > maxLength {
+		return fmt.Sprintf("must be between %d and %d characters", p.MinLength, maxLength)
+	}
+	if p.Pattern != nil && !p.Pattern.MatchString(username) {
+		return p.Description
+	}
+	return ""
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"cruder/pkg/logger"
+)
+
+// Headers set on every webhook delivery.
+const (
+	HeaderWebhookEvent     = "X-Cruder-Event"
+	HeaderWebhookSignature = "X-Cruder-Signature"
+	HeaderWebhookRequestID = "X-Request-ID"
+)
+
+// Defaults applied to zero WebhookOptions fields.
+const (
+	DefaultWebhookWorkers     = 4
+	DefaultWebhookQueueSize   = 256
+	DefaultWebhookMaxAttempts = 3
+	DefaultWebhookBackoff     = 500 * time.Millisecond
+	DefaultWebhookTimeout     = 5 * time.Second
+)
+
+// WebhookOptions configures NewWebhookPublisher.
+type WebhookOptions struct {
+	// URLs each receive every event.
+	URLs []string
+	// Secret keys the HMAC-SHA256 signature sent in HeaderWebhookSignature.
+	Secret string
+	// Workers is the number of concurrent deliveries.
+	Workers int
+	// QueueSize bounds deliveries waiting for a worker; when it is full,
+	// Publish drops the event with ErrEventBufferFull.
+	QueueSize int
+	// MaxAttempts bounds tries per delivery. Only transport errors and 5xx
+	// responses are retried.
+	MaxAttempts int
+	// Backoff is the wait before the first retry; it doubles for each
+	// following one.
+	Backoff time.Duration
+	// Timeout bounds a single attempt.
+	Timeout time.Duration
+	// Logger receives delivery failures. Nil means the global logger.
+	Logger *logger.Logger
+}
+
+// WebhookPublisher POSTs events as JSON to every configured URL from a pool
+// of background workers, so a slow endpoint never delays the mutation.
+type WebhookPublisher struct {
+	opts   WebhookOptions
+	client *http.Client
+	log    *logger.Logger
+	// ctx is cancelled once Close gives up waiting, which aborts in-flight
+	// attempts and backoffs.
+	ctx  context.Context
+	stop context.CancelFunc
+
+	mu     sync.RWMutex
+	queue  chan webhookDelivery
+	closed bool
+	wg     sync.WaitGroup
+}
+
+type webhookDelivery struct {
+	url   string
+	event UserEvent
+	body  []byte
+}
+
+func NewWebhookPublisher(opts WebhookOptions) *WebhookPublisher {
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultWebhookWorkers
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultWebhookQueueSize
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultWebhookMaxAttempts
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = DefaultWebhookBackoff
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultWebhookTimeout
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	p := &WebhookPublisher{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		log:    logger.OrGlobal(opts.Logger).With(slog.String("component", "service.webhooks")),
+		ctx:    ctx,
+		stop:   stop,
+		queue:  make(chan webhookDelivery, opts.QueueSize),
+	}
+	p.wg.Add(opts.Workers)
+	for range opts.Workers {
+		go p.work()
+	}
+	return p
+}
+
+// Publish queues the event for every URL and returns without waiting for
+// delivery.
+func (p *WebhookPublisher) Publish(_ context.Context, event UserEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return nil
+	}
+	for _, url := range p.opts.URLs {
+		select {
+		case p.queue <- webhookDelivery{url: url, event: event, body: body}:
+		default:
+			return ErrEventBufferFull
+		}
+	}
+	return nil
+}
+
+// Close stops accepting events and waits for queued deliveries to finish
+// until ctx ends. Deliveries still queued or in flight then are dropped, each
+// logged, and Close returns ctx's error.
+func (p *WebhookPublisher) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		p.stop()
+		return nil
+	case <-ctx.Done():
+	}
+	p.stop()
+	<-done
+	return ctx.Err()
+}
+
+// SignWebhook returns the HeaderWebhookSignature value for body: "sha256="
+// and the hex HMAC-SHA256 of body under secret. Receivers recompute it to
+// verify a delivery.
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (p *WebhookPublisher) work() {
+	defer p.wg.Done()
+	for d := range p.queue {
+		p.deliver(d)
+	}
+}
+
+func (p *WebhookPublisher) deliver(d webhookDelivery) {
+	backoff := p.opts.Backoff
+	for attempt := 1; ; attempt++ {
+		if p.ctx.Err() != nil {
+			p.logDropped(d, attempt-1)
+			return
+		}
+		retry, err := p.post(d)
+		if err == nil {
+			return
+		}
+		if p.ctx.Err() != nil {
+			p.logDropped(d, attempt)
+			return
+		}
+		if !retry || attempt == p.opts.MaxAttempts {
+			p.log.Error("webhook delivery failed",
+				slog.String("webhook.url", d.url),
+				slog.String("event.action", d.event.Action),
+				slog.String("user.uuid", d.event.UserUUID),
+				slog.Int("webhook.attempts", attempt),
+				slog.String("error", err.Error()),
+			)
+			return
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-p.ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// logDropped records a delivery abandoned because Close ran out of time.
+func (p *WebhookPublisher) logDropped(d webhookDelivery, attempts int) {
+	p.log.Warn("webhook delivery dropped at shutdown",
+		slog.String("webhook.url", d.url),
+		slog.String("event.action", d.event.Action),
+		slog.String("user.uuid", d.event.UserUUID),
+		slog.Int("webhook.attempts", attempts),
+	)
+}
+
+// post makes one attempt and reports whether a failure is worth retrying.
+func (p *WebhookPublisher) post(d webhookDelivery) (bool, error) {
+	req, err := http.NewRequestWithContext(p.ctx, http.MethodPost, d.url, bytes.NewReader(d.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookEvent, d.event.Action)
+	req.Header.Set(HeaderWebhookSignature, SignWebhook(p.opts.Secret, d.body))
+	if d.event.RequestID != "" {
+		req.Header.Set(HeaderWebhookRequestID, d.event.RequestID)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_ = resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return true, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	case resp.StatusCode >= 300:
+		return false, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return false, nil
+}
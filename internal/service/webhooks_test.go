@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type webhookRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookReceiver answers with statuses in turn, then 204, and records every
+// request it gets.
+func webhookReceiver(t *testing.T, statuses ...int) (*httptest.Server, <-chan webhookRequest) {
+	t.Helper()
+	received := make(chan webhookRequest, 10)
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- webhookRequest{header: r.Header.Clone(), body: body}
+		status := http.StatusNoContent
+		if n := int(calls.Add(1)) - 1; n < len(statuses) {
+			status = statuses[n]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func newTestWebhookPublisher(urls ...string) *WebhookPublisher {
+	return NewWebhookPublisher(WebhookOptions{URLs: urls, Secret: "s3cret", Workers: 1, Backoff: time.Millisecond})
+}
+
+func TestWebhookPublisher_PostsSignedEvent(t *testing.T) {
+	// Given: a publisher pointed at a receiver
+	server, received := webhookReceiver(t)
+	publisher := newTestWebhookPublisher(server.URL)
+	event := UserEvent{Action: AuditActionCreate, UserUUID: "8d2f6a1e-0000-4000-8000-000000000001", Actor: "billing", RequestID: "req-1"}
+
+	// When: an event is published and the publisher drained
+	require.NoError(t, publisher.Publish(context.Background(), event))
+	require.NoError(t, publisher.Close(context.Background()))
+
+	// Then: the receiver got the JSON event, its type, the request id and a valid signature
+	req := <-received
+	var got UserEvent
+	require.NoError(t, json.Unmarshal(req.body, &got))
+	require.Equal(t, event, got)
+	require.Equal(t, "application/json", req.header.Get("Content-Type"))
+	require.Equal(t, AuditActionCreate, req.header.Get(HeaderWebhookEvent))
+	require.Equal(t, "req-1", req.header.Get(HeaderWebhookRequestID))
+	require.Equal(t, SignWebhook("s3cret", req.body), req.header.Get(HeaderWebhookSignature))
+}
+
+func TestWebhookPublisher_RetriesServerErrors(t *testing.T) {
+	server, received := webhookReceiver(t, http.StatusBadGateway, http.StatusServiceUnavailable)
+	publisher := newTestWebhookPublisher(server.URL)
+
+	require.NoError(t, publisher.Publish(context.Background(), UserEvent{Action: AuditActionDelete}))
+	require.NoError(t, publisher.Close(context.Background()))
+
+	require.Len(t, received, DefaultWebhookMaxAttempts)
+}
+
+func TestWebhookPublisher_DoesNotRetryClientErrors(t *testing.T) {
+	server, received := webhookReceiver(t, http.StatusBadRequest)
+	publisher := newTestWebhookPublisher(server.URL)
+
+	require.NoError(t, publisher.Publish(context.Background(), UserEvent{Action: AuditActionDelete}))
+	require.NoError(t, publisher.Close(context.Background()))
+
+	require.Len(t, received, 1)
+}
+
+func TestWebhookPublisher_DoesNotBlockOnSlowEndpoint(t *testing.T) {
+	// Given: an endpoint that hangs until the test ends
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { <-release }))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+	publisher := NewWebhookPublisher(WebhookOptions{URLs: []string{server.URL}, Secret: "s3cret", Workers: 1, QueueSize: 1})
+
+	// When: publishing more events than the worker and queue can hold
+	var errs []error
+	start := time.Now()
+	for range 3 {
+		errs = append(errs, publisher.Publish(context.Background(), UserEvent{Action: AuditActionCreate}))
+	}
+
+	// Then: Publish returned straight away and reported the dropped event
+	require.Less(t, time.Since(start), time.Second)
+	require.ErrorIs(t, errs[len(errs)-1], ErrEventBufferFull)
+}
+
+func TestWebhookPublisher_CloseGivesUpAtDeadline(t *testing.T) {
+	// Given: a dead endpoint that fails every attempt, slow retries, and a
+	// queue of deliveries behind the one in flight
+	server, received := webhookReceiver(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	publisher := NewWebhookPublisher(WebhookOptions{URLs: []string{server.URL}, Secret: "s3cret", Workers: 1, Backoff: time.Hour})
+	for range 3 {
+		require.NoError(t, publisher.Publish(context.Background(), UserEvent{Action: AuditActionCreate}))
+	}
+	<-received
+
+	// When: closing with a short deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := publisher.Close(ctx)
+
+	// Then: Close returns at the deadline instead of waiting out the backoff,
+	// and the remaining deliveries are never attempted
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+	require.Empty(t, received)
+}
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE api_keys
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ NULL;
+
+-- +goose Down
+ALTER TABLE api_keys
+    DROP COLUMN IF EXISTS expires_at;
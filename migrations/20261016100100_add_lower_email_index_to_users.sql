@@ -0,0 +1,37 @@
+-- +goose Up
+-- Both the domain rewrite below and the unique index fail with a bare 23505
+-- when two accounts differ only in letter case, so refuse up front and name
+-- the accounts that have to be merged or renamed by hand first.
+-- +goose StatementBegin
+DO $$
+DECLARE
+    collisions TEXT;
+BEGIN
+    SELECT string_agg(ids, '; ' ORDER BY ids)
+    INTO collisions
+    FROM (
+        SELECT 'ids ' || string_agg(id::text, ', ' ORDER BY id) AS ids
+        FROM users
+        GROUP BY lower(email)
+        HAVING count(*) > 1
+    ) AS duplicates;
+
+    IF collisions IS NOT NULL THEN
+        RAISE EXCEPTION 'users share an email address that differs only in letter case: %', collisions
+            USING HINT = 'merge or rename these accounts, then rerun the migrations';
+    END IF;
+END
+$$;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE users
+SET email = left(email, length(email) - strpos(reverse(email), '@'))
+    || lower(right(email, strpos(reverse(email), '@')))
+WHERE strpos(email, '@') > 0;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (lower(email));
+-- +goose StatementEnd
+
+-- +goose Down
+DROP INDEX IF EXISTS users_email_lower_key;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION api_keys_notify_change() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('api_keys_changed', OLD.key_hash);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER api_keys_notify_change
+    AFTER UPDATE OR DELETE ON api_keys
+    FOR EACH ROW EXECUTE FUNCTION api_keys_notify_change();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS api_keys_notify_change ON api_keys;
+DROP FUNCTION IF EXISTS api_keys_notify_change();
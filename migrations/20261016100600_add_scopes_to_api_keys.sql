@@ -0,0 +1,6 @@
+-- +goose Up
+-- existing keys keep the full access they had before scopes existed
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS scopes TEXT[] NOT NULL DEFAULT '{*}';
+
+-- +goose Down
+ALTER TABLE api_keys DROP COLUMN IF EXISTS scopes;
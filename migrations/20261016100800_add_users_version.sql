@@ -0,0 +1,7 @@
+-- +goose Up
+-- version is bumped by every UPDATE of the row, so a client can send back the
+-- version it read and have the update refused if someone else wrote first.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS version;
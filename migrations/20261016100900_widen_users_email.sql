@@ -0,0 +1,11 @@
+-- +goose Up
+-- 254 characters is the longest address RFC 5321 allows; the service checks
+-- service.MaxEmailLength before writing. Widening a VARCHAR doesn't rewrite
+-- the table.
+ALTER TABLE users ALTER COLUMN email TYPE VARCHAR(254);
+ALTER TABLE email_verification_tokens ALTER COLUMN email TYPE VARCHAR(254);
+
+-- +goose Down
+-- fails if a longer address has been stored since
+ALTER TABLE email_verification_tokens ALTER COLUMN email TYPE VARCHAR(100);
+ALTER TABLE users ALTER COLUMN email TYPE VARCHAR(100);
@@ -0,0 +1,14 @@
+-- +goose Up
+-- a client may hold several keys (one per environment, or old and new during
+-- a rotation); the label tells them apart
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS label TEXT NOT NULL DEFAULT '';
+
+-- clients that already hold several keys keep them, labelled by id
+UPDATE api_keys SET label = 'key-' || id
+WHERE id NOT IN (SELECT MIN(id) FROM api_keys GROUP BY client_name);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_client_name_label ON api_keys (client_name, label);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_api_keys_client_name_label;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS label;
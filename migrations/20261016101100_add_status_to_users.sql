@@ -0,0 +1,17 @@
+-- +goose Up
+-- CREATE TYPE has no IF NOT EXISTS, so a rerun swallows the duplicate
+-- +goose StatementBegin
+DO $$
+BEGIN
+    CREATE TYPE user_status AS ENUM ('active', 'suspended');
+EXCEPTION
+    WHEN duplicate_object THEN NULL;
+END
+$$;
+-- +goose StatementEnd
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status user_status NOT NULL DEFAULT 'active';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS status;
+DROP TYPE IF EXISTS user_status;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- NULL means no avatar; the service checks service.MaxAvatarURLLength and
+-- that the value is an http(s) URL before writing.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_url VARCHAR(2048);
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_url;
@@ -0,0 +1,10 @@
+// Package migrations embeds the goose SQL migrations so they ship inside the
+// server binary.
+package migrations
+
+import "embed"
+
+// FS holds every migration file in this directory.
+//
+//go:embed *.sql
+var FS embed.FS
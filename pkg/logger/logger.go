@@ -9,26 +9,76 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	otellog "go.opentelemetry.io/otel/log"
 )
 
 const (
 	OutputStdout = "stdout"
 	OutputFile   = "file"
 	OutputBoth   = "both"
+	OutputOTel   = "otel"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Time formats for the timestamp of stdout and file records. The RFC 3339
+// ones are always written in UTC.
+const (
+	TimeFormatRFC3339Nano = "rfc3339nano"
+	TimeFormatRFC3339     = "rfc3339"
+	TimeFormatUnixMilli   = "unixmilli"
 )
 
 type Options struct {
 	Output   string
 	FilePath string
 	Level    string
+	// Format selects the local handler: "json" (default) or "text".
+	Format string
+	// TimeFormat selects how the timestamp of stdout and file records is
+	// written: TimeFormatRFC3339Nano (default), TimeFormatRFC3339, or
+	// TimeFormatUnixMilli. OpenTelemetry records carry their own timestamp.
+	TimeFormat string
+
+	// MaxSizeMB rotates the log file once it would grow past this size.
+	// Zero disables rotation.
+	MaxSizeMB int
+	// MaxBackups caps how many rotated files are kept. Zero keeps them all.
+	MaxBackups int
+
+	// OTelLogs additionally routes records through an OpenTelemetry log
+	// provider. Output "otel" implies it and disables stdout/file output.
+	OTelLogs bool
+	// OTelProvider overrides the OTLP/HTTP provider built when OTelLogs is set.
+	// The caller keeps ownership and is responsible for shutting it down.
+	OTelProvider otellog.LoggerProvider
+
+	// MaskKeys lists attribute keys, in addition to DefaultMaskKeys, whose
+	// values are replaced with MaskedValue before any handler sees them.
+	MaskKeys []string
+
+	// ServiceName and Environment, when set, are added to every record as
+	// service.name and deployment.environment, ahead of any With attributes,
+	// so logs shipped from several services can be told apart.
+	ServiceName string
+	Environment string
 }
 
 type Logger struct {
 	base    *slog.Logger
 	closers []io.Closer
+	// ctx is passed to every record when set by BindContext, so the
+	// OpenTelemetry output can attach its span.
+	ctx context.Context
 }
 
 type ctxKey struct{}
@@ -40,8 +90,10 @@ var (
 
 func DefaultOptions() Options {
 	return Options{
-		Output: OutputStdout,
-		Level:  "info",
+		Output:     OutputStdout,
+		Level:      "info",
+		Format:     FormatJSON,
+		TimeFormat: TimeFormatRFC3339Nano,
 	}
 }
 
@@ -72,6 +124,28 @@ func Configure(opts Options) (*Logger, error) {
 	return inst, nil
 }
 
+// New builds a Logger from opts without installing it as the global
+// logger or touching the slog and standard library defaults, for programs
+// that embed this one and own their logging setup. The caller must Close it.
+func New(opts Options) (*Logger, error) {
+	return newLogger(normalizeOptions(opts))
+}
+
+// FromSlog wraps an existing slog.Logger. The wrapper owns no outputs, so
+// closing it is a no-op.
+func FromSlog(base *slog.Logger) *Logger {
+	return &Logger{base: base}
+}
+
+// OrGlobal returns l, or the global logger when l is nil, for constructors
+// whose logger is optional.
+func OrGlobal(l *Logger) *Logger {
+	if l != nil {
+		return l
+	}
+	return Get()
+}
+
 func Get() *Logger {
 	inst := global.Load()
 	if inst != nil {
@@ -89,25 +163,62 @@ func (l *Logger) Base() *slog.Logger {
 }
 
 func (l *Logger) Info(msg string, attrs ...any) {
-	l.base.Info(msg, attrs...)
+	l.base.InfoContext(l.context(), msg, attrs...)
 }
 
 func (l *Logger) Warn(msg string, attrs ...any) {
-	l.base.Warn(msg, attrs...)
+	l.base.WarnContext(l.context(), msg, attrs...)
 }
 
 func (l *Logger) Error(msg string, attrs ...any) {
-	l.base.Error(msg, attrs...)
+	l.base.ErrorContext(l.context(), msg, attrs...)
 }
 
 func (l *Logger) Debug(msg string, attrs ...any) {
-	l.base.Debug(msg, attrs...)
+	l.base.DebugContext(l.context(), msg, attrs...)
+}
+
+// InfoContext is Info with the record tied to ctx, so it carries the trace
+// and span ids of the span in ctx, if any.
+func (l *Logger) InfoContext(ctx context.Context, msg string, attrs ...any) {
+	l.base.InfoContext(ctx, msg, attrs...)
+}
+
+func (l *Logger) WarnContext(ctx context.Context, msg string, attrs ...any) {
+	l.base.WarnContext(ctx, msg, attrs...)
+}
+
+func (l *Logger) ErrorContext(ctx context.Context, msg string, attrs ...any) {
+	l.base.ErrorContext(ctx, msg, attrs...)
+}
+
+func (l *Logger) DebugContext(ctx context.Context, msg string, attrs ...any) {
+	l.base.DebugContext(ctx, msg, attrs...)
+}
+
+// BindContext returns a copy of l whose Info, Warn, Error, and Debug behave
+// like the *Context variants with ctx. The request logger is bound to its
+// request, so everything logged through it carries the request's trace.
+func (l *Logger) BindContext(ctx context.Context) *Logger {
+	return &Logger{
+		base:    l.base,
+		closers: l.closers,
+		ctx:     ctx,
+	}
+}
+
+func (l *Logger) context() context.Context {
+	if l.ctx != nil {
+		return l.ctx
+	}
+	return context.Background()
 }
 
 func (l *Logger) With(attrs ...any) *Logger {
 	return &Logger{
 		base:    l.base.With(attrs...),
 		closers: l.closers,
+		ctx:     l.ctx,
 	}
 }
 
@@ -181,7 +292,13 @@ func newLogger(opts Options) (*Logger, error) {
 		if path == "" {
 			return fmt.Errorf("file path cannot be empty when output includes file")
 		}
-		f, err := openLogFile(path)
+		var f io.WriteCloser
+		var err error
+		if opts.MaxSizeMB > 0 {
+			f, err = newRotatingFile(path, int64(opts.MaxSizeMB)*bytesPerMB, opts.MaxBackups)
+		} else {
+			f, err = openLogFile(path)
+		}
 		if err != nil {
 			return fmt.Errorf("open log file: %w", err)
 		}
@@ -202,19 +319,75 @@ func newLogger(opts Options) (*Logger, error) {
 		if err := addFile(opts.FilePath); err != nil {
 			return nil, err
 		}
+	case OutputOTel:
 	default:
 		writers = append(writers, os.Stdout)
 	}
 
-	handlerOpts := buildHandlerOptions(level)
-	handler := slog.NewJSONHandler(io.MultiWriter(writers...), handlerOpts)
+	masker := newKeyMasker(opts.MaskKeys)
+	var handlers fanoutHandler
+	if len(writers) > 0 {
+		formatTime, err := parseTimeFormat(opts.TimeFormat)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid log time format %q, falling back to %q\n", opts.TimeFormat, DefaultOptions().TimeFormat)
+			formatTime, _ = parseTimeFormat(DefaultOptions().TimeFormat)
+		}
+		handlers = append(handlers, newFormatHandler(opts.Format, io.MultiWriter(writers...), buildHandlerOptions(level, masker, formatTime)))
+	}
+
+	if opts.OTelLogs {
+		provider := opts.OTelProvider
+		if provider == nil {
+			sdkProvider, err := newOTLPProvider()
+			if err != nil {
+				return nil, err
+			}
+			provider = sdkProvider
+			closers = append(closers, providerCloser{provider: sdkProvider})
+		}
+		handlers = append(handlers, newOTelHandler(provider, level, masker))
+	}
+
+	var handler slog.Handler = handlers
+	if len(handlers) == 1 {
+		handler = handlers[0]
+	}
+
+	base := slog.New(handler)
+	if attrs := opts.baseAttrs(); len(attrs) > 0 {
+		base = base.With(attrs...)
+	}
 
 	return &Logger{
-		base:    slog.New(handler),
+		base:    base,
 		closers: closers,
 	}, nil
 }
 
+// baseAttrs returns the attributes every record of the logger carries.
+func (o Options) baseAttrs() []any {
+	var attrs []any
+	if o.ServiceName != "" {
+		attrs = append(attrs, slog.String("service.name", o.ServiceName))
+	}
+	if o.Environment != "" {
+		attrs = append(attrs, slog.String("deployment.environment", o.Environment))
+	}
+	return attrs
+}
+
+func newFormatHandler(format string, w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+	switch strings.ToLower(format) {
+	case FormatText:
+		return slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		return slog.NewJSONHandler(w, opts)
+	default:
+		fmt.Fprintf(os.Stderr, "invalid log format %q, falling back to %q\n", format, DefaultOptions().Format)
+		return slog.NewJSONHandler(w, opts)
+	}
+}
+
 func parseLevel(value string) (slog.Leveler, error) {
 	switch strings.ToLower(strings.TrimSpace(value)) {
 	case "debug":
@@ -249,27 +422,95 @@ func openLogFile(path string) (*os.File, error) {
 	return os.OpenFile(cleanPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
 }
 
-func buildHandlerOptions(level slog.Leveler) *slog.HandlerOptions {
+// parseTimeFormat returns the encoder for one of the TimeFormat constants.
+func parseTimeFormat(value string) (func(time.Time) slog.Value, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case TimeFormatRFC3339Nano, "":
+		return func(t time.Time) slog.Value { return slog.StringValue(t.UTC().Format(time.RFC3339Nano)) }, nil
+	case TimeFormatRFC3339:
+		return func(t time.Time) slog.Value { return slog.StringValue(t.UTC().Format(time.RFC3339)) }, nil
+	case TimeFormatUnixMilli:
+		return func(t time.Time) slog.Value { return slog.Int64Value(t.UnixMilli()) }, nil
+	default:
+		return nil, fmt.Errorf("unknown log time format: %s", value)
+	}
+}
+
+func buildHandlerOptions(level slog.Leveler, masker keyMasker, formatTime func(time.Time) slog.Value) *slog.HandlerOptions {
 	return &slog.HandlerOptions{
 		Level: level,
 		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if len(groups) > 0 {
+				return masker.mask(attr)
+			}
 			switch attr.Key {
 			case slog.TimeKey:
 				attr.Key = "timestamp"
+				if t, ok := attr.Value.Any().(time.Time); ok {
+					attr.Value = formatTime(t)
+				}
 			case slog.MessageKey:
 				attr.Key = "message"
+			case slog.LevelKey, slog.SourceKey:
+			default:
+				attr = masker.mask(attr)
 			}
 			return attr
 		},
 	}
 }
 
+// Validate reports every option Configure would reject or silently replace
+// with a default, so bad settings can fail startup instead.
+func (o Options) Validate() error {
+	o = normalizeOptions(o)
+	var errs []error
+	switch o.Output {
+	case OutputStdout, OutputFile, OutputBoth, OutputOTel:
+	default:
+		errs = append(errs, fmt.Errorf("unknown log output: %s", o.Output))
+	}
+	if _, err := parseLevel(o.Level); err != nil {
+		errs = append(errs, err)
+	}
+	switch strings.ToLower(o.Format) {
+	case FormatJSON, FormatText:
+	default:
+		errs = append(errs, fmt.Errorf("unknown log format: %s", o.Format))
+	}
+	if _, err := parseTimeFormat(o.TimeFormat); err != nil {
+		errs = append(errs, err)
+	}
+	if o.Output == OutputFile || o.Output == OutputBoth {
+		switch {
+		case o.FilePath == "":
+			errs = append(errs, fmt.Errorf("file path cannot be empty when output includes file"))
+		case !filepath.IsAbs(o.FilePath):
+			errs = append(errs, fmt.Errorf("log file path must be absolute: %s", o.FilePath))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func normalizeOptions(opts Options) Options {
 	defaults := DefaultOptions()
 
 	opts.Output = cleanOption(opts.Output, defaults.Output)
 	opts.Level = cleanOption(opts.Level, defaults.Level)
+	opts.Format = cleanOption(opts.Format, defaults.Format)
+	opts.TimeFormat = cleanOption(opts.TimeFormat, defaults.TimeFormat)
 	opts.FilePath = strings.TrimSpace(opts.FilePath)
+	opts.ServiceName = strings.TrimSpace(opts.ServiceName)
+	opts.Environment = strings.TrimSpace(opts.Environment)
+	if opts.MaxSizeMB < 0 {
+		opts.MaxSizeMB = 0
+	}
+	if opts.MaxBackups < 0 {
+		opts.MaxBackups = 0
+	}
+	if opts.Output == OutputOTel {
+		opts.OTelLogs = true
+	}
 
 	return opts
 }
@@ -287,8 +528,44 @@ func cleanOption(value, fallback string) string {
 
 func OptionsFromEnv(env map[string]string) Options {
 	return normalizeOptions(Options{
-		Output:   env["LOG_OUTPUT"],
-		FilePath: env["LOG_FILE"],
-		Level:    env["LOG_LEVEL"],
+		Output:      env["LOG_OUTPUT"],
+		FilePath:    env["LOG_FILE"],
+		Level:       env["LOG_LEVEL"],
+		Format:      env["LOG_FORMAT"],
+		TimeFormat:  env["LOG_TIME_FORMAT"],
+		MaxSizeMB:   parseIntOption("LOG_MAX_SIZE_MB", env["LOG_MAX_SIZE_MB"]),
+		MaxBackups:  parseIntOption("LOG_MAX_BACKUPS", env["LOG_MAX_BACKUPS"]),
+		OTelLogs:    parseBoolOption(env["OTEL_LOGS"]),
+		MaskKeys:    parseListOption(env["LOG_MASK_KEYS"]),
+		ServiceName: env["SERVICE_NAME"],
+		Environment: env["DEPLOYMENT_ENVIRONMENT"],
 	})
 }
+
+func parseIntOption(name, value string) int {
+	n, err := strconv.Atoi(cleanOption(value, "0"))
+	if err != nil || n < 0 {
+		fmt.Fprintf(os.Stderr, "invalid %s %q, falling back to 0\n", name, value)
+		return 0
+	}
+	return n
+}
+
+func parseListOption(value string) []string {
+	var items []string
+	for _, item := range strings.Split(cleanOption(value, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseBoolOption(value string) bool {
+	enabled, err := strconv.ParseBool(cleanOption(value, "false"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid boolean option %q, falling back to false: %v\n", value, err)
+		return false
+	}
+	return enabled
+}
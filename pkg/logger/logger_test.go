@@ -0,0 +1,180 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newFileLogger(t *testing.T, format string) (*Logger, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.log")
+	l, err := newLogger(normalizeOptions(Options{
+		Output:   OutputFile,
+		FilePath: path,
+		Format:   format,
+	}))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	return l, path
+}
+
+func TestNewLogger_TextFormat(t *testing.T) {
+	// Given: a logger configured for text output
+	l, path := newFileLogger(t, FormatText)
+
+	// When: logging a record with attributes
+	l.Info("user created", "user.id", 42)
+
+	// Then: the line is key=value with the renamed time and message keys
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Regexp(t, `^timestamp=\S+ level=INFO message="user created" user.id=42\n$`, string(out))
+}
+
+func TestNewLogger_DefaultsToJSON(t *testing.T) {
+	l, path := newFileLogger(t, "")
+
+	l.Info("user created", "user.id", 42)
+
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(out, &record))
+	require.Equal(t, "user created", record["message"])
+	require.Contains(t, record, "timestamp")
+	require.EqualValues(t, 42, record["user.id"])
+}
+
+func TestNewLogger_TimeFormat(t *testing.T) {
+	tests := []struct {
+		timeFormat string
+		check      func(t *testing.T, ts any)
+	}{
+		{
+			timeFormat: "",
+			check: func(t *testing.T, ts any) {
+				require.IsType(t, "", ts)
+				parsed, err := time.Parse(time.RFC3339Nano, ts.(string))
+				require.NoError(t, err)
+				require.Equal(t, time.UTC, parsed.Location())
+				require.WithinDuration(t, time.Now(), parsed, time.Minute)
+			},
+		},
+		{
+			timeFormat: TimeFormatRFC3339,
+			check: func(t *testing.T, ts any) {
+				require.Regexp(t, `^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ$`, ts)
+			},
+		},
+		{
+			timeFormat: TimeFormatUnixMilli,
+			check: func(t *testing.T, ts any) {
+				require.IsType(t, float64(0), ts)
+				require.InDelta(t, time.Now().UnixMilli(), ts, float64(time.Minute.Milliseconds()))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run("format "+tt.timeFormat, func(t *testing.T) {
+			// Given: a logger writing to stdout and a file
+			path := filepath.Join(t.TempDir(), "app.log")
+			l, err := newLogger(OptionsFromEnv(map[string]string{
+				"LOG_OUTPUT":      OutputBoth,
+				"LOG_FILE":        path,
+				"LOG_TIME_FORMAT": tt.timeFormat,
+			}))
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = l.Close() })
+
+			// When: logging a record
+			l.Info("user created")
+
+			// Then: the file line carries the timestamp in the chosen format
+			out, err := os.ReadFile(path)
+			require.NoError(t, err)
+			var record map[string]any
+			require.NoError(t, json.Unmarshal(out, &record))
+			tt.check(t, record["timestamp"])
+		})
+	}
+}
+
+func TestNewLogger_BaseAttributes(t *testing.T) {
+	// Given: a logger configured with a service name and environment
+	path := filepath.Join(t.TempDir(), "app.log")
+	l, err := newLogger(OptionsFromEnv(map[string]string{
+		"LOG_OUTPUT":             OutputFile,
+		"LOG_FILE":               path,
+		"LOG_FORMAT":             FormatText,
+		"SERVICE_NAME":           "cruder",
+		"DEPLOYMENT_ENVIRONMENT": " staging ",
+	}))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	// When: a component logger adds its own attributes
+	l.With("component", "service.user").Info("user created", "user.id", 42)
+
+	// Then: the base attributes come first
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Regexp(t, `message="user created" service.name=cruder deployment.environment=staging component=service.user user.id=42\n$`, string(out))
+}
+
+func TestOptionsFromEnv_Format(t *testing.T) {
+	require.Equal(t, FormatJSON, OptionsFromEnv(map[string]string{}).Format)
+	require.Equal(t, FormatText, OptionsFromEnv(map[string]string{"LOG_FORMAT": "text"}).Format)
+}
+
+func TestOptionsValidate(t *testing.T) {
+	require.NoError(t, Options{}.Validate(), "defaults are valid")
+	require.NoError(t, Options{Output: OutputBoth, FilePath: "/var/log/app.json", Level: "WARN", Format: "Text"}.Validate())
+
+	err := Options{Output: "syslog", Level: "loud", Format: "xml"}.Validate()
+	require.ErrorContains(t, err, "unknown log output: syslog")
+	require.ErrorContains(t, err, "unknown log level: loud")
+	require.ErrorContains(t, err, "unknown log format: xml")
+	require.ErrorContains(t, Options{TimeFormat: "epoch"}.Validate(), "unknown log time format: epoch")
+
+	require.ErrorContains(t, Options{Output: OutputFile}.Validate(), "file path cannot be empty")
+	require.ErrorContains(t, Options{Output: OutputFile, FilePath: "logs/app.json"}.Validate(), "must be absolute")
+}
+
+func TestNew_LeavesGlobalsAlone(t *testing.T) {
+	// Given: the process-wide loggers as they are
+	prevGlobal, prevDefault := global.Load(), slog.Default()
+
+	// When: building a logger for an embedding program
+	l, err := New(Options{Output: OutputFile, FilePath: filepath.Join(t.TempDir(), "app.log")})
+	require.NoError(t, err)
+	defer l.Close()
+
+	// Then: neither the global logger nor slog's default changed
+	require.Same(t, prevGlobal, global.Load())
+	require.Same(t, prevDefault, slog.Default())
+}
+
+func TestFromSlog(t *testing.T) {
+	var out bytes.Buffer
+	l := FromSlog(slog.New(slog.NewTextHandler(&out, nil)))
+
+	l.With("component", "embedded").Info("hello")
+
+	require.Contains(t, out.String(), "msg=hello component=embedded")
+	require.NoError(t, l.Close())
+}
+
+func TestOrGlobal(t *testing.T) {
+	own := FromSlog(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	require.Same(t, own, OrGlobal(own))
+	require.Same(t, Get(), OrGlobal(nil))
+}
@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// MaskedValue replaces the value of every masked attribute.
+const MaskedValue = "***"
+
+// DefaultMaskKeys are always masked; Options.MaskKeys adds to them.
+var DefaultMaskKeys = []string{"email", "api_key", "authorization", "x-api-key"}
+
+// keyMasker hides attribute values by key. A key matches when it, or its
+// last dot-separated segment, equals an entry case-insensitively, so "email"
+// masks "user.email" but "api_key" leaves "api_key.id" alone.
+type keyMasker map[string]struct{}
+
+func newKeyMasker(extra []string) keyMasker {
+	m := keyMasker{}
+	for _, key := range append(append([]string(nil), DefaultMaskKeys...), extra...) {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			m[key] = struct{}{}
+		}
+	}
+	return m
+}
+
+func (m keyMasker) masks(key string) bool {
+	key = strings.ToLower(key)
+	if _, ok := m[key]; ok {
+		return true
+	}
+	if i := strings.LastIndexByte(key, '.'); i >= 0 {
+		_, ok := m[key[i+1:]]
+		return ok
+	}
+	return false
+}
+
+// mask returns attr with its value hidden when its key is masked. Groups are
+// left to the caller, which sees their members one by one.
+func (m keyMasker) mask(attr slog.Attr) slog.Attr {
+	if attr.Value.Kind() != slog.KindGroup && m.masks(attr.Key) {
+		attr.Value = slog.StringValue(MaskedValue)
+	}
+	return attr
+}
@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyMasker_Masks(t *testing.T) {
+	masker := newKeyMasker([]string{"Password"})
+
+	for key, want := range map[string]bool{
+		"email":                          true,
+		"user.email":                     true,
+		"http.request.header.X-API-Key":  true,
+		"Authorization":                  true,
+		"password":                       true,
+		"api_key.id":                     false,
+		"email_verified":                 false,
+		"user.uuid":                      false,
+		"http.request.header.user-agent": false,
+	} {
+		require.Equal(t, want, masker.masks(key), key)
+	}
+}
+
+func TestNewLogger_MasksSensitiveAttrs(t *testing.T) {
+	// Given: a JSON logger with an extra masked key
+	path := filepath.Join(t.TempDir(), "app.log")
+	l, err := newLogger(normalizeOptions(Options{Output: OutputFile, FilePath: path, MaskKeys: []string{"token"}}))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	// When: logging sensitive values at the top level, through With, and in a group
+	l.With(slog.String("authorization", "Bearer abc")).Info("user created",
+		slog.String("user.email", "jdoe@example.com"),
+		slog.String("token", "t0k3n"),
+		slog.Group("http", slog.String("x-api-key", "secret")),
+		slog.Int("api_key.id", 7),
+	)
+
+	// Then: their values are masked and the rest is untouched
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(out, &record))
+	require.Equal(t, MaskedValue, record["authorization"])
+	require.Equal(t, MaskedValue, record["user.email"])
+	require.Equal(t, MaskedValue, record["token"])
+	require.Equal(t, map[string]any{"x-api-key": MaskedValue}, record["http"])
+	require.EqualValues(t, 7, record["api_key.id"])
+	require.Equal(t, "user created", record["message"])
+	require.NotContains(t, string(out), "jdoe@example.com")
+}
+
+func TestOTelLogs_MasksSensitiveAttrs(t *testing.T) {
+	l, exporter := newMemoryLogger(t, "info")
+
+	l.With(slog.String("api_key", "secret")).Base().InfoContext(context.Background(), "user created",
+		slog.Group("user", slog.String("email", "jdoe@example.com"), slog.String("uuid", "u-1")))
+
+	records := exporter.Records()
+	require.Len(t, records, 1)
+	attrs := recordAttributes(records[0])
+	require.Equal(t, MaskedValue, attrs["api_key"].AsString())
+	require.Equal(t, MaskedValue, attrs["user.email"].AsString())
+	require.Equal(t, "u-1", attrs["user.uuid"].AsString())
+}
+
+func TestOptionsFromEnv_MaskKeys(t *testing.T) {
+	opts := OptionsFromEnv(map[string]string{"LOG_MASK_KEYS": " password, token ,"})
+	require.Equal(t, []string{"password", "token"}, opts.MaskKeys)
+}
@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+const (
+	otelScopeName       = "cruder"
+	otelShutdownTimeout = 5 * time.Second
+)
+
+// otelHandler is a slog.Handler that emits records through an OpenTelemetry
+// logger. The context passed to Handle is forwarded to Emit, so the SDK
+// attaches the trace and span ids of any active span automatically.
+type otelHandler struct {
+	logger otellog.Logger
+	level  slog.Leveler
+	attrs  []otellog.KeyValue
+	prefix string
+	masker keyMasker
+}
+
+func newOTelHandler(provider otellog.LoggerProvider, level slog.Leveler, masker keyMasker) *otelHandler {
+	return &otelHandler{
+		logger: provider.Logger(otelScopeName),
+		level:  level,
+		masker: masker,
+	}
+}
+
+func (h *otelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *otelHandler) Handle(ctx context.Context, record slog.Record) error {
+	var rec otellog.Record
+	rec.SetTimestamp(record.Time)
+	rec.SetObservedTimestamp(time.Now())
+	rec.SetBody(otellog.StringValue(record.Message))
+	rec.SetSeverity(otelSeverity(record.Level))
+	rec.SetSeverityText(record.Level.String())
+
+	attrs := make([]otellog.KeyValue, 0, len(h.attrs)+record.NumAttrs())
+	attrs = append(attrs, h.attrs...)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = appendOTelAttr(attrs, h.masker, h.prefix, attr)
+		return true
+	})
+	rec.AddAttributes(attrs...)
+
+	h.logger.Emit(ctx, rec)
+	return nil
+}
+
+func (h *otelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = make([]otellog.KeyValue, len(h.attrs), len(h.attrs)+len(attrs))
+	copy(clone.attrs, h.attrs)
+	for _, attr := range attrs {
+		clone.attrs = appendOTelAttr(clone.attrs, h.masker, h.prefix, attr)
+	}
+	return &clone
+}
+
+func (h *otelHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+func appendOTelAttr(attrs []otellog.KeyValue, masker keyMasker, prefix string, attr slog.Attr) []otellog.KeyValue {
+	attr.Value = attr.Value.Resolve()
+	attr = masker.mask(attr)
+	if attr.Equal(slog.Attr{}) {
+		return attrs
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if attr.Key != "" {
+			groupPrefix = prefix + attr.Key + "."
+		}
+		for _, member := range attr.Value.Group() {
+			attrs = appendOTelAttr(attrs, masker, groupPrefix, member)
+		}
+		return attrs
+	}
+	return append(attrs, otellog.KeyValue{Key: prefix + attr.Key, Value: otelValue(attr.Value)})
+}
+
+func otelValue(value slog.Value) otellog.Value {
+	switch value.Kind() {
+	case slog.KindString:
+		return otellog.StringValue(value.String())
+	case slog.KindInt64:
+		return otellog.Int64Value(value.Int64())
+	case slog.KindUint64:
+		return otellog.Int64Value(int64(value.Uint64()))
+	case slog.KindFloat64:
+		return otellog.Float64Value(value.Float64())
+	case slog.KindBool:
+		return otellog.BoolValue(value.Bool())
+	case slog.KindDuration:
+		return otellog.StringValue(value.Duration().String())
+	case slog.KindTime:
+		return otellog.StringValue(value.Time().Format(time.RFC3339Nano))
+	default:
+		if err, ok := value.Any().(error); ok {
+			return otellog.StringValue(err.Error())
+		}
+		return otellog.StringValue(fmt.Sprint(value.Any()))
+	}
+}
+
+func otelSeverity(level slog.Level) otellog.Severity {
+	switch {
+	case level < slog.LevelInfo:
+		return otellog.SeverityDebug
+	case level < slog.LevelWarn:
+		return otellog.SeverityInfo
+	case level < slog.LevelError:
+		return otellog.SeverityWarn
+	default:
+		return otellog.SeverityError
+	}
+}
+
+// newOTLPProvider builds a provider that batches records to an OTLP/HTTP
+// collector configured through the standard OTEL_EXPORTER_OTLP_* variables.
+func newOTLPProvider() (*sdklog.LoggerProvider, error) {
+	exporter, err := otlploghttp.New(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("create otlp log exporter: %w", err)
+	}
+	return sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)),
+	), nil
+}
+
+// providerCloser flushes and shuts down an SDK provider when the logger closes.
+type providerCloser struct {
+	provider *sdklog.LoggerProvider
+}
+
+func (c providerCloser) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), otelShutdownTimeout)
+	defer cancel()
+	return c.provider.Shutdown(ctx)
+}
+
+var _ io.Closer = providerCloser{}
+
+// fanoutHandler dispatches each record to every handler that accepts it.
+type fanoutHandler []slog.Handler
+
+func (f fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range f {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanoutHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, h := range f {
+		if !h.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, record.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanoutHandler, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (f fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make(fanoutHandler, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type memoryExporter struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (e *memoryExporter) Export(_ context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, record := range records {
+		e.records = append(e.records, record.Clone())
+	}
+	return nil
+}
+
+func (e *memoryExporter) Shutdown(context.Context) error   { return nil }
+func (e *memoryExporter) ForceFlush(context.Context) error { return nil }
+
+func (e *memoryExporter) Records() []sdklog.Record {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]sdklog.Record(nil), e.records...)
+}
+
+func newMemoryLogger(t *testing.T, level string) (*Logger, *memoryExporter) {
+	t.Helper()
+	exporter := &memoryExporter{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	l, err := newLogger(normalizeOptions(Options{
+		Output:       OutputOTel,
+		Level:        level,
+		OTelProvider: provider,
+	}))
+	require.NoError(t, err)
+	return l, exporter
+}
+
+func recordAttributes(record sdklog.Record) map[string]otellog.Value {
+	attrs := map[string]otellog.Value{}
+	record.WalkAttributes(func(kv otellog.KeyValue) bool {
+		attrs[kv.Key] = kv.Value
+		return true
+	})
+	return attrs
+}
+
+func TestOTelLogs_EmitsRecordWithTraceContext(t *testing.T) {
+	// Given: a logger routed to an in-memory OTel exporter
+	l, exporter := newMemoryLogger(t, "info")
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03},
+		SpanID:     trace.SpanID{0x04, 0x05},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanContext)
+
+	// When: logging with component attributes inside a traced context
+	l.With(slog.String("component", "service.user")).
+		InfoContext(ctx, "user created", slog.Int64("user.id", 42), slog.Group("http", slog.Int("status", 201)))
+
+	// Then: one record carries the message, attributes, severity, and trace ids
+	records := exporter.Records()
+	require.Len(t, records, 1)
+	record := records[0]
+	require.Equal(t, "user created", record.Body().AsString())
+	require.Equal(t, otellog.SeverityInfo, record.Severity())
+	require.Equal(t, spanContext.TraceID(), record.TraceID())
+	require.Equal(t, spanContext.SpanID(), record.SpanID())
+
+	attrs := recordAttributes(record)
+	require.Equal(t, "service.user", attrs["component"].AsString())
+	require.Equal(t, int64(42), attrs["user.id"].AsInt64())
+	require.Equal(t, int64(201), attrs["http.status"].AsInt64())
+}
+
+func TestOTelLogs_BoundLoggerCarriesTraceContext(t *testing.T) {
+	// Given: a logger bound to a traced request context
+	l, exporter := newMemoryLogger(t, "info")
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x0a},
+		SpanID:  trace.SpanID{0x0b},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanContext)
+	bound := l.BindContext(ctx)
+
+	// When: logging through it, and through a child, without passing ctx
+	bound.Warn("request rejected")
+	bound.With(slog.String("component", "controller.users")).Info("user fetched")
+
+	// Then: both records carry the trace ids
+	records := exporter.Records()
+	require.Len(t, records, 2)
+	for _, record := range records {
+		require.Equal(t, spanContext.TraceID(), record.TraceID())
+		require.Equal(t, spanContext.SpanID(), record.SpanID())
+	}
+}
+
+func TestOTelLogs_RespectsLevel(t *testing.T) {
+	l, exporter := newMemoryLogger(t, "warn")
+
+	l.Info("dropped")
+	l.Warn("kept")
+
+	records := exporter.Records()
+	require.Len(t, records, 1)
+	require.Equal(t, "kept", records[0].Body().AsString())
+	require.Equal(t, otellog.SeverityWarn, records[0].Severity())
+}
+
+func TestOptionsFromEnv_OTelLogs(t *testing.T) {
+	require.True(t, OptionsFromEnv(map[string]string{"OTEL_LOGS": "true"}).OTelLogs)
+	require.False(t, OptionsFromEnv(map[string]string{"OTEL_LOGS": "nope"}).OTelLogs)
+	require.True(t, OptionsFromEnv(map[string]string{"LOG_OUTPUT": "otel"}).OTelLogs)
+}
@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	bytesPerMB          = 1 << 20
+	backupTimestampForm = "20060102T150405.000000000"
+)
+
+// rotatingFile is an append-only log file that is renamed with a timestamp
+// suffix and replaced by a fresh file once it would grow past maxBytes.
+// Writes and rotation share one mutex, so concurrent writers never interleave
+// with a rename.
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxBytes   int64
+	maxBackups int
+	file       *os.File
+	size       int64
+	// rename is os.Rename, replaced in tests to make rotation fail.
+	rename func(oldpath, newpath string) error
+	// rotateFailed is set while rotation keeps failing, so the failure is
+	// reported once rather than on every write.
+	rotateFailed bool
+}
+
+func newRotatingFile(path string, maxBytes int64, maxBackups int) (*rotatingFile, error) {
+	r := &rotatingFile{
+		path:       path,
+		maxBytes:   maxBytes,
+		maxBackups: maxBackups,
+		rename:     os.Rename,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.size > 0 && r.size+int64(len(p)) > r.maxBytes {
+		err := r.rotate()
+		switch {
+		case err == nil:
+			r.rotateFailed = false
+		case r.file == nil:
+			return 0, err
+		case !r.rotateFailed:
+			// slog drops write errors, so this is the only trace of it
+			r.rotateFailed = true
+			fmt.Fprintf(os.Stderr, "log rotation failed, appending to %s past its size limit: %v\n", r.path, err)
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func (r *rotatingFile) open() error {
+	f, err := openLogFile(r.path)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("stat log file: %w", err)
+	}
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+// rotate moves the log aside and opens a fresh one. When the rename fails,
+// as on a full disk, it reopens the log where it was so writing carries on;
+// r.file is nil afterwards only if no file could be opened at all.
+func (r *rotatingFile) rotate() error {
+	closeErr := r.file.Close()
+	r.file = nil
+
+	var renameErr error
+	if closeErr == nil {
+		renameErr = r.rename(r.path, r.backupName(time.Now()))
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return fmt.Errorf("close log file: %w", closeErr)
+	}
+	if renameErr != nil {
+		return fmt.Errorf("rename log file: %w", renameErr)
+	}
+	return r.pruneBackups()
+}
+
+func (r *rotatingFile) backupName(now time.Time) string {
+	ext := filepath.Ext(r.path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(r.path, ext), now.UTC().Format(backupTimestampForm), ext)
+}
+
+// pruneBackups removes the oldest backups beyond maxBackups. The timestamp
+// suffix sorts lexically, so name order is age order.
+func (r *rotatingFile) pruneBackups() error {
+	if r.maxBackups <= 0 {
+		return nil
+	}
+	backups, err := r.backups()
+	if err != nil {
+		return err
+	}
+	if len(backups) <= r.maxBackups {
+		return nil
+	}
+	sort.Strings(backups)
+	for _, old := range backups[:len(backups)-r.maxBackups] {
+		if err := os.Remove(old); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove log backup: %w", err)
+		}
+	}
+	return nil
+}
+
+// backups lists the files backupName made for r.path. Names that share the
+// prefix but carry no timestamp, such as app-access.log next to app.log, are
+// left out, so pruning never counts or removes them.
+func (r *rotatingFile) backups() ([]string, error) {
+	ext := filepath.Ext(r.path)
+	prefix := strings.TrimSuffix(r.path, ext) + "-"
+	matches, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return nil, fmt.Errorf("list log backups: %w", err)
+	}
+	backups := matches[:0]
+	for _, name := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		if _, err := time.Parse(backupTimestampForm, stamp); err == nil {
+			backups = append(backups, name)
+		}
+	}
+	return backups, nil
+}
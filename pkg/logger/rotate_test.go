@@ -0,0 +1,176 @@
+package logger
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func logBackups(t *testing.T, path string) []string {
+	t.Helper()
+	backups, err := (&rotatingFile{path: path}).backups()
+	require.NoError(t, err)
+	return backups
+}
+
+func TestRotatingFile_RotatesWhenSizeExceeded(t *testing.T) {
+	// Given: a log file capped at 32 bytes
+	path := filepath.Join(t.TempDir(), "app.json")
+	f, err := newRotatingFile(path, 32, 0)
+	require.NoError(t, err)
+	defer f.Close()
+
+	// When: writing past the cap
+	_, err = f.Write([]byte(strings.Repeat("a", 20) + "\n"))
+	require.NoError(t, err)
+	_, err = f.Write([]byte(strings.Repeat("b", 20) + "\n"))
+	require.NoError(t, err)
+
+	// Then: the first line moved to a timestamped backup and the active file holds the second
+	backups := logBackups(t, path)
+	require.Len(t, backups, 1)
+	old, err := os.ReadFile(backups[0])
+	require.NoError(t, err)
+	require.Equal(t, strings.Repeat("a", 20)+"\n", string(old))
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, strings.Repeat("b", 20)+"\n", string(current))
+}
+
+func TestRotatingFile_KeepsAtMostMaxBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	f, err := newRotatingFile(path, 8, 2)
+	require.NoError(t, err)
+	defer f.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := fmt.Fprintf(f, "line-%d\n", i)
+		require.NoError(t, err)
+	}
+
+	backups := logBackups(t, path)
+	require.Len(t, backups, 2)
+	newest, err := os.ReadFile(backups[1])
+	require.NoError(t, err)
+	require.Equal(t, "line-3\n", string(newest))
+}
+
+func TestRotatingFile_PruningSparesUnrelatedFiles(t *testing.T) {
+	// Given: another log whose name starts like this one's backups
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.json")
+	access := filepath.Join(dir, "app-access.json")
+	require.NoError(t, os.WriteFile(access, []byte("access\n"), 0o600))
+	f, err := newRotatingFile(path, 8, 1)
+	require.NoError(t, err)
+	defer f.Close()
+
+	// When: rotating often enough to prune
+	for i := 0; i < 4; i++ {
+		_, err := fmt.Fprintf(f, "line-%d\n", i)
+		require.NoError(t, err)
+	}
+
+	// Then: the other log is neither counted nor removed
+	require.Len(t, logBackups(t, path), 1)
+	content, err := os.ReadFile(access)
+	require.NoError(t, err)
+	require.Equal(t, "access\n", string(content))
+}
+
+func TestRotatingFile_KeepsWritingWhenRotationFails(t *testing.T) {
+	// Given: a log whose backup can't be created, as across devices
+	path := filepath.Join(t.TempDir(), "app.json")
+	f, err := newRotatingFile(path, 8, 0)
+	require.NoError(t, err)
+	defer f.Close()
+	f.rename = func(string, string) error { return syscall.EXDEV }
+
+	// When: writing past the cap
+	for i := 0; i < 3; i++ {
+		_, err := fmt.Fprintf(f, "line-%d\n", i)
+		require.NoError(t, err)
+	}
+
+	// Then: every line is appended to the original file
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "line-0\nline-1\nline-2\n", string(content))
+	require.Empty(t, logBackups(t, path))
+
+	// When: rotation works again
+	f.rename = os.Rename
+	_, err = f.Write([]byte("line-3\n"))
+
+	// Then: the oversized file is rotated away
+	require.NoError(t, err)
+	require.Len(t, logBackups(t, path), 1)
+	content, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "line-3\n", string(content))
+}
+
+func TestRotatingFile_ContinuesExistingFileSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("x", 30)), 0o600))
+
+	f, err := newRotatingFile(path, 32, 0)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.Write([]byte("overflow\n"))
+	require.NoError(t, err)
+
+	require.Len(t, logBackups(t, path), 1)
+}
+
+func TestRotatingFile_ConcurrentWritesStayIntact(t *testing.T) {
+	// Given: a small cap so rotation happens while many goroutines write
+	path := filepath.Join(t.TempDir(), "app.json")
+	f, err := newRotatingFile(path, 256, 0)
+	require.NoError(t, err)
+
+	const writers, lines = 8, 50
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				_, _ = fmt.Fprintf(f, "writer-%d line-%03d\n", w, i)
+			}
+		}(w)
+	}
+	wg.Wait()
+	require.NoError(t, f.Close())
+
+	// Then: every line landed whole in exactly one file
+	total := 0
+	for _, name := range append(logBackups(t, path), path) {
+		file, err := os.Open(name)
+		require.NoError(t, err)
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			require.Regexp(t, `^writer-\d line-\d{3}$`, scanner.Text())
+			total++
+		}
+		require.NoError(t, file.Close())
+	}
+	require.Equal(t, writers*lines, total)
+}
+
+func TestOptionsFromEnv_Rotation(t *testing.T) {
+	opts := OptionsFromEnv(map[string]string{"LOG_MAX_SIZE_MB": "100", "LOG_MAX_BACKUPS": "5"})
+	require.Equal(t, 100, opts.MaxSizeMB)
+	require.Equal(t, 5, opts.MaxBackups)
+
+	opts = OptionsFromEnv(map[string]string{"LOG_MAX_SIZE_MB": "-1", "LOG_MAX_BACKUPS": "many"})
+	require.Zero(t, opts.MaxSizeMB)
+	require.Zero(t, opts.MaxBackups)
+}